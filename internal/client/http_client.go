@@ -1,11 +1,21 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"resty.dev/v3"
 )
@@ -27,27 +37,234 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
 }
 
-// NewHTTPClient creates a new HTTPClient with basic auth and JSON headers.
-func NewHTTPClient(baseURL, username, password string) *HTTPClient {
+// RetriableError marks a failure that may succeed if the request is repeated: a
+// connection failure, a timeout, 429 Too Many Requests or a 5xx response. It wraps
+// the original error, so errors.As still finds an HTTPError underneath.
+type RetriableError struct {
+	Err error
+}
+
+func (e *RetriableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetriableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetriable reports whether err, or any error it wraps, is a RetriableError.
+func IsRetriable(err error) bool {
+	var retriable *RetriableError
+	return errors.As(err, &retriable)
+}
+
+// classifyError wraps err in a RetriableError when repeating the request could succeed.
+// Logic errors such as 4xx responses and cancellation by the caller are returned as-is.
+func classifyError(err error) error {
+	if err == nil || IsRetriable(err) {
+		return err
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError {
+			return &RetriableError{Err: err}
+		}
+		return err
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(),
+		errors.As(err, &opErr),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return &RetriableError{Err: err}
+	}
+	return err
+}
+
+// ConflictError marks a write rejected because the resource changed since it was read:
+// 409 Conflict, or 412 Precondition Failed when an If-Match ETag no longer matches.
+// Callers re-read the resource and retry. It wraps the original HTTPError.
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// IsConflict reports whether err, or any error it wraps, is a ConflictError.
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// classifyConflict wraps err in a ConflictError when it is a 409 or 412 response.
+func classifyConflict(err error) error {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusConflict || httpErr.StatusCode == http.StatusPreconditionFailed) {
+		return &ConflictError{Err: err}
+	}
+	return err
+}
+
+// Backend names reported by BackendUnavailableError.
+const (
+	BackendNexus    = "nexus"
+	BackendIQServer = "iq_server"
+)
+
+// BackendUnavailableError is returned by Ping when a backend cannot be reached or
+// does not respond successfully. Backend names which one is down.
+type BackendUnavailableError struct {
+	Backend string
+	Err     error
+}
+
+func (e *BackendUnavailableError) Error() string {
+	return fmt.Sprintf("%s unavailable: %v", e.Backend, e.Err)
+}
+
+func (e *BackendUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseHeaders holds the response headers callers act on after a successful
+// request: Location names a newly created resource and ETag identifies the version
+// that was returned, for conditional follow-up requests.
+type ResponseHeaders struct {
+	Location string
+	ETag     string
+}
+
+// HeadersOf extracts the ResponseHeaders from resp. A relative Location is resolved
+// against the request URL, so callers always get an absolute URL.
+func HeadersOf(resp *resty.Response) ResponseHeaders {
+	if resp == nil || resp.RawResponse == nil {
+		return ResponseHeaders{}
+	}
+	headers := ResponseHeaders{
+		Location: resp.Header().Get("Location"),
+		ETag:     resp.Header().Get("ETag"),
+	}
+	if headers.Location != "" && resp.RawResponse.Request != nil {
+		if loc, err := resp.RawResponse.Request.URL.Parse(headers.Location); err == nil {
+			headers.Location = loc.String()
+		}
+	}
+	return headers
+}
+
+// HTTPClientOption customizes an HTTPClient at construction time.
+type HTTPClientOption func(*resty.Client)
+
+// WithTransport routes requests through rt instead of the default transport, so tests
+// can stub responses and production code can add interceptors (logging, tracing).
+func WithTransport(rt http.RoundTripper) HTTPClientOption {
+	return func(c *resty.Client) {
+		c.SetTransport(rt)
+	}
+}
+
+// TransportSettings tunes how connections to a backend are kept open and reused.
+// Zero fields take the config.DefaultHTTP* defaults.
+type TransportSettings struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+}
+
+// transport returns an http.Transport like http.DefaultTransport, with connection
+// reuse tuned by s.
+func (s TransportSettings) transport() *http.Transport {
+	if s.MaxIdleConns <= 0 {
+		s.MaxIdleConns = config.DefaultHTTPMaxIdleConns
+	}
+	if s.MaxIdleConnsPerHost <= 0 {
+		s.MaxIdleConnsPerHost = config.DefaultHTTPMaxIdleConnsPerHost
+	}
+	if s.IdleConnTimeout <= 0 {
+		s.IdleConnTimeout = config.DefaultHTTPIdleConnTimeout
+	}
+	if s.KeepAlive <= 0 {
+		s.KeepAlive = config.DefaultHTTPKeepAlive
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: s.KeepAlive}).DialContext
+	t.MaxIdleConns = s.MaxIdleConns
+	t.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	t.IdleConnTimeout = s.IdleConnTimeout
+	return t
+}
+
+// WithTransportSettings replaces the default connection reuse settings with s.
+func WithTransportSettings(s TransportSettings) HTTPClientOption {
+	return func(c *resty.Client) {
+		c.SetTransport(s.transport())
+	}
+}
+
+// NewHTTPClient creates a new HTTPClient with basic auth, JSON headers and the given
+// per-request timeout, then applies opts. Connections are reused as the default
+// TransportSettings allow, so concurrent batch workers keep their connections open.
+func NewHTTPClient(baseURL, username, password string, timeout time.Duration, opts ...HTTPClientOption) *HTTPClient {
 	baseURL = strings.TrimSuffix(baseURL, "/")
-	return &HTTPClient{
-		client: resty.New().
-			SetBaseURL(baseURL).
-			SetHeader("Accept", "application/json").
-			SetHeader("Content-Type", "application/json").
-			SetBasicAuth(username, password).
-			SetTimeout(30 * time.Second),
+	client := resty.New().
+		SetTransport(TransportSettings{}.transport()).
+		SetBaseURL(baseURL).
+		SetHeader("Accept", "application/json").
+		SetHeader("Content-Type", "application/json").
+		SetBasicAuth(username, password).
+		SetTimeout(timeout)
+	for _, opt := range opts {
+		opt(client)
 	}
+	return &HTTPClient{client: client}
 }
 
 // DoReq performs an HTTP request with the given method, endpoint, body, and query params.
-// Logs errors for 4xx/5xx responses and truncates long bodies.
-func (c *HTTPClient) DoReq(method, endpoint string, body any, params map[string]string) (*resty.Response, error) {
+// See DoReqWithHeaders.
+func (c *HTTPClient) DoReq(ctx context.Context, method, endpoint string, body any, params map[string]string) (*resty.Response, error) {
+	return c.DoReqWithHeaders(ctx, method, endpoint, body, params, nil)
+}
+
+// DoReqWithHeaders performs an HTTP request like DoReq, adding headers (e.g. If-Match)
+// to the client's defaults.
+// Logs errors for 4xx/5xx responses and truncates long bodies. Connection failures,
+// timeouts, 429 and 5xx responses are returned as a RetriableError. Each call is
+// recorded as a client span that is a child of any span carried by ctx.
+func (c *HTTPClient) DoReqWithHeaders(ctx context.Context, method, endpoint string, body any, params, headers map[string]string) (*resty.Response, error) {
+	ctx, span := utils.Tracer().Start(ctx, "HTTP "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("http.endpoint", endpoint),
+		))
+	defer span.End()
+
 	request := c.client.R().
+		SetContext(ctx).
 		SetBody(body).
 		SetQueryParams(params)
+	for name, value := range headers {
+		request.SetHeader(name, value)
+	}
 
-	utils.Logger.Debug("HTTP request start",
+	logger := utils.WithComponentContext(ctx, "http_client")
+	logger.Debug("HTTP request start",
 		zap.String("method", method),
 		zap.String("endpoint", endpoint))
 
@@ -55,13 +272,17 @@ func (c *HTTPClient) DoReq(method, endpoint string, body any, params map[string]
 	response, err := request.Execute(method, endpoint)
 	duration := time.Since(start)
 	if err != nil {
-		utils.Logger.Error("HTTP request failed",
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.Error("HTTP request failed",
 			zap.String("method", method),
 			zap.String("endpoint", endpoint),
 			zap.Error(err))
-		return nil, err
+		return nil, classifyError(err)
 	}
 
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode()))
+
 	// When status >= 400, log differently for 404 (common existence check) vs other errors
 	if response.StatusCode() >= 400 {
 		responseBody := strings.TrimSpace(response.String())
@@ -70,7 +291,7 @@ func (c *HTTPClient) DoReq(method, endpoint string, body any, params map[string]
 		}
 		if response.StatusCode() == 404 {
 			// 404 is often used to detect non-existence; quieter debug-level log to reduce noise
-			utils.Logger.Debug("API returned 404 (resource not found)",
+			logger.Debug("API returned 404 (resource not found)",
 				zap.String("method", method),
 				zap.String("url", response.Request.URL),
 				zap.Int("status_code", response.StatusCode()),
@@ -78,7 +299,7 @@ func (c *HTTPClient) DoReq(method, endpoint string, body any, params map[string]
 				zap.Duration("duration", duration))
 		} else if response.StatusCode() >= 500 {
 			// Server errors are noteworthy
-			utils.Logger.Error("API error response (server)",
+			logger.Error("API error response (server)",
 				zap.String("method", method),
 				zap.String("url", response.Request.URL),
 				zap.Int("status_code", response.StatusCode()),
@@ -86,17 +307,18 @@ func (c *HTTPClient) DoReq(method, endpoint string, body any, params map[string]
 				zap.Duration("duration", duration))
 		} else {
 			// Client errors (other than 404) are warnings
-			utils.Logger.Warn("API error response (client)",
+			logger.Warn("API error response (client)",
 				zap.String("method", method),
 				zap.String("url", response.Request.URL),
 				zap.Int("status_code", response.StatusCode()),
 				zap.String("body", responseBody),
 				zap.Duration("duration", duration))
 		}
-		return nil, &HTTPError{StatusCode: response.StatusCode(), Body: responseBody}
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", response.StatusCode()))
+		return nil, classifyError(&HTTPError{StatusCode: response.StatusCode(), Body: responseBody})
 	}
 
-	utils.Logger.Debug("HTTP request completed",
+	logger.Debug("HTTP request completed",
 		zap.String("method", method),
 		zap.String("url", response.Request.URL),
 		zap.Int("status_code", response.StatusCode()),
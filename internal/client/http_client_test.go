@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// timeoutError mimics the net.Error returned when a client timeout fires.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	t.Run("Timeout is retriable", func(t *testing.T) {
+		err := classifyError(&url.Error{Op: "Get", URL: "http://nexus/v1/status", Err: timeoutError{}})
+		assert.True(t, IsRetriable(err))
+	})
+
+	t.Run("Deadline exceeded is retriable", func(t *testing.T) {
+		assert.True(t, IsRetriable(classifyError(fmt.Errorf("request: %w", context.DeadlineExceeded))))
+	})
+
+	t.Run("Connection refused is retriable", func(t *testing.T) {
+		err := classifyError(&url.Error{Op: "Get", URL: "http://nexus", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}})
+		assert.True(t, IsRetriable(err))
+	})
+
+	t.Run("429 and 5xx are retriable and still expose the HTTPError", func(t *testing.T) {
+		for _, code := range []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+			err := classifyError(&HTTPError{StatusCode: code})
+			assert.True(t, IsRetriable(err), code)
+			var httpErr *HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, code, httpErr.StatusCode)
+		}
+	})
+
+	t.Run("400 is not retriable", func(t *testing.T) {
+		err := classifyError(&HTTPError{StatusCode: http.StatusBadRequest})
+		assert.False(t, IsRetriable(err))
+	})
+
+	t.Run("Cancellation is not retriable", func(t *testing.T) {
+		assert.False(t, IsRetriable(classifyError(context.Canceled)))
+	})
+
+	t.Run("Wrapping survives fmt.Errorf", func(t *testing.T) {
+		err := fmt.Errorf("get user 'u': %w", classifyError(&HTTPError{StatusCode: http.StatusBadGateway}))
+		assert.True(t, IsRetriable(err))
+	})
+}
+
+func TestBackendUnavailableError(t *testing.T) {
+	cause := &HTTPError{StatusCode: http.StatusServiceUnavailable}
+	err := fmt.Errorf("startup check: %w", &BackendUnavailableError{Backend: BackendIQServer, Err: cause})
+
+	var unavailable *BackendUnavailableError
+	assert.True(t, errors.As(err, &unavailable))
+	assert.Equal(t, BackendIQServer, unavailable.Backend)
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.Error(), "iq_server unavailable")
+}
+
+// stubTransport answers every request with a fixed status, headers and body, recording
+// the last request.
+type stubTransport struct {
+	status int
+	header http.Header
+	body   string
+	last   *http.Request
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.last = req
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	for k, v := range s.header {
+		header[k] = v
+	}
+	return &http.Response{
+		StatusCode: s.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestDoReq_WithTransport(t *testing.T) {
+	newClient := func(rt http.RoundTripper) *HTTPClient {
+		return NewHTTPClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, WithTransport(rt))
+	}
+
+	t.Run("Success returns the response", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusOK, body: `{"name":"npm-release-app1"}`}
+		resp, err := newClient(rt).DoReq(context.Background(), "GET", "/v1/repositories/npm-release-app1", nil, map[string]string{"a": "b"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"name":"npm-release-app1"}`, resp.String())
+		assert.Equal(t, "/service/rest/v1/repositories/npm-release-app1", rt.last.URL.Path)
+		assert.Equal(t, "b", rt.last.URL.Query().Get("a"))
+		user, pass, ok := rt.last.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "admin", user)
+		assert.Equal(t, "secret", pass)
+	})
+
+	t.Run("Location and ETag are exposed", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusCreated, header: http.Header{
+			"Location": []string{"../repositories/npm-release-app1"},
+			"Etag":     []string{`"v2"`},
+		}}
+		resp, err := newClient(rt).DoReq(context.Background(), "POST", "/v1/security/roles", nil, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ResponseHeaders{
+			Location: "http://nexus.test/service/rest/v1/repositories/npm-release-app1",
+			ETag:     `"v2"`,
+		}, HeadersOf(resp))
+	})
+
+	t.Run("404 is a non-retriable HTTPError", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusNotFound, body: "not found"}
+		_, err := newClient(rt).DoReq(context.Background(), "GET", "/v1/repositories/missing", nil, nil)
+
+		var httpErr *HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Equal(t, "not found", httpErr.Body)
+		assert.False(t, IsRetriable(err))
+	})
+
+	t.Run("500 is a retriable HTTPError", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusInternalServerError, body: "  boom  "}
+		_, err := newClient(rt).DoReq(context.Background(), "POST", "/v1/security/roles", map[string]string{"id": "r"}, nil)
+
+		var httpErr *HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+		assert.Equal(t, "boom", httpErr.Body)
+		assert.True(t, IsRetriable(err))
+	})
+}
+
+func TestNewHTTPClient_ReusesConnections(t *testing.T) {
+	var mu sync.Mutex
+	opened := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			opened++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+	connections := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return opened
+	}
+
+	const workers = 10
+	c := NewHTTPClient(server.URL, "admin", "secret", time.Second,
+		WithTransportSettings(TransportSettings{MaxIdleConnsPerHost: workers}))
+	round := func() {
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Go(func() {
+				_, err := c.DoReq(context.Background(), "GET", "/v1/status", nil, nil)
+				assert.NoError(t, err)
+			})
+		}
+		wg.Wait()
+	}
+
+	round()
+	afterFirst := connections()
+	assert.LessOrEqual(t, afterFirst, workers)
+
+	// Idle connections are returned to the pool asynchronously
+	time.Sleep(50 * time.Millisecond)
+	for range 3 {
+		round()
+	}
+	assert.Equal(t, afterFirst, connections(), "later rounds should reuse the idle connections")
+}
+
+func TestTransportSettings_Defaults(t *testing.T) {
+	transport := TransportSettings{}.transport()
+	assert.Equal(t, config.DefaultHTTPMaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, config.DefaultHTTPMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, config.DefaultHTTPIdleConnTimeout, transport.IdleConnTimeout)
+
+	transport = TransportSettings{MaxIdleConns: 5, MaxIdleConnsPerHost: 2, IdleConnTimeout: time.Second}.transport()
+	assert.Equal(t, 5, transport.MaxIdleConns)
+	assert.Equal(t, 2, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Second, transport.IdleConnTimeout)
+}
@@ -1,32 +1,59 @@
 package client
 
-import "github.com/anmicius0/sonatype-resource-automation/internal/config"
+import (
+	"context"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+)
 
 // NexusClient defines the operations we perform against a Nexus repository manager.
 // Use the concrete NewNexusClient to obtain an implementation that satisfies this
 // interface.
 type NexusClient interface {
-	GetRepository(name string) (*Repository, error)
-	GetRepositories() ([]Repository, error)
-	CreateProxyRepository(config *config.OperationConfig) error
-	DeleteRepository(name string) error
-	GetPrivilege(name string) (*Privilege, error)
-	GetPrivileges() ([]Privilege, error)
-	CreatePrivilege(config *config.OperationConfig) error
-	DeletePrivilege(name string) error
-	GetRole(name string) (*Role, error)
-	CreateRole(config *config.OperationConfig) error
-	UpdateRole(role *Role) error
-	DeleteRole(name string) error
-	GetUser(username string) (*User, error)
-	UpdateUser(user *User) error
+	GetRepository(ctx context.Context, name string) (*Repository, error)
+	RepositoryExists(ctx context.Context, name string) (bool, error)
+	GetRepositories(ctx context.Context) ([]Repository, error)
+	// GetRepositoriesByFormat returns the repositories of one format, such as "npm"
+	GetRepositoriesByFormat(ctx context.Context, format string) ([]Repository, error)
+	CreateProxyRepository(ctx context.Context, config *config.OperationConfig) (string, error)
+	DeleteRepository(ctx context.Context, name string) error
+	SetRepositoryOnline(ctx context.Context, name string, online bool) error
+	InvalidateCache(ctx context.Context, repositoryName string) error
+	GetPrivilege(ctx context.Context, name string) (*Privilege, error)
+	PrivilegeExists(ctx context.Context, name string) (bool, error)
+	GetPrivileges(ctx context.Context) ([]Privilege, error)
+	CreatePrivilege(ctx context.Context, config *config.OperationConfig) error
+	DeletePrivilege(ctx context.Context, name string) error
+	GetRole(ctx context.Context, name string) (*Role, error)
+	RoleExists(ctx context.Context, name string) (bool, error)
+	GetRolesByPrivilege(ctx context.Context, privilegeName string) ([]Role, error)
+	CreateRole(ctx context.Context, config *config.OperationConfig) error
+	UpdateRole(ctx context.Context, role *Role) error
+	DeleteRole(ctx context.Context, name string) error
+	GetUser(ctx context.Context, username string) (*User, error)
+	GetUsersByRole(ctx context.Context, roleName string) ([]User, error)
+	CreateUser(ctx context.Context, user *User) error
+	UpdateUser(ctx context.Context, user *User) error
+	DeleteUser(ctx context.Context, userID string) error
+	Ping(ctx context.Context) error
 }
 
 // IQClient defines the operations we perform against an IQ Server instance.
 // Use NewIQServerClient to create a real implementation.
 type IQClient interface {
-	GetRoles() ([]IQRole, error)
-	FindOwnerRoleID() (string, error)
-	AddOwnerRoleToUser(opConfig *config.OperationConfig) error
-	RemoveOwnerRoleFromUser(opConfig *config.OperationConfig) error
+	GetRoles(ctx context.Context) ([]IQRole, error)
+	FindOwnerRoleID(ctx context.Context) (string, error)
+	// InvalidateRoleCache forgets the owner role ID cached by FindOwnerRoleID
+	InvalidateRoleCache()
+	AddOwnerRoleToUser(ctx context.Context, opConfig *config.OperationConfig) error
+	// RemoveOwnerRoleFromUser reports whether a membership was actually removed
+	RemoveOwnerRoleFromUser(ctx context.Context, opConfig *config.OperationConfig) (bool, error)
+	GetOrganizationAncestors(ctx context.Context, organizationID string) ([]string, error)
+	// FindOrganization returns the ID of the organization called name, or "" when IQ
+	// Server has none
+	FindOrganization(ctx context.Context, name string) (string, error)
+	// CreateOrganization returns the ID of the organization called name, creating it
+	// only when IQ Server does not have one yet
+	CreateOrganization(ctx context.Context, name string) (string, error)
+	Ping(ctx context.Context) error
 }
@@ -1,10 +1,15 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
@@ -15,18 +20,49 @@ import (
 // It is intentionally unexported so callers use the IQClient interface.
 type iqServerClient struct {
 	*HTTPClient
+	ownerRoleName string
+
+	// ownerRoleID caches the ID found by FindOwnerRoleID until InvalidateRoleCache
+	mu          sync.Mutex
+	ownerRoleID string
 }
 
-// NewIQServerClient creates a new IQServerClient instance.
-func NewIQServerClient(url, username, password string) IQClient {
+// NewIQServerClient creates a new IQServerClient instance whose requests time out after
+// timeout. ownerRoleName names the role granted to users; empty means
+// config.DefaultIQOwnerRoleName.
+func NewIQServerClient(url, username, password string, timeout time.Duration, ownerRoleName string, opts ...HTTPClientOption) IQClient {
+	if ownerRoleName == "" {
+		ownerRoleName = config.DefaultIQOwnerRoleName
+	}
 	return &iqServerClient{
-		HTTPClient: NewHTTPClient(url, username, password),
+		HTTPClient:    NewHTTPClient(url, username, password, timeout, opts...),
+		ownerRoleName: ownerRoleName,
 	}
 }
 
-// GetRoles fetches all roles from IQ Server, returning empty on 404.
-func (c *iqServerClient) GetRoles() ([]IQRole, error) {
-	response, err := c.DoReq("GET", "/api/v2/roles", nil, nil)
+const (
+	// iqRolesPageSize is the number of roles requested per page
+	iqRolesPageSize = 100
+	// maxIQRolePages stops a misbehaving server from paging forever
+	maxIQRolePages = 1000
+	// maxIQOrganizationDepth stops GetOrganizationAncestors on a cyclic hierarchy
+	maxIQOrganizationDepth = 32
+)
+
+// RootOrganizationID is the ID IQ Server gives the top of the organization hierarchy.
+const RootOrganizationID = "ROOT_ORGANIZATION_ID"
+
+// iqRolesPage is one page of GET /api/v2/roles. Servers that do not paginate omit
+// the page fields and return every role at once.
+type iqRolesPage struct {
+	Roles     []IQRole
+	Page      int
+	PageCount int
+}
+
+// GetRoles fetches all roles from IQ Server, following pagination, returning empty on 404.
+func (c *iqServerClient) GetRoles(ctx context.Context) ([]IQRole, error) {
+	roles, err := collectIQRoles(ctx, c.getRolesPage)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
@@ -34,83 +70,256 @@ func (c *iqServerClient) GetRoles() ([]IQRole, error) {
 		}
 		return nil, fmt.Errorf("get IQ Server roles: %w", err)
 	}
-	var rolesResponse struct {
-		Roles []IQRole
+	return roles, nil
+}
+
+// getRolesPage fetches a single page of roles, numbered from 1.
+func (c *iqServerClient) getRolesPage(ctx context.Context, page int) (*iqRolesPage, error) {
+	params := map[string]string{
+		"page":     strconv.Itoa(page),
+		"pageSize": strconv.Itoa(iqRolesPageSize),
 	}
-	if err := json.Unmarshal(response.Bytes(), &rolesResponse); err != nil {
-		return nil, fmt.Errorf("get IQ Server roles: failed to unmarshal response: %w", err)
+	response, err := c.DoReq(ctx, "GET", "/api/v2/roles", nil, params)
+	if err != nil {
+		return nil, err
+	}
+	var rolesPage iqRolesPage
+	if err := json.Unmarshal(response.Bytes(), &rolesPage); err != nil {
+		return nil, fmt.Errorf("page %d: failed to unmarshal response: %w", page, err)
+	}
+	return &rolesPage, nil
+}
+
+// collectIQRoles requests pages until the server reports the last one, returns an
+// empty page, or does not paginate at all.
+func collectIQRoles(ctx context.Context, fetchPage func(context.Context, int) (*iqRolesPage, error)) ([]IQRole, error) {
+	roles := []IQRole{}
+	for page := 1; page <= maxIQRolePages; page++ {
+		rolesPage, err := fetchPage(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, rolesPage.Roles...)
+		if rolesPage.PageCount == 0 || page >= rolesPage.PageCount || len(rolesPage.Roles) == 0 {
+			return roles, nil
+		}
 	}
-	return rolesResponse.Roles, nil
+	return nil, fmt.Errorf("more than %d pages of roles", maxIQRolePages)
 }
 
-// FindOwnerRoleID searches for the "Owner" role ID among fetched roles.
-func (c *iqServerClient) FindOwnerRoleID() (string, error) {
-	roles, err := c.GetRoles()
+// FindOwnerRoleID searches the fetched roles for the ID of the configured owner role.
+// A found ID is cached, so later calls make no request until InvalidateRoleCache.
+func (c *iqServerClient) FindOwnerRoleID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	cached := c.ownerRoleID
+	c.mu.Unlock()
+	if cached != "" {
+		return cached, nil
+	}
+
+	roles, err := c.GetRoles(ctx)
 	if err != nil {
 		return "", fmt.Errorf("find owner role: get roles failed: %w", err)
 	}
 	for _, role := range roles {
-		if role.Name == "Owner" {
+		if role.Name == c.ownerRoleName {
 			if role.ID != "" {
+				c.mu.Lock()
+				c.ownerRoleID = role.ID
+				c.mu.Unlock()
 				return role.ID, nil
 			}
-			utils.Logger.Warn("'Owner' role found but id is empty")
-			return "", fmt.Errorf("find owner role: 'Owner' role exists but id is missing")
+			utils.Logger.Warn("Owner role found but id is empty", zap.String("role_name", c.ownerRoleName))
+			return "", fmt.Errorf("find owner role: '%s' role exists but id is missing", c.ownerRoleName)
 		}
 	}
-	utils.Logger.Warn("'Owner' role not found in IQ Server")
+	utils.Logger.Warn("Owner role not found in IQ Server", zap.String("role_name", c.ownerRoleName))
 	return "", nil
 }
 
-// AddOwnerRoleToUser adds the Owner role to the user in the organization.
-func (c *iqServerClient) AddOwnerRoleToUser(opConfig *config.OperationConfig) error {
-	utils.Logger.Debug("AddOwnerRoleToUser called",
-		zap.String("ldap_username", opConfig.LdapUsername),
-		zap.String("organization_id", opConfig.OrganizationID))
+// InvalidateRoleCache forgets the cached owner role ID, so the next FindOwnerRoleID
+// looks it up again, e.g. after the role was renamed or recreated in IQ Server.
+func (c *iqServerClient) InvalidateRoleCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ownerRoleID = ""
+}
 
-	roleID, err := c.FindOwnerRoleID()
+// requireOwnerRoleID is FindOwnerRoleID, failing when the owner role does not exist.
+func (c *iqServerClient) requireOwnerRoleID(ctx context.Context) (string, error) {
+	roleID, err := c.FindOwnerRoleID(ctx)
 	if err != nil {
-		return fmt.Errorf("add owner role to user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
+		return "", err
 	}
 	if roleID == "" {
-		return fmt.Errorf("add owner role to user '%s' in organization '%s': owner role id not found", opConfig.LdapUsername, opConfig.OrganizationID)
+		return "", fmt.Errorf("role '%s' not found", c.ownerRoleName)
 	}
-	endpoint := fmt.Sprintf("/api/v2/roleMemberships/organization/%s/role/%s/user/%s", opConfig.OrganizationID, roleID, opConfig.LdapUsername)
-	_, err = c.DoReq("PUT", endpoint, nil, nil)
+	return roleID, nil
+}
+
+// ownerRoleEndpoint returns the endpoint of the user's membership of the role in the organization.
+func ownerRoleEndpoint(opConfig *config.OperationConfig, roleID string) string {
+	return fmt.Sprintf("/api/v2/roleMemberships/organization/%s/role/%s/user/%s", opConfig.OrganizationID, roleID, opConfig.LdapUsername)
+}
+
+// addOwnerRoleMembership runs call with the owner role membership endpoint for the
+// user in the organization. Adding a membership only answers 404 when the role or
+// organization is unknown, so it may mean the cached ID went stale: the cache is
+// invalidated and, if the role now has another ID, call is retried once.
+func (c *iqServerClient) addOwnerRoleMembership(ctx context.Context, opConfig *config.OperationConfig, call func(endpoint string) error) error {
+	roleID, err := c.requireOwnerRoleID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = call(ownerRoleEndpoint(opConfig, roleID))
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		return err
+	}
+	c.InvalidateRoleCache()
+	freshID, findErr := c.FindOwnerRoleID(ctx)
+	if findErr != nil || freshID == "" || freshID == roleID {
+		return err
+	}
+	utils.Logger.Info("Owner role ID changed, retrying membership call",
+		zap.String("role_name", c.ownerRoleName),
+		zap.String("stale_role_id", roleID),
+		zap.String("role_id", freshID))
+	return call(ownerRoleEndpoint(opConfig, freshID))
+}
+
+// AddOwnerRoleToUser adds the Owner role to the user in the organization.
+func (c *iqServerClient) AddOwnerRoleToUser(ctx context.Context, opConfig *config.OperationConfig) error {
+	utils.Logger.Debug("AddOwnerRoleToUser called",
+		zap.String("ldap_username", opConfig.LdapUsername),
+		zap.String("organization_id", opConfig.OrganizationID),
+		zap.String("role_name", c.ownerRoleName))
+
+	var roleEndpoint string
+	err := c.addOwnerRoleMembership(ctx, opConfig, func(endpoint string) error {
+		roleEndpoint = endpoint
+		_, err := c.DoReq(ctx, "PUT", endpoint, nil, nil)
+		return err
+	})
 	if err != nil {
 		utils.Logger.Error("Failed adding owner role to user",
 			zap.String("ldap_username", opConfig.LdapUsername),
 			zap.String("organization_id", opConfig.OrganizationID),
+			zap.String("role_name", c.ownerRoleName),
 			zap.Error(err))
 		return fmt.Errorf("add owner role to user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
 	}
 	utils.Logger.Debug("Successfully requested add owner role",
 		zap.String("ldap_username", opConfig.LdapUsername),
 		zap.String("organization_id", opConfig.OrganizationID),
-		zap.String("role_id", roleID))
+		zap.String("role_name", c.ownerRoleName),
+		zap.String("endpoint", roleEndpoint))
 	return nil
 }
 
-// RemoveOwnerRoleFromUser removes the Owner role from the user in the organization, ignoring 404.
-func (c *iqServerClient) RemoveOwnerRoleFromUser(opConfig *config.OperationConfig) error {
-	roleID, err := c.FindOwnerRoleID()
+// RemoveOwnerRoleFromUser removes the Owner role from the user in the organization.
+// removed is false when IQ Server answered 404, i.e. the user held no Owner membership
+// there; that is not an error, and unlike when adding it leaves the cached role ID alone.
+func (c *iqServerClient) RemoveOwnerRoleFromUser(ctx context.Context, opConfig *config.OperationConfig) (removed bool, err error) {
+	roleID, err := c.requireOwnerRoleID(ctx)
 	if err != nil {
-		return fmt.Errorf("remove owner role from user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
+		return false, fmt.Errorf("remove owner role from user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
 	}
-	if roleID == "" {
-		return fmt.Errorf("remove owner role from user '%s' in organization '%s': owner role id not found", opConfig.LdapUsername, opConfig.OrganizationID)
-	}
-	endpoint := fmt.Sprintf("/api/v2/roleMemberships/organization/%s/role/%s/user/%s", opConfig.OrganizationID, roleID, opConfig.LdapUsername)
-	response, err := c.DoReq("DELETE", endpoint, nil, nil)
+	response, err := c.DoReq(ctx, "DELETE", ownerRoleEndpoint(opConfig, roleID), nil, nil)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
-			return nil // Membership not found; already removed
+			return false, nil // Membership not found; already removed
 		}
-		return fmt.Errorf("remove owner role from user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
+		return false, fmt.Errorf("remove owner role from user '%s' in organization '%s': %w", opConfig.LdapUsername, opConfig.OrganizationID, err)
 	}
-	if response.StatusCode() != http.StatusOK && response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusNotFound {
-		return fmt.Errorf("remove owner role from user '%s' in organization '%s': unexpected status code %d", opConfig.LdapUsername, opConfig.OrganizationID, response.StatusCode())
+	switch response.StatusCode() {
+	case http.StatusOK, http.StatusNoContent:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("remove owner role from user '%s' in organization '%s': unexpected status code %d", opConfig.LdapUsername, opConfig.OrganizationID, response.StatusCode())
+	}
+}
+
+// GetOrganizationAncestors returns the IDs of the organization's ancestors in the IQ
+// Server hierarchy, nearest first. The root organization is left out, as removing
+// Owner there would revoke it everywhere.
+func (c *iqServerClient) GetOrganizationAncestors(ctx context.Context, organizationID string) ([]string, error) {
+	var ancestors []string
+	current := organizationID
+	for range maxIQOrganizationDepth {
+		response, err := c.DoReq(ctx, "GET", "/api/v2/organizations/"+url.PathEscape(current), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get organization '%s': %w", current, err)
+		}
+		var org IQOrganization
+		if err := json.Unmarshal(response.Bytes(), &org); err != nil {
+			return nil, fmt.Errorf("get organization '%s': failed to unmarshal response: %w", current, err)
+		}
+		parent := org.ParentOrganizationID
+		if parent == "" || parent == RootOrganizationID {
+			return ancestors, nil
+		}
+		ancestors = append(ancestors, parent)
+		current = parent
+	}
+	return nil, fmt.Errorf("organization '%s' is nested deeper than %d levels", organizationID, maxIQOrganizationDepth)
+}
+
+// FindOrganization returns the ID of the organization called name, or "" when IQ
+// Server has none. It never creates anything.
+func (c *iqServerClient) FindOrganization(ctx context.Context, name string) (string, error) {
+	response, err := c.DoReq(ctx, "GET", "/api/v2/organizations", nil, map[string]string{"organizationName": name})
+	if err != nil {
+		return "", fmt.Errorf("find organization '%s': %w", name, err)
+	}
+	var existing struct {
+		Organizations []IQOrganization `json:"organizations"`
+	}
+	if err := json.Unmarshal(response.Bytes(), &existing); err != nil {
+		return "", fmt.Errorf("find organization '%s': failed to unmarshal response: %w", name, err)
+	}
+	for _, org := range existing.Organizations {
+		if org.Name == name {
+			return org.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// CreateOrganization returns the ID of the organization called name. When IQ Server
+// has none, it is created under the root organization, so repeated calls are safe.
+func (c *iqServerClient) CreateOrganization(ctx context.Context, name string) (string, error) {
+	id, err := c.FindOrganization(ctx, name)
+	if err != nil || id != "" {
+		return id, err
+	}
+
+	body := map[string]string{"name": name, "parentOrganizationId": RootOrganizationID}
+	response, err := c.DoReq(ctx, "POST", "/api/v2/organizations", body, nil)
+	if err != nil {
+		return "", fmt.Errorf("create organization '%s': %w", name, err)
+	}
+	var created IQOrganization
+	if err := json.Unmarshal(response.Bytes(), &created); err != nil {
+		return "", fmt.Errorf("create organization '%s': failed to unmarshal response: %w", name, err)
+	}
+	if created.ID == "" {
+		return "", fmt.Errorf("create organization '%s': response has no id", name)
+	}
+	return created.ID, nil
+}
+
+// Ping checks that IQ Server is reachable and responding, within config.ReadinessTimeout.
+// Failures are returned as a BackendUnavailableError.
+func (c *iqServerClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ReadinessTimeout)
+	defer cancel()
+	if _, err := c.DoReq(ctx, "GET", "/ping", nil, nil); err != nil {
+		return &BackendUnavailableError{Backend: BackendIQServer, Err: err}
 	}
 	return nil
 }
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIQRolesServer serves roles in pages of pageSize, like a paginating IQ Server.
+func fakeIQRolesServer(roles []IQRole, pageSize int) (func(context.Context, int) (*iqRolesPage, error), *[]int) {
+	var requested []int
+	pageCount := (len(roles) + pageSize - 1) / pageSize
+	return func(_ context.Context, page int) (*iqRolesPage, error) {
+		requested = append(requested, page)
+		start := min((page-1)*pageSize, len(roles))
+		end := min(start+pageSize, len(roles))
+		return &iqRolesPage{Roles: roles[start:end], Page: page, PageCount: pageCount}, nil
+	}, &requested
+}
+
+func TestCollectIQRoles(t *testing.T) {
+	t.Run("Follows every page", func(t *testing.T) {
+		roles := []IQRole{
+			{ID: "1", Name: "Developer"},
+			{ID: "2", Name: "Viewer"},
+			{ID: "3", Name: "Component Evaluator"},
+			{ID: "4", Name: "Policy Administrator"},
+			{ID: "5", Name: "Owner"},
+		}
+		fetch, requested := fakeIQRolesServer(roles, 2)
+
+		got, err := collectIQRoles(context.Background(), fetch)
+
+		assert.NoError(t, err)
+		assert.Equal(t, roles, got)
+		assert.Equal(t, []int{1, 2, 3}, *requested)
+	})
+
+	t.Run("Unpaginated response is read once", func(t *testing.T) {
+		calls := 0
+		fetch := func(context.Context, int) (*iqRolesPage, error) {
+			calls++
+			return &iqRolesPage{Roles: []IQRole{{ID: "5", Name: "Owner"}}}, nil
+		}
+
+		got, err := collectIQRoles(context.Background(), fetch)
+
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Page error is returned", func(t *testing.T) {
+		fetch := func(_ context.Context, page int) (*iqRolesPage, error) {
+			if page == 2 {
+				return nil, errors.New("boom")
+			}
+			return &iqRolesPage{Roles: []IQRole{{ID: "1"}}, Page: page, PageCount: 3}, nil
+		}
+
+		_, err := collectIQRoles(context.Background(), fetch)
+
+		assert.EqualError(t, err, "boom")
+	})
+}
+
+func TestIQServerClient_FindOwnerRoleID(t *testing.T) {
+	body := `{"roles":[{"id":"5","name":"Owner"},{"id":"9","name":"Eigentümer"}]}`
+
+	t.Run("Configured role name", func(t *testing.T) {
+		c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "Eigentümer",
+			WithTransport(&stubTransport{status: http.StatusOK, body: body}))
+
+		id, err := c.FindOwnerRoleID(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "9", id)
+	})
+
+	t.Run("Empty role name falls back to Owner", func(t *testing.T) {
+		c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "",
+			WithTransport(&stubTransport{status: http.StatusOK, body: body}))
+
+		id, err := c.FindOwnerRoleID(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "5", id)
+	})
+
+	t.Run("Missing role yields an empty ID", func(t *testing.T) {
+		c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "Maintainer",
+			WithTransport(&stubTransport{status: http.StatusOK, body: body}))
+
+		id, err := c.FindOwnerRoleID(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, id)
+	})
+}
+
+func TestIQServerClient_OwnerRoleCache(t *testing.T) {
+	const rolesPath = "GET /api/v2/roles"
+	newClient := func() (IQClient, *routeTransport) {
+		transport := &routeTransport{routes: map[string]*stubTransport{
+			rolesPath: {status: http.StatusOK, body: `{"roles":[{"id":"5","name":"Owner"}]}`},
+			"PUT /api/v2/roleMemberships/organization/org-1/role/7/user/john": {status: http.StatusNoContent},
+		}}
+		return NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "",
+			WithTransport(transport)), transport
+	}
+	roleLookups := func(transport *routeTransport) int {
+		n := 0
+		for _, req := range transport.requests {
+			if req.Method+" "+req.URL.Path == rolesPath {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("Cached until invalidated", func(t *testing.T) {
+		c, transport := newClient()
+
+		for range 2 {
+			id, err := c.FindOwnerRoleID(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "5", id)
+		}
+		assert.Equal(t, 1, roleLookups(transport))
+
+		// The role is recreated with a new ID
+		transport.routes[rolesPath].body = `{"roles":[{"id":"7","name":"Owner"}]}`
+		c.InvalidateRoleCache()
+
+		id, err := c.FindOwnerRoleID(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "7", id)
+		assert.Equal(t, 2, roleLookups(transport))
+	})
+
+	t.Run("Membership 404 refreshes a stale ID", func(t *testing.T) {
+		c, transport := newClient()
+		_, err := c.FindOwnerRoleID(context.Background())
+		assert.NoError(t, err)
+		transport.routes[rolesPath].body = `{"roles":[{"id":"7","name":"Owner"}]}`
+
+		err = c.AddOwnerRoleToUser(context.Background(), &config.OperationConfig{OrganizationID: "org-1", LdapUsername: "john"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, roleLookups(transport))
+		last := transport.requests[len(transport.requests)-1]
+		assert.Equal(t, "/api/v2/roleMemberships/organization/org-1/role/7/user/john", last.URL.Path)
+	})
+
+	t.Run("Membership 404 with an unchanged ID fails", func(t *testing.T) {
+		c, transport := newClient()
+
+		err := c.AddOwnerRoleToUser(context.Background(), &config.OperationConfig{OrganizationID: "org-2", LdapUsername: "john"})
+
+		var httpErr *HTTPError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Equal(t, 2, roleLookups(transport))
+	})
+}
+
+func TestIQServerClient_GetOrganizationAncestors(t *testing.T) {
+	transport := &routeTransport{routes: map[string]*stubTransport{
+		"GET /api/v2/organizations/team":     {status: http.StatusOK, body: `{"id":"team","name":"Team","parentOrganizationId":"division"}`},
+		"GET /api/v2/organizations/division": {status: http.StatusOK, body: `{"id":"division","name":"Division","parentOrganizationId":"ROOT_ORGANIZATION_ID"}`},
+	}}
+	c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "", WithTransport(transport))
+
+	ancestors, err := c.GetOrganizationAncestors(context.Background(), "team")
+
+	assert.NoError(t, err)
+	// The root organization is never returned
+	assert.Equal(t, []string{"division"}, ancestors)
+}
+
+func TestIQServerClient_CreateOrganization(t *testing.T) {
+	t.Run("creates a missing organization under the root", func(t *testing.T) {
+		transport := &routeTransport{routes: map[string]*stubTransport{
+			"GET /api/v2/organizations":  {status: http.StatusOK, body: `{"organizations":[]}`},
+			"POST /api/v2/organizations": {status: http.StatusOK, body: `{"id":"org-new","name":"Team","parentOrganizationId":"ROOT_ORGANIZATION_ID"}`},
+		}}
+		c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "", WithTransport(transport))
+
+		id, err := c.CreateOrganization(context.Background(), "Team")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "org-new", id)
+		assert.Len(t, transport.requests, 2)
+		assert.Equal(t, "Team", transport.requests[0].URL.Query().Get("organizationName"))
+		body := decodeBody(t, transport.requests[1])
+		assert.Equal(t, "Team", body["name"])
+		assert.Equal(t, RootOrganizationID, body["parentOrganizationId"])
+		assert.NotContains(t, body, "id")
+	})
+
+	t.Run("returns an existing organization without creating it", func(t *testing.T) {
+		transport := &routeTransport{routes: map[string]*stubTransport{
+			"GET /api/v2/organizations": {status: http.StatusOK, body: `{"organizations":[{"id":"org-1","name":"Team","parentOrganizationId":"ROOT_ORGANIZATION_ID"}]}`},
+		}}
+		c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "", WithTransport(transport))
+
+		id, err := c.CreateOrganization(context.Background(), "Team")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "org-1", id)
+		assert.Len(t, transport.requests, 1)
+	})
+}
+
+func TestIQServerClient_FindOrganization(t *testing.T) {
+	transport := &routeTransport{routes: map[string]*stubTransport{
+		"GET /api/v2/organizations": {status: http.StatusOK, body: `{"organizations":[]}`},
+	}}
+	c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "", WithTransport(transport))
+
+	id, err := c.FindOrganization(context.Background(), "Team")
+
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+	// A lookup never creates the organization
+	assert.Len(t, transport.requests, 1)
+}
+
+func TestIQServerClient_RemoveOwnerRoleFromUser(t *testing.T) {
+	transport := &routeTransport{routes: map[string]*stubTransport{
+		"GET /api/v2/roles": {status: http.StatusOK, body: `{"roles":[{"id":"5","name":"Owner"}]}`},
+		"DELETE /api/v2/roleMemberships/organization/org-1/role/5/user/john": {status: http.StatusNoContent},
+	}}
+	c := NewIQServerClient("http://iq.test/", "admin", "secret", time.Second, "", WithTransport(transport))
+
+	removed, err := c.RemoveOwnerRoleFromUser(context.Background(), &config.OperationConfig{OrganizationID: "org-1", LdapUsername: "john"})
+	assert.NoError(t, err)
+	assert.True(t, removed)
+
+	// No membership in org-2: a no-op rather than an error
+	removed, err = c.RemoveOwnerRoleFromUser(context.Background(), &config.OperationConfig{OrganizationID: "org-2", LdapUsername: "john"})
+	assert.NoError(t, err)
+	assert.False(t, removed)
+
+	// The 404 means the membership is gone, not that the cached role ID is stale
+	lookups := 0
+	for _, req := range transport.requests {
+		if req.Method == http.MethodGet {
+			lookups++
+		}
+	}
+	assert.Equal(t, 1, lookups)
+}
@@ -1,11 +1,17 @@
 package client
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 )
@@ -17,20 +23,20 @@ type nexusClient struct {
 }
 
 // NewNexusClient creates a configured NexusClient implementation for the provided
-// Nexus base URL and credentials. It accepts a map of supported package format
-// configurations used when creating proxy repositories.
+// Nexus base URL and credentials, with requests timing out after timeout. It accepts
+// a map of supported package format configurations used when creating proxy repositories.
 //
 // The concrete returned type is unexported; callers work with the NexusClient
 // interface.
-func NewNexusClient(url, username, password string, supportedFormats map[string]config.PackageManager) NexusClient {
+func NewNexusClient(url, username, password string, timeout time.Duration, supportedFormats map[string]config.PackageManager, opts ...HTTPClientOption) NexusClient {
 	return &nexusClient{
-		HTTPClient:       NewHTTPClient(url, username, password),
+		HTTPClient:       NewHTTPClient(url, username, password, timeout, opts...),
 		supportedFormats: supportedFormats,
 	}
 }
 
-func (c *nexusClient) GetRepository(name string) (*Repository, error) {
-	resp, err := c.DoReq("GET", fmt.Sprintf("/v1/repositories/%s", name), nil, nil)
+func (c *nexusClient) GetRepository(ctx context.Context, name string) (*Repository, error) {
+	resp, err := c.DoReq(ctx, "GET", fmt.Sprintf("/v1/repositories/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("get repository '%s': %w", name, err)
 	}
@@ -41,8 +47,17 @@ func (c *nexusClient) GetRepository(name string) (*Repository, error) {
 	return &repo, nil
 }
 
-func (c *nexusClient) GetRepositories() ([]Repository, error) {
-	resp, err := c.DoReq("GET", "/v1/repositories", nil, nil)
+// RepositoryExists reports whether the repository exists without fetching its details.
+func (c *nexusClient) RepositoryExists(ctx context.Context, name string) (bool, error) {
+	exists, err := c.exists(ctx, fmt.Sprintf("/v1/repositories/%s", url.PathEscape(name)))
+	if err != nil {
+		return false, fmt.Errorf("check repository '%s': %w", name, err)
+	}
+	return exists, nil
+}
+
+func (c *nexusClient) GetRepositories(ctx context.Context) ([]Repository, error) {
+	resp, err := c.DoReq(ctx, "GET", "/v1/repositories", nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("get repositories: %w", err)
 	}
@@ -53,55 +68,124 @@ func (c *nexusClient) GetRepositories() ([]Repository, error) {
 	return repos, nil
 }
 
-func (c *nexusClient) CreateProxyRepository(config *config.OperationConfig) error {
-	manager, ok := c.supportedFormats[strings.ToLower(config.PackageManager)]
+// GetRepositoriesByFormat returns the repositories of the given format, filtering the
+// full list. "maven" and "maven2" both select Maven repositories. It is never nil.
+func (c *nexusClient) GetRepositoriesByFormat(ctx context.Context, format string) ([]Repository, error) {
+	repos, err := c.GetRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	want := config.NexusFormat(format)
+	matching := []Repository{}
+	for _, repo := range repos {
+		if config.NexusFormat(repo.Format) == want {
+			matching = append(matching, repo)
+		}
+	}
+	return matching, nil
+}
+
+// CreateProxyRepository creates the proxy repository described by opConfig and returns
+// its URL from the Location header, or "" when Nexus does not send one.
+func (c *nexusClient) CreateProxyRepository(ctx context.Context, opConfig *config.OperationConfig) (string, error) {
+	manager, ok := c.supportedFormats[opConfig.PackageManager]
 	if !ok {
-		return fmt.Errorf("create proxy repository '%s': unsupported package manager format '%s'", config.RepositoryName, config.PackageManager)
+		return "", fmt.Errorf("create proxy repository '%s': unsupported package manager format '%s'", opConfig.RepositoryName, opConfig.PackageManager)
 	}
-	apiEndpointIface := manager.APIEndpoint
 	path := manager.APIEndpoint.Path
 
+	repoConfig, err := proxyRepositoryConfig(manager, opConfig)
+	if err != nil {
+		return "", fmt.Errorf("create proxy repository '%s': %w", opConfig.RepositoryName, err)
+	}
+
+	resp, err := c.DoReq(ctx, "POST", path, repoConfig, nil)
+	if err != nil {
+		return "", fmt.Errorf("create proxy repository '%s' at endpoint '%s': %w", opConfig.RepositoryName, path, err)
+	}
+	return HeadersOf(resp).Location, nil
+}
+
+// proxyRepositoryConfig builds the POST body for a proxy repository from the generic
+// proxy settings, the package manager's format-specific blocks and its defaults.
+func proxyRepositoryConfig(manager config.PackageManager, opConfig *config.OperationConfig) (map[string]any, error) {
 	repoConfig := map[string]any{
-		"name":   config.RepositoryName,
+		"name":   opConfig.RepositoryName,
 		"online": true,
 		"storage": map[string]any{
 			"blobStoreName":               "default",
-			"strictContentTypeValidation": true,
+			"strictContentTypeValidation": manager.ValidatesContentTypes(),
 		},
 		"proxy": map[string]any{
-			"remoteUrl":      config.RemoteURL,
+			"remoteUrl":      opConfig.RemoteURL,
 			"contentMaxAge":  1440,
 			"metadataMaxAge": 1440,
 		},
-		"negativeCache": map[string]any{
-			"enabled":    true,
-			"timeToLive": 1440,
-		},
 		"httpClient": map[string]any{
 			"blocked":   false,
-			"autoBlock": true,
+			"autoBlock": manager.AutoBlocks(),
 		},
 	}
 
-	formatSpecific := apiEndpointIface.FormatSpecificConfig
+	negativeCache, err := manager.NegativeCache()
+	if err != nil {
+		return nil, err
+	}
+	repoConfig["negativeCache"] = negativeCache
+	if opConfig.RepositoryDescription != "" {
+		repoConfig["description"] = opConfig.RepositoryDescription
+	}
+
+	if opConfig.RemoteUsername != "" {
+		repoConfig["httpClient"].(map[string]any)["authentication"] = map[string]any{
+			"type":     "username",
+			"username": opConfig.RemoteUsername,
+			"password": opConfig.RemotePassword,
+		}
+	}
+
+	formatSpecific := manager.APIEndpoint.FormatSpecificConfig
 	for k, v := range formatSpecific {
 		repoConfig[k] = v
 	}
+	if manager.Format() == config.FormatDocker {
+		connector, err := manager.DockerConnector(opConfig.DockerHTTPPort, opConfig.DockerHTTPSPort)
+		if err != nil {
+			return nil, err
+		}
+		repoConfig[config.FormatDocker] = connector
+	}
+	if manager.Format() == config.FormatMaven {
+		policies, err := manager.MavenPolicies(opConfig.MavenVersionPolicy, opConfig.MavenLayoutPolicy)
+		if err != nil {
+			return nil, err
+		}
+		repoConfig[config.FormatMaven] = policies
+	}
+	if manager.Format() == config.FormatNuGet {
+		nugetProxy, err := manager.NuGetProxy()
+		if err != nil {
+			return nil, err
+		}
+		repoConfig[config.NuGetProxyBlock] = nugetProxy
+	}
+	if manager.Format() == config.FormatAPT {
+		apt, err := manager.APTSettings()
+		if err != nil {
+			return nil, err
+		}
+		repoConfig[config.FormatAPT] = apt
+	}
 
 	defaults := manager.DefaultConfig
 	for k, v := range defaults {
 		repoConfig[k] = v
 	}
-
-	_, err := c.DoReq("POST", path, repoConfig, nil)
-	if err != nil {
-		return fmt.Errorf("create proxy repository '%s' at endpoint '%s': %w", config.RepositoryName, path, err)
-	}
-	return nil
+	return repoConfig, nil
 }
 
-func (c *nexusClient) DeleteRepository(name string) error {
-	resp, err := c.DoReq("DELETE", fmt.Sprintf("/v1/repositories/%s", name), nil, nil)
+func (c *nexusClient) DeleteRepository(ctx context.Context, name string) error {
+	resp, err := c.DoReq(ctx, "DELETE", fmt.Sprintf("/v1/repositories/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
@@ -115,8 +199,57 @@ func (c *nexusClient) DeleteRepository(name string) error {
 	return nil
 }
 
-func (c *nexusClient) GetPrivilege(name string) (*Privilege, error) {
-	resp, err := c.DoReq("GET", fmt.Sprintf("/v1/security/privileges/%s", name), nil, nil)
+// SetRepositoryOnline takes the repository online or offline, e.g. for maintenance,
+// by fetching its full settings and putting them back with the online flag changed.
+// A missing repository is reported as the underlying 404 HTTPError.
+func (c *nexusClient) SetRepositoryOnline(ctx context.Context, name string, online bool) error {
+	repo, err := c.GetRepository(ctx, name)
+	if err != nil {
+		return fmt.Errorf("set repository '%s' online=%t: %w", name, online, err)
+	}
+	path := repositorySettingsPath(repo)
+	resp, err := c.DoReq(ctx, "GET", path, nil, nil)
+	if err != nil {
+		return fmt.Errorf("set repository '%s' online=%t: get settings: %w", name, online, err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(resp.Bytes(), &settings); err != nil {
+		return fmt.Errorf("set repository '%s' online=%t: failed to unmarshal settings: %w", name, online, err)
+	}
+	settings["online"] = online
+	if _, err := c.DoReq(ctx, "PUT", path, settings, nil); err != nil {
+		return fmt.Errorf("set repository '%s' online=%t: %w", name, online, err)
+	}
+	return nil
+}
+
+// InvalidateCache clears the proxy and negative caches of a proxy repository, so
+// Nexus fetches artifacts from the upstream again. A missing repository has nothing
+// to invalidate and is not an error.
+func (c *nexusClient) InvalidateCache(ctx context.Context, repositoryName string) error {
+	if _, err := c.DoReq(ctx, "POST", fmt.Sprintf("/v1/repositories/%s/invalidate-cache", url.PathEscape(repositoryName)), nil, nil); err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("invalidate cache of repository '%s': %w", repositoryName, err)
+	}
+	return nil
+}
+
+// repositorySettingsPath returns the format- and type-specific endpoint that reads and
+// updates the full settings of repo. Nexus reports maven repositories as "maven2" but
+// serves them under "maven".
+func repositorySettingsPath(repo *Repository) string {
+	format := repo.Format
+	if format == "maven2" {
+		format = "maven"
+	}
+	return fmt.Sprintf("/v1/repositories/%s/%s/%s", url.PathEscape(format), url.PathEscape(repo.Type), url.PathEscape(repo.Name))
+}
+
+func (c *nexusClient) GetPrivilege(ctx context.Context, name string) (*Privilege, error) {
+	resp, err := c.DoReq(ctx, "GET", fmt.Sprintf("/v1/security/privileges/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("get privilege '%s': %w", name, err)
 	}
@@ -127,8 +260,17 @@ func (c *nexusClient) GetPrivilege(name string) (*Privilege, error) {
 	return &priv, nil
 }
 
-func (c *nexusClient) GetPrivileges() ([]Privilege, error) {
-	resp, err := c.DoReq("GET", "/v1/security/privileges", nil, nil)
+// PrivilegeExists reports whether the privilege exists without fetching its details.
+func (c *nexusClient) PrivilegeExists(ctx context.Context, name string) (bool, error) {
+	exists, err := c.exists(ctx, fmt.Sprintf("/v1/security/privileges/%s", url.PathEscape(name)))
+	if err != nil {
+		return false, fmt.Errorf("check privilege '%s': %w", name, err)
+	}
+	return exists, nil
+}
+
+func (c *nexusClient) GetPrivileges(ctx context.Context) ([]Privilege, error) {
+	resp, err := c.DoReq(ctx, "GET", "/v1/security/privileges", nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("get privileges: %w", err)
 	}
@@ -139,31 +281,36 @@ func (c *nexusClient) GetPrivileges() ([]Privilege, error) {
 	return privs, nil
 }
 
-func (c *nexusClient) CreatePrivilege(config *config.OperationConfig) error {
-	pmLower := strings.ToLower(config.PackageManager)
-	privFormat := pmLower
+// CreatePrivilege creates the repository-view privilege granting opConfig.PrivilegeActions
+// on the repository, or every action when none are set.
+func (c *nexusClient) CreatePrivilege(ctx context.Context, opConfig *config.OperationConfig) error {
+	privFormat := c.supportedFormats[opConfig.PackageManager].NexusPrivilegeFormat(opConfig.PackageManager)
 
-	// We call it "maven" in the API but Nexus expects "maven2"
-	if privFormat == "maven" {
-		privFormat = "maven2"
+	actions := opConfig.PrivilegeActions
+	if len(actions) == 0 {
+		actions = config.DefaultPrivilegeActions
+	}
+	description := fmt.Sprintf("All permissions for repository '%s'", opConfig.RepositoryName)
+	if !slices.Equal(actions, config.PrivilegeActions) {
+		description = fmt.Sprintf("%s permissions for repository '%s'", strings.Join(actions, ", "), opConfig.RepositoryName)
 	}
 
 	privConfig := map[string]interface{}{
-		"name":        config.PrivilegeName,
-		"description": fmt.Sprintf("All permissions for repository '%s'", config.RepositoryName),
-		"actions":     []string{"BROWSE", "READ", "EDIT", "ADD", "DELETE"},
+		"name":        opConfig.PrivilegeName,
+		"description": description,
+		"actions":     actions,
 		"format":      privFormat,
-		"repository":  config.RepositoryName,
+		"repository":  opConfig.RepositoryName,
 	}
-	_, err := c.DoReq("POST", "/v1/security/privileges/repository-view", privConfig, nil)
+	_, err := c.DoReq(ctx, "POST", "/v1/security/privileges/repository-view", privConfig, nil)
 	if err != nil {
-		return fmt.Errorf("create privilege '%s' for repository '%s' (format='%s'): %w", config.PrivilegeName, config.RepositoryName, privFormat, err)
+		return fmt.Errorf("create privilege '%s' for repository '%s' (format='%s'): %w", opConfig.PrivilegeName, opConfig.RepositoryName, privFormat, err)
 	}
 	return nil
 }
 
-func (c *nexusClient) DeletePrivilege(name string) error {
-	resp, err := c.DoReq("DELETE", fmt.Sprintf("/v1/security/privileges/%s", name), nil, nil)
+func (c *nexusClient) DeletePrivilege(ctx context.Context, name string) error {
+	resp, err := c.DoReq(ctx, "DELETE", fmt.Sprintf("/v1/security/privileges/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
@@ -177,8 +324,8 @@ func (c *nexusClient) DeletePrivilege(name string) error {
 	return nil
 }
 
-func (c *nexusClient) GetRole(name string) (*Role, error) {
-	resp, err := c.DoReq("GET", fmt.Sprintf("/v1/security/roles/%s", name), nil, nil)
+func (c *nexusClient) GetRole(ctx context.Context, name string) (*Role, error) {
+	resp, err := c.DoReq(ctx, "GET", fmt.Sprintf("/v1/security/roles/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
@@ -190,10 +337,53 @@ func (c *nexusClient) GetRole(name string) (*Role, error) {
 	if err := json.Unmarshal(resp.Bytes(), &role); err != nil {
 		return nil, fmt.Errorf("get role '%s': failed to unmarshal response: %w", name, err)
 	}
+	role.ETag = HeadersOf(resp).ETag
 	return &role, nil
 }
 
-func (c *nexusClient) CreateRole(config *config.OperationConfig) error {
+// RoleExists reports whether the role exists without fetching its details.
+func (c *nexusClient) RoleExists(ctx context.Context, name string) (bool, error) {
+	exists, err := c.exists(ctx, fmt.Sprintf("/v1/security/roles/%s", url.PathEscape(name)))
+	if err != nil {
+		return false, fmt.Errorf("check role '%s': %w", name, err)
+	}
+	return exists, nil
+}
+
+// GetRolesByPrivilege lists every role and returns those whose privileges include
+// privilegeName. The result is never nil.
+func (c *nexusClient) GetRolesByPrivilege(ctx context.Context, privilegeName string) ([]Role, error) {
+	resp, err := c.DoReq(ctx, "GET", "/v1/security/roles", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get roles referencing privilege '%s': %w", privilegeName, err)
+	}
+	var roles []Role
+	if err := json.Unmarshal(resp.Bytes(), &roles); err != nil {
+		return nil, fmt.Errorf("get roles referencing privilege '%s': failed to unmarshal response: %w", privilegeName, err)
+	}
+	referencing := []Role{}
+	for _, role := range roles {
+		if slices.Contains(role.Privileges, privilegeName) {
+			referencing = append(referencing, role)
+		}
+	}
+	return referencing, nil
+}
+
+// exists issues a HEAD request for endpoint: a success means the resource exists and
+// a 404 means it doesn't. No body is transferred or decoded.
+func (c *nexusClient) exists(ctx context.Context, endpoint string) (bool, error) {
+	if _, err := c.DoReq(ctx, "HEAD", endpoint, nil, nil); err != nil {
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *nexusClient) CreateRole(ctx context.Context, config *config.OperationConfig) error {
 	roleConfig := map[string]interface{}{
 		"id":          config.RoleName,
 		"name":        config.RoleName,
@@ -201,7 +391,7 @@ func (c *nexusClient) CreateRole(config *config.OperationConfig) error {
 		"privileges":  []string{config.PrivilegeName},
 		"roles":       []string{},
 	}
-	_, err := c.DoReq("POST", "/v1/security/roles", roleConfig, nil)
+	_, err := c.DoReq(ctx, "POST", "/v1/security/roles", roleConfig, nil)
 	if err != nil {
 		var httpErr *HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusBadRequest {
@@ -213,19 +403,25 @@ func (c *nexusClient) CreateRole(config *config.OperationConfig) error {
 	return nil
 }
 
-func (c *nexusClient) UpdateRole(role *Role) error {
+// UpdateRole replaces the role. When the role carries an ETag from GetRole the update
+// is conditional on it, and a concurrent change is reported as a ConflictError.
+func (c *nexusClient) UpdateRole(ctx context.Context, role *Role) error {
 	if role.ID == "" {
 		return fmt.Errorf("update role: role id is empty")
 	}
-	_, err := c.DoReq("PUT", fmt.Sprintf("/v1/security/roles/%s", role.ID), role, nil)
+	var headers map[string]string
+	if role.ETag != "" {
+		headers = map[string]string{"If-Match": role.ETag}
+	}
+	_, err := c.DoReqWithHeaders(ctx, "PUT", fmt.Sprintf("/v1/security/roles/%s", url.PathEscape(role.ID)), role, nil, headers)
 	if err != nil {
-		return fmt.Errorf("update role '%s': %w", role.ID, err)
+		return fmt.Errorf("update role '%s': %w", role.ID, classifyConflict(err))
 	}
 	return nil
 }
 
-func (c *nexusClient) DeleteRole(name string) error {
-	resp, err := c.DoReq("DELETE", fmt.Sprintf("/v1/security/roles/%s", name), nil, nil)
+func (c *nexusClient) DeleteRole(ctx context.Context, name string) error {
+	resp, err := c.DoReq(ctx, "DELETE", fmt.Sprintf("/v1/security/roles/%s", url.PathEscape(name)), nil, nil)
 	if err != nil {
 		return err
 	}
@@ -235,9 +431,9 @@ func (c *nexusClient) DeleteRole(name string) error {
 	return nil
 }
 
-func (c *nexusClient) GetUser(userID string) (*User, error) {
+func (c *nexusClient) GetUser(ctx context.Context, userID string) (*User, error) {
 	params := map[string]string{"userId": userID}
-	resp, err := c.DoReq("GET", "/v1/security/users", nil, params)
+	resp, err := c.DoReq(ctx, "GET", "/v1/security/users", nil, params)
 	if err != nil {
 		return nil, fmt.Errorf("get user '%s': %w", userID, err)
 	}
@@ -253,16 +449,99 @@ func (c *nexusClient) GetUser(userID string) (*User, error) {
 	return nil, nil
 }
 
-func (c *nexusClient) UpdateUser(user *User) error {
+// GetUsersByRole lists every user and returns those holding roleName. The result is
+// never nil.
+func (c *nexusClient) GetUsersByRole(ctx context.Context, roleName string) ([]User, error) {
+	resp, err := c.DoReq(ctx, "GET", "/v1/security/users", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get users holding role '%s': %w", roleName, err)
+	}
+	var users []User
+	if err := json.Unmarshal(resp.Bytes(), &users); err != nil {
+		return nil, fmt.Errorf("get users holding role '%s': failed to unmarshal response: %w", roleName, err)
+	}
+	holding := []User{}
+	for _, user := range users {
+		if slices.Contains(user.Roles, roleName) {
+			holding = append(holding, user)
+		}
+	}
+	return holding, nil
+}
+
+// CreateUser creates a local Nexus user. Nexus requires a password on creation, so a
+// random one is generated; the user is expected to sign in through the realm that
+// owns the account rather than with this password.
+func (c *nexusClient) CreateUser(ctx context.Context, user *User) error {
+	if user.UserID == "" {
+		return fmt.Errorf("create user: userId is empty")
+	}
+	password, err := randomPassword()
+	if err != nil {
+		return fmt.Errorf("create user '%s': %w", user.UserID, err)
+	}
+	if user.FirstName == "" {
+		user.FirstName = user.UserID
+	}
+	// always set these values, matching UpdateUser
+	user.EmailAddress = "useless@example.com"
+	user.LastName = "useless"
+	userConfig := map[string]interface{}{
+		"userId":       user.UserID,
+		"firstName":    user.FirstName,
+		"lastName":     user.LastName,
+		"emailAddress": user.EmailAddress,
+		"password":     password,
+		"status":       user.Status,
+		"roles":        user.Roles,
+	}
+	if _, err := c.DoReq(ctx, "POST", "/v1/security/users", userConfig, nil); err != nil {
+		return fmt.Errorf("create user '%s': %w", user.UserID, err)
+	}
+	return nil
+}
+
+// randomPassword returns a 32-character hex password from crypto/rand.
+func randomPassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (c *nexusClient) UpdateUser(ctx context.Context, user *User) error {
 	if user.UserID == "" {
 		return fmt.Errorf("update user: userId is empty")
 	}
 	// always set these values
 	user.EmailAddress = "useless@example.com"
 	user.LastName = "useless"
-	_, err := c.DoReq("PUT", fmt.Sprintf("/v1/security/users/%s", user.UserID), user, nil)
+	_, err := c.DoReq(ctx, "PUT", fmt.Sprintf("/v1/security/users/%s", url.PathEscape(user.UserID)), user, nil)
 	if err != nil {
 		return fmt.Errorf("update user '%s': %w", user.UserID, err)
 	}
 	return nil
 }
+
+// DeleteUser removes a user from Nexus. The caller decides how to treat a 404.
+func (c *nexusClient) DeleteUser(ctx context.Context, userID string) error {
+	if userID == "" {
+		return fmt.Errorf("delete user: userId is empty")
+	}
+	if _, err := c.DoReq(ctx, "DELETE", fmt.Sprintf("/v1/security/users/%s", url.PathEscape(userID)), nil, nil); err != nil {
+		return fmt.Errorf("delete user '%s': %w", userID, err)
+	}
+	return nil
+}
+
+// Ping checks that Nexus is reachable and able to serve requests, within config.ReadinessTimeout.
+// Failures are returned as a BackendUnavailableError.
+func (c *nexusClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ReadinessTimeout)
+	defer cancel()
+	if _, err := c.DoReq(ctx, "GET", "/v1/status", nil, nil); err != nil {
+		return &BackendUnavailableError{Backend: BackendNexus, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,569 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyRepositoryConfig_Docker(t *testing.T) {
+	manager := config.PackageManager{
+		DefaultURL: "https://registry-1.docker.io/",
+		APIEndpoint: &config.APIEndpoint{
+			Path: "/v1/repositories/docker/proxy",
+			FormatSpecificConfig: map[string]any{
+				"docker":      map[string]any{"v1Enabled": false, "forceBasicAuth": true, "httpPort": nil, "httpsPort": nil},
+				"dockerProxy": map[string]any{"indexType": "HUB"},
+			},
+		},
+	}
+	opConfig := &config.OperationConfig{
+		RepositoryName:  "docker-release-app1",
+		PackageManager:  "docker",
+		RemoteURL:       "https://registry-1.docker.io/",
+		DockerHTTPSPort: 8443,
+	}
+
+	body, err := proxyRepositoryConfig(manager, opConfig)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"v1Enabled":      false,
+		"forceBasicAuth": true,
+		"httpPort":       nil,
+		"httpsPort":      8443,
+	}, body["docker"])
+	assert.Equal(t, map[string]any{"indexType": "HUB"}, body["dockerProxy"])
+
+	opConfig.DockerHTTPSPort = 0
+	_, err = proxyRepositoryConfig(manager, opConfig)
+	assert.Error(t, err)
+}
+
+func TestProxyRepositoryConfig_MavenSnapshot(t *testing.T) {
+	manager := config.PackageManager{
+		DefaultURL: "https://repo1.maven.org/maven2/",
+		APIEndpoint: &config.APIEndpoint{
+			Path: "/v1/repositories/maven/proxy",
+			FormatSpecificConfig: map[string]any{
+				"maven": map[string]any{"versionPolicy": "RELEASE", "layoutPolicy": "STRICT", "contentDisposition": "ATTACHMENT"},
+			},
+		},
+	}
+	opConfig := &config.OperationConfig{
+		RepositoryName:     "maven-release-app1",
+		PackageManager:     "maven",
+		RemoteURL:          "https://repo1.maven.org/maven2/",
+		MavenVersionPolicy: "SNAPSHOT",
+	}
+
+	body, err := proxyRepositoryConfig(manager, opConfig)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"versionPolicy":      "SNAPSHOT",
+		"layoutPolicy":       "STRICT",
+		"contentDisposition": "ATTACHMENT",
+	}, body["maven"])
+	// The shared package manager config is left untouched
+	assert.Equal(t, "RELEASE", manager.APIEndpoint.FormatSpecificConfig["maven"].(map[string]any)["versionPolicy"])
+}
+
+func TestProxyRepositoryConfig_RemoteAuthentication(t *testing.T) {
+	manager := config.PackageManager{
+		DefaultURL:  "https://npm.example.com",
+		APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"},
+	}
+	opConfig := &config.OperationConfig{RepositoryName: "npm-release-app1", PackageManager: "npm"}
+
+	body, err := proxyRepositoryConfig(manager, opConfig)
+	assert.NoError(t, err)
+	assert.NotContains(t, body["httpClient"], "authentication")
+
+	opConfig.RemoteUsername = "mirror-user"
+	opConfig.RemotePassword = "s3cret"
+	body, err = proxyRepositoryConfig(manager, opConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"type":     "username",
+		"username": "mirror-user",
+		"password": "s3cret",
+	}, body["httpClient"].(map[string]any)["authentication"])
+}
+
+func TestNexusClient_Exists(t *testing.T) {
+	newClient := func(status int) (NexusClient, *stubTransport) {
+		rt := &stubTransport{status: status}
+		return NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt)), rt
+	}
+	checks := []struct {
+		name  string
+		path  string
+		check func(NexusClient) (bool, error)
+	}{
+		{"Repository", "/service/rest/v1/repositories/npm-release-app1", func(c NexusClient) (bool, error) {
+			return c.RepositoryExists(context.Background(), "npm-release-app1")
+		}},
+		{"Privilege", "/service/rest/v1/security/privileges/npm-release-app1", func(c NexusClient) (bool, error) {
+			return c.PrivilegeExists(context.Background(), "npm-release-app1")
+		}},
+		{"Role", "/service/rest/v1/security/roles/user1", func(c NexusClient) (bool, error) {
+			return c.RoleExists(context.Background(), "user1")
+		}},
+	}
+
+	for _, tc := range checks {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rt := newClient(http.StatusOK)
+			exists, err := tc.check(c)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, http.MethodHead, rt.last.Method)
+			assert.Equal(t, tc.path, rt.last.URL.Path)
+
+			c, _ = newClient(http.StatusNotFound)
+			exists, err = tc.check(c)
+			assert.NoError(t, err)
+			assert.False(t, exists)
+
+			// Other failures are errors rather than "doesn't exist"
+			c, _ = newClient(http.StatusInternalServerError)
+			exists, err = tc.check(c)
+			assert.Error(t, err)
+			assert.True(t, IsRetriable(err))
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestNexusClient_CreateProxyRepository_Location(t *testing.T) {
+	formats := map[string]config.PackageManager{
+		"npm": {DefaultURL: "https://registry.npmjs.org", APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"}},
+	}
+	opConfig := &config.OperationConfig{RepositoryName: "npm-release-app1", PackageManager: "npm", RemoteURL: "https://registry.npmjs.org"}
+
+	t.Run("Location header is returned", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusCreated, header: http.Header{
+			"Location": []string{"http://nexus.test/service/rest/v1/repositories/npm-release-app1"},
+		}}
+		c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+		location, err := c.CreateProxyRepository(context.Background(), opConfig)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "http://nexus.test/service/rest/v1/repositories/npm-release-app1", location)
+		assert.Equal(t, "/service/rest/v1/repositories/npm/proxy", rt.last.URL.Path)
+	})
+
+	t.Run("Missing Location header is empty", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusCreated}
+		c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+		location, err := c.CreateProxyRepository(context.Background(), opConfig)
+
+		assert.NoError(t, err)
+		assert.Empty(t, location)
+	})
+}
+
+func TestNexusClient_UpdateRole_IfMatch(t *testing.T) {
+	newClient := func(rt http.RoundTripper) NexusClient {
+		return NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+	}
+
+	t.Run("GetRole captures the ETag", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusOK, header: http.Header{"Etag": []string{`"v1"`}}, body: `{"id":"user1","privileges":[]}`}
+		role, err := newClient(rt).GetRole(context.Background(), "user1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, `"v1"`, role.ETag)
+	})
+
+	t.Run("412 is a ConflictError", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusPreconditionFailed}
+		err := newClient(rt).UpdateRole(context.Background(), &Role{ID: "user1", ETag: `"v1"`})
+
+		assert.True(t, IsConflict(err))
+		assert.False(t, IsRetriable(err))
+		assert.Equal(t, `"v1"`, rt.last.Header.Get("If-Match"))
+	})
+
+	t.Run("No ETag sends an unconditional update", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusNoContent}
+		err := newClient(rt).UpdateRole(context.Background(), &Role{ID: "user1"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, rt.last.Header.Get("If-Match"))
+	})
+}
+
+func TestNexusClient_GetRolesByPrivilege(t *testing.T) {
+	rt := &stubTransport{status: http.StatusOK, body: `[
+		{"id": "user1", "privileges": ["npm-release-app1", "maven-release-app1"]},
+		{"id": "user2", "privileges": ["npm-release-app2"]},
+		{"id": "repositories.share", "privileges": []}
+	]`}
+	c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+	roles, err := c.GetRolesByPrivilege(context.Background(), "npm-release-app1")
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.Equal(t, "user1", roles[0].ID)
+	assert.Equal(t, "/service/rest/v1/security/roles", rt.last.URL.Path)
+
+	roles, err = c.GetRolesByPrivilege(context.Background(), "unreferenced")
+	assert.NoError(t, err)
+	assert.NotNil(t, roles)
+	assert.Empty(t, roles)
+}
+
+func TestNexusClient_GetRepositoriesByFormat(t *testing.T) {
+	rt := &stubTransport{status: http.StatusOK, body: `[
+		{"name": "npm-release-app1", "format": "npm", "type": "proxy"},
+		{"name": "maven-release-app1", "format": "maven2", "type": "proxy"},
+		{"name": "npm-release-app2", "format": "npm", "type": "proxy"},
+		{"name": "docker-release-app1", "format": "docker", "type": "proxy"}
+	]`}
+	c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+	repos, err := c.GetRepositoriesByFormat(context.Background(), "npm")
+	assert.NoError(t, err)
+	assert.Len(t, repos, 2)
+	assert.Equal(t, "npm-release-app1", repos[0].Name)
+	assert.Equal(t, "npm-release-app2", repos[1].Name)
+	assert.Equal(t, "/service/rest/v1/repositories", rt.last.URL.Path)
+
+	// The API path name and the name Nexus reports select the same repositories
+	for _, format := range []string{"maven", "maven2", "Maven"} {
+		repos, err = c.GetRepositoriesByFormat(context.Background(), format)
+		assert.NoError(t, err)
+		assert.Len(t, repos, 1, format)
+		assert.Equal(t, "maven-release-app1", repos[0].Name)
+	}
+
+	repos, err = c.GetRepositoriesByFormat(context.Background(), "pypi")
+	assert.NoError(t, err)
+	assert.NotNil(t, repos)
+	assert.Empty(t, repos)
+}
+
+func TestNexusClient_GetUsersByRole(t *testing.T) {
+	rt := &stubTransport{status: http.StatusOK, body: `[
+		{"userId": "user1", "roles": ["nx-anonymous", "user1", "repositories.share"]},
+		{"userId": "user2", "roles": ["nx-anonymous", "user2", "repositories.share"]},
+		{"userId": "user3", "roles": ["nx-anonymous", "user3"]}
+	]`}
+	c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+	users, err := c.GetUsersByRole(context.Background(), "repositories.share")
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "user1", users[0].UserID)
+	assert.Equal(t, "user2", users[1].UserID)
+	assert.Equal(t, "/service/rest/v1/security/users", rt.last.URL.Path)
+
+	users, err = c.GetUsersByRole(context.Background(), "user3")
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "user3", users[0].UserID)
+
+	users, err = c.GetUsersByRole(context.Background(), "unassigned")
+	assert.NoError(t, err)
+	assert.NotNil(t, users)
+	assert.Empty(t, users)
+}
+
+func TestNexusClient_CreateProxyRepository_Formats(t *testing.T) {
+	formats := map[string]config.PackageManager{
+		config.FormatNuGet:    {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/nuget/proxy"}},
+		config.FormatPyPI:     {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/pypi/proxy"}},
+		config.FormatRubyGems: {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/rubygems/proxy"}},
+		config.FormatAPT: {APIEndpoint: &config.APIEndpoint{
+			Path:                 "/v1/repositories/apt/proxy",
+			FormatSpecificConfig: map[string]any{"apt": map[string]any{"distribution": "jammy"}},
+		}},
+	}
+	tests := []struct {
+		format string
+		path   string
+		block  string
+		want   map[string]any
+	}{
+		{config.FormatNuGet, "/service/rest/v1/repositories/nuget/proxy", "nugetProxy", map[string]any{"nugetVersion": "V3", "queryCacheItemMaxAge": float64(3600)}},
+		{config.FormatPyPI, "/service/rest/v1/repositories/pypi/proxy", "", nil},
+		{config.FormatRubyGems, "/service/rest/v1/repositories/rubygems/proxy", "", nil},
+		{config.FormatAPT, "/service/rest/v1/repositories/apt/proxy", "apt", map[string]any{"distribution": "jammy", "flat": false}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			rt := &stubTransport{status: http.StatusCreated}
+			c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+			_, err := c.CreateProxyRepository(context.Background(), &config.OperationConfig{
+				RepositoryName: tc.format + "-release-app1",
+				PackageManager: tc.format,
+				RemoteURL:      "https://upstream.example.com/",
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.path, rt.last.URL.Path)
+
+			bodyReader, err := rt.last.GetBody()
+			assert.NoError(t, err)
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(bodyReader).Decode(&body))
+			assert.Equal(t, tc.format+"-release-app1", body["name"])
+			assert.Equal(t, "https://upstream.example.com/", body["proxy"].(map[string]any)["remoteUrl"])
+			if tc.block != "" {
+				assert.Equal(t, tc.want, body[tc.block])
+			}
+		})
+	}
+
+	t.Run("apt without a distribution is rejected before calling Nexus", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusCreated}
+		c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, map[string]config.PackageManager{
+			config.FormatAPT: {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/apt/proxy"}},
+		}, WithTransport(rt))
+
+		_, err := c.CreateProxyRepository(context.Background(), &config.OperationConfig{RepositoryName: "apt-release-app1", PackageManager: "apt"})
+
+		assert.ErrorContains(t, err, "distribution")
+		assert.Nil(t, rt.last)
+	})
+}
+
+// routeTransport answers each "METHOD path" with its own stubbed response, 404 for
+// anything else, and records every request in order.
+type routeTransport struct {
+	routes   map[string]*stubTransport
+	requests []*http.Request
+}
+
+func (r *routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	if stub, ok := r.routes[req.Method+" "+req.URL.Path]; ok {
+		return stub.RoundTrip(req)
+	}
+	return (&stubTransport{status: http.StatusNotFound}).RoundTrip(req)
+}
+
+// decodeBody decodes the JSON body of a recorded request.
+func decodeBody(t *testing.T, req *http.Request) map[string]any {
+	t.Helper()
+	bodyReader, err := req.GetBody()
+	assert.NoError(t, err)
+	var body map[string]any
+	assert.NoError(t, json.NewDecoder(bodyReader).Decode(&body))
+	return body
+}
+
+func TestNexusClient_SetRepositoryOnline(t *testing.T) {
+	newTransport := func() *routeTransport {
+		return &routeTransport{routes: map[string]*stubTransport{
+			"GET /service/rest/v1/repositories/maven-release-app1":             {status: http.StatusOK, body: `{"name":"maven-release-app1","format":"maven2","type":"proxy","online":true}`},
+			"GET /service/rest/v1/repositories/maven/proxy/maven-release-app1": {status: http.StatusOK, body: `{"name":"maven-release-app1","online":true,"proxy":{"remoteUrl":"https://repo1.maven.org/maven2/"}}`},
+			"PUT /service/rest/v1/repositories/maven/proxy/maven-release-app1": {status: http.StatusNoContent},
+		}}
+	}
+
+	for _, online := range []bool{false, true} {
+		t.Run(fmt.Sprintf("online=%t", online), func(t *testing.T) {
+			rt := newTransport()
+			c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+			err := c.SetRepositoryOnline(context.Background(), "maven-release-app1", online)
+
+			assert.NoError(t, err)
+			assert.Len(t, rt.requests, 3)
+			update := rt.requests[2]
+			assert.Equal(t, http.MethodPut, update.Method)
+			body := decodeBody(t, update)
+			assert.Equal(t, online, body["online"])
+			// The rest of the settings are sent back unchanged
+			assert.Equal(t, "https://repo1.maven.org/maven2/", body["proxy"].(map[string]any)["remoteUrl"])
+		})
+	}
+
+	t.Run("Missing repository is a 404", func(t *testing.T) {
+		rt := newTransport()
+		c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+		err := c.SetRepositoryOnline(context.Background(), "missing", false)
+
+		var httpErr *HTTPError
+		assert.ErrorAs(t, err, &httpErr)
+		assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+		assert.Len(t, rt.requests, 1)
+	})
+}
+
+func TestNexusClient_InvalidateCache(t *testing.T) {
+	newClient := func(rt http.RoundTripper) NexusClient {
+		return NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+	}
+
+	t.Run("Calls the invalidate-cache endpoint", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusNoContent}
+		err := newClient(rt).InvalidateCache(context.Background(), "npm-release-app1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPost, rt.last.Method)
+		assert.Equal(t, "/service/rest/v1/repositories/npm-release-app1/invalidate-cache", rt.last.URL.Path)
+	})
+
+	t.Run("404 is tolerated", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusNotFound}
+		assert.NoError(t, newClient(rt).InvalidateCache(context.Background(), "missing"))
+	})
+
+	t.Run("Other statuses are errors", func(t *testing.T) {
+		rt := &stubTransport{status: http.StatusBadRequest, body: "not a proxy repository"}
+		err := newClient(rt).InvalidateCache(context.Background(), "npm-hosted")
+
+		assert.ErrorContains(t, err, "invalidate cache of repository 'npm-hosted'")
+		assert.ErrorContains(t, err, "HTTP 400")
+	})
+}
+
+func TestNexusClient_EscapesPathSegments(t *testing.T) {
+	rt := &stubTransport{status: http.StatusNoContent}
+	c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+	assert.NoError(t, c.DeleteRepository(context.Background(), "../security/users/admin?x"))
+
+	// The name stays one segment of the repositories endpoint
+	assert.Equal(t, "/service/rest/v1/repositories/../security/users/admin?x", rt.last.URL.Path)
+	assert.Equal(t, "/service/rest/v1/repositories/..%2Fsecurity%2Fusers%2Fadmin%3Fx", rt.last.URL.EscapedPath())
+	assert.Empty(t, rt.last.URL.RawQuery)
+}
+
+func TestNexusClient_CreatePrivilege_Actions(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []string
+		want    []any
+	}{
+		{"Full access by default", nil, []any{"BROWSE", "READ", "EDIT", "ADD", "DELETE"}},
+		{"Read-only", config.ReadOnlyPrivilegeActions, []any{"BROWSE", "READ"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := &stubTransport{status: http.StatusCreated}
+			c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, nil, WithTransport(rt))
+
+			err := c.CreatePrivilege(context.Background(), &config.OperationConfig{
+				PrivilegeName:    "maven-release-app1",
+				RepositoryName:   "maven-release-app1",
+				PackageManager:   "maven",
+				PrivilegeActions: tc.actions,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "/service/rest/v1/security/privileges/repository-view", rt.last.URL.Path)
+			body := decodeBody(t, rt.last)
+			assert.Equal(t, tc.want, body["actions"])
+			assert.Equal(t, "maven2", body["format"])
+		})
+	}
+}
+
+func TestNexusClient_CreatePrivilege_Format(t *testing.T) {
+	formats := map[string]config.PackageManager{
+		"maven":  {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/maven/proxy"}},
+		"gradle": {PrivilegeFormat: "maven2", APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/gradle/proxy"}},
+		"npm":    {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"}},
+	}
+	tests := []struct {
+		packageManager string
+		want           string
+	}{
+		{"maven", "maven2"},
+		{"gradle", "maven2"},
+		{"npm", "npm"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.packageManager, func(t *testing.T) {
+			rt := &stubTransport{status: http.StatusCreated}
+			c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+			err := c.CreatePrivilege(context.Background(), &config.OperationConfig{
+				PrivilegeName:  tc.packageManager + "-release-app1",
+				RepositoryName: tc.packageManager + "-release-app1",
+				PackageManager: tc.packageManager,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, decodeBody(t, rt.last)["format"])
+		})
+	}
+}
+
+func TestNexusClient_CreateProxyRepository_ContentValidationAndAutoBlock(t *testing.T) {
+	relaxed := false
+	formats := map[string]config.PackageManager{
+		"npm": {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"}},
+		"raw": {
+			APIEndpoint:                 &config.APIEndpoint{Path: "/v1/repositories/raw/proxy"},
+			StrictContentTypeValidation: &relaxed,
+			AutoBlock:                   &relaxed,
+		},
+	}
+
+	for format, want := range map[string]bool{"npm": true, "raw": false} {
+		t.Run(format, func(t *testing.T) {
+			rt := &stubTransport{status: http.StatusCreated}
+			c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+			_, err := c.CreateProxyRepository(context.Background(), &config.OperationConfig{
+				RepositoryName: format + "-release-app1",
+				PackageManager: format,
+				RemoteURL:      "https://upstream.example.com/",
+			})
+
+			assert.NoError(t, err)
+			body := decodeBody(t, rt.last)
+			assert.Equal(t, want, body["storage"].(map[string]any)["strictContentTypeValidation"])
+			assert.Equal(t, want, body["httpClient"].(map[string]any)["autoBlock"])
+		})
+	}
+}
+
+func TestNexusClient_CreateProxyRepository_Description(t *testing.T) {
+	formats := map[string]config.PackageManager{
+		"npm": {APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"}},
+	}
+	rt := &stubTransport{status: http.StatusCreated}
+	c := NewNexusClient("http://nexus.test/service/rest/", "admin", "secret", time.Second, formats, WithTransport(rt))
+
+	_, err := c.CreateProxyRepository(context.Background(), &config.OperationConfig{
+		RepositoryName:        "npm-release-app1",
+		RepositoryDescription: "Managed by sonatype-resource-automation for user1/app1",
+		PackageManager:        "npm",
+		RemoteURL:             "https://registry.npmjs.org",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Managed by sonatype-resource-automation for user1/app1", decodeBody(t, rt.last)["description"])
+}
+
+func TestProxyRepositoryConfig_NegativeCache(t *testing.T) {
+	disabled := false
+	manager := config.PackageManager{
+		APIEndpoint:          &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"},
+		NegativeCacheEnabled: &disabled,
+	}
+
+	body, err := proxyRepositoryConfig(manager, &config.OperationConfig{RepositoryName: "npm-release-app1", PackageManager: "npm"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"enabled": false, "timeToLive": config.DefaultNegativeCacheTTL}, body["negativeCache"])
+}
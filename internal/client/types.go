@@ -27,9 +27,15 @@ type Role struct {
 	Description string   `json:"description,omitempty"`
 	Privileges  []string `json:"privileges"`
 	Roles       []string `json:"roles"`
+	// ETag is the version returned by GetRole; UpdateRole sends it as If-Match
+	ETag string `json:"-"`
 }
 
 // User represents a Nexus user.
+// LocalUserSource is the Source of users in Nexus's own realm. Users of any other
+// source, such as LDAP, have their status managed outside Nexus.
+const LocalUserSource = "default"
+
 type User struct {
 	UserID       string   `json:"userId"`
 	FirstName    string   `json:"firstName"`
@@ -40,6 +46,14 @@ type User struct {
 	Roles        []string `json:"roles"`
 }
 
+// IQOrganization represents an organization in IQ Server. ParentOrganizationID is
+// empty for the root organization.
+type IQOrganization struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	ParentOrganizationID string `json:"parentOrganizationId,omitempty"`
+}
+
 // IQRole represents a role in IQ Server.
 type IQRole struct {
 	ID          string `json:"id"`
@@ -2,11 +2,16 @@
 package config
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
@@ -16,33 +21,135 @@ var validate = validator.New()
 
 // Config holds the application's configuration, loaded from .env and JSON files.
 type Config struct {
-	NexusURL         string `validate:"required,url"`
-	NexusUsername    string `validate:"required"`
-	NexusPassword    string `validate:"required"`
-	BaseRoles        []string
-	ExtraRoles       []string
-	IQServerURL      string `validate:"required,url"`
-	IQServerUsername string `validate:"required"`
-	IQServerPassword string `validate:"required"`
-	APIHost          string `validate:"required"`
-	Port             int    `validate:"required,min=1,max=65535"`
-	APIToken         string `validate:"required"`
-	Orgs             map[string]string
-	PackageManagers  map[string]PackageManager `validate:"required,dive"`
-}
-
-func parseRoles(value string) []string {
+	NexusURL       string `validate:"required,url"`
+	NexusUsername  string `validate:"required"`
+	NexusPassword  string `validate:"required"`
+	NexusTimeout   time.Duration
+	BaseRoles      []string
+	ExtraRoles     []string
+	ProtectedRoles []string
+	// SharedRoleName is the Nexus role granting access to shared repositories
+	SharedRoleName string `validate:"required"`
+	// IQDisabled is set by IQ_ENABLED=false; the IQ Server settings are then optional
+	// and operations skip every IQ step
+	IQDisabled       bool
+	IQServerURL      string `validate:"required_unless=IQDisabled true,omitempty,url"`
+	IQServerUsername string `validate:"required_unless=IQDisabled true"`
+	IQServerPassword string `validate:"required_unless=IQDisabled true"`
+	IQServerTimeout  time.Duration
+	IQOwnerRoleName  string `validate:"required_unless=IQDisabled true"`
+	// IQOwnerAncestorFallback retries Owner removal at the ancestor organizations when
+	// the user holds no Owner membership at the requested one
+	IQOwnerAncestorFallback bool
+	// HTTPMaxIdleConns, HTTPMaxIdleConnsPerHost, HTTPIdleConnTimeout and HTTPKeepAlive
+	// tune connection reuse by the Nexus and IQ Server clients
+	HTTPMaxIdleConns        int `validate:"min=1"`
+	HTTPMaxIdleConnsPerHost int `validate:"min=1"`
+	HTTPIdleConnTimeout     time.Duration
+	HTTPKeepAlive           time.Duration
+	APIHost                 string `validate:"required"`
+	Port                    int    `validate:"required,min=1,max=65535"`
+	APIToken                string `validate:"required"`
+	MaxBatchSize            int    `validate:"min=1"`
+	// MaxFailedRequestDetails caps the failed requests of a job stored with their details
+	MaxFailedRequestDetails int `validate:"min=1"`
+	// MaxConcurrentJobs bounds in-flight batch jobs; further submissions get 429
+	MaxConcurrentJobs int `validate:"min=1"`
+	// JobWorkers bounds the requests of one job processed at once; zero uses DefaultJobWorkers
+	JobWorkers int `validate:"min=1"`
+	// OperationTimeout bounds a single create or delete operation; zero means no limit
+	OperationTimeout time.Duration
+	// OperationMaxRetries bounds the retries of a batch request that failed with a
+	// retriable error; zero disables retries
+	OperationMaxRetries int `validate:"min=0"`
+	// OperationRetryBackoff is the wait before the first retry, doubled after each failure
+	OperationRetryBackoff time.Duration
+	// BatchRetryBudget bounds the retries of all the requests of one job together
+	BatchRetryBudget int `validate:"min=0"`
+	// ShutdownTimeout bounds how long shutdown waits for open HTTP connections to finish
+	ShutdownTimeout time.Duration
+	// JobDrainTimeout bounds how long shutdown then waits for running batch jobs
+	JobDrainTimeout time.Duration
+	Orgs            map[string]Organization   `validate:"dive"`
+	PackageManagers map[string]PackageManager `validate:"required,dive"`
+
+	// CaseInsensitiveRoles compares role names ignoring case during cleanup
+	CaseInsensitiveRoles bool
+	// RollbackOnFailure undoes resources created by a creation that fails part-way
+	RollbackOnFailure bool
+	// CreateMissingUsers creates Nexus users that don't exist yet instead of failing creation
+	CreateMissingUsers bool
+	// AutoCreateOrg lets creation name organizations missing from the mapping; they are
+	// looked up in IQ Server and created there when it has none
+	AutoCreateOrg bool
+	// OffboardingUserAction is what offboarding does to the Nexus user: disable, reset-only or delete
+	OffboardingUserAction string
+	// DisableExternalUsers lets offboarding disable users of an external source such as
+	// LDAP, whose status Nexus does not manage; by default their roles are only reset
+	DisableExternalUsers bool
+	// RoleCleanupMode is how a deletion treats the role: skip, delete-if-empty or force-delete
+	RoleCleanupMode string
+	// StripPrivilegeRefs removes a privilege from the roles still referencing it before
+	// the privilege is deleted, instead of only warning about them
+	StripPrivilegeRefs bool
+	// PrivilegeActions are the actions granted by the privileges of full-access requests
+	PrivilegeActions []string
+	// DefaultPackageManager is used when a request omits PackageManager (except offboarding)
+	DefaultPackageManager string
+
+	// RepositoryNameTemplate names repositories and privileges, e.g. "{packageManager}-release-{appId}"
+	RepositoryNameTemplate string
+	// RepositoryDescriptionTemplate describes created repositories, e.g. "Managed for {ldapUsername}/{appId}"; {ldapUsername} is empty for shared ones
+	RepositoryDescriptionTemplate string
+	// OffboardingMatchPattern optionally overrides the glob offboarding uses to find an
+	// application's resources; empty derives it from RepositoryNameTemplate
+	OffboardingMatchPattern string
+
+	// OrphanScanInterval is how often to scan for orphaned repositories; zero disables the scan
+	OrphanScanInterval time.Duration
+	// OrphanScanDelete lets the scan delete the orphans two consecutive scans find instead of only reporting them
+	OrphanScanDelete bool
+
+	// OperationHookURL receives a JSON POST after every operation; empty disables the hook
+	OperationHookURL string `validate:"omitempty,url"`
+	// OperationHookTimeout bounds each POST to OperationHookURL
+	OperationHookTimeout time.Duration
+	// OperationHookMaxAttempts bounds the deliveries of one event, the first included
+	OperationHookMaxAttempts int `validate:"min=1"`
+	// OperationHookRetryBackoff is the wait before the first redelivery, doubled after each failure
+	OperationHookRetryBackoff time.Duration
+	// OperationHookQueueSize bounds the events waiting for redelivery; more are dropped
+	OperationHookQueueSize int `validate:"min=1"`
+
+	// AllowedCIDRs restricts the API to clients in these ranges; empty allows every client
+	AllowedCIDRs []netip.Prefix
+	// TrustedProxies are the proxies whose X-Forwarded-For header is believed when
+	// determining the client IP; empty ignores the header
+	TrustedProxies []string
+	// ExposeOrganizationIDs includes the IQ Server organization IDs when listing organizations
+	ExposeOrganizationIDs bool
+	// EnablePprof serves the net/http/pprof profiles, behind the API token, under /debug/pprof
+	EnablePprof bool
+	// StartupBackendCheck holds mutating endpoints at 503 until Nexus and IQ Server
+	// have answered a ping, instead of only until the configuration is loaded
+	StartupBackendCheck bool
+	// RoutePrefix mounts every route under a path such as "/api/v1"; empty mounts them at the root
+	RoutePrefix string
+}
+
+// parseList splits a comma-separated value into its trimmed, non-empty parts.
+func parseList(value string) []string {
 	if strings.TrimSpace(value) == "" {
 		return nil
 	}
 	parts := strings.Split(value, ",")
-	roles := make([]string, 0, len(parts))
+	items := make([]string, 0, len(parts))
 	for _, raw := range parts {
-		if role := strings.TrimSpace(raw); role != "" {
-			roles = append(roles, role)
+		if item := strings.TrimSpace(raw); item != "" {
+			items = append(items, item)
 		}
 	}
-	return roles
+	return items
 }
 
 // Load loads and validates the full application configuration.
@@ -54,58 +161,213 @@ func Load() (*Config, error) {
 	v.AutomaticEnv()
 	v.SetDefault("API_HOST", "127.0.0.1")
 	v.SetDefault("PORT", 5000)
+	v.SetDefault("MAX_BATCH_SIZE", DefaultMaxBatchSize)
+	v.SetDefault("MAX_FAILED_REQUEST_DETAILS", DefaultMaxFailedRequestDetails)
+	v.SetDefault("MAX_CONCURRENT_JOBS", DefaultMaxConcurrentJobs)
+	v.SetDefault("JOB_WORKERS", DefaultJobWorkers)
+	v.SetDefault("REPOSITORY_NAME_TEMPLATE", DefaultRepositoryNameTemplate)
+	v.SetDefault("REPOSITORY_DESCRIPTION_TEMPLATE", DefaultRepositoryDescriptionTemplate)
+	v.SetDefault("OFFBOARDING_USER_ACTION", DefaultOffboardingUserAction)
+	v.SetDefault("ROLE_CLEANUP_MODE", DefaultRoleCleanupMode)
+	v.SetDefault("NEXUS_TIMEOUT", DefaultBackendTimeout)
+	v.SetDefault("IQ_ENABLED", true)
+	v.SetDefault("IQSERVER_TIMEOUT", DefaultBackendTimeout)
+	v.SetDefault("HTTP_MAX_IDLE_CONNS", DefaultHTTPMaxIdleConns)
+	v.SetDefault("HTTP_MAX_IDLE_CONNS_PER_HOST", DefaultHTTPMaxIdleConnsPerHost)
+	v.SetDefault("HTTP_IDLE_CONN_TIMEOUT", DefaultHTTPIdleConnTimeout)
+	v.SetDefault("HTTP_KEEP_ALIVE", DefaultHTTPKeepAlive)
+	v.SetDefault("IQSERVER_OWNER_ROLE_NAME", DefaultIQOwnerRoleName)
+	v.SetDefault("SHARED_ROLE_NAME", DefaultSharedRoleName)
+	v.SetDefault("OPERATION_TIMEOUT", DefaultOperationTimeout)
+	v.SetDefault("OPERATION_MAX_RETRIES", DefaultOperationMaxRetries)
+	v.SetDefault("OPERATION_RETRY_BACKOFF", DefaultOperationRetryBackoff)
+	v.SetDefault("BATCH_RETRY_BUDGET", DefaultBatchRetryBudget)
+	v.SetDefault("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout)
+	v.SetDefault("JOB_DRAIN_TIMEOUT", DefaultJobDrainTimeout)
+	v.SetDefault("ORPHAN_SCAN_INTERVAL", "0")
+	v.SetDefault("OPERATION_HOOK_TIMEOUT", DefaultOperationHookTimeout)
+	v.SetDefault("OPERATION_HOOK_MAX_ATTEMPTS", DefaultOperationHookMaxAttempts)
+	v.SetDefault("OPERATION_HOOK_RETRY_BACKOFF", DefaultOperationHookRetryBackoff)
+	v.SetDefault("OPERATION_HOOK_QUEUE_SIZE", DefaultOperationHookQueueSize)
 
+	// The .env file is optional; the environment alone may configure everything.
+	// An explicit config file that is missing surfaces as a plain os error
 	if err := v.ReadInConfig(); err != nil {
 		var cfgErr viper.ConfigFileNotFoundError
-		if !errors.As(err, &cfgErr) {
+		if !errors.As(err, &cfgErr) && !errors.Is(err, os.ErrNotExist) {
 			return nil, fmt.Errorf("failed to read config: %w", err)
 		}
 	}
 
 	appConfig := &Config{
-		NexusURL:         v.GetString("NEXUS_URL"),
-		NexusUsername:    v.GetString("NEXUS_USERNAME"),
-		NexusPassword:    v.GetString("NEXUS_PASSWORD"),
-		IQServerURL:      v.GetString("IQSERVER_URL"),
-		IQServerUsername: v.GetString("IQSERVER_USERNAME"),
-		IQServerPassword: v.GetString("IQSERVER_PASSWORD"),
-		APIHost:          v.GetString("API_HOST"),
-		Port:             v.GetInt("PORT"),
-		APIToken:         v.GetString("API_TOKEN"),
+		NexusURL:                v.GetString("NEXUS_URL"),
+		NexusUsername:           v.GetString("NEXUS_USERNAME"),
+		NexusTimeout:            v.GetDuration("NEXUS_TIMEOUT"),
+		IQDisabled:              !v.GetBool("IQ_ENABLED"),
+		IQServerURL:             v.GetString("IQSERVER_URL"),
+		IQServerUsername:        v.GetString("IQSERVER_USERNAME"),
+		IQServerTimeout:         v.GetDuration("IQSERVER_TIMEOUT"),
+		HTTPMaxIdleConns:        v.GetInt("HTTP_MAX_IDLE_CONNS"),
+		HTTPMaxIdleConnsPerHost: v.GetInt("HTTP_MAX_IDLE_CONNS_PER_HOST"),
+		HTTPIdleConnTimeout:     v.GetDuration("HTTP_IDLE_CONN_TIMEOUT"),
+		HTTPKeepAlive:           v.GetDuration("HTTP_KEEP_ALIVE"),
+		IQOwnerRoleName:         strings.TrimSpace(v.GetString("IQSERVER_OWNER_ROLE_NAME")),
+		SharedRoleName:          strings.TrimSpace(v.GetString("SHARED_ROLE_NAME")),
+		IQOwnerAncestorFallback: v.GetBool("IQSERVER_OWNER_ANCESTOR_FALLBACK"),
+		APIHost:                 v.GetString("API_HOST"),
+		Port:                    v.GetInt("PORT"),
+		MaxBatchSize:            v.GetInt("MAX_BATCH_SIZE"),
+		MaxFailedRequestDetails: v.GetInt("MAX_FAILED_REQUEST_DETAILS"),
+		MaxConcurrentJobs:       v.GetInt("MAX_CONCURRENT_JOBS"),
+		JobWorkers:              v.GetInt("JOB_WORKERS"),
+		OperationTimeout:        v.GetDuration("OPERATION_TIMEOUT"),
+		OperationMaxRetries:     v.GetInt("OPERATION_MAX_RETRIES"),
+		OperationRetryBackoff:   v.GetDuration("OPERATION_RETRY_BACKOFF"),
+		BatchRetryBudget:        v.GetInt("BATCH_RETRY_BUDGET"),
+		ShutdownTimeout:         v.GetDuration("SHUTDOWN_TIMEOUT"),
+		JobDrainTimeout:         v.GetDuration("JOB_DRAIN_TIMEOUT"),
+		CaseInsensitiveRoles:    v.GetBool("CASE_INSENSITIVE_ROLES"),
+		RollbackOnFailure:       v.GetBool("ROLLBACK_ON_FAILURE"),
+		CreateMissingUsers:      v.GetBool("NEXUS_CREATE_MISSING_USERS"),
+		AutoCreateOrg:           v.GetBool("AUTO_CREATE_ORG"),
+		StripPrivilegeRefs:      v.GetBool("STRIP_PRIVILEGE_REFERENCES"),
+
+		ExposeOrganizationIDs: v.GetBool("EXPOSE_ORGANIZATION_IDS"),
+		EnablePprof:           v.GetBool("ENABLE_PPROF"),
+		StartupBackendCheck:   v.GetBool("STARTUP_BACKEND_CHECK"),
+
+		OffboardingUserAction: v.GetString("OFFBOARDING_USER_ACTION"),
+		DisableExternalUsers:  v.GetBool("DISABLE_EXTERNAL_USERS"),
+		RoleCleanupMode:       v.GetString("ROLE_CLEANUP_MODE"),
+		DefaultPackageManager: NormalizePackageManager(v.GetString("DEFAULT_PACKAGE_MANAGER")),
+
+		RepositoryNameTemplate:        v.GetString("REPOSITORY_NAME_TEMPLATE"),
+		RepositoryDescriptionTemplate: v.GetString("REPOSITORY_DESCRIPTION_TEMPLATE"),
+		OffboardingMatchPattern:       v.GetString("OFFBOARDING_MATCH_PATTERN"),
+
+		OrphanScanInterval: v.GetDuration("ORPHAN_SCAN_INTERVAL"),
+		OrphanScanDelete:   v.GetBool("ORPHAN_SCAN_DELETE"),
+
+		OperationHookURL:          strings.TrimSpace(v.GetString("OPERATION_HOOK_URL")),
+		OperationHookTimeout:      v.GetDuration("OPERATION_HOOK_TIMEOUT"),
+		OperationHookMaxAttempts:  v.GetInt("OPERATION_HOOK_MAX_ATTEMPTS"),
+		OperationHookRetryBackoff: v.GetDuration("OPERATION_HOOK_RETRY_BACKOFF"),
+		OperationHookQueueSize:    v.GetInt("OPERATION_HOOK_QUEUE_SIZE"),
 	}
 
 	extraRole := v.GetString("EXTRA_ROLE")
-	appConfig.ExtraRoles = parseRoles(extraRole)
+	appConfig.ExtraRoles = parseList(extraRole)
+
+	appConfig.ProtectedRoles = parseList(v.GetString("PROTECTED_ROLES"))
+
+	privilegeActions, err := parsePrivilegeActions(v.GetString("PRIVILEGE_ACTIONS"))
+	if err != nil {
+		return nil, err
+	}
+	appConfig.PrivilegeActions = privilegeActions
+
+	// Secrets may come from files mounted by the platform instead of the environment
+	if appConfig.NexusPassword, err = loadSecret(v, "NEXUS_PASSWORD"); err != nil {
+		return nil, err
+	}
+	if appConfig.IQServerPassword, err = loadSecret(v, "IQSERVER_PASSWORD"); err != nil {
+		return nil, err
+	}
+	if appConfig.APIToken, err = loadSecret(v, "API_TOKEN"); err != nil {
+		return nil, err
+	}
 
 	// Parse Base Roles
 	baseRoleStr := v.GetString("BASE_ROLE")
-	appConfig.BaseRoles = parseRoles(baseRoleStr)
+	appConfig.BaseRoles = parseList(baseRoleStr)
 
 	// Validate: Manually check if at least one base role exists if it is required
 	if len(appConfig.BaseRoles) == 0 {
 		return nil, fmt.Errorf("BASE_ROLE cannot be empty")
 	}
 
-	// Load organizations.json
-	file, err := os.Open("config/organizations.json")
+	if err := validateNamingTemplate("REPOSITORY_NAME_TEMPLATE", appConfig.RepositoryNameTemplate); err != nil {
+		return nil, err
+	}
+	if appConfig.OffboardingMatchPattern != "" {
+		if err := requirePlaceholder("OFFBOARDING_MATCH_PATTERN", appConfig.OffboardingMatchPattern, PlaceholderAppID); err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(appConfig.OffboardingMatchPattern, ""); err != nil {
+			return nil, fmt.Errorf("OFFBOARDING_MATCH_PATTERN '%s': %w", appConfig.OffboardingMatchPattern, err)
+		}
+	}
+
+	if err := validateOffboardingUserAction(appConfig.OffboardingUserAction); err != nil {
+		return nil, err
+	}
+	if err := validateRoleCleanupMode(appConfig.RoleCleanupMode); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("NEXUS_TIMEOUT", v.GetString("NEXUS_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("IQSERVER_TIMEOUT", v.GetString("IQSERVER_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("HTTP_IDLE_CONN_TIMEOUT", v.GetString("HTTP_IDLE_CONN_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("HTTP_KEEP_ALIVE", v.GetString("HTTP_KEEP_ALIVE")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("OPERATION_TIMEOUT", v.GetString("OPERATION_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("SHUTDOWN_TIMEOUT", v.GetString("SHUTDOWN_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("OPERATION_RETRY_BACKOFF", v.GetString("OPERATION_RETRY_BACKOFF")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("JOB_DRAIN_TIMEOUT", v.GetString("JOB_DRAIN_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateInterval("ORPHAN_SCAN_INTERVAL", v.GetString("ORPHAN_SCAN_INTERVAL")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("OPERATION_HOOK_TIMEOUT", v.GetString("OPERATION_HOOK_TIMEOUT")); err != nil {
+		return nil, err
+	}
+	if err := validateTimeout("OPERATION_HOOK_RETRY_BACKOFF", v.GetString("OPERATION_HOOK_RETRY_BACKOFF")); err != nil {
+		return nil, err
+	}
+
+	allowedCIDRs, err := parsePrefixes("ALLOWED_CIDRS", v.GetString("ALLOWED_CIDRS"))
 	if err != nil {
-		return nil, fmt.Errorf("open organizations.json: %w", err)
+		return nil, err
+	}
+	appConfig.AllowedCIDRs = allowedCIDRs
+	if _, err := parsePrefixes("TRUSTED_PROXIES", v.GetString("TRUSTED_PROXIES")); err != nil {
+		return nil, err
 	}
-	defer file.Close()
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&appConfig.Orgs); err != nil {
-		return nil, fmt.Errorf("failed to decode organizations: %w", err)
+	appConfig.TrustedProxies = parseList(v.GetString("TRUSTED_PROXIES"))
+	if appConfig.RoutePrefix, err = normalizeRoutePrefix(v.GetString("ROUTE_PREFIX")); err != nil {
+		return nil, err
 	}
 
-	// Load packageManager.json
-	file, err = os.Open("config/packageManager.json")
+	// Load organizations.json, or the ORGS variable when the file is missing
+	appConfig.Orgs, err = loadOrganizations(OrganizationFile, v.GetString("ORGS"))
 	if err != nil {
-		return nil, fmt.Errorf("open packageManager.json: %w", err)
+		return nil, err
 	}
-	defer file.Close()
-	decoder = json.NewDecoder(file)
-	if err := decoder.Decode(&appConfig.PackageManagers); err != nil {
-		return nil, fmt.Errorf("failed to decode packageManager.json: %w", err)
+
+	// Load packageManager.json, one file per package manager from packageManagers/, or
+	// the PACKAGE_MANAGERS variable when neither exists
+	appConfig.PackageManagers, err = loadPackageManagers(PackageManagerFile, PackageManagerDir, v.GetString("PACKAGE_MANAGERS"))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateFormatSettings(appConfig.PackageManagers); err != nil {
+		return nil, err
+	}
+	if err := appConfig.validateDefaultPackageManager(); err != nil {
+		return nil, err
 	}
 
 	// Validate everything together
@@ -115,16 +377,83 @@ func Load() (*Config, error) {
 	return appConfig, nil
 }
 
+// NormalizePackageManager returns the canonical form of a package manager name, the
+// lowercase key it has in packageManager.json.
+func NormalizePackageManager(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// HasPackageManager reports whether the package manager is configured, ignoring case.
+func (c Config) HasPackageManager(name string) bool {
+	_, ok := c.PackageManagers[NormalizePackageManager(name)]
+	return ok
+}
+
+// OrganizationNames returns the configured organization names in sorted order.
+func (c Config) OrganizationNames() []string {
+	names := make([]string, 0, len(c.Orgs))
+	for name := range c.Orgs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AutoCreatesOrganizations reports whether creation may use organizations missing from
+// the mapping. It needs IQ Server, which owns the organizations.
+func (c Config) AutoCreatesOrganizations() bool {
+	return c.AutoCreateOrg && !c.IQDisabled
+}
+
+// SupportedPackageManagers returns the configured package manager names in sorted order.
+func (c Config) SupportedPackageManagers() []string {
+	names := make([]string, 0, len(c.PackageManagers))
+	for name := range c.PackageManagers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// WithDefaultPackageManager returns the request with DefaultPackageManager filled in when
+// it omits PackageManager. Offboarding (a shared delete) must not name a package
+// manager, so it is returned unchanged.
+func (c Config) WithDefaultPackageManager(r RepositoryRequest, action string) RepositoryRequest {
+	if r.PackageManager == "" && c.DefaultPackageManager != "" && !(action == "delete" && r.Shared) {
+		r.PackageManager = c.DefaultPackageManager
+	}
+	return r
+}
+
+// CanonicalRequest returns the request with the default package manager applied and
+// its package manager normalized, so every later name, lookup and privilege format
+// uses the same spelling whatever case the caller sent.
+func (c Config) CanonicalRequest(r RepositoryRequest, action string) RepositoryRequest {
+	r = c.WithDefaultPackageManager(r, action)
+	r.PackageManager = NormalizePackageManager(r.PackageManager)
+	return r
+}
+
 // CreateOpConfig creates an OperationConfig from a validated repository request and action.
 func (c Config) CreateOpConfig(r RepositoryRequest, action string) (*OperationConfig, error) {
-	// Get Organization ID
-	orgID, ok := c.Orgs[r.OrganizationName]
+	r = c.CanonicalRequest(r, action)
+
+	// Get Organization ID, and the roles it uses in place of the global ones
+	org, ok := c.Orgs[r.OrganizationName]
 	if !ok {
 		return nil, fmt.Errorf("organization '%s' not found", r.OrganizationName)
 	}
+	baseRoles, extraRoles := c.BaseRoles, c.ExtraRoles
+	if org.BaseRoles != nil {
+		baseRoles = org.BaseRoles
+	}
+	if org.ExtraRoles != nil {
+		extraRoles = org.ExtraRoles
+	}
 
 	var remoteURL string
 	var repoName string
+	var repoDescription string
 	var privilegeName string
 
 	// Only attempt to resolve Package Manager details if PackageManager is provided.
@@ -136,38 +465,240 @@ func (c Config) CreateOpConfig(r RepositoryRequest, action string) (*OperationCo
 			return nil, fmt.Errorf("package manager '%s' not found", r.PackageManager)
 		}
 		remoteURL = manager.DefaultURL
+		if r.RemoteURL != "" {
+			if err := ValidateRemoteURL(r.RemoteURL); err != nil {
+				return nil, err
+			}
+			remoteURL = r.RemoteURL
+		}
 
 		// Generate Repository Name
-		// Logic: If AppID is present, use it. Otherwise, if Shared is true, use "shared".
+		// Logic: An explicit RepositoryName wins. Otherwise, if AppID is present, use it;
+		// if Shared is true, use "shared".
 		suffix := r.AppID
 		if suffix == "" && r.Shared {
 			suffix = "shared"
 		}
-		repoName = fmt.Sprintf("%s-release-%s", strings.ToLower(r.PackageManager), suffix)
+		repoName = RepositoryName(c.namingTemplate(), r.PackageManager, suffix)
+		repoDescription = RepositoryDescription(c.descriptionTemplate(r.Shared), r, suffix)
+		if r.RepositoryName != "" {
+			// Deletions only ever target the generated, managed name
+			if action != "create" {
+				return nil, fmt.Errorf("repository name override '%s' is only allowed on create", r.RepositoryName)
+			}
+			if err := ValidateRepositoryName(r.RepositoryName); err != nil {
+				return nil, err
+			}
+			repoName = r.RepositoryName
+		}
 		privilegeName = repoName
 	}
 
+	// Offboarding (delete with Shared=true and one or more AppIDs) discovers resources by pattern
+	var offboardingPatterns []string
+	if action == "delete" && r.Shared {
+		for _, appID := range r.AppIDs() {
+			offboardingPatterns = append(offboardingPatterns, OffboardingPatterns(c.namingTemplate(), c.OffboardingMatchPattern, appID, c.SupportedPackageManagers())...)
+		}
+	}
+
 	// Determine Role Name
-	// Logic: If Shared=true AND AppID is empty, use "repositories.share".
+	// Logic: If Shared=true AND AppID is empty, use the shared role (SHARED_ROLE_NAME).
 	// If Shared=true AND AppID is NOT empty (Special Delete Case), we target the User Role (r.LdapUsername).
 	// If Shared=false, we target the User Role (r.LdapUsername).
 	roleName := r.LdapUsername
 	if r.Shared && r.AppID == "" {
-		roleName = "repositories.share"
+		roleName = sharedRoleOrDefault(c.SharedRoleName)
 	}
 
 	return &OperationConfig{
-		Action:         action,
-		LdapUsername:   r.LdapUsername,
-		OrganizationID: orgID,
-		RemoteURL:      remoteURL,
-		ExtraRoles:     c.ExtraRoles,
-		BaseRoles:      c.BaseRoles,
-		RepositoryName: repoName,
-		PrivilegeName:  privilegeName,
-		RoleName:       roleName,
-		PackageManager: r.PackageManager,
-		Shared:         r.Shared,
-		AppID:          r.AppID,
+		Action:                  action,
+		LdapUsername:            r.LdapUsername,
+		OrganizationID:          org.ID,
+		RemoteURL:               remoteURL,
+		ExtraRoles:              extraRoles,
+		BaseRoles:               baseRoles,
+		ProtectedRoles:          c.ProtectedRoles,
+		SharedRoleName:          c.SharedRoleName,
+		CaseInsensitiveRoles:    c.CaseInsensitiveRoles,
+		Rollback:                c.RollbackOnFailure,
+		CreateMissingUsers:      c.CreateMissingUsers,
+		OffboardingUserAction:   c.OffboardingUserAction,
+		DisableExternalUsers:    c.DisableExternalUsers,
+		RoleCleanupMode:         c.RoleCleanupMode,
+		StripPrivilegeRefs:      c.StripPrivilegeRefs,
+		IQOwnerAncestorFallback: c.IQOwnerAncestorFallback,
+		Force:                   r.Force,
+		RepositoryName:          repoName,
+		RepositoryDescription:   repoDescription,
+		PrivilegeName:           privilegeName,
+		PrivilegeActions:        c.privilegeActions(r.PrivilegeAccess),
+		RoleName:                roleName,
+		PackageManager:          r.PackageManager,
+		Shared:                  r.Shared,
+		AppID:                   r.AppID,
+		DockerHTTPPort:          r.DockerHTTPPort,
+		DockerHTTPSPort:         r.DockerHTTPSPort,
+		MavenVersionPolicy:      r.MavenVersionPolicy,
+		MavenLayoutPolicy:       r.MavenLayoutPolicy,
+		RemoteUsername:          r.RemoteUsername,
+		RemotePassword:          r.RemotePassword,
+		OffboardingPatterns:     offboardingPatterns,
 	}, nil
 }
+
+// ValidateRemoteURL requires an absolute http or https URL with a host.
+func ValidateRemoteURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remote URL '%s' is invalid (expected an absolute http or https URL)", raw)
+	}
+	return nil
+}
+
+// repositoryNamePattern is the Nexus rule for repository names: letters, digits, '.',
+// '_' and '-', not starting with '.' or '-'.
+var repositoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// ValidateRepositoryName requires a name Nexus accepts for a repository, which also
+// keeps it to a single URL path segment.
+func ValidateRepositoryName(name string) error {
+	if !repositoryNamePattern.MatchString(name) {
+		return fmt.Errorf("repository name '%s' is invalid (allowed: letters, digits, '.', '_' and '-', not starting with '.' or '-')", name)
+	}
+	return nil
+}
+
+// validateDefaultPackageManager requires DEFAULT_PACKAGE_MANAGER, when set, to name a
+// configured package manager.
+func (c Config) validateDefaultPackageManager() error {
+	if c.DefaultPackageManager == "" || c.HasPackageManager(c.DefaultPackageManager) {
+		return nil
+	}
+	return fmt.Errorf("DEFAULT_PACKAGE_MANAGER '%s' is not configured (supported: %s)",
+		c.DefaultPackageManager, strings.Join(c.SupportedPackageManagers(), ", "))
+}
+
+// validateOffboardingUserAction rejects OFFBOARDING_USER_ACTION values other than
+// disable, reset-only and delete.
+func validateOffboardingUserAction(action string) error {
+	if !slices.Contains(OffboardingUserActions, action) {
+		return fmt.Errorf("OFFBOARDING_USER_ACTION '%s' is invalid (allowed: %s)",
+			action, strings.Join(OffboardingUserActions, ", "))
+	}
+	return nil
+}
+
+// parsePrivilegeActions parses the comma-separated PRIVILEGE_ACTIONS, ignoring case,
+// and rejects actions Nexus does not know. Empty selects DefaultPrivilegeActions.
+func parsePrivilegeActions(value string) ([]string, error) {
+	actions := parseList(strings.ToUpper(value))
+	if len(actions) == 0 {
+		return DefaultPrivilegeActions, nil
+	}
+	for _, action := range actions {
+		if !slices.Contains(PrivilegeActions, action) {
+			return nil, fmt.Errorf("PRIVILEGE_ACTIONS '%s' is invalid (allowed: %s)",
+				action, strings.Join(PrivilegeActions, ", "))
+		}
+	}
+	return actions, nil
+}
+
+// privilegeActions returns the actions granted for a request's PrivilegeAccess.
+func (c Config) privilegeActions(access string) []string {
+	if access == PrivilegeAccessReadOnly {
+		return ReadOnlyPrivilegeActions
+	}
+	if len(c.PrivilegeActions) == 0 {
+		return DefaultPrivilegeActions
+	}
+	return c.PrivilegeActions
+}
+
+// validateRoleCleanupMode rejects ROLE_CLEANUP_MODE values other than skip,
+// delete-if-empty and force-delete.
+func validateRoleCleanupMode(mode string) error {
+	if !slices.Contains(RoleCleanupModes, mode) {
+		return fmt.Errorf("ROLE_CLEANUP_MODE '%s' is invalid (allowed: %s)",
+			mode, strings.Join(RoleCleanupModes, ", "))
+	}
+	return nil
+}
+
+// validateTimeout requires a positive Go duration such as "30s" or "2m".
+func validateTimeout(name, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s '%s' must be a positive duration (e.g. 30s, 2m)", name, value)
+	}
+	return nil
+}
+
+// validateInterval requires a Go duration of zero or more; zero turns the task off.
+func validateInterval(name, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("%s '%s' must be a duration such as 1h, or 0 to disable", name, value)
+	}
+	return nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDR ranges such as "10.0.0.0/8". A
+// single IP address stands for a range holding only that address.
+func parsePrefixes(name, value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range parseList(value) {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s entry '%s' is not a CIDR range or IP address", name, entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// normalizeRoutePrefix turns ROUTE_PREFIX into the form gin expects, "/api/v1", adding
+// the leading slash and dropping trailing ones; "" and "/" both mean the root. Route
+// parameters and wildcards are rejected.
+func normalizeRoutePrefix(prefix string) (string, error) {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(prefix, ":*?# ") {
+		return "", fmt.Errorf("ROUTE_PREFIX '%s' must be a plain path such as /api/v1", prefix)
+	}
+	return "/" + prefix, nil
+}
+
+// RepositoryPattern returns the glob (see path.Match) matching every repository and
+// privilege named by the naming template, whatever its package manager and AppID.
+func (c Config) RepositoryPattern() string {
+	return strings.NewReplacer(PlaceholderPackageManager, "*", PlaceholderAppID, "*").
+		Replace(escapeGlob(c.namingTemplate()))
+}
+
+// descriptionTemplate returns the repository description template, falling back to the
+// default for shared or per-user repositories.
+func (c Config) descriptionTemplate(shared bool) string {
+	if c.RepositoryDescriptionTemplate == "" || (shared && c.RepositoryDescriptionTemplate == DefaultRepositoryDescriptionTemplate) {
+		if shared {
+			return DefaultSharedRepositoryDescriptionTemplate
+		}
+		return DefaultRepositoryDescriptionTemplate
+	}
+	return c.RepositoryDescriptionTemplate
+}
+
+// namingTemplate returns the repository naming template, falling back to the default.
+func (c Config) namingTemplate() string {
+	if c.RepositoryNameTemplate == "" {
+		return DefaultRepositoryNameTemplate
+	}
+	return c.RepositoryNameTemplate
+}
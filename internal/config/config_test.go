@@ -1,7 +1,12 @@
 package config
 
 import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -9,8 +14,8 @@ import (
 func TestCreateOpConfig(t *testing.T) {
 	// Setup Config
 	cfg := Config{
-		Orgs: map[string]string{
-			"org1": "org-id-1",
+		Orgs: map[string]Organization{
+			"org1": {ID: "org-id-1"},
 		},
 		PackageManagers: map[string]PackageManager{
 			"npm": {DefaultURL: "https://registry.npmjs.org"},
@@ -37,18 +42,20 @@ func TestCreateOpConfig(t *testing.T) {
 			},
 			action: "create",
 			expected: &OperationConfig{
-				Action:         "create",
-				LdapUsername:   "user1",
-				OrganizationID: "org-id-1",
-				RemoteURL:      "https://registry.npmjs.org",
-				ExtraRoles:     []string{"extra-role"},
-				BaseRoles:      []string{"base-role"},
-				RepositoryName: "npm-release-app1",
-				PrivilegeName:  "npm-release-app1",
-				RoleName:       "user1",
-				PackageManager: "npm",
-				Shared:         false,
-				AppID:          "app1",
+				Action:                "create",
+				LdapUsername:          "user1",
+				OrganizationID:        "org-id-1",
+				RemoteURL:             "https://registry.npmjs.org",
+				ExtraRoles:            []string{"extra-role"},
+				BaseRoles:             []string{"base-role"},
+				RepositoryName:        "npm-release-app1",
+				RepositoryDescription: "Managed by sonatype-resource-automation for user1/app1",
+				PrivilegeName:         "npm-release-app1",
+				PrivilegeActions:      DefaultPrivilegeActions,
+				RoleName:              "user1",
+				PackageManager:        "npm",
+				Shared:                false,
+				AppID:                 "app1",
 			},
 			expectError: false,
 		},
@@ -63,18 +70,20 @@ func TestCreateOpConfig(t *testing.T) {
 			},
 			action: "create",
 			expected: &OperationConfig{
-				Action:         "create",
-				LdapUsername:   "user1",
-				OrganizationID: "org-id-1",
-				RemoteURL:      "https://registry.npmjs.org",
-				ExtraRoles:     []string{"extra-role"},
-				BaseRoles:      []string{"base-role"},
-				RepositoryName: "npm-release-shared",
-				PrivilegeName:  "npm-release-shared",
-				RoleName:       "repositories.share",
-				PackageManager: "npm",
-				Shared:         true,
-				AppID:          "",
+				Action:                "create",
+				LdapUsername:          "user1",
+				OrganizationID:        "org-id-1",
+				RemoteURL:             "https://registry.npmjs.org",
+				ExtraRoles:            []string{"extra-role"},
+				BaseRoles:             []string{"base-role"},
+				RepositoryName:        "npm-release-shared",
+				RepositoryDescription: "Managed by sonatype-resource-automation for shared",
+				PrivilegeName:         "npm-release-shared",
+				PrivilegeActions:      DefaultPrivilegeActions,
+				RoleName:              "repositories.share",
+				PackageManager:        "npm",
+				Shared:                true,
+				AppID:                 "",
 			},
 			expectError: false,
 		},
@@ -112,3 +121,340 @@ func TestCreateOpConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateOpConfig_SharedRoleName(t *testing.T) {
+	cfg := &Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		SharedRoleName:  "team.shared",
+	}
+	req := RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", LdapUsername: "user1", Shared: true}
+
+	opConfig, err := cfg.CreateOpConfig(req, "create")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "team.shared", opConfig.RoleName)
+	assert.Equal(t, "team.shared", opConfig.SharedRole())
+	assert.Equal(t, DefaultSharedRoleName, (&OperationConfig{}).SharedRole())
+}
+
+func TestValidateOffboardingUserAction(t *testing.T) {
+	for _, action := range OffboardingUserActions {
+		assert.NoError(t, validateOffboardingUserAction(action), action)
+	}
+	assert.Error(t, validateOffboardingUserAction("archive"))
+	assert.Error(t, validateOffboardingUserAction(""))
+}
+
+func TestValidateTimeout(t *testing.T) {
+	assert.NoError(t, validateTimeout("NEXUS_TIMEOUT", "30s"))
+	assert.NoError(t, validateTimeout("IQSERVER_TIMEOUT", "2m"))
+	assert.NoError(t, validateTimeout("OPERATION_TIMEOUT", "5m"))
+	assert.Error(t, validateTimeout("NEXUS_TIMEOUT", "0s"))
+	assert.Error(t, validateTimeout("NEXUS_TIMEOUT", "-5s"))
+	assert.Error(t, validateTimeout("IQSERVER_TIMEOUT", "soon"))
+}
+
+func TestValidateRoleCleanupMode(t *testing.T) {
+	for _, mode := range RoleCleanupModes {
+		assert.NoError(t, validateRoleCleanupMode(mode), mode)
+	}
+	assert.Error(t, validateRoleCleanupMode("delete"))
+	assert.Error(t, validateRoleCleanupMode(""))
+}
+
+func TestCreateOpConfig_Overrides(t *testing.T) {
+	cfg := Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", AppID: "app1", LdapUsername: "user1"}
+
+	t.Run("Defaults when empty", func(t *testing.T) {
+		opConfig, err := cfg.CreateOpConfig(req, "create")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://registry.npmjs.org", opConfig.RemoteURL)
+		assert.Equal(t, "npm-release-app1", opConfig.RepositoryName)
+	})
+
+	t.Run("Overrides remote URL and repository name", func(t *testing.T) {
+		r := req
+		r.RemoteURL = "https://npm.eu.example.com/registry"
+		r.RepositoryName = "npm-eu-mirror"
+		opConfig, err := cfg.CreateOpConfig(r, "create")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://npm.eu.example.com/registry", opConfig.RemoteURL)
+		assert.Equal(t, "npm-eu-mirror", opConfig.RepositoryName)
+		assert.Equal(t, "npm-eu-mirror", opConfig.PrivilegeName)
+	})
+
+	t.Run("Rejects malformed remote URL", func(t *testing.T) {
+		r := req
+		r.RemoteURL = "registry.example.com"
+		_, err := cfg.CreateOpConfig(r, "create")
+		assert.ErrorContains(t, err, "remote URL 'registry.example.com' is invalid")
+	})
+
+	t.Run("Rejects an invalid repository name", func(t *testing.T) {
+		r := req
+		r.RepositoryName = "npm/../users"
+		_, err := cfg.CreateOpConfig(r, "create")
+		assert.ErrorContains(t, err, "repository name 'npm/../users' is invalid")
+	})
+
+	t.Run("Rejects a repository name on delete", func(t *testing.T) {
+		r := req
+		r.RepositoryName = "hand-made-proxy"
+		_, err := cfg.CreateOpConfig(r, "delete")
+		assert.ErrorContains(t, err, "only allowed on create")
+	})
+}
+
+func TestParsePrivilegeActions(t *testing.T) {
+	actions, err := parsePrivilegeActions("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPrivilegeActions, actions)
+
+	actions, err = parsePrivilegeActions("browse, READ,add")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BROWSE", "READ", "ADD"}, actions)
+
+	_, err = parsePrivilegeActions("READ,ADMIN")
+	assert.ErrorContains(t, err, "PRIVILEGE_ACTIONS 'ADMIN' is invalid")
+}
+
+func TestCreateOpConfig_PrivilegeActions(t *testing.T) {
+	cfg := Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", AppID: "app1", LdapUsername: "user1"}
+
+	opConfig, err := cfg.CreateOpConfig(req, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPrivilegeActions, opConfig.PrivilegeActions)
+
+	cfg.PrivilegeActions = []string{"BROWSE", "READ", "ADD"}
+	opConfig, err = cfg.CreateOpConfig(req, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BROWSE", "READ", "ADD"}, opConfig.PrivilegeActions)
+
+	req.PrivilegeAccess = PrivilegeAccessReadOnly
+	opConfig, err = cfg.CreateOpConfig(req, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BROWSE", "READ"}, opConfig.PrivilegeActions)
+}
+
+func TestValidateRemoteURL(t *testing.T) {
+	assert.NoError(t, ValidateRemoteURL("https://registry.npmjs.org"))
+	assert.NoError(t, ValidateRemoteURL("http://mirror.internal:8080/npm/"))
+	assert.Error(t, ValidateRemoteURL("ftp://mirror.internal/npm"))
+	assert.Error(t, ValidateRemoteURL("https://"))
+	assert.Error(t, ValidateRemoteURL("not a url"))
+}
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := parsePrefixes("ALLOWED_CIDRS", " 10.20.0.0/16, 192.0.2.10 ,10.1.2.3/8, 2001:db8::/32")
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.20.0.0/16"),
+		netip.MustParsePrefix("192.0.2.10/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, prefixes)
+
+	prefixes, err = parsePrefixes("ALLOWED_CIDRS", "")
+	assert.NoError(t, err)
+	assert.Empty(t, prefixes)
+
+	_, err = parsePrefixes("ALLOWED_CIDRS", "10.0.0.0/8,runners")
+	assert.EqualError(t, err, "ALLOWED_CIDRS entry 'runners' is not a CIDR range or IP address")
+}
+
+func TestNormalizeRoutePrefix(t *testing.T) {
+	for input, want := range map[string]string{"": "", "/": "", "api/v1": "/api/v1", " /api/v1/ ": "/api/v1"} {
+		got, err := normalizeRoutePrefix(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	for _, input := range []string{"/api/:version", "/api/*", "/api?x=1"} {
+		_, err := normalizeRoutePrefix(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestCreateOpConfig_DefaultPackageManager(t *testing.T) {
+	cfg := Config{
+		Orgs:                  map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:       map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		DefaultPackageManager: "npm",
+	}
+
+	create, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", AppID: "app1", LdapUsername: "user1"}, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "npm", create.PackageManager)
+	assert.Equal(t, "npm-release-app1", create.RepositoryName)
+	assert.Equal(t, "https://registry.npmjs.org", create.RemoteURL)
+
+	// Offboarding never takes a package manager
+	offboard := cfg.WithDefaultPackageManager(RepositoryRequest{AppID: "app1", Shared: true}, "delete")
+	assert.Empty(t, offboard.PackageManager)
+
+	// An explicit package manager wins
+	explicit := cfg.WithDefaultPackageManager(RepositoryRequest{PackageManager: "maven"}, "create")
+	assert.Equal(t, "maven", explicit.PackageManager)
+}
+
+func TestCreateOpConfig_MixedCasePackageManager(t *testing.T) {
+	cfg := Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"maven": {DefaultURL: "https://repo1.maven.org/maven2"}},
+	}
+
+	for _, pm := range []string{"maven", "Maven", "MAVEN", " maven "} {
+		opConfig, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", PackageManager: pm, AppID: "app1", LdapUsername: "user1"}, "create")
+		assert.NoError(t, err, pm)
+		assert.Equal(t, "maven", opConfig.PackageManager, pm)
+		assert.Equal(t, "maven-release-app1", opConfig.RepositoryName, pm)
+		assert.Equal(t, "maven-release-app1", opConfig.PrivilegeName, pm)
+		assert.Equal(t, "https://repo1.maven.org/maven2", opConfig.RemoteURL, pm)
+	}
+}
+
+func TestValidateDefaultPackageManager(t *testing.T) {
+	cfg := Config{PackageManagers: map[string]PackageManager{"npm": {}, "maven": {}}}
+	assert.NoError(t, cfg.validateDefaultPackageManager())
+
+	cfg.DefaultPackageManager = "npm"
+	assert.NoError(t, cfg.validateDefaultPackageManager())
+
+	cfg.DefaultPackageManager = "pypi"
+	assert.EqualError(t, cfg.validateDefaultPackageManager(), "DEFAULT_PACKAGE_MANAGER 'pypi' is not configured (supported: maven, npm)")
+}
+
+func TestOrganization_UnmarshalJSON(t *testing.T) {
+	var orgs map[string]Organization
+	err := json.Unmarshal([]byte(`{
+		"Department A": "iq-org-a",
+		"Department B": {"id": "iq-org-b", "baseRoles": ["dept-b-base"], "extraRoles": []}
+	}`), &orgs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Organization{ID: "iq-org-a"}, orgs["Department A"])
+	assert.Equal(t, Organization{ID: "iq-org-b", BaseRoles: []string{"dept-b-base"}, ExtraRoles: []string{}}, orgs["Department B"])
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Department C": 42}`), &orgs))
+}
+
+func TestOrganization_Validation(t *testing.T) {
+	assert.NoError(t, validate.Struct(Organization{ID: "iq-org-a"}))
+	assert.NoError(t, validate.Struct(Organization{ID: "iq-org-a", BaseRoles: []string{"base"}}))
+	assert.Error(t, validate.Struct(Organization{BaseRoles: []string{"base"}}))
+	assert.Error(t, validate.Struct(Organization{ID: "iq-org-a", BaseRoles: []string{}}))
+}
+
+func TestIQOwnerRoleName_Validation(t *testing.T) {
+	assert.NoError(t, validate.StructPartial(Config{IQOwnerRoleName: "Eigentümer"}, "IQOwnerRoleName"))
+	assert.Error(t, validate.StructPartial(Config{}, "IQOwnerRoleName"))
+	assert.NoError(t, validate.StructPartial(Config{IQDisabled: true}, "IQOwnerRoleName"))
+}
+
+func TestCreateOpConfig_OrganizationRoles(t *testing.T) {
+	cfg := Config{
+		Orgs: map[string]Organization{
+			"org1": {ID: "org-id-1"},
+			"org2": {ID: "org-id-2", BaseRoles: []string{"org2-base"}, ExtraRoles: []string{"org2-extra"}},
+			"org3": {ID: "org-id-3", ExtraRoles: []string{}},
+		},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		BaseRoles:       []string{"base-role"},
+		ExtraRoles:      []string{"extra-role"},
+	}
+	opConfig := func(org string) *OperationConfig {
+		t.Helper()
+		c, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: org, PackageManager: "npm", AppID: "app1", LdapUsername: "user1"}, "create")
+		assert.NoError(t, err)
+		return c
+	}
+
+	// Organizations without overrides use the global roles
+	global := opConfig("org1")
+	assert.Equal(t, []string{"base-role"}, global.BaseRoles)
+	assert.Equal(t, []string{"extra-role"}, global.ExtraRoles)
+
+	custom := opConfig("org2")
+	assert.Equal(t, "org-id-2", custom.OrganizationID)
+	assert.Equal(t, []string{"org2-base"}, custom.BaseRoles)
+	assert.Equal(t, []string{"org2-extra"}, custom.ExtraRoles)
+
+	// An explicit empty list opts out of the global extra roles
+	noExtra := opConfig("org3")
+	assert.Equal(t, []string{"base-role"}, noExtra.BaseRoles)
+	assert.Empty(t, noExtra.ExtraRoles)
+}
+
+// setMinimalEnvironment configures everything Load requires through the environment,
+// in an empty working directory so no config files are found.
+func setMinimalEnvironment(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("NEXUS_URL", "https://nexus.example.com/service/rest")
+	t.Setenv("NEXUS_USERNAME", "admin")
+	t.Setenv("NEXUS_PASSWORD", "secret")
+	t.Setenv("IQ_ENABLED", "false")
+	t.Setenv("API_TOKEN", "token")
+	t.Setenv("BASE_ROLE", "nx-anonymous")
+	t.Setenv("ORGS", "Department A=iq-org-a")
+	t.Setenv("PACKAGE_MANAGERS", `{"npm": {"defaultURL": "https://registry.npmjs.org", "apiEndpoint": {"path": "/v1/repositories/npm/proxy"}}}`)
+}
+
+func TestLoad_ConfigFilesOptionalWithEnvironment(t *testing.T) {
+	// No config/ directory at all: everything comes from the environment
+	setMinimalEnvironment(t)
+
+	cfg, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Organization{"Department A": {ID: "iq-org-a"}}, cfg.Orgs)
+	assert.Equal(t, []string{"npm"}, cfg.SupportedPackageManagers())
+
+	t.Setenv("PACKAGE_MANAGERS", "")
+	_, err = Load()
+	assert.ErrorContains(t, err, "open packageManager.json")
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	setMinimalEnvironment(t)
+	file := filepath.Join(t.TempDir(), "api-token")
+	assert.NoError(t, os.WriteFile(file, []byte("mounted-token\n"), 0o600))
+	t.Setenv("API_TOKEN", "")
+	t.Setenv("API_TOKEN_FILE", file)
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "mounted-token", cfg.APIToken)
+
+	t.Setenv("API_TOKEN", "token")
+	_, err = Load()
+	assert.ErrorContains(t, err, "set either API_TOKEN or API_TOKEN_FILE")
+}
+
+func TestLoad_ShutdownTimeouts(t *testing.T) {
+	setMinimalEnvironment(t)
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultShutdownTimeout, cfg.ShutdownTimeout)
+	assert.Equal(t, DefaultJobDrainTimeout, cfg.JobDrainTimeout)
+
+	t.Setenv("SHUTDOWN_TIMEOUT", "20s")
+	t.Setenv("JOB_DRAIN_TIMEOUT", "10m")
+	cfg, err = Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, 10*time.Minute, cfg.JobDrainTimeout)
+
+	t.Setenv("JOB_DRAIN_TIMEOUT", "0s")
+	_, err = Load()
+	assert.ErrorContains(t, err, "JOB_DRAIN_TIMEOUT")
+}
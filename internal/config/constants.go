@@ -9,4 +9,164 @@ const (
 	DefaultWriteTimeout    = 15 * time.Second
 	DefaultIdleTimeout     = 60 * time.Second
 	DefaultShutdownTimeout = 5 * time.Second
+
+	// SyncBatchWait bounds how long a ?sync=true batch is waited for before the response
+	// falls back to 202 with the job to poll. It stays below DefaultWriteTimeout so the
+	// response can still be written.
+	SyncBatchWait = 10 * time.Second
+
+	// DefaultJobDrainTimeout is how long shutdown waits for running batch jobs once the
+	// HTTP server has stopped, overridable via JOB_DRAIN_TIMEOUT
+	DefaultJobDrainTimeout = 30 * time.Second
+
+	// DefaultBackendTimeout is the per-request timeout for Nexus and IQ Server calls,
+	// overridable via NEXUS_TIMEOUT and IQSERVER_TIMEOUT
+	DefaultBackendTimeout = 30 * time.Second
+
+	// DefaultHTTPMaxIdleConns and DefaultHTTPMaxIdleConnsPerHost bound the idle
+	// connections kept open to the backends for reuse. The per-host limit exceeds
+	// DefaultJobWorkers, so the workers of a job don't reconnect for every call.
+	// Overridable via HTTP_MAX_IDLE_CONNS and HTTP_MAX_IDLE_CONNS_PER_HOST
+	DefaultHTTPMaxIdleConns        = 100
+	DefaultHTTPMaxIdleConnsPerHost = 32
+	// DefaultHTTPIdleConnTimeout is how long an idle backend connection is kept,
+	// overridable via HTTP_IDLE_CONN_TIMEOUT
+	DefaultHTTPIdleConnTimeout = 90 * time.Second
+	// DefaultHTTPKeepAlive is the TCP keep-alive interval of backend connections,
+	// overridable via HTTP_KEEP_ALIVE
+	DefaultHTTPKeepAlive = 30 * time.Second
+
+	// DefaultOperationTimeout bounds a whole create or delete operation across all of its
+	// backend calls, overridable via OPERATION_TIMEOUT
+	DefaultOperationTimeout = 5 * time.Minute
+
+	// DefaultOperationMaxRetries bounds the retries of a batch request that failed with
+	// a retriable error, overridable via OPERATION_MAX_RETRIES
+	DefaultOperationMaxRetries = 2
+	// DefaultOperationRetryBackoff is the wait before the first retry, doubled after
+	// each further failure, overridable via OPERATION_RETRY_BACKOFF
+	DefaultOperationRetryBackoff = time.Second
+	// MaxOperationRetryBackoff caps the doubled wait between retries
+	MaxOperationRetryBackoff = 30 * time.Second
+	// DefaultBatchRetryBudget bounds the retries of all the requests of one job
+	// together, overridable via BATCH_RETRY_BUDGET
+	DefaultBatchRetryBudget = 50
+
+	// DefaultOperationHookTimeout bounds each POST to OPERATION_HOOK_URL, overridable
+	// via OPERATION_HOOK_TIMEOUT
+	DefaultOperationHookTimeout = 5 * time.Second
+	// DefaultOperationHookMaxAttempts bounds the deliveries of one event, the first
+	// included, overridable via OPERATION_HOOK_MAX_ATTEMPTS
+	DefaultOperationHookMaxAttempts = 5
+	// DefaultOperationHookRetryBackoff is the wait before the first redelivery, doubled
+	// after each further failure, overridable via OPERATION_HOOK_RETRY_BACKOFF
+	DefaultOperationHookRetryBackoff = time.Second
+	// MaxOperationHookRetryBackoff caps the doubled wait between redeliveries
+	MaxOperationHookRetryBackoff = 5 * time.Minute
+	// DefaultOperationHookQueueSize bounds the events waiting for redelivery, overridable
+	// via OPERATION_HOOK_QUEUE_SIZE
+	DefaultOperationHookQueueSize = 1000
+
+	// ReadinessTimeout bounds each backend ping, so a hung backend cannot stall the
+	// readiness check
+	ReadinessTimeout = 3 * time.Second
+	// StartupCheckInterval is how often startup re-pings unreachable backends when
+	// STARTUP_BACKEND_CHECK is on
+	StartupCheckInterval = 5 * time.Second
+
+	// DefaultMaxBatchSize caps the number of requests accepted in a single batch
+	DefaultMaxBatchSize = 500
+
+	// DefaultMaxFailedRequestDetails caps the failed requests of one job kept with their
+	// details, overridable via MAX_FAILED_REQUEST_DETAILS
+	DefaultMaxFailedRequestDetails = 1000
+
+	// DefaultMaxConcurrentJobs caps the number of batch jobs running at once
+	DefaultMaxConcurrentJobs = 10
+
+	// DefaultQueueRetryAfter is the Retry-After hint of a batch rejected because the
+	// job queue is full, until a job has finished and its duration can be used
+	DefaultQueueRetryAfter = 30 * time.Second
+	// MaxQueueRetryAfter caps the Retry-After hint estimated from job durations
+	MaxQueueRetryAfter = 10 * time.Minute
+
+	// DefaultJobWorkers caps the requests of one job processed at once, overridable
+	// via JOB_WORKERS
+	DefaultJobWorkers = 20
+
+	// DefaultSharedRoleName is the Nexus role granting access to shared repositories,
+	// overridable via SHARED_ROLE_NAME
+	DefaultSharedRoleName = "repositories.share"
+
+	// DefaultIQOwnerRoleName is the IQ Server role granted to users, overridable via
+	// IQSERVER_OWNER_ROLE_NAME for instances that renamed or localized it
+	DefaultIQOwnerRoleName = "Owner"
+
+	// DefaultRepositoryNameTemplate is the naming scheme for repositories and privileges
+	DefaultRepositoryNameTemplate = PlaceholderPackageManager + "-release-" + PlaceholderAppID
+	// DefaultRepositoryDescriptionTemplate describes created repositories so they can be
+	// told apart from manually created ones
+	DefaultRepositoryDescriptionTemplate = "Managed by sonatype-resource-automation for " + PlaceholderLdapUsername + "/" + PlaceholderAppID
+	// DefaultSharedRepositoryDescriptionTemplate replaces the default for shared
+	// repositories, which belong to no one user
+	DefaultSharedRepositoryDescriptionTemplate = "Managed by sonatype-resource-automation for " + PlaceholderAppID
+)
+
+// Offboarding user actions, selected with OFFBOARDING_USER_ACTION
+const (
+	// OffboardingUserDisable resets the user's roles and disables the account
+	OffboardingUserDisable = "disable"
+	// OffboardingUserResetOnly resets the user's roles but leaves the account active
+	OffboardingUserResetOnly = "reset-only"
+	// OffboardingUserDelete deletes the Nexus user
+	OffboardingUserDelete = "delete"
+
+	DefaultOffboardingUserAction = OffboardingUserDisable
+)
+
+// OffboardingUserActions lists the accepted OFFBOARDING_USER_ACTION values.
+var OffboardingUserActions = []string{OffboardingUserDisable, OffboardingUserResetOnly, OffboardingUserDelete}
+
+// Actions a repository-view privilege can grant in Nexus
+const (
+	PrivilegeActionBrowse = "BROWSE"
+	PrivilegeActionRead   = "READ"
+	PrivilegeActionEdit   = "EDIT"
+	PrivilegeActionAdd    = "ADD"
+	PrivilegeActionDelete = "DELETE"
+)
+
+// PrivilegeActions lists the accepted PRIVILEGE_ACTIONS values.
+var PrivilegeActions = []string{PrivilegeActionBrowse, PrivilegeActionRead, PrivilegeActionEdit, PrivilegeActionAdd, PrivilegeActionDelete}
+
+// DefaultPrivilegeActions grants full access to the repository, overridable via PRIVILEGE_ACTIONS
+var DefaultPrivilegeActions = PrivilegeActions
+
+// ReadOnlyPrivilegeActions are granted to requests with PrivilegeAccess "read-only"
+var ReadOnlyPrivilegeActions = []string{PrivilegeActionBrowse, PrivilegeActionRead}
+
+// Privilege access levels a request selects with PrivilegeAccess
+const (
+	// PrivilegeAccessFull grants the configured PRIVILEGE_ACTIONS
+	PrivilegeAccessFull = "full"
+	// PrivilegeAccessReadOnly grants only BROWSE and READ
+	PrivilegeAccessReadOnly = "read-only"
 )
+
+// PrivilegeAccessLevels lists the accepted PrivilegeAccess values.
+var PrivilegeAccessLevels = []string{PrivilegeAccessFull, PrivilegeAccessReadOnly}
+
+// Role cleanup modes for deletions, selected with ROLE_CLEANUP_MODE
+const (
+	// RoleCleanupSkip never deletes the role
+	RoleCleanupSkip = "skip"
+	// RoleCleanupDeleteIfEmpty deletes the role only once it has no privileges left
+	RoleCleanupDeleteIfEmpty = "delete-if-empty"
+	// RoleCleanupForceDelete deletes the role even if it still has privileges
+	RoleCleanupForceDelete = "force-delete"
+
+	DefaultRoleCleanupMode = RoleCleanupDeleteIfEmpty
+)
+
+// RoleCleanupModes lists the accepted ROLE_CLEANUP_MODE values.
+var RoleCleanupModes = []string{RoleCleanupSkip, RoleCleanupDeleteIfEmpty, RoleCleanupForceDelete}
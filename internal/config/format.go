@@ -0,0 +1,275 @@
+// internal/config/format.go
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Nexus repository formats that take format-specific settings on creation. PyPI and
+// RubyGems proxies need none beyond the generic proxy settings; any configured block
+// is sent as-is.
+const (
+	FormatDocker   = "docker"
+	FormatMaven    = "maven"
+	FormatNuGet    = "nuget"
+	FormatAPT      = "apt"
+	FormatPyPI     = "pypi"
+	FormatRubyGems = "rubygems"
+)
+
+// Accepted values of the maven block's versionPolicy and layoutPolicy.
+var (
+	MavenVersionPolicies = []string{"RELEASE", "SNAPSHOT", "MIXED"}
+	MavenLayoutPolicies  = []string{"STRICT", "PERMISSIVE"}
+)
+
+// NuGetVersions are the accepted values of the nugetProxy block's nugetVersion.
+var NuGetVersions = []string{"V3", "V2"}
+
+// NuGetProxyBlock names the block holding a nuget proxy's settings.
+const NuGetProxyBlock = "nugetProxy"
+
+// DefaultNegativeCacheTTL is how long, in minutes, a proxy caches upstream 404s.
+const DefaultNegativeCacheTTL = 1440
+
+// DefaultNuGetQueryCacheMaxAge is how long, in seconds, a nuget proxy caches query results.
+const DefaultNuGetQueryCacheMaxAge = 3600
+
+// DefaultPrivilegeFormats maps repository formats whose privileges Nexus names
+// differently to the name it expects, for package managers without a privilegeFormat.
+var DefaultPrivilegeFormats = map[string]string{
+	FormatMaven: "maven2",
+}
+
+// NexusPrivilegeFormat returns the format of the repository-view privileges of the
+// package manager called name: its privilegeFormat when set, otherwise the repository
+// format (or, without an API path, the name) mapped by DefaultPrivilegeFormats, or
+// lower-cased as-is when it has no mapping.
+func (p PackageManager) NexusPrivilegeFormat(name string) string {
+	if p.PrivilegeFormat != "" {
+		return strings.ToLower(strings.TrimSpace(p.PrivilegeFormat))
+	}
+	format := p.Format()
+	if format == "" {
+		format = name
+	}
+	return NexusFormat(format)
+}
+
+// NexusFormat returns the name Nexus uses for a repository format, accepting the name
+// in its API paths as well: "maven" and "maven2" are both "maven2".
+func NexusFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if mapped, ok := DefaultPrivilegeFormats[format]; ok {
+		return mapped
+	}
+	return format
+}
+
+// Format returns the Nexus repository format the package manager creates, taken from
+// its API path (e.g. "/v1/repositories/docker/proxy" is "docker").
+func (p PackageManager) Format() string {
+	if p.APIEndpoint == nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(p.APIEndpoint.Path, "/"), "/")
+	if len(parts) < 3 || parts[1] != "repositories" {
+		return ""
+	}
+	return parts[2]
+}
+
+// DockerConnector returns the "docker" block for a docker proxy repository: the
+// configured block with v1Enabled and forceBasicAuth defaulted, and httpPort and
+// httpsPort replaced by any non-zero override. Docker clients can only reach a
+// repository through a connector, so at least one port must end up set.
+func (p PackageManager) DockerConnector(httpPort, httpsPort int) (map[string]any, error) {
+	block := p.formatBlock(FormatDocker)
+
+	if block["v1Enabled"] == nil {
+		block["v1Enabled"] = false
+	}
+	if block["forceBasicAuth"] == nil {
+		block["forceBasicAuth"] = true
+	}
+	if httpPort != 0 {
+		block["httpPort"] = httpPort
+	}
+	if httpsPort != 0 {
+		block["httpsPort"] = httpsPort
+	}
+
+	if !isPort(block["httpPort"]) && !isPort(block["httpsPort"]) {
+		return nil, fmt.Errorf("docker repositories need an httpPort or httpsPort connector")
+	}
+	return block, nil
+}
+
+// isPort reports whether v holds a usable port number, as an int or a JSON-decoded float64.
+func isPort(v any) bool {
+	switch port := v.(type) {
+	case int:
+		return port > 0 && port <= 65535
+	case float64:
+		return port > 0 && port <= 65535 && port == float64(int(port))
+	default:
+		return false
+	}
+}
+
+// MavenPolicies returns the "maven" block for a maven proxy repository: the configured
+// block with versionPolicy and layoutPolicy replaced by any non-empty override. Both
+// policies are checked against the values Nexus accepts, so a typo in
+// packageManager.json or a request fails before Nexus is called.
+func (p PackageManager) MavenPolicies(versionPolicy, layoutPolicy string) (map[string]any, error) {
+	block := p.formatBlock(FormatMaven)
+
+	if versionPolicy != "" {
+		block["versionPolicy"] = strings.ToUpper(versionPolicy)
+	}
+	if layoutPolicy != "" {
+		block["layoutPolicy"] = strings.ToUpper(layoutPolicy)
+	}
+	if block["versionPolicy"] == nil {
+		block["versionPolicy"] = MavenVersionPolicies[0]
+	}
+	if block["layoutPolicy"] == nil {
+		block["layoutPolicy"] = MavenLayoutPolicies[0]
+	}
+
+	if err := checkPolicy("versionPolicy", block["versionPolicy"], MavenVersionPolicies); err != nil {
+		return nil, err
+	}
+	if err := checkPolicy("layoutPolicy", block["layoutPolicy"], MavenLayoutPolicies); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// checkPolicy rejects a maven policy outside allowed.
+func checkPolicy(name string, value any, allowed []string) error {
+	policy, ok := value.(string)
+	if !ok || !slices.Contains(allowed, policy) {
+		return fmt.Errorf("maven %s '%v' is invalid (allowed: %s)", name, value, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// formatBlock returns a copy of the configured format-specific block named name, or an
+// empty block, so defaults can be filled in without mutating the shared config.
+func (p PackageManager) formatBlock(name string) map[string]any {
+	block := map[string]any{}
+	if p.APIEndpoint != nil {
+		if configured, ok := p.APIEndpoint.FormatSpecificConfig[name].(map[string]any); ok {
+			maps.Copy(block, configured)
+		}
+	}
+	return block
+}
+
+// NuGetProxy returns the "nugetProxy" block for a nuget proxy repository: the
+// configured block with nugetVersion defaulted to V3 and queryCacheItemMaxAge to
+// DefaultNuGetQueryCacheMaxAge seconds. Both are checked, as Nexus rejects the
+// repository without them.
+func (p PackageManager) NuGetProxy() (map[string]any, error) {
+	block := p.formatBlock(NuGetProxyBlock)
+	if block["nugetVersion"] == nil {
+		block["nugetVersion"] = NuGetVersions[0]
+	}
+	if block["queryCacheItemMaxAge"] == nil {
+		block["queryCacheItemMaxAge"] = DefaultNuGetQueryCacheMaxAge
+	}
+
+	version, ok := block["nugetVersion"].(string)
+	if !ok || !slices.Contains(NuGetVersions, strings.ToUpper(version)) {
+		return nil, fmt.Errorf("nuget nugetVersion '%v' is invalid (allowed: %s)", block["nugetVersion"], strings.Join(NuGetVersions, ", "))
+	}
+	block["nugetVersion"] = strings.ToUpper(version)
+	if !isNonNegativeInt(block["queryCacheItemMaxAge"]) {
+		return nil, fmt.Errorf("nuget queryCacheItemMaxAge '%v' must be a whole number of seconds", block["queryCacheItemMaxAge"])
+	}
+	return block, nil
+}
+
+// APTSettings returns the "apt" block for an apt proxy repository: the configured
+// block with flat defaulted to false. Nexus cannot proxy an apt repository without
+// knowing its distribution, so a missing one is an error.
+func (p PackageManager) APTSettings() (map[string]any, error) {
+	block := p.formatBlock(FormatAPT)
+	if block["flat"] == nil {
+		block["flat"] = false
+	}
+
+	if distribution, ok := block["distribution"].(string); !ok || strings.TrimSpace(distribution) == "" {
+		return nil, fmt.Errorf("apt repositories need a distribution (e.g. \"focal\")")
+	}
+	if _, ok := block["flat"].(bool); !ok {
+		return nil, fmt.Errorf("apt flat '%v' must be true or false", block["flat"])
+	}
+	return block, nil
+}
+
+// ValidatesContentTypes reports whether the repository rejects content that does not
+// match its declared type. It is on unless StrictContentTypeValidation is false.
+func (p PackageManager) ValidatesContentTypes() bool {
+	return p.StrictContentTypeValidation == nil || *p.StrictContentTypeValidation
+}
+
+// AutoBlocks reports whether Nexus blocks the upstream while it is unreachable. It is
+// on unless AutoBlock is false.
+func (p PackageManager) AutoBlocks() bool {
+	return p.AutoBlock == nil || *p.AutoBlock
+}
+
+// NegativeCache returns the "negativeCache" block of a proxy repository: enabled unless
+// NegativeCacheEnabled is false, for NegativeCacheTTL or DefaultNegativeCacheTTL minutes.
+func (p PackageManager) NegativeCache() (map[string]any, error) {
+	ttl := DefaultNegativeCacheTTL
+	if p.NegativeCacheTTL != nil {
+		ttl = *p.NegativeCacheTTL
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("negativeCacheTTL '%d' must not be negative", ttl)
+	}
+	return map[string]any{
+		"enabled":    p.NegativeCacheEnabled == nil || *p.NegativeCacheEnabled,
+		"timeToLive": ttl,
+	}, nil
+}
+
+// isNonNegativeInt reports whether v holds a whole number >= 0, as an int or a
+// JSON-decoded float64.
+func isNonNegativeInt(v any) bool {
+	switch n := v.(type) {
+	case int:
+		return n >= 0
+	case float64:
+		return n >= 0 && n == float64(int(n))
+	default:
+		return false
+	}
+}
+
+// validateFormatSettings checks the negative cache and the format-specific settings
+// that requests cannot override, so a broken package manager fails at startup rather
+// than on first use.
+func validateFormatSettings(managers map[string]PackageManager) error {
+	for _, name := range slices.Sorted(maps.Keys(managers)) {
+		manager := managers[name]
+		_, err := manager.NegativeCache()
+		switch {
+		case err != nil:
+		case manager.Format() == FormatNuGet:
+			_, err = manager.NuGetProxy()
+		case manager.Format() == FormatAPT:
+			_, err = manager.APTSettings()
+		}
+		if err != nil {
+			return fmt.Errorf("package manager '%s': %w", name, err)
+		}
+	}
+	return nil
+}
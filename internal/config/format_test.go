@@ -0,0 +1,206 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dockerManager(block map[string]any) PackageManager {
+	return PackageManager{
+		DefaultURL: "https://registry-1.docker.io/",
+		APIEndpoint: &APIEndpoint{
+			Path:                 "/v1/repositories/docker/proxy",
+			FormatSpecificConfig: map[string]any{"docker": block},
+		},
+	}
+}
+
+func TestPackageManager_Format(t *testing.T) {
+	assert.Equal(t, "docker", dockerManager(nil).Format())
+	assert.Equal(t, "maven", PackageManager{APIEndpoint: &APIEndpoint{Path: "/v1/repositories/maven/proxy"}}.Format())
+	assert.Equal(t, "", PackageManager{}.Format())
+}
+
+func TestPackageManager_NexusPrivilegeFormat(t *testing.T) {
+	maven := PackageManager{APIEndpoint: &APIEndpoint{Path: "/v1/repositories/maven/proxy"}}
+	assert.Equal(t, "maven2", maven.NexusPrivilegeFormat("maven"))
+	assert.Equal(t, "maven2", PackageManager{}.NexusPrivilegeFormat("Maven"))
+
+	// A configured privilegeFormat wins over the built-in mapping
+	gradle := PackageManager{PrivilegeFormat: "Gradle2", APIEndpoint: &APIEndpoint{Path: "/v1/repositories/maven/proxy"}}
+	assert.Equal(t, "gradle2", gradle.NexusPrivilegeFormat("gradle"))
+
+	// Without a mapping the repository format is used, lower-cased
+	yarn := PackageManager{APIEndpoint: &APIEndpoint{Path: "/v1/repositories/npm/proxy"}}
+	assert.Equal(t, "npm", yarn.NexusPrivilegeFormat("yarn"))
+	assert.Equal(t, "npm", PackageManager{}.NexusPrivilegeFormat("NPM"))
+}
+
+func TestPackageManager_DockerConnector(t *testing.T) {
+	t.Run("Fills defaults and applies port overrides", func(t *testing.T) {
+		manager := dockerManager(map[string]any{"httpPort": nil, "httpsPort": nil, "subdomain": nil})
+
+		block, err := manager.DockerConnector(8082, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 8082, block["httpPort"])
+		assert.Nil(t, block["httpsPort"])
+		assert.Equal(t, false, block["v1Enabled"])
+		assert.Equal(t, true, block["forceBasicAuth"])
+	})
+
+	t.Run("Configured port is enough", func(t *testing.T) {
+		manager := dockerManager(map[string]any{"httpsPort": float64(8443), "forceBasicAuth": false})
+
+		block, err := manager.DockerConnector(0, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, float64(8443), block["httpsPort"])
+		assert.Equal(t, false, block["forceBasicAuth"])
+	})
+
+	t.Run("No port is rejected", func(t *testing.T) {
+		_, err := dockerManager(map[string]any{"httpPort": nil}).DockerConnector(0, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Overrides do not mutate the shared config", func(t *testing.T) {
+		configured := map[string]any{"httpPort": nil}
+		_, err := dockerManager(configured).DockerConnector(8082, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, configured["httpPort"])
+	})
+}
+
+func TestPackageManager_MavenPolicies(t *testing.T) {
+	manager := PackageManager{
+		APIEndpoint: &APIEndpoint{
+			Path: "/v1/repositories/maven/proxy",
+			FormatSpecificConfig: map[string]any{"maven": map[string]any{
+				"versionPolicy":      "RELEASE",
+				"layoutPolicy":       "STRICT",
+				"contentDisposition": "ATTACHMENT",
+			}},
+		},
+	}
+
+	t.Run("Configured policies are used by default", func(t *testing.T) {
+		block, err := manager.MavenPolicies("", "")
+		assert.NoError(t, err)
+		assert.Equal(t, "RELEASE", block["versionPolicy"])
+		assert.Equal(t, "STRICT", block["layoutPolicy"])
+		assert.Equal(t, "ATTACHMENT", block["contentDisposition"])
+	})
+
+	t.Run("Overrides are normalized to upper case", func(t *testing.T) {
+		block, err := manager.MavenPolicies("snapshot", "Permissive")
+		assert.NoError(t, err)
+		assert.Equal(t, "SNAPSHOT", block["versionPolicy"])
+		assert.Equal(t, "PERMISSIVE", block["layoutPolicy"])
+	})
+
+	t.Run("Unknown policies are rejected", func(t *testing.T) {
+		_, err := manager.MavenPolicies("NIGHTLY", "")
+		assert.EqualError(t, err, "maven versionPolicy 'NIGHTLY' is invalid (allowed: RELEASE, SNAPSHOT, MIXED)")
+
+		invalid := PackageManager{APIEndpoint: &APIEndpoint{
+			Path:                 "/v1/repositories/maven/proxy",
+			FormatSpecificConfig: map[string]any{"maven": map[string]any{"layoutPolicy": "LOOSE"}},
+		}}
+		_, err = invalid.MavenPolicies("", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestPackageManager_NuGetProxy(t *testing.T) {
+	t.Run("Fills defaults", func(t *testing.T) {
+		manager := PackageManager{APIEndpoint: &APIEndpoint{Path: "/v1/repositories/nuget/proxy"}}
+
+		block, err := manager.NuGetProxy()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "V3", block["nugetVersion"])
+		assert.Equal(t, DefaultNuGetQueryCacheMaxAge, block["queryCacheItemMaxAge"])
+	})
+
+	t.Run("Configured values are kept", func(t *testing.T) {
+		manager := PackageManager{APIEndpoint: &APIEndpoint{
+			Path:                 "/v1/repositories/nuget/proxy",
+			FormatSpecificConfig: map[string]any{"nugetProxy": map[string]any{"nugetVersion": "v2", "queryCacheItemMaxAge": float64(60)}},
+		}}
+
+		block, err := manager.NuGetProxy()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "V2", block["nugetVersion"])
+		assert.Equal(t, float64(60), block["queryCacheItemMaxAge"])
+	})
+
+	t.Run("Invalid values are rejected", func(t *testing.T) {
+		for _, block := range []map[string]any{
+			{"nugetVersion": "V4"},
+			{"queryCacheItemMaxAge": float64(-1)},
+			{"queryCacheItemMaxAge": "an hour"},
+		} {
+			manager := PackageManager{APIEndpoint: &APIEndpoint{
+				Path:                 "/v1/repositories/nuget/proxy",
+				FormatSpecificConfig: map[string]any{"nugetProxy": block},
+			}}
+			_, err := manager.NuGetProxy()
+			assert.Error(t, err, block)
+		}
+	})
+}
+
+func TestPackageManager_APTSettings(t *testing.T) {
+	aptManager := func(block map[string]any) PackageManager {
+		return PackageManager{APIEndpoint: &APIEndpoint{
+			Path:                 "/v1/repositories/apt/proxy",
+			FormatSpecificConfig: map[string]any{"apt": block},
+		}}
+	}
+
+	t.Run("Distribution is kept and flat defaulted", func(t *testing.T) {
+		block, err := aptManager(map[string]any{"distribution": "focal"}).APTSettings()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "focal", block["distribution"])
+		assert.Equal(t, false, block["flat"])
+	})
+
+	t.Run("Missing distribution is rejected", func(t *testing.T) {
+		_, err := aptManager(map[string]any{"flat": true}).APTSettings()
+		assert.ErrorContains(t, err, "distribution")
+
+		_, err = PackageManager{APIEndpoint: &APIEndpoint{Path: "/v1/repositories/apt/proxy"}}.APTSettings()
+		assert.ErrorContains(t, err, "distribution")
+	})
+}
+
+func TestValidateFormatSettings(t *testing.T) {
+	managers := map[string]PackageManager{
+		"pypi": {APIEndpoint: &APIEndpoint{Path: "/v1/repositories/pypi/proxy"}},
+		"apt":  {APIEndpoint: &APIEndpoint{Path: "/v1/repositories/apt/proxy"}},
+	}
+	assert.ErrorContains(t, validateFormatSettings(managers), "package manager 'apt'")
+
+	managers["apt"].APIEndpoint.FormatSpecificConfig = map[string]any{"apt": map[string]any{"distribution": "focal"}}
+	assert.NoError(t, validateFormatSettings(managers))
+}
+
+func TestPackageManager_NegativeCache(t *testing.T) {
+	block, err := PackageManager{}.NegativeCache()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"enabled": true, "timeToLive": DefaultNegativeCacheTTL}, block)
+
+	disabled, ttl := false, 5
+	block, err = PackageManager{NegativeCacheEnabled: &disabled, NegativeCacheTTL: &ttl}.NegativeCache()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"enabled": false, "timeToLive": 5}, block)
+
+	ttl = -1
+	_, err = PackageManager{NegativeCacheTTL: &ttl}.NegativeCache()
+	assert.ErrorContains(t, err, "must not be negative")
+	assert.ErrorContains(t, validateFormatSettings(map[string]PackageManager{"npm": {NegativeCacheTTL: &ttl}}), "package manager 'npm'")
+}
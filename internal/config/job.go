@@ -3,6 +3,7 @@ package config
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -29,6 +30,10 @@ type Job struct {
 	CreatedAt time.Time
 	// UpdatedAt is the time when the job was last updated
 	UpdatedAt time.Time
+	// StartedAt is the time processing began; nil while the job is pending
+	StartedAt *time.Time
+	// DurationMs is the processing time from StartedAt until the job finished; zero until then
+	DurationMs int64
 	// TotalRequests is the count of valid requests accepted into the job
 	TotalRequests int
 	// SuccessfulOperations counts requests that completed without error
@@ -37,10 +42,137 @@ type Job struct {
 	FailedOperations int
 	// NotProcessedOperations counts requests not yet processed
 	NotProcessedOperations int
-	// FailedRequests contains details of requests that failed
+	// ErrorSummary groups FailedRequests by reason, most frequent first
+	ErrorSummary []FailureReasonCount
+	// FailedRequests contains details of requests that failed, at most
+	// MAX_FAILED_REQUEST_DETAILS of them
 	FailedRequests []FailedRequest
+	// OmittedFailedRequests counts the failures left out of FailedRequests by that cap
+	OmittedFailedRequests int
 	// Message is a human-readable status message
 	Message string
+	// HookDeliveries tracks the operation hook deliveries of the job's operations; nil
+	// while no hook is configured
+	HookDeliveries *HookDeliveries
+}
+
+// Operation hook delivery statuses.
+const (
+	HookDeliveryDelivered = "delivered"
+	HookDeliveryPending   = "pending"
+	HookDeliveryFailed    = "failed"
+)
+
+// HookDeliveries counts a job's operation hook deliveries by status. Status is pending
+// while any delivery is still being retried, failed if any was given up on, and
+// delivered otherwise.
+type HookDeliveries struct {
+	Status    string
+	Delivered int
+	Pending   int
+	Failed    int
+}
+
+// record moves one delivery from the status from, empty for a new delivery, to the status to.
+func (d *HookDeliveries) record(from, to string) {
+	if count := d.count(from); count != nil {
+		*count--
+	}
+	if count := d.count(to); count != nil {
+		*count++
+	}
+	switch {
+	case d.Pending > 0:
+		d.Status = HookDeliveryPending
+	case d.Failed > 0:
+		d.Status = HookDeliveryFailed
+	default:
+		d.Status = HookDeliveryDelivered
+	}
+}
+
+// FailureReasonCount is the number of failed requests that share a reason.
+type FailureReasonCount struct {
+	Reason string
+	Count  int
+}
+
+// SummarizeFailures groups failed requests by reason, ordered by count descending and
+// then by reason so the summary is stable.
+func SummarizeFailures(failed []FailedRequest) []FailureReasonCount {
+	counts := make(map[string]int)
+	for _, f := range failed {
+		counts[f.Reason]++
+	}
+	return summarizeReasons(counts)
+}
+
+// summarizeReasons orders reason counts as SummarizeFailures does.
+func summarizeReasons(counts map[string]int) []FailureReasonCount {
+	summary := make([]FailureReasonCount, 0, len(counts))
+	for reason, count := range counts {
+		summary = append(summary, FailureReasonCount{Reason: reason, Count: count})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Count != summary[j].Count {
+			return summary[i].Count > summary[j].Count
+		}
+		return summary[i].Reason < summary[j].Reason
+	})
+	return summary
+}
+
+// FailureLog collects the failures of a job. Every failure is counted and summarized,
+// but only the first limit keep their details, so a huge batch that fails entirely
+// does not hold every request in memory.
+type FailureLog struct {
+	limit   int
+	details []FailedRequest
+	omitted int
+	reasons map[string]int
+}
+
+// NewFailureLog returns a log keeping the details of at most limit failures, or of
+// DefaultMaxFailedRequestDetails when limit is not positive.
+func NewFailureLog(limit int) *FailureLog {
+	if limit <= 0 {
+		limit = DefaultMaxFailedRequestDetails
+	}
+	return &FailureLog{limit: limit, details: []FailedRequest{}, reasons: make(map[string]int)}
+}
+
+// Add records a failure, dropping its details once the limit is reached.
+func (l *FailureLog) Add(f FailedRequest) {
+	l.reasons[f.Reason]++
+	if len(l.details) < l.limit {
+		l.details = append(l.details, f)
+		return
+	}
+	l.omitted++
+}
+
+// Details returns the failures that kept their details, in the order they were added.
+func (l *FailureLog) Details() []FailedRequest { return l.details }
+
+// Omitted returns the number of failures whose details were dropped.
+func (l *FailureLog) Omitted() int { return l.omitted }
+
+// Summary groups every failure, including those without details, by reason.
+func (l *FailureLog) Summary() []FailureReasonCount { return summarizeReasons(l.reasons) }
+
+// FailedRepositoryRequests returns the original requests of the job's failures, in
+// order, so they can be resubmitted as a new batch. It is never nil. The stored
+// requests hold only a redacted RemotePassword, so it is left empty: resubmitting a
+// request with a RemoteUsername fails validation until the password is supplied again,
+// rather than configuring the proxy with the redacted placeholder.
+func (j *Job) FailedRepositoryRequests() []RepositoryRequest {
+	requests := make([]RepositoryRequest, 0, len(j.FailedRequests))
+	for _, f := range j.FailedRequests {
+		request := f.Request
+		request.RemotePassword = ""
+		requests = append(requests, request)
+	}
+	return requests
 }
 
 // JobStore manages in-memory job tracking (use database for production)
@@ -71,6 +203,7 @@ func (js *JobStore) CreateJob(id, action string, totalRequests int) *Job {
 		SuccessfulOperations:   0,
 		FailedOperations:       0,
 		NotProcessedOperations: totalRequests,
+		ErrorSummary:           make([]FailureReasonCount, 0),
 		FailedRequests:         make([]FailedRequest, 0),
 		Message:                "Job queued",
 	}
@@ -86,6 +219,95 @@ func (js *JobStore) GetJob(id string) (*Job, bool) {
 	return job, exists
 }
 
+// JobStats aggregates every job in the store. Rates are fractions between 0 and 1,
+// zero while nothing has been processed.
+type JobStats struct {
+	TotalJobs      int
+	PendingJobs    int
+	ProcessingJobs int
+	CompletedJobs  int
+	FailedJobs     int
+	// JobSuccessRate and JobFailureRate are the shares of finished jobs that completed or failed
+	JobSuccessRate float64
+	JobFailureRate float64
+	// ProcessedOperations counts the requests that finished, successfully or not
+	ProcessedOperations  int
+	SuccessfulOperations int
+	FailedOperations     int
+	// OperationSuccessRate and OperationFailureRate are the shares of processed operations
+	// that succeeded or failed
+	OperationSuccessRate float64
+	OperationFailureRate float64
+	// AverageDurationMs is the mean processing time of the finished jobs
+	AverageDurationMs int64
+}
+
+// Stats aggregates the jobs in the store. Jobs are kept until restart, so the stats
+// cover every job since then.
+func (js *JobStore) Stats() JobStats {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	var stats JobStats
+	var finished int
+	var totalDurationMs int64
+	for _, job := range js.jobs {
+		stats.TotalJobs++
+		switch job.Status {
+		case JobStatusPending:
+			stats.PendingJobs++
+		case JobStatusProcessing:
+			stats.ProcessingJobs++
+		case JobStatusCompleted:
+			stats.CompletedJobs++
+		case JobStatusFailed:
+			stats.FailedJobs++
+		}
+		if job.Status == JobStatusCompleted || job.Status == JobStatusFailed {
+			finished++
+			totalDurationMs += job.DurationMs
+		}
+		stats.SuccessfulOperations += job.SuccessfulOperations
+		stats.FailedOperations += job.FailedOperations
+	}
+	stats.ProcessedOperations = stats.SuccessfulOperations + stats.FailedOperations
+	if finished > 0 {
+		stats.JobSuccessRate = float64(stats.CompletedJobs) / float64(finished)
+		stats.JobFailureRate = float64(stats.FailedJobs) / float64(finished)
+		stats.AverageDurationMs = totalDurationMs / int64(finished)
+	}
+	if stats.ProcessedOperations > 0 {
+		stats.OperationSuccessRate = float64(stats.SuccessfulOperations) / float64(stats.ProcessedOperations)
+		stats.OperationFailureRate = float64(stats.FailedOperations) / float64(stats.ProcessedOperations)
+	}
+	return stats
+}
+
+// count returns the counter of status, or nil for an unknown status.
+func (d *HookDeliveries) count(status string) *int {
+	switch status {
+	case HookDeliveryDelivered:
+		return &d.Delivered
+	case HookDeliveryPending:
+		return &d.Pending
+	case HookDeliveryFailed:
+		return &d.Failed
+	default:
+		return nil
+	}
+}
+
+// RecordHookDelivery moves one of the job's operation hook deliveries from the status
+// from, empty for a new delivery, to the status to.
+func (js *JobStore) RecordHookDelivery(id, from, to string) error {
+	return js.UpdateJob(id, func(job *Job) {
+		if job.HookDeliveries == nil {
+			job.HookDeliveries = &HookDeliveries{}
+		}
+		job.HookDeliveries.record(from, to)
+	})
+}
+
 // UpdateJob updates a job's status and data
 func (js *JobStore) UpdateJob(id string, updateFn func(*Job)) error {
 	js.mu.Lock()
@@ -60,3 +60,81 @@ func TestUpdateJob(t *testing.T) {
 	err = store.UpdateJob("job-2", func(j *Job) {})
 	assert.Error(t, err)
 }
+
+func TestJobStore_Stats(t *testing.T) {
+	store := NewJobStore()
+	assert.Equal(t, JobStats{}, store.Stats())
+
+	finish := func(id string, status JobStatus, successful, failed int, durationMs int64) {
+		store.CreateJob(id, "create", successful+failed)
+		assert.NoError(t, store.UpdateJob(id, func(j *Job) {
+			j.Status = status
+			j.SuccessfulOperations = successful
+			j.FailedOperations = failed
+			j.NotProcessedOperations = 0
+			j.DurationMs = durationMs
+		}))
+	}
+	finish("job-1", JobStatusCompleted, 8, 0, 1000)
+	finish("job-2", JobStatusCompleted, 6, 2, 2000)
+	finish("job-3", JobStatusFailed, 0, 4, 3000)
+	store.CreateJob("job-4", "delete", 5)
+	assert.NoError(t, store.UpdateJob("job-4", func(j *Job) {
+		j.Status = JobStatusProcessing
+		j.SuccessfulOperations = 2
+		j.NotProcessedOperations = 3
+	}))
+	store.CreateJob("job-5", "create", 1)
+
+	assert.Equal(t, JobStats{
+		TotalJobs:            5,
+		PendingJobs:          1,
+		ProcessingJobs:       1,
+		CompletedJobs:        2,
+		FailedJobs:           1,
+		JobSuccessRate:       2.0 / 3,
+		JobFailureRate:       1.0 / 3,
+		ProcessedOperations:  22,
+		SuccessfulOperations: 16,
+		FailedOperations:     6,
+		OperationSuccessRate: 16.0 / 22,
+		OperationFailureRate: 6.0 / 22,
+		AverageDurationMs:    2000,
+	}, store.Stats())
+}
+
+func TestSummarizeFailures(t *testing.T) {
+	failed := []FailedRequest{
+		{Reason: "organization not found"},
+		{Reason: "nexus unavailable"},
+		{Reason: "organization not found"},
+		{Reason: "user not found"},
+		{Reason: "organization not found"},
+		{Reason: "nexus unavailable"},
+	}
+
+	assert.Equal(t, []FailureReasonCount{
+		{Reason: "organization not found", Count: 3},
+		{Reason: "nexus unavailable", Count: 2},
+		{Reason: "user not found", Count: 1},
+	}, SummarizeFailures(failed))
+	assert.Empty(t, SummarizeFailures(nil))
+}
+
+func TestJob_FailedRepositoryRequests(t *testing.T) {
+	job := &Job{FailedRequests: []FailedRequest{
+		{Request: RepositoryRequest{AppID: "app1"}, Reason: "nexus unavailable"},
+		{Request: RepositoryRequest{AppID: "app2"}, Reason: "user not found"},
+	}}
+	assert.Equal(t, []RepositoryRequest{{AppID: "app1"}, {AppID: "app2"}}, job.FailedRepositoryRequests())
+
+	// The redacted password is never handed back for resubmission
+	job = &Job{FailedRequests: []FailedRequest{
+		{Request: RepositoryRequest{AppID: "app1", RemoteUsername: "mirror", RemotePassword: "[REDACTED]"}},
+	}}
+	assert.Equal(t, []RepositoryRequest{{AppID: "app1", RemoteUsername: "mirror"}}, job.FailedRepositoryRequests())
+
+	empty := (&Job{}).FailedRepositoryRequests()
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+}
@@ -2,6 +2,12 @@
 // Package config provides configuration loading, validation, and data models.
 package config
 
+import (
+	"encoding/json"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+)
+
 // OperationConfig holds configuration for a single repository creation or deletion operation.
 type OperationConfig struct {
 	// Action is either "create" or "delete"
@@ -16,10 +22,38 @@ type OperationConfig struct {
 	ExtraRoles []string
 	// BaseRoles are the base roles to preserve during operations
 	BaseRoles []string
+	// ProtectedRoles are never removed from a user by automation
+	ProtectedRoles []string
+	// SharedRoleName is the role granting access to shared repositories; empty means
+	// DefaultSharedRoleName
+	SharedRoleName string
+	// CaseInsensitiveRoles makes role-name comparisons ignore case during cleanup
+	CaseInsensitiveRoles bool
+	// Rollback undoes the resources a creation made when a later step fails
+	Rollback bool
+	// CreateMissingUsers creates the Nexus user during creation instead of failing when it doesn't exist
+	CreateMissingUsers bool
+	// OffboardingUserAction is what offboarding does to the Nexus user: disable, reset-only or delete
+	OffboardingUserAction string
+	// DisableExternalUsers disables offboarded users of an external source such as LDAP too
+	DisableExternalUsers bool
+	// RoleCleanupMode is how a deletion treats the role: skip, delete-if-empty or force-delete
+	RoleCleanupMode string
+	// StripPrivilegeRefs removes a privilege from the roles referencing it before deleting it
+	StripPrivilegeRefs bool
+	// IQOwnerAncestorFallback retries Owner removal at ancestor organizations when the
+	// requested organization holds no Owner membership for the user
+	IQOwnerAncestorFallback bool
+	// Force removes the role from the user and deletes it even if it still has privileges
+	Force bool
 	// RepositoryName is the generated or specified repository name
 	RepositoryName string
+	// RepositoryDescription marks a created repository as managed by this service
+	RepositoryDescription string
 	// PrivilegeName is the privilege name matching the repository
 	PrivilegeName string
+	// PrivilegeActions are the actions the privilege grants, e.g. BROWSE and READ
+	PrivilegeActions []string
 	// RoleName is the role name for privilege assignment
 	RoleName string
 	// PackageManager is the package manager type (e.g., "npm", "maven", "docker")
@@ -28,6 +62,30 @@ type OperationConfig struct {
 	Shared bool
 	// AppID is the application identifier (if applicable)
 	AppID string
+	// DockerHTTPPort and DockerHTTPSPort override the docker connector ports when non-zero
+	DockerHTTPPort  int
+	DockerHTTPSPort int
+	// MavenVersionPolicy and MavenLayoutPolicy override the maven policies when non-empty
+	MavenVersionPolicy string
+	MavenLayoutPolicy  string
+	// RemoteUsername and RemotePassword authenticate the proxy against its upstream when set
+	RemoteUsername string
+	RemotePassword string
+	// OffboardingPatterns are the globs matching each AppID's repositories and privileges during offboarding
+	OffboardingPatterns []string
+}
+
+// SharedRole returns the role granting access to shared repositories.
+func (o *OperationConfig) SharedRole() string {
+	return sharedRoleOrDefault(o.SharedRoleName)
+}
+
+// sharedRoleOrDefault returns name, or DefaultSharedRoleName when it is empty.
+func sharedRoleOrDefault(name string) string {
+	if name == "" {
+		return DefaultSharedRoleName
+	}
+	return name
 }
 
 // RepositoryRequest represents a single repository operation request from the API.
@@ -42,8 +100,42 @@ type RepositoryRequest struct {
 	PackageManager string
 	// Shared indicates whether this repository is shared across applications
 	Shared bool
-	// AppID is the application identifier for non-shared repositories; must be empty for shared repositories
+	// AppID is the application identifier for non-shared repositories; must be empty for shared repositories.
+	// Offboarding accepts a comma-separated list to decommission several applications at once.
 	AppID string
+	// DockerHTTPPort and DockerHTTPSPort optionally set the connector ports of a docker repository
+	DockerHTTPPort  int `binding:"omitempty,min=1,max=65535"`
+	DockerHTTPSPort int `binding:"omitempty,min=1,max=65535"`
+	// MavenVersionPolicy (RELEASE, SNAPSHOT or MIXED) and MavenLayoutPolicy (STRICT or
+	// PERMISSIVE) optionally override the policies of a maven repository
+	MavenVersionPolicy string
+	MavenLayoutPolicy  string
+	// RemoteUsername and RemotePassword optionally authenticate the proxy against an
+	// upstream registry that requires credentials; both or neither must be set
+	RemoteUsername string
+	RemotePassword string
+	// RemoteURL optionally replaces the package manager's default upstream, e.g. a regional mirror
+	RemoteURL string
+	// RepositoryName optionally replaces the name generated from REPOSITORY_NAME_TEMPLATE
+	RepositoryName string
+	// PrivilegeAccess optionally limits the privilege: "read-only" grants BROWSE and READ,
+	// "full" (the default) grants PRIVILEGE_ACTIONS
+	PrivilegeAccess string
+	// Force makes a deletion remove the role from the user and delete it even if the
+	// role still grants other privileges; by default such a role is kept
+	Force bool
+}
+
+// Redacted returns a copy of the request with RemotePassword hidden, for storing in
+// jobs and echoing in responses.
+func (r RepositoryRequest) Redacted() RepositoryRequest {
+	r.RemotePassword = utils.Redact(r.RemotePassword)
+	return r
+}
+
+// AppIDs returns the request's application identifiers, splitting a comma-separated AppID.
+func (r RepositoryRequest) AppIDs() []string {
+	return SplitAppIDs(r.AppID)
 }
 
 // FailedRequest represents a request that failed during processing along with the error reason.
@@ -52,13 +144,58 @@ type FailedRequest struct {
 	Request RepositoryRequest
 	// Reason is the error message describing why the request failed
 	Reason string
+	// Retriable reports that the failure was transient, so resubmitting the request may succeed
+	Retriable bool
+	// ErrorCode classifies the failure, e.g. user_not_found, timeout or backend_error
+	ErrorCode string
+	// CompletedSteps lists the steps (repository, privilege, role, user, iq) that finished
+	// before FailedStep stopped the request
+	CompletedSteps []string
+	FailedStep     string
+}
+
+// Organization is an entry of organizations.json: the IQ Server organization ID and,
+// optionally, roles that replace the global BASE_ROLE and EXTRA_ROLE for its requests.
+type Organization struct {
+	ID string `validate:"required"`
+	// BaseRoles and ExtraRoles override the globals when set; an empty ExtraRoles list
+	// gives the organization no extra roles
+	BaseRoles  []string `validate:"omitnil,min=1"`
+	ExtraRoles []string
+}
+
+// UnmarshalJSON accepts the simple form, where the organization name maps straight to
+// its ID, as well as the object form with id, baseRoles and extraRoles.
+func (o *Organization) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*o = Organization{ID: id}
+		return nil
+	}
+	type organization Organization
+	var org organization
+	if err := json.Unmarshal(data, &org); err != nil {
+		return err
+	}
+	*o = Organization(org)
+	return nil
 }
 
 type PackageManager struct {
-	DefaultURL      string `validate:"required,url"`
-	DefaultConfig   map[string]any
+	DefaultURL    string `validate:"required,url"`
+	DefaultConfig map[string]any
+	// PrivilegeFormat is the format Nexus expects on the repository's privilege when it
+	// differs from the repository format, e.g. "maven2" for maven
 	PrivilegeFormat string
 	APIEndpoint     *APIEndpoint `validate:"required"`
+	// StrictContentTypeValidation and AutoBlock default to true; false relaxes them for
+	// upstreams that mislabel content types or are intermittently down
+	StrictContentTypeValidation *bool
+	AutoBlock                   *bool
+	// NegativeCacheEnabled defaults to true and NegativeCacheTTL, in minutes, to
+	// DefaultNegativeCacheTTL; fast-moving upstreams may want stale 404s cached briefly or not at all
+	NegativeCacheEnabled *bool
+	NegativeCacheTTL     *int
 	// Future proofing for additional fields
 	ExtraFields map[string]any `json:"-"`
 }
@@ -0,0 +1,103 @@
+// internal/config/naming.go
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholders understood by REPOSITORY_NAME_TEMPLATE and OFFBOARDING_MATCH_PATTERN.
+const (
+	PlaceholderPackageManager = "{packageManager}"
+	PlaceholderAppID          = "{appId}"
+)
+
+// Placeholders understood by REPOSITORY_DESCRIPTION_TEMPLATE in addition to the ones above.
+const (
+	PlaceholderLdapUsername     = "{ldapUsername}"
+	PlaceholderOrganizationName = "{organizationName}"
+)
+
+// RepositoryDescription renders the repository description template for a request,
+// with appID in place of the request's own, as for RepositoryName. A shared repository
+// is used by everyone, so {ldapUsername} renders empty rather than naming whoever
+// happened to create it.
+func RepositoryDescription(template string, r RepositoryRequest, appID string) string {
+	ldapUsername := r.LdapUsername
+	if r.Shared {
+		ldapUsername = ""
+	}
+	return strings.NewReplacer(
+		PlaceholderPackageManager, strings.ToLower(r.PackageManager),
+		PlaceholderAppID, appID,
+		PlaceholderLdapUsername, ldapUsername,
+		PlaceholderOrganizationName, r.OrganizationName,
+	).Replace(template)
+}
+
+// RepositoryName renders the repository naming template for a package manager and
+// application ID. The package manager is lowercased, as repository names always were.
+func RepositoryName(template, packageManager, appID string) string {
+	return strings.NewReplacer(
+		PlaceholderPackageManager, strings.ToLower(packageManager),
+		PlaceholderAppID, appID,
+	).Replace(template)
+}
+
+// OffboardingPatterns returns the globs (see path.Match) that match every repository
+// and privilege belonging to appID. A non-empty matchPattern is used as a glob;
+// otherwise the naming template is, with its literal parts escaped, which keeps
+// offboarding consistent with creation. {packageManager} expands to each of
+// packageManagers in turn, one exact pattern each, so it cannot swallow part of
+// another application's ID; with no packageManagers, as when the configuration is not
+// at hand, it matches any. The appID is escaped so glob metacharacters in it match
+// literally.
+func OffboardingPatterns(template, matchPattern, appID string, packageManagers []string) []string {
+	pattern := matchPattern
+	if pattern == "" {
+		pattern = escapeGlob(template)
+	}
+	if !strings.Contains(pattern, PlaceholderPackageManager) {
+		return []string{strings.ReplaceAll(pattern, PlaceholderAppID, escapeGlob(appID))}
+	}
+	if len(packageManagers) == 0 {
+		return []string{strings.NewReplacer(PlaceholderPackageManager, "*", PlaceholderAppID, escapeGlob(appID)).Replace(pattern)}
+	}
+	patterns := make([]string, 0, len(packageManagers))
+	for _, packageManager := range packageManagers {
+		patterns = append(patterns, strings.NewReplacer(
+			PlaceholderPackageManager, escapeGlob(strings.ToLower(packageManager)),
+			PlaceholderAppID, escapeGlob(appID),
+		).Replace(pattern))
+	}
+	return patterns
+}
+
+// SplitAppIDs splits a comma-separated AppID into its trimmed, non-empty parts.
+func SplitAppIDs(appID string) []string {
+	return parseList(appID)
+}
+
+// validateNamingTemplate ensures a naming template tells applications, and each
+// application's package managers, apart.
+func validateNamingTemplate(name, template string) error {
+	for _, placeholder := range []string{PlaceholderAppID, PlaceholderPackageManager} {
+		if err := requirePlaceholder(name, template, placeholder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requirePlaceholder fails unless template contains placeholder.
+func requirePlaceholder(name, template, placeholder string) error {
+	if !strings.Contains(template, placeholder) {
+		return fmt.Errorf("%s '%s' must contain %s", name, template, placeholder)
+	}
+	return nil
+}
+
+// escapeGlob backslash-escapes the characters path.Match treats specially.
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(s)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryName(t *testing.T) {
+	assert.Equal(t, "npm-release-app1", RepositoryName(DefaultRepositoryNameTemplate, "NPM", "app1"))
+	assert.Equal(t, "app1-npm-proxy", RepositoryName("{appId}-{packageManager}-proxy", "npm", "app1"))
+}
+
+func TestOffboardingPatterns(t *testing.T) {
+	matchesAny := func(patterns []string, name string) bool {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("One exact pattern per package manager", func(t *testing.T) {
+		patterns := OffboardingPatterns("{appId}-{packageManager}-proxy", "", "app1", []string{"maven2", "NPM"})
+		assert.Equal(t, []string{"app1-maven2-proxy", "app1-npm-proxy"}, patterns)
+		assert.False(t, matchesAny(patterns, "app10-maven2-proxy"))
+	})
+
+	t.Run("Package manager cannot swallow part of another AppID", func(t *testing.T) {
+		patterns := OffboardingPatterns("{appId}-{packageManager}", "", "team", []string{"npm"})
+		assert.True(t, matchesAny(patterns, "team-npm"))
+		assert.False(t, matchesAny(patterns, "team-a-npm"))
+	})
+
+	t.Run("Literal template parts are escaped", func(t *testing.T) {
+		patterns := OffboardingPatterns("team[a]-{packageManager}-{appId}", "", "app1", []string{"npm"})
+		assert.Equal(t, []string{`team\[a]-npm-app1`}, patterns)
+		assert.True(t, matchesAny(patterns, "team[a]-npm-app1"))
+	})
+
+	t.Run("Any package manager without the configuration", func(t *testing.T) {
+		patterns := OffboardingPatterns(DefaultRepositoryNameTemplate, "", "app1", nil)
+		assert.Equal(t, []string{"*-release-app1"}, patterns)
+	})
+
+	t.Run("Explicit prefix pattern wins", func(t *testing.T) {
+		patterns := OffboardingPatterns(DefaultRepositoryNameTemplate, "{appId}*", "app1", []string{"npm"})
+		assert.True(t, matchesAny(patterns, "app1-anything"))
+	})
+
+	t.Run("Glob characters in the AppID match literally", func(t *testing.T) {
+		patterns := OffboardingPatterns(DefaultRepositoryNameTemplate, "", "app*", []string{"npm"})
+		assert.True(t, matchesAny(patterns, "npm-release-app*"))
+		assert.False(t, matchesAny(patterns, "npm-release-app1"))
+	})
+}
+
+func TestValidateNamingTemplate(t *testing.T) {
+	assert.NoError(t, validateNamingTemplate("REPOSITORY_NAME_TEMPLATE", DefaultRepositoryNameTemplate))
+	assert.ErrorContains(t, validateNamingTemplate("REPOSITORY_NAME_TEMPLATE", "release-{appId}"), "must contain {packageManager}")
+	assert.ErrorContains(t, validateNamingTemplate("REPOSITORY_NAME_TEMPLATE", "{packageManager}-release"), "must contain {appId}")
+}
+
+func TestCreateOpConfig_CustomNamingTemplate(t *testing.T) {
+	cfg := Config{
+		Orgs:                   map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:        map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		RepositoryNameTemplate: "{appId}-{packageManager}-proxy",
+	}
+
+	create, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", AppID: "app1", LdapUsername: "user1"}, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "app1-npm-proxy", create.RepositoryName)
+	assert.Empty(t, create.OffboardingPatterns)
+
+	offboard, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", AppID: "app1", LdapUsername: "user1", Shared: true}, "delete")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app1-npm-proxy"}, offboard.OffboardingPatterns)
+}
+
+func TestCreateOpConfig_RepositoryDescription(t *testing.T) {
+	cfg := Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := RepositoryRequest{OrganizationName: "org1", PackageManager: "NPM", AppID: "app1", LdapUsername: "user1"}
+
+	create, err := cfg.CreateOpConfig(req, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "Managed by sonatype-resource-automation for user1/app1", create.RepositoryDescription)
+
+	cfg.RepositoryDescriptionTemplate = "{organizationName}: {packageManager} proxy of {ldapUsername} for {appId}"
+	create, err = cfg.CreateOpConfig(req, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "org1: npm proxy of user1 for app1", create.RepositoryDescription)
+
+	shared, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", LdapUsername: "user1", Shared: true}, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "org1: npm proxy of  for shared", shared.RepositoryDescription)
+
+	// The default names no user for a shared repository
+	cfg.RepositoryDescriptionTemplate = DefaultRepositoryDescriptionTemplate
+	shared, err = cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", PackageManager: "npm", LdapUsername: "user1", Shared: true}, "create")
+	assert.NoError(t, err)
+	assert.Equal(t, "Managed by sonatype-resource-automation for shared", shared.RepositoryDescription)
+}
+
+func TestCreateOpConfig_MultipleAppIDs(t *testing.T) {
+	cfg := Config{
+		Orgs:            map[string]Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+
+	offboard, err := cfg.CreateOpConfig(RepositoryRequest{OrganizationName: "org1", AppID: "app1, app2", LdapUsername: "user1", Shared: true}, "delete")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"npm-release-app1", "npm-release-app2"}, offboard.OffboardingPatterns)
+}
+
+func TestConfig_RepositoryPattern(t *testing.T) {
+	assert.Equal(t, "*-release-*", Config{}.RepositoryPattern())
+	assert.Equal(t, `team\[a]-*-*`, Config{RepositoryNameTemplate: "team[a]-{packageManager}-{appId}"}.RepositoryPattern())
+}
@@ -0,0 +1,66 @@
+// internal/config/organizations.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OrganizationFile maps organization names to their IQ Server organizations
+const OrganizationFile = "config/organizations.json"
+
+// loadOrganizations reads the organizations from file. When the file does not exist
+// they are parsed from env, the value of ORGS, instead. Either source must define at
+// least one organization.
+func loadOrganizations(file, env string) (map[string]Organization, error) {
+	var orgs map[string]Organization
+	data, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &orgs); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(file), err)
+		}
+	case errors.Is(err, os.ErrNotExist) && strings.TrimSpace(env) != "":
+		if orgs, err = parseOrganizations(env); err != nil {
+			return nil, fmt.Errorf("ORGS: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("open %s: %w", filepath.Base(file), err)
+	}
+
+	if len(orgs) == 0 {
+		return nil, fmt.Errorf("no organizations are defined")
+	}
+	return orgs, nil
+}
+
+// parseOrganizations parses ORGS, either as JSON in the format of organizations.json
+// or as a comma-separated list of name=id pairs.
+func parseOrganizations(value string) (map[string]Organization, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		var orgs map[string]Organization
+		if err := json.Unmarshal([]byte(value), &orgs); err != nil {
+			return nil, fmt.Errorf("failed to decode: %w", err)
+		}
+		return orgs, nil
+	}
+
+	orgs := map[string]Organization{}
+	for _, pair := range parseList(value) {
+		name, id, ok := strings.Cut(pair, "=")
+		name, id = strings.TrimSpace(name), strings.TrimSpace(id)
+		if !ok || name == "" || id == "" {
+			return nil, fmt.Errorf("entry '%s' is not name=id", pair)
+		}
+		if _, ok := orgs[name]; ok {
+			return nil, fmt.Errorf("organization '%s' is defined twice", name)
+		}
+		orgs[name] = Organization{ID: id}
+	}
+	return orgs, nil
+}
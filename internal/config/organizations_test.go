@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrganizations(t *testing.T) {
+	t.Run("File is the primary source", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "organizations.json")
+		writeFile(t, file, `{"Department A": "iq-org-a"}`)
+
+		orgs, err := loadOrganizations(file, "Department B=iq-org-b")
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Organization{"Department A": {ID: "iq-org-a"}}, orgs)
+	})
+
+	t.Run("JSON from ORGS when the file is missing", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "organizations.json")
+
+		orgs, err := loadOrganizations(file, `{"Department A": "iq-org-a", "Department B": {"id": "iq-org-b", "baseRoles": ["dept-b-base"]}}`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Organization{
+			"Department A": {ID: "iq-org-a"},
+			"Department B": {ID: "iq-org-b", BaseRoles: []string{"dept-b-base"}},
+		}, orgs)
+	})
+
+	t.Run("Key-value pairs from ORGS when the file is missing", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "organizations.json")
+
+		orgs, err := loadOrganizations(file, "Department A=iq-org-a, Department B = iq-org-b")
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Organization{
+			"Department A": {ID: "iq-org-a"},
+			"Department B": {ID: "iq-org-b"},
+		}, orgs)
+	})
+
+	t.Run("Malformed ORGS", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "organizations.json")
+
+		_, err := loadOrganizations(file, "Department A")
+		assert.EqualError(t, err, "ORGS: entry 'Department A' is not name=id")
+
+		_, err = loadOrganizations(file, "A=1,A=2")
+		assert.EqualError(t, err, "ORGS: organization 'A' is defined twice")
+
+		_, err = loadOrganizations(file, `{"Department A": 42}`)
+		assert.ErrorContains(t, err, "ORGS: failed to decode")
+	})
+
+	t.Run("At least one organization is required", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "organizations.json")
+
+		_, err := loadOrganizations(file, "{}")
+		assert.EqualError(t, err, "no organizations are defined")
+
+		writeFile(t, file, `{}`)
+		_, err = loadOrganizations(file, "")
+		assert.EqualError(t, err, "no organizations are defined")
+	})
+
+	t.Run("Missing file without ORGS", func(t *testing.T) {
+		_, err := loadOrganizations(filepath.Join(t.TempDir(), "organizations.json"), "")
+		assert.ErrorContains(t, err, "open organizations.json")
+	})
+}
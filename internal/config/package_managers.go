@@ -0,0 +1,94 @@
+// internal/config/package_managers.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// PackageManagerFile holds every package manager in one JSON object keyed by name
+	PackageManagerFile = "config/packageManager.json"
+	// PackageManagerDir optionally holds one JSON file per package manager; when it
+	// exists it is used instead of PackageManagerFile
+	PackageManagerDir = "config/packageManagers"
+)
+
+// packageManagerFile is one file of PackageManagerDir. Format names the package
+// manager; when empty, the file name without its extension is used.
+type packageManagerFile struct {
+	Format string
+	PackageManager
+}
+
+// loadPackageManagers reads the package managers from dir when it exists, and from
+// file otherwise. When neither exists they are decoded from env, the JSON value of
+// PACKAGE_MANAGERS, in the format of file.
+func loadPackageManagers(file, dir, env string) (map[string]PackageManager, error) {
+	info, err := os.Stat(dir)
+	switch {
+	case err == nil && info.IsDir():
+		return loadPackageManagerDir(dir)
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("stat %s: %w", dir, err)
+	}
+
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) && strings.TrimSpace(env) != "" {
+		var managers map[string]PackageManager
+		if err := json.Unmarshal([]byte(env), &managers); err != nil {
+			return nil, fmt.Errorf("failed to decode PACKAGE_MANAGERS: %w", err)
+		}
+		return managers, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", filepath.Base(file), err)
+	}
+	defer f.Close()
+	var managers map[string]PackageManager
+	if err := json.NewDecoder(f).Decode(&managers); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(file), err)
+	}
+	return managers, nil
+}
+
+// loadPackageManagerDir merges the *.json files of dir, one package manager per file.
+// Names are lower-cased, and a name defined by more than one file is rejected.
+func loadPackageManagerDir(dir string) (map[string]PackageManager, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", dir, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no package manager files (*.json) in %s", dir)
+	}
+
+	managers := make(map[string]PackageManager, len(paths))
+	definedIn := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+		var pm packageManagerFile
+		if err := json.Unmarshal(data, &pm); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+
+		name := pm.Format
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		name = strings.ToLower(name)
+		if previous, ok := definedIn[name]; ok {
+			return nil, fmt.Errorf("package manager '%s' is defined in both %s and %s", name, previous, path)
+		}
+		definedIn[name] = path
+		managers[name] = pm.PackageManager
+	}
+	return managers, nil
+}
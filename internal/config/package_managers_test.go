@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPackageManagers(t *testing.T) {
+	t.Run("Single file when the directory is missing", func(t *testing.T) {
+		root := t.TempDir()
+		file := filepath.Join(root, "packageManager.json")
+		writeFile(t, file, `{"npm": {"defaultURL": "https://registry.npmjs.org", "apiEndpoint": {"path": "/v1/repositories/npm/proxy"}}}`)
+
+		managers, err := loadPackageManagers(file, filepath.Join(root, "packageManagers"), "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"npm"}, Config{PackageManagers: managers}.SupportedPackageManagers())
+	})
+
+	t.Run("One file per format", func(t *testing.T) {
+		root := t.TempDir()
+		dir := filepath.Join(root, "packageManagers")
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, "npm.json"), `{"defaultURL": "https://registry.npmjs.org", "apiEndpoint": {"path": "/v1/repositories/npm/proxy"}}`)
+		writeFile(t, filepath.Join(dir, "Maven.json"), `{"defaultURL": "https://repo1.maven.org/maven2/", "apiEndpoint": {"path": "/v1/repositories/maven/proxy"}}`)
+		writeFile(t, filepath.Join(dir, "python.json"), `{"format": "pypi", "defaultURL": "https://pypi.org/", "apiEndpoint": {"path": "/v1/repositories/pypi/proxy"}}`)
+		writeFile(t, filepath.Join(dir, "README.md"), `not a package manager`)
+
+		// The directory wins over the single file, which doesn't even need to exist
+		managers, err := loadPackageManagers(filepath.Join(root, "packageManager.json"), dir, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"maven", "npm", "pypi"}, Config{PackageManagers: managers}.SupportedPackageManagers())
+		assert.Equal(t, "https://pypi.org/", managers["pypi"].DefaultURL)
+		assert.Equal(t, FormatMaven, managers["maven"].Format())
+	})
+
+	t.Run("Duplicate formats are rejected", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "npm.json"), `{"defaultURL": "https://registry.npmjs.org"}`)
+		writeFile(t, filepath.Join(dir, "npm-mirror.json"), `{"format": "NPM", "defaultURL": "https://npm.example.com"}`)
+
+		_, err := loadPackageManagers(filepath.Join(dir, "packageManager.json"), dir, "")
+
+		assert.ErrorContains(t, err, "package manager 'npm' is defined in both")
+	})
+
+	t.Run("Content type validation and auto-block must be booleans", func(t *testing.T) {
+		root := t.TempDir()
+		file := filepath.Join(root, "packageManager.json")
+		writeFile(t, file, `{"raw": {"defaultURL": "https://example.com", "strictContentTypeValidation": false, "autoBlock": false}}`)
+
+		managers, err := loadPackageManagers(file, filepath.Join(root, "packageManagers"), "")
+		assert.NoError(t, err)
+		assert.False(t, managers["raw"].ValidatesContentTypes())
+		assert.False(t, managers["raw"].AutoBlocks())
+
+		writeFile(t, file, `{"raw": {"defaultURL": "https://example.com", "autoBlock": "no"}}`)
+		_, err = loadPackageManagers(file, filepath.Join(root, "packageManagers"), "")
+		assert.ErrorContains(t, err, "autoBlock of type bool")
+	})
+
+	t.Run("PACKAGE_MANAGERS when no file exists", func(t *testing.T) {
+		root := t.TempDir()
+		env := `{"npm": {"defaultURL": "https://registry.npmjs.org", "apiEndpoint": {"path": "/v1/repositories/npm/proxy"}}}`
+
+		managers, err := loadPackageManagers(filepath.Join(root, "packageManager.json"), filepath.Join(root, "packageManagers"), env)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://registry.npmjs.org", managers["npm"].DefaultURL)
+
+		_, err = loadPackageManagers(filepath.Join(root, "packageManager.json"), filepath.Join(root, "packageManagers"), "npm")
+		assert.ErrorContains(t, err, "failed to decode PACKAGE_MANAGERS")
+	})
+
+	t.Run("The file wins over PACKAGE_MANAGERS", func(t *testing.T) {
+		root := t.TempDir()
+		file := filepath.Join(root, "packageManager.json")
+		writeFile(t, file, `{"npm": {"defaultURL": "https://registry.npmjs.org"}}`)
+
+		managers, err := loadPackageManagers(file, filepath.Join(root, "packageManagers"), `{"pypi": {"defaultURL": "https://pypi.org/"}}`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"npm"}, Config{PackageManagers: managers}.SupportedPackageManagers())
+	})
+
+	t.Run("Missing file without PACKAGE_MANAGERS", func(t *testing.T) {
+		root := t.TempDir()
+		_, err := loadPackageManagers(filepath.Join(root, "packageManager.json"), filepath.Join(root, "packageManagers"), "")
+		assert.ErrorContains(t, err, "open packageManager.json")
+	})
+
+	t.Run("Empty directory is an error", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := loadPackageManagers(filepath.Join(dir, "packageManager.json"), dir, "")
+		assert.ErrorContains(t, err, "no package manager files")
+	})
+}
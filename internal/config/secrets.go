@@ -0,0 +1,38 @@
+// internal/config/secrets.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretFileSuffix names the variable holding the path of a file that contains a secret,
+// e.g. NEXUS_PASSWORD_FILE for NEXUS_PASSWORD, as mounted by Kubernetes secrets.
+const SecretFileSuffix = "_FILE"
+
+// loadSecret returns the secret name from the file named by name+SecretFileSuffix, or
+// from name itself. Setting both is an error, so a stale inline value cannot silently
+// shadow or be shadowed by the mounted one. Trailing newlines are trimmed from the file.
+func loadSecret(v *viper.Viper, name string) (string, error) {
+	inline := v.GetString(name)
+	file := v.GetString(name + SecretFileSuffix)
+	if file == "" {
+		return inline, nil
+	}
+	if inline != "" {
+		return "", fmt.Errorf("set either %s or %s%s, not both", name, name, SecretFileSuffix)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("%s%s: %w", name, SecretFileSuffix, err)
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s%s: %s is empty", name, SecretFileSuffix, file)
+	}
+	return secret, nil
+}
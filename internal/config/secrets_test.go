@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSecret(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "nexus-password")
+	assert.NoError(t, os.WriteFile(file, []byte("s3cret\n"), 0o600))
+
+	t.Run("inline value", func(t *testing.T) {
+		v := viper.New()
+		v.Set("NEXUS_PASSWORD", "inline")
+		secret, err := loadSecret(v, "NEXUS_PASSWORD")
+		assert.NoError(t, err)
+		assert.Equal(t, "inline", secret)
+	})
+
+	t.Run("file with trailing newline", func(t *testing.T) {
+		v := viper.New()
+		v.Set("NEXUS_PASSWORD_FILE", file)
+		secret, err := loadSecret(v, "NEXUS_PASSWORD")
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cret", secret)
+	})
+
+	t.Run("both sources", func(t *testing.T) {
+		v := viper.New()
+		v.Set("NEXUS_PASSWORD", "inline")
+		v.Set("NEXUS_PASSWORD_FILE", file)
+		_, err := loadSecret(v, "NEXUS_PASSWORD")
+		assert.EqualError(t, err, "set either NEXUS_PASSWORD or NEXUS_PASSWORD_FILE, not both")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		v := viper.New()
+		v.Set("NEXUS_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+		_, err := loadSecret(v, "NEXUS_PASSWORD")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty")
+		assert.NoError(t, os.WriteFile(empty, []byte("\n"), 0o600))
+		v := viper.New()
+		v.Set("NEXUS_PASSWORD_FILE", empty)
+		_, err := loadSecret(v, "NEXUS_PASSWORD")
+		assert.ErrorContains(t, err, "is empty")
+	})
+}
@@ -0,0 +1,82 @@
+// internal/server/binding.go
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// strictJSON is a gin binding that rejects unknown JSON fields, so typos such as
+// `pkgManager` surface as errors instead of being silently dropped.
+var strictJSON binding.BindingBody = strictJSONBinding{}
+
+type strictJSONBinding struct{}
+
+// unknownFieldError reports a JSON field that does not exist on the target type.
+type unknownFieldError struct {
+	Field string
+}
+
+func (e *unknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field '%s'", e.Field)
+}
+
+func (strictJSONBinding) Name() string { return "strict-json" }
+
+func (b strictJSONBinding) Bind(req *http.Request, obj any) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	return decodeStrictJSON(req.Body, obj)
+}
+
+func (strictJSONBinding) BindBody(body []byte, obj any) error {
+	return decodeStrictJSON(bytes.NewReader(body), obj)
+}
+
+// decodeStrictJSON decodes r into obj, disallowing unknown fields, then runs the
+// `binding` tag validation gin would normally apply.
+func decodeStrictJSON(r io.Reader, obj any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		// encoding/json has no typed error for this case; the message format is stable.
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return &unknownFieldError{Field: strings.Trim(field, `"`)}
+		}
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// maxNDJSONLineSize bounds a single line of an NDJSON body.
+const maxNDJSONLineSize = 1 << 20
+
+// scanNDJSON calls each with the 1-based line number and content of every non-blank
+// line of r, so that only one line of a large body is held in memory at a time. It
+// stops at the first error returned by each or encountered while reading.
+func scanNDJSON(r io.Reader, each func(line int, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxNDJSONLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if err := each(line, data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read NDJSON body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// internal/server/compression.go
+package server
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the smallest response body worth compressing.
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses response bodies of at least gzipMinSize bytes for clients
+// whose Accept-Encoding allows gzip. Smaller bodies, bodies that are already encoded and
+// event streams are sent as they are, as is anything written before a Flush.
+func gzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+		w := &gzipResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		w.finish()
+		c.Writer = w.ResponseWriter
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either by name or
+// through "*", with a non-zero quality.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, entry := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(entry, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the start of the body until it is clear whether it
+// is worth compressing: once gzipMinSize bytes are written it compresses, and a body
+// that ends or is flushed before that is written unchanged.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what was written so far. A body that is flushed before compression
+// started, such as an event stream, stays uncompressed.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController, so a handler can still
+// adjust its write deadline.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide settles whether the body is compressed and writes out the held-back bytes.
+func (w *gzipResponseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if compress && header.Get("Content-Encoding") == "" && !isEventStream(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish completes the body once the handlers are done.
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+// isEventStream reports whether contentType is a server-sent event stream.
+func isEventStream(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/event-stream"
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	r, _ := setupRouter(nil)
+	r.Use(gzipMiddleware())
+	jobs := make([]gin.H, 200)
+	for i := range jobs {
+		jobs[i] = gin.H{"jobId": i, "status": "completed"}
+	}
+	r.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+	})
+	r.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	r.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		for i := 0; i < 100; i++ {
+			c.SSEvent("progress", strings.Repeat("x", 50))
+			c.Writer.Flush()
+		}
+	})
+
+	send := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Large response is compressed", func(t *testing.T) {
+		w := send("/large", "br, gzip;q=0.8")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		gz, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		var resp map[string][]any
+		assert.NoError(t, json.Unmarshal(body, &resp))
+		assert.Len(t, resp["jobs"], len(jobs))
+	})
+
+	t.Run("Client without gzip support", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "br", "gzip;q=0"} {
+			w := send("/large", acceptEncoding)
+			assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.True(t, json.Valid(w.Body.Bytes()), acceptEncoding)
+		}
+	})
+
+	t.Run("Small response is sent as-is", func(t *testing.T) {
+		w := send("/small", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.JSONEq(t, `{"success":true}`, w.Body.String())
+	})
+
+	t.Run("Event stream is sent as-is", func(t *testing.T) {
+		w := send("/events", "gzip")
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, 100, strings.Count(w.Body.String(), "event:progress"))
+	})
+}
@@ -1,36 +1,130 @@
 package server
 
+import "github.com/anmicius0/sonatype-resource-automation/internal/client"
+
+const (
+	HealthEndpoint      = "/health"
+	ReadyEndpoint       = "/ready"
+	VersionEndpoint     = "/version"
+	MetricsEndpoint     = "/metrics"
+	RepositoriesPath    = "/repositories"
+	JobsPath            = "/jobs"
+	StatsPath           = "/stats"
+	SinglePath          = RepositoriesPath + "/single"
+	OffboardingPath     = "/offboarding"
+	PreviewPath         = OffboardingPath + "/preview"
+	BatchPath           = "/batch"
+	ValidatePath        = BatchPath + "/validate"
+	OnlinePath          = "/online"
+	InvalidateCachePath = "/invalidate-cache"
+	PackageManagersPath = "/package-managers"
+	OrganizationsPath   = "/organizations"
+	PprofPath           = "/debug/pprof"
+	IQRoleCachePath     = "/iq/cache/invalidate"
+)
+
+// MIMENDJSON is the media type of batch bodies sent as one request per line.
+const MIMENDJSON = "application/x-ndjson"
+
+const (
+	// RequestIDHeader carries the request correlation ID in and out of the API.
+	RequestIDHeader = "X-Request-ID"
+	// RetryAfterHeader tells a client rejected with 429 how many seconds to wait.
+	RetryAfterHeader = "Retry-After"
+	// maxRequestIDLength bounds an incoming X-Request-ID before it is replaced.
+	maxRequestIDLength = 128
+)
+
 const (
-	HealthEndpoint   = "/health"
-	RepositoriesPath = "/repositories"
-	JobsPath         = "/jobs"
+	// QueryOmitEmpty is the query parameter that opts in to dropping empty response fields.
+	QueryOmitEmpty = "omitEmpty"
+	// QuerySync is the query parameter that makes batch endpoints wait for the results.
+	QuerySync = "sync"
 )
 
 // keys constants were intentionally removed. Responses are generated via structs
 // and use lowerCamelCase JSON fields.
 
 const (
-	StatusHealthy = "healthy"
-	StatusPending = "pending"
+	StatusHealthy     = "healthy"
+	StatusPending     = "pending"
+	StatusReady       = "ready"
+	StatusUnavailable = "unavailable"
+	StatusStarting    = "starting"
 )
 
 const (
-	MessageJobQueued          = "Job queued for processing"
-	MessageValidationFailed   = "All requests failed validation"
-	MessageInvalidRequestBody = "Invalid request body"
-	MessageBatchEmpty         = "Batch must contain at least one request"
-	MessageInvalidToken       = "Invalid token"
+	MessageJobQueued            = "Job queued for processing"
+	MessageValidationFailed     = "All requests failed validation"
+	MessageInvalidRequestBody   = "Invalid request body"
+	MessageBatchEmpty           = "Batch must contain at least one request"
+	MessageInvalidToken         = "Invalid token"
+	MessageBatchTooLarge        = "Batch exceeds the maximum number of requests"
+	MessageQueueFull            = "The job queue is full; retry later"
+	MessageUnsupportedMediaType = "Content-Type must be application/json"
+	MessageUnknownFieldFmt      = "Unknown field '%s' in request body"
+	MessageRepoNotFoundFmt      = "Repository %s not found"
+	MessageInvalidRepoName      = "Invalid repository name"
+	MessageNexusLookupFailed    = "Failed to look up repository in Nexus"
+	MessageNexusUpdateFailed    = "Failed to update repository in Nexus"
+	MessageOperationSucceeded   = "Operation completed successfully"
+	MessageOperationFailed      = "Operation failed"
+	MessagePreviewFailed        = "Failed to build offboarding preview"
+	MessageClientNotAllowed     = "Client IP is not allowed"
+	MessageNotReady             = "Service is starting; retry later"
 )
 
 const (
-	ErrorCodeInvalidRequestBody = "invalid_request_body"
-	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodeInvalidRequestBody   = "invalid_request_body"
+	ErrorCodeValidationFailed     = "validation_failed"
+	ErrorCodeBatchTooLarge        = "batch_too_large"
+	ErrorCodeQueueFull            = "queue_full"
+	ErrorCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrorCodeUnknownField         = "unknown_field"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeBackendError         = "backend_error"
+	ErrorCodeOperationFailed      = "operation_failed"
+	ErrorCodeForbidden            = "forbidden"
+	ErrorCodeUserNotFound         = "user_not_found"
+	ErrorCodeConflict             = "conflict"
+	ErrorCodeTimeout              = "timeout"
+	ErrorCodeCancelled            = "cancelled"
+	ErrorCodeNotReady             = "not_ready"
 )
 
 const (
 	JobNotFoundMessageFmt = "Job %s not found"
 )
 
+// Validation rejection reasons, the reason label of ValidationRejectionsMetricName.
+const (
+	ReasonMalformedRequest            = "malformed_request"
+	ReasonBatchTooLarge               = "batch_too_large"
+	ReasonUnknownOrganization         = "unknown_organization"
+	ReasonMissingPackageManager       = "missing_package_manager"
+	ReasonUnsupportedPackageManager   = "unsupported_package_manager"
+	ReasonPackageManagerNotAllowed    = "package_manager_not_allowed"
+	ReasonMissingAppID                = "missing_appid"
+	ReasonAppIDNotAllowed             = "appid_not_allowed"
+	ReasonMultipleAppIDsNotAllowed    = "multiple_appids_not_allowed"
+	ReasonInvalidDockerSettings       = "invalid_docker_settings"
+	ReasonDockerPortsNotAllowed       = "docker_ports_not_allowed"
+	ReasonInvalidMavenPolicy          = "invalid_maven_policy"
+	ReasonMavenPoliciesNotAllowed     = "maven_policies_not_allowed"
+	ReasonIncompleteRemoteCredentials = "incomplete_remote_credentials"
+	ReasonRedactedRemotePassword      = "redacted_remote_password"
+	ReasonInvalidRemoteURL            = "invalid_remote_url"
+	ReasonInvalidRepositoryName       = "invalid_repository_name"
+	ReasonRepositoryNameNotAllowed    = "repository_name_not_allowed"
+	ReasonInvalidPrivilegeAccess      = "invalid_privilege_access"
+	ReasonForceNotAllowed             = "force_not_allowed"
+)
+
+const (
+	BackendNexus    = client.BackendNexus
+	BackendIQServer = client.BackendIQServer
+)
+
 const (
 	MethodCreate = "create"
 	MethodDelete = "delete"
@@ -1,12 +1,31 @@
 package server
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/service"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"github.com/anmicius0/sonatype-resource-automation/internal/version"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -30,6 +49,47 @@ func (h *Handler) health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "status": StatusHealthy})
 }
 
+// ready is the readiness probe: unlike health, it verifies the backends are reachable.
+// Until startup completes it reports starting without pinging them.
+func (h *Handler) ready(c *gin.Context) {
+	respBuilder := newResponseBuilder()
+	if !h.batchManager.Started() {
+		c.JSON(http.StatusServiceUnavailable, respBuilder.BuildStartingResponse())
+		return
+	}
+	backends := h.batchManager.CheckBackends(c.Request.Context())
+	body, ok := respBuilder.BuildReadinessResponse(backends)
+	if !ok {
+		utils.LoggerFromContext(c.Request.Context()).Warn("Readiness check failed",
+			zap.Any("backends", backends))
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// version reports the build metadata stamped into the binary.
+func (h *Handler) version(c *gin.Context) {
+	c.JSON(http.StatusOK, toCamelCaseMap(version.Get()))
+}
+
+// metrics exposes the operation counters in the Prometheus text format.
+func (h *Handler) metrics(c *gin.Context) {
+	c.String(http.StatusOK, h.batchManager.metrics.render())
+}
+
+// listPackageManagers returns the configured package managers, so clients can offer
+// the accepted values instead of hardcoding them.
+func (h *Handler) listPackageManagers(c *gin.Context) {
+	c.JSON(http.StatusOK, newResponseBuilder().BuildPackageManagersResponse(h.cfg))
+}
+
+// listOrganizations returns the configured organizations, so clients can submit a
+// valid OrganizationName.
+func (h *Handler) listOrganizations(c *gin.Context) {
+	c.JSON(http.StatusOK, newResponseBuilder().BuildOrganizationsResponse(h.cfg))
+}
+
 func (h *Handler) createBatch(c *gin.Context) {
 	h.processBatch(c, MethodCreate)
 }
@@ -39,136 +99,906 @@ func (h *Handler) deleteBatch(c *gin.Context) {
 }
 
 func (h *Handler) processBatch(c *gin.Context, action string) {
-	// Validate and parse the incoming batch request
-	var batch batchRepositoryRequest
-	if err := c.ShouldBindJSON(&batch); err != nil {
-		utils.Logger.Error("Invalid request body",
-			zap.Error(err))
+	wait, _ := strconv.ParseBool(c.Query(QuerySync))
+	if !wait && isNDJSON(c) {
+		h.queueStreamedBatch(c, action)
+		return
+	}
+
+	// Parse and validate the incoming batch request
+	validationResult, ok := h.bindBatch(c, action)
+	if !ok {
+		return
+	}
+	totalRequests := len(validationResult.ValidRequests) + len(validationResult.InvalidRequests)
+
+	// If all requests are invalid, return a validation failed response
+	if len(validationResult.ValidRequests) == 0 {
 		respBuilder := newResponseBuilder()
+		utils.LoggerFromContext(c.Request.Context()).Info("All requests failed validation",
+			zap.Int("invalid_count", len(validationResult.InvalidRequests)))
+		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildValidationFailedResponse(validationResult))
+		return
+	}
+
+	// With ?sync=true, process before responding and map the outcome to a status code
+	if wait {
+		jobID, outcomes, outcome, finished, err := h.batchManager.ProcessBatchSync(c.Request.Context(), validationResult, action)
+		if err != nil {
+			h.respondQueueFull(c)
+			return
+		}
+		respBuilder := newResponseBuilder()
+		if !finished {
+			// The job outlasted the wait; hand it over for polling before the write deadline
+			c.JSON(http.StatusAccepted, respBuilder.BuildAcceptedResponse(jobID, totalRequests, len(validationResult.ValidRequests), len(validationResult.InvalidRequests), validationResult))
+			return
+		}
+		if outcome == service.OutcomeSucceeded && len(validationResult.InvalidRequests) > 0 {
+			// Requests rejected by validation also count against the aggregate
+			outcome = service.OutcomePartial
+		}
+		c.JSON(statusCodeForOutcome(outcome), respBuilder.BuildSyncBatchResponse(jobID, totalRequests, outcome, outcomes, validationResult))
+		return
+	}
+
+	// Process the valid requests asynchronously
+	jobID, validCount, invalidCount, err := h.batchManager.ProcessBatchAsync(c.Request.Context(), validationResult, action)
+	if err != nil {
+		h.respondQueueFull(c)
+		return
+	}
+	respBuilder := newResponseBuilder()
+	c.JSON(http.StatusAccepted, respBuilder.BuildAcceptedResponse(jobID, totalRequests, validCount, invalidCount, validationResult))
+}
+
+// isNDJSON reports whether the request body is an NDJSON batch.
+func isNDJSON(c *gin.Context) bool {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	return mediaType == MIMENDJSON
+}
+
+// bindBatch parses a batch body and validates its requests, responding with an error and
+// reporting false when the body is unusable. NDJSON bodies are streamed line by line.
+func (h *Handler) bindBatch(c *gin.Context, action string) (*ValidationResult, bool) {
+	if isNDJSON(c) {
+		validationResult := &ValidationResult{
+			ValidRequests: []config.RepositoryRequest{},
+			ValidIndexes:  []int{},
+		}
+		invalid, rejections, count, err := h.streamBatch(c.Request.Body, action, func(index int, req config.RepositoryRequest) error {
+			validationResult.ValidRequests = append(validationResult.ValidRequests, req)
+			validationResult.ValidIndexes = append(validationResult.ValidIndexes, index)
+			return nil
+		})
+		validationResult.InvalidRequests = invalid
+		if err != nil && !errors.Is(err, errBatchTooLarge) {
+			respondBindError(c, err)
+			return nil, false
+		}
+		if !h.checkSubmittedBatchSize(c, count) {
+			return nil, false
+		}
+		h.recordRejections(rejections...)
+		return validationResult, true
+	}
+	var batch batchRepositoryRequest
+	if err := c.ShouldBindWith(&batch, strictJSON); err != nil {
+		respondBindError(c, err)
+		return nil, false
+	}
+	if !h.checkSubmittedBatchSize(c, len(batch.Requests)) {
+		return nil, false
+	}
+	return h.validateBatchRequest(batch, action), true
+}
+
+// queueStreamedBatch processes an NDJSON batch asynchronously, submitting each valid
+// request to the job's workers as soon as it is read, so the requests are never
+// collected. The job is started with the first valid request; a batch without one is
+// rejected like any other. When reading fails or the batch turns out to be too large,
+// the requests submitted until then still run in the job, which the 413 response names.
+func (h *Handler) queueStreamedBatch(c *gin.Context, action string) {
+	var job *streamingJob
+	invalid, rejections, count, err := h.streamBatch(c.Request.Body, action, func(_ int, req config.RepositoryRequest) error {
+		if job == nil {
+			started, err := h.batchManager.StartStreamingJob(c.Request.Context(), action)
+			if err != nil {
+				return err
+			}
+			job = started
+		}
+		job.Submit(req)
+		return nil
+	})
+	if job != nil {
+		job.Close()
+	}
+	validationResult := &ValidationResult{InvalidRequests: invalid}
+
+	switch {
+	case errors.Is(err, ErrTooManyJobs):
+		h.respondQueueFull(c)
+		return
+	case err != nil && !errors.Is(err, errBatchTooLarge):
+		if job != nil {
+			utils.LoggerFromContext(c.Request.Context()).Warn("Failed to read the rest of the batch; submitted requests keep running",
+				zap.String(utils.FieldJobID, job.ID),
+				zap.Int("submitted_count", job.Queued()),
+				zap.Error(err))
+		}
+		respondBindError(c, err)
+		return
+	case job != nil && h.cfg.MaxBatchSize > 0 && count > h.cfg.MaxBatchSize:
+		h.recordRejections(rejection{Reason: ReasonBatchTooLarge})
+		utils.LoggerFromContext(c.Request.Context()).Warn("Batch exceeds maximum size; submitted requests keep running",
+			zap.String(utils.FieldJobID, job.ID),
+			zap.Int("submitted_count", job.Queued()),
+			zap.Int("max_batch_size", h.cfg.MaxBatchSize))
+		c.JSON(http.StatusRequestEntityTooLarge, newResponseBuilder().BuildErrorResponse(
+			ErrorCodeBatchTooLarge,
+			MessageBatchTooLarge,
+			StreamedBatchSizeDetails{SubmittedCount: count, MaxBatchSize: h.cfg.MaxBatchSize, JobID: job.ID},
+		))
+		return
+	case !h.checkSubmittedBatchSize(c, count):
+		return
+	}
+	h.recordRejections(rejections...)
+
+	if job == nil {
+		utils.LoggerFromContext(c.Request.Context()).Info("All requests failed validation",
+			zap.Int("invalid_count", len(invalid)))
+		c.JSON(http.StatusUnprocessableEntity, newResponseBuilder().BuildValidationFailedResponse(validationResult))
+		return
+	}
+	c.JSON(http.StatusAccepted, newResponseBuilder().BuildAcceptedResponse(job.ID, count, job.Queued(), len(invalid), validationResult))
+}
+
+// errBatchTooLarge stops reading an NDJSON batch once it exceeds MaxBatchSize.
+var errBatchTooLarge = errors.New("batch too large")
+
+// streamBatch reads an NDJSON batch, one request per line, validating each request as
+// it is read so the raw body is never held in memory as a whole. Each valid request is
+// passed to accept with its index among the lines. A line that is not a valid request
+// is returned as an invalid request rather than failing the batch, and its rejections
+// are returned uncounted, to be recorded once the batch is accepted. Reading stops at
+// the first error from accept, or with errBatchTooLarge at the first line past
+// MaxBatchSize; count is the number of lines read.
+func (h *Handler) streamBatch(body io.Reader, action string, accept func(index int, req config.RepositoryRequest) error) (invalid []ValidationError, rejections []rejection, count int, err error) {
+	invalid = []ValidationError{}
+	err = scanNDJSON(body, func(line int, data []byte) error {
+		count++
+		if h.cfg.MaxBatchSize > 0 && count > h.cfg.MaxBatchSize {
+			return errBatchTooLarge
+		}
+		index := count - 1
+		var req config.RepositoryRequest
+		if err := decodeStrictJSON(bytes.NewReader(data), &req); err != nil {
+			rejections = append(rejections, rejection{Reason: ReasonMalformedRequest})
+			invalid = append(invalid, ValidationError{
+				Index:   index,
+				Request: req,
+				Reasons: []string{fmt.Sprintf("line %d: %v", line, err)},
+			})
+			return nil
+		}
+		req = h.cfg.CanonicalRequest(req, action)
+		if reqRejections := h.checkRequest(req, action); len(reqRejections) > 0 {
+			rejections = append(rejections, reqRejections...)
+			invalid = append(invalid, ValidationError{
+				Index:   index,
+				Request: req,
+				Reasons: rejectionMessages(reqRejections),
+			})
+			return nil
+		}
+		return accept(index, req)
+	})
+	return invalid, rejections, count, err
+}
+
+// respondQueueFull rejects a batch because MaxConcurrentJobs jobs are already running,
+// with a Retry-After hint estimated from the average job duration.
+func (h *Handler) respondQueueFull(c *gin.Context) {
+	running := h.batchManager.RunningJobs()
+	retryAfter := int(math.Ceil(h.batchManager.RetryAfter().Seconds()))
+	utils.LoggerFromContext(c.Request.Context()).Warn("Rejecting batch: job queue is full",
+		zap.Int("running_jobs", running),
+		zap.Int("max_concurrent_jobs", h.cfg.MaxConcurrentJobs),
+		zap.Int("retry_after_seconds", retryAfter))
+	respBuilder := newResponseBuilder()
+	c.Header(RetryAfterHeader, strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, respBuilder.BuildErrorResponse(
+		ErrorCodeQueueFull,
+		MessageQueueFull,
+		ConcurrencyDetails{
+			RunningJobs:       running,
+			MaxConcurrentJobs: h.cfg.MaxConcurrentJobs,
+			RetryAfterSeconds: retryAfter,
+		},
+	))
+}
+
+func (h *Handler) createSingle(c *gin.Context) {
+	h.processSingle(c, MethodCreate)
+}
+
+func (h *Handler) deleteSingle(c *gin.Context) {
+	h.processSingle(c, MethodDelete)
+}
+
+// processSingle handles one RepositoryRequest synchronously, returning the outcome
+// directly instead of queueing a job.
+func (h *Handler) processSingle(c *gin.Context, action string) {
+	var req config.RepositoryRequest
+	if err := c.ShouldBindWith(&req, strictJSON); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	req = h.cfg.CanonicalRequest(req, action)
+
+	respBuilder := newResponseBuilder()
+	if reasons := h.validateSubmission(req, action); len(reasons) > 0 {
+		utils.LoggerFromContext(c.Request.Context()).Info("Single request failed validation",
+			zap.Strings("reasons", reasons))
+		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildValidationFailedResponse(&ValidationResult{
+			InvalidRequests: []ValidationError{{Request: req, Reasons: reasons}},
+		}))
+		return
+	}
+
+	h.extendWriteDeadline(c)
+	result := h.batchManager.ProcessSingle(c.Request.Context(), req, action)
+	if !result.Success {
 		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildErrorResponse(
-			ErrorCodeInvalidRequestBody,
-			MessageInvalidRequestBody,
-			err.Error(),
+			ErrorCodeOperationFailed,
+			MessageOperationFailed,
+			result.Error,
 		))
 		return
 	}
+	c.JSON(http.StatusOK, respBuilder.BuildSingleOperationResponse(req, action, result.Result))
+}
+
+// extendWriteDeadline lifts the server's write timeout for a response that waits on an
+// operation, which may run for up to cfg.OperationTimeout. The write timeout is left on
+// top for writing the response; without an operation timeout there is no deadline.
+func (h *Handler) extendWriteDeadline(c *gin.Context) {
+	var deadline time.Time
+	if h.cfg.OperationTimeout > 0 {
+		deadline = time.Now().Add(h.cfg.OperationTimeout + config.DefaultWriteTimeout)
+	}
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Debug("Could not extend the write deadline", zap.Error(err))
+	}
+}
 
+// checkBatchSize rejects an empty or oversized batch before any work is spawned. It
+// reports whether the batch may proceed.
+func (h *Handler) checkBatchSize(c *gin.Context, count int) bool {
 	// Ensure at least one request is present
-	if len(batch.Requests) == 0 {
+	if count == 0 {
 		respBuilder := newResponseBuilder()
 		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildErrorResponse(
 			ErrorCodeValidationFailed,
 			MessageBatchEmpty,
 			nil,
 		))
+		return false
+	}
+
+	// Reject oversized batches
+	if h.cfg.MaxBatchSize > 0 && count > h.cfg.MaxBatchSize {
+		utils.LoggerFromContext(c.Request.Context()).Warn("Batch exceeds maximum size",
+			zap.Int("submitted_count", count),
+			zap.Int("max_batch_size", h.cfg.MaxBatchSize))
+		respBuilder := newResponseBuilder()
+		c.JSON(http.StatusRequestEntityTooLarge, respBuilder.BuildErrorResponse(
+			ErrorCodeBatchTooLarge,
+			MessageBatchTooLarge,
+			BatchSizeDetails{SubmittedCount: count, MaxBatchSize: h.cfg.MaxBatchSize},
+		))
+		return false
+	}
+	return true
+}
+
+// checkSubmittedBatchSize is checkBatchSize for a batch submitted for processing: an
+// oversized batch is also counted once in the validation metrics.
+func (h *Handler) checkSubmittedBatchSize(c *gin.Context, count int) bool {
+	if h.cfg.MaxBatchSize > 0 && count > h.cfg.MaxBatchSize {
+		h.recordRejections(rejection{Reason: ReasonBatchTooLarge})
+	}
+	return h.checkBatchSize(c, count)
+}
+
+// validateBatch runs the batch validation for the given action and reports the result
+// without queueing a job, so clients can check a payload before submitting it.
+func (h *Handler) validateBatch(c *gin.Context) {
+	var batch batchValidateRequest
+	if err := c.ShouldBindWith(&batch, strictJSON); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !h.checkBatchSize(c, len(batch.Requests)) {
 		return
 	}
 
-	// Validate the request body format
-	validationResult := h.validateBatchRequest(batch, action)
+	// Nothing is submitted, so the rejections are not counted in the validation metrics
+	validationResult := h.validateRequests(batch.Requests, batch.Action, h.validateRequest)
+	utils.LoggerFromContext(c.Request.Context()).Debug("Batch validated",
+		zap.String(utils.FieldAction, batch.Action),
+		zap.Int("valid_count", len(validationResult.ValidRequests)),
+		zap.Int("invalid_count", len(validationResult.InvalidRequests)))
+	respBuilder := newResponseBuilder()
+	c.JSON(http.StatusOK, respBuilder.BuildBatchValidationResponse(batch.Action, validationResult))
+}
 
-	// If all requests are invalid, return a validation failed response
-	if len(validationResult.ValidRequests) == 0 {
-		respBuilder := newResponseBuilder()
-		utils.Logger.Info("All requests failed validation",
-			zap.Int("invalid_count", len(validationResult.InvalidRequests)))
-		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildValidationFailedResponse(validationResult))
+// previewOffboarding reports what an offboarding request would remove without
+// removing anything.
+func (h *Handler) previewOffboarding(c *gin.Context) {
+	var req config.RepositoryRequest
+	if err := c.ShouldBindWith(&req, strictJSON); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
-	// Process the valid requests asynchronously
-	jobID, totalRequests, validCount, invalidCount := h.batchManager.ProcessBatchAsync(validationResult, batch, action)
 	respBuilder := newResponseBuilder()
-	c.JSON(http.StatusAccepted, respBuilder.BuildAcceptedResponse(jobID, totalRequests, validCount, invalidCount, validationResult))
+	reasons := h.validateRequest(req, MethodDelete)
+	if !req.Shared {
+		reasons = append(reasons, "shared must be true for an offboarding preview")
+	}
+	if len(reasons) > 0 {
+		utils.LoggerFromContext(c.Request.Context()).Info("Offboarding preview request failed validation",
+			zap.Strings("reasons", reasons))
+		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildValidationFailedResponse(&ValidationResult{
+			InvalidRequests: []ValidationError{{Request: req, Reasons: reasons}},
+		}))
+		return
+	}
+
+	plan, err := h.batchManager.PreviewOffboarding(c.Request.Context(), req)
+	if err != nil {
+		utils.LoggerFromContext(c.Request.Context()).Error("Offboarding preview failed",
+			zap.String("ldap_username", req.LdapUsername),
+			zap.Error(err))
+		c.JSON(http.StatusBadGateway, respBuilder.BuildErrorResponse(
+			ErrorCodeBackendError,
+			MessagePreviewFailed,
+			err.Error(),
+		))
+		return
+	}
+	c.JSON(http.StatusOK, respBuilder.BuildOffboardingPreviewResponse(req, plan))
 }
 
 func (h *Handler) getJobStatus(c *gin.Context) {
 	jobID := c.Param("id")
 	job, exists := h.jobStore.GetJob(jobID)
 	if !exists {
-		utils.Logger.Debug("Job not found",
+		utils.LoggerFromContext(c.Request.Context()).Debug("Job not found",
 			zap.String(utils.FieldJobID, jobID))
 		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf(JobNotFoundMessageFmt, jobID)})
 		return
 	}
 
-	respBuilder := newResponseBuilder()
+	// Clients can opt in to dropping empty fields with ?omitEmpty=true
+	omitEmpty, _ := strconv.ParseBool(c.Query(QueryOmitEmpty))
+	respBuilder := newResponseBuilder().WithOmitEmpty(omitEmpty)
 	c.JSON(http.StatusOK, respBuilder.BuildJobResponse(job))
 }
 
+// getStats returns statistics aggregated over every job, for dashboards.
+func (h *Handler) getStats(c *gin.Context) {
+	c.JSON(http.StatusOK, newResponseBuilder().BuildStatsResponse(h.jobStore.Stats()))
+}
+
+// getFailedRequests returns a job's failed requests as a batch body that clients can
+// fix and resubmit to the batch endpoints.
+func (h *Handler) getFailedRequests(c *gin.Context) {
+	jobID := c.Param("id")
+	job, exists := h.jobStore.GetJob(jobID)
+	if !exists {
+		utils.LoggerFromContext(c.Request.Context()).Debug("Job not found",
+			zap.String(utils.FieldJobID, jobID))
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf(JobNotFoundMessageFmt, jobID)})
+		return
+	}
+
+	respBuilder := newResponseBuilder()
+	c.JSON(http.StatusOK, respBuilder.BuildFailedRequestsResponse(job))
+}
+
+// getRepository returns the Nexus details of a single repository so clients can
+// confirm the outcome of a batch.
+func (h *Handler) getRepository(c *gin.Context) {
+	name, ok := repositoryParam(c)
+	if !ok {
+		return
+	}
+	repo, err := h.batchManager.nexus.GetRepository(c.Request.Context(), name)
+	if err != nil {
+		respondRepositoryError(c, name, MessageNexusLookupFailed, err)
+		return
+	}
+	c.JSON(http.StatusOK, newResponseBuilder().BuildRepositoryResponse(repo))
+}
+
+// setRepositoryOnline takes a repository online or offline without deleting it,
+// e.g. to put a proxy repository into maintenance.
+func (h *Handler) setRepositoryOnline(c *gin.Context) {
+	name, ok := repositoryParam(c)
+	if !ok {
+		return
+	}
+	var req repositoryOnlineRequest
+	if err := c.ShouldBindWith(&req, strictJSON); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if err := h.batchManager.nexus.SetRepositoryOnline(c.Request.Context(), name, *req.Online); err != nil {
+		respondRepositoryError(c, name, MessageNexusUpdateFailed, err)
+		return
+	}
+	utils.LoggerFromContext(c.Request.Context()).Info("Changed repository online state",
+		zap.String(utils.FieldRepo, name),
+		zap.Bool("online", *req.Online))
+	c.JSON(http.StatusOK, newResponseBuilder().BuildRepositoryStateResponse(name, *req.Online))
+}
+
+// invalidateCache clears the caches of a proxy repository, e.g. after an upstream
+// artifact was fixed, so Nexus fetches it again.
+func (h *Handler) invalidateCache(c *gin.Context) {
+	name, ok := repositoryParam(c)
+	if !ok {
+		return
+	}
+	if err := h.batchManager.nexus.InvalidateCache(c.Request.Context(), name); err != nil {
+		respondRepositoryError(c, name, MessageNexusUpdateFailed, err)
+		return
+	}
+	utils.LoggerFromContext(c.Request.Context()).Info("Invalidated repository cache",
+		zap.String(utils.FieldRepo, name))
+	c.JSON(http.StatusOK, newResponseBuilder().BuildCacheInvalidatedResponse(name))
+}
+
+// invalidateIQRoleCache makes the IQ Server client look up the owner role ID again,
+// e.g. after an admin renamed or recreated the role.
+func (h *Handler) invalidateIQRoleCache(c *gin.Context) {
+	h.batchManager.iq.InvalidateRoleCache()
+	utils.LoggerFromContext(c.Request.Context()).Info("Invalidated IQ Server owner role cache",
+		zap.String("role_name", h.cfg.IQOwnerRoleName))
+	c.JSON(http.StatusOK, newResponseBuilder().BuildRoleCacheInvalidatedResponse(h.cfg.IQOwnerRoleName))
+}
+
+// repositoryParam returns the repository named in the path, responding with 400 and
+// reporting false when it is not a valid Nexus repository name.
+func repositoryParam(c *gin.Context) (string, bool) {
+	name := c.Param("name")
+	if err := config.ValidateRepositoryName(name); err != nil {
+		c.JSON(http.StatusBadRequest, newResponseBuilder().BuildErrorResponse(
+			ErrorCodeValidationFailed,
+			MessageInvalidRepoName,
+			err.Error(),
+		))
+		return "", false
+	}
+	return name, true
+}
+
+// respondRepositoryError writes 404 when Nexus has no repository called name, and 502
+// with message for any other failure.
+func respondRepositoryError(c *gin.Context, name, message string, err error) {
+	respBuilder := newResponseBuilder()
+	var httpErr *client.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+		c.JSON(http.StatusNotFound, respBuilder.BuildErrorResponse(
+			ErrorCodeNotFound,
+			fmt.Sprintf(MessageRepoNotFoundFmt, name),
+			nil,
+		))
+		return
+	}
+	utils.LoggerFromContext(c.Request.Context()).Error(message,
+		zap.String(utils.FieldRepo, name),
+		zap.Error(err))
+	c.JSON(http.StatusBadGateway, respBuilder.BuildErrorResponse(
+		ErrorCodeBackendError,
+		message,
+		err.Error(),
+	))
+}
+
+// statusCodeForOutcome maps the aggregate outcome of a synchronous batch to its HTTP status:
+// all succeeded is 200, all failed is 502, and a mix is 207 Multi-Status.
+func statusCodeForOutcome(outcome service.Outcome) int {
+	switch outcome {
+	case service.OutcomeSucceeded:
+		return http.StatusOK
+	case service.OutcomeFailed:
+		return http.StatusBadGateway
+	default:
+		return http.StatusMultiStatus
+	}
+}
+
+// respondBindError writes the 422 response for a request body that failed to bind,
+// naming the offending field when the body contained an unknown one.
+func respondBindError(c *gin.Context, err error) {
+	utils.LoggerFromContext(c.Request.Context()).Error("Invalid request body",
+		zap.Error(err))
+	respBuilder := newResponseBuilder()
+	var unknownField *unknownFieldError
+	if errors.As(err, &unknownField) {
+		c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildErrorResponse(
+			ErrorCodeUnknownField,
+			fmt.Sprintf(MessageUnknownFieldFmt, unknownField.Field),
+			UnknownFieldDetails{Field: unknownField.Field},
+		))
+		return
+	}
+	c.JSON(http.StatusUnprocessableEntity, respBuilder.BuildErrorResponse(
+		ErrorCodeInvalidRequestBody,
+		MessageInvalidRequestBody,
+		err.Error(),
+	))
+}
+
 func authMiddleware(expectedToken string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		expectedAuth := fmt.Sprintf("Bearer %s", expectedToken)
 		if authHeader != expectedAuth {
-			utils.Logger.Warn("Unauthorized access attempt",
+			utils.LoggerFromContext(c.Request.Context()).Warn("Unauthorized access attempt",
 				zap.String(utils.FieldPath, c.Request.URL.Path))
 			c.JSON(http.StatusUnauthorized, gin.H{"error": MessageInvalidToken})
 			c.Abort()
 			return
 		}
+		// There is one shared token, so the client address is the best caller identity we have
+		c.Request = c.Request.WithContext(utils.ContextWithCaller(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// readinessMiddleware rejects requests with 503 until the startup checks complete, so
+// early requests fail clearly instead of against backends not yet verified. It guards
+// the endpoints that change state.
+func readinessMiddleware(bm *BatchManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if bm == nil || bm.Started() {
+			c.Next()
+			return
+		}
+		utils.LoggerFromContext(c.Request.Context()).Warn("Rejecting request: startup checks not complete",
+			zap.String(utils.FieldPath, c.Request.URL.Path))
+		respBuilder := newResponseBuilder()
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, respBuilder.BuildErrorResponse(
+			ErrorCodeNotReady,
+			MessageNotReady,
+			nil,
+		))
+	}
+}
+
+// allowlistMiddleware rejects clients whose IP is outside the allowed ranges with 403.
+// The client IP is gin's ClientIP, which only believes X-Forwarded-For when the
+// request comes from a trusted proxy. An empty list allows every client.
+func allowlistMiddleware(allowed []netip.Prefix) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(allowed) == 0 {
+			c.Next()
+			return
+		}
+		clientIP := c.ClientIP()
+		addr, err := netip.ParseAddr(clientIP)
+		if err == nil && slices.ContainsFunc(allowed, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) }) {
+			c.Next()
+			return
+		}
+		utils.LoggerFromContext(c.Request.Context()).Warn("Rejected request from client outside the allowlist",
+			zap.String(utils.FieldPath, c.Request.URL.Path),
+			zap.String("client_ip", clientIP))
+		respBuilder := newResponseBuilder()
+		c.AbortWithStatusJSON(http.StatusForbidden, respBuilder.BuildErrorResponse(
+			ErrorCodeForbidden,
+			MessageClientNotAllowed,
+			ClientIPDetails{ClientIP: clientIP},
+		))
+	}
+}
+
+// requireJSONMiddleware rejects bodies that are not declared as application/json, or
+// one of the other accepted media types, with 415, so clients get a clear error instead
+// of a confusing binding failure. Media type parameters such as a charset are allowed.
+func requireJSONMiddleware(accepted ...string) gin.HandlerFunc {
+	accepted = append([]string{gin.MIMEJSON}, accepted...)
+	return func(c *gin.Context) {
+		contentType := c.GetHeader("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !slices.Contains(accepted, mediaType) {
+			utils.LoggerFromContext(c.Request.Context()).Info("Rejected request with unsupported content type",
+				zap.String(utils.FieldPath, c.Request.URL.Path),
+				zap.String("content_type", contentType))
+			respBuilder := newResponseBuilder()
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, respBuilder.BuildErrorResponse(
+				ErrorCodeUnsupportedMediaType,
+				MessageUnsupportedMediaType,
+				ContentTypeDetails{ContentType: contentType},
+			))
+			return
+		}
 		c.Next()
 	}
 }
 
-// validateBatchRequest validates the individual requests in a batch.
+// requestIDMiddleware assigns every request an ID, reusing a well-formed incoming
+// X-Request-ID, stores it in the request context for LoggerFromContext, echoes it in
+// the response header and records it on the server span.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		ctx := utils.ContextWithRequestID(c.Request.Context(), requestID)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("http.request.id", requestID))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// isValidRequestID accepts IDs of at most maxRequestIDLength printable, non-space
+// ASCII characters, so a caller cannot inject arbitrary text into the logs.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// tracingMiddleware starts a server span for every request, continuing any
+// trace propagated by the caller, and exposes it via the request context.
+func tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := utils.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// validateBatchRequest validates the individual requests in a batch submitted for
+// processing, counting the rejections in the validation metrics.
+// Every rule is evaluated so that a request reports all of its problems at once.
 func (h *Handler) validateBatchRequest(batch batchRepositoryRequest, action string) *ValidationResult {
+	return h.validateRequests(batch.Requests, action, h.validateSubmission)
+}
+
+// validateRequests validates each request with validate, either validateRequest or
+// validateSubmission, and sorts them into the valid and invalid requests.
+func (h *Handler) validateRequests(requests []config.RepositoryRequest, action string, validate func(config.RepositoryRequest, string) []string) *ValidationResult {
 	validationResult := &ValidationResult{
-		ValidRequests:   make([]config.RepositoryRequest, 0, len(batch.Requests)),
-		InvalidRequests: make([]ValidationError, 0, len(batch.Requests)),
-	}
-	for _, req := range batch.Requests {
-		// 1. Validate PackageManager
-		// Case A: Delete + Shared = Offboarding. PackageManager MUST be empty.
-		// Case B: All other cases. PackageManager MUST be present.
-		if action == MethodDelete && req.Shared {
-			if req.PackageManager != "" {
-				validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
-					Request: req,
-					Reasons: []string{"packageManager must be empty for shared delete operations"},
-				})
-				continue
-			}
-		} else {
-			if req.PackageManager == "" {
-				validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
-					Request: req,
-					Reasons: []string{"packageManager is required for this operation type"},
-				})
-				continue
-			}
+		ValidRequests:   make([]config.RepositoryRequest, 0, len(requests)),
+		ValidIndexes:    make([]int, 0, len(requests)),
+		InvalidRequests: make([]ValidationError, 0, len(requests)),
+	}
+	for i, req := range requests {
+		h.validateInto(validationResult, i, req, action, validate)
+	}
+	return validationResult
+}
+
+// validateInto validates req, the index-th request of the batch, with validate after
+// applying the default package manager and normalizing it, and adds it to the valid or
+// invalid requests of validationResult.
+func (h *Handler) validateInto(validationResult *ValidationResult, index int, req config.RepositoryRequest, action string, validate func(config.RepositoryRequest, string) []string) {
+	req = h.cfg.CanonicalRequest(req, action)
+	if reasons := validate(req, action); len(reasons) > 0 {
+		validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
+			Index:   index,
+			Request: req,
+			Reasons: reasons,
+		})
+		return
+	}
+	validationResult.ValidRequests = append(validationResult.ValidRequests, req)
+	validationResult.ValidIndexes = append(validationResult.ValidIndexes, index)
+}
+
+// rejection is one reason a request failed validation. Reason is one of the Reason*
+// keys, stable for metrics, and Message the text returned to the client.
+type rejection struct {
+	Reason  string
+	Message string
+}
+
+// rejectionMessages returns the client-facing messages of rejections, or nil for none.
+func rejectionMessages(rejections []rejection) []string {
+	var messages []string
+	for _, r := range rejections {
+		messages = append(messages, r.Message)
+	}
+	return messages
+}
+
+// validateFormatSettings checks the docker and maven settings a create request would
+// send to Nexus, combining packageManager.json with the request's overrides.
+func (h *Handler) validateFormatSettings(req config.RepositoryRequest) []rejection {
+	var rejections []rejection
+	manager, ok := h.cfg.PackageManagers[req.PackageManager]
+	format := ""
+	if ok {
+		format = manager.Format()
+	}
+
+	// Docker repositories need a connector port, from packageManager.json or the request
+	if format == config.FormatDocker {
+		if _, err := manager.DockerConnector(req.DockerHTTPPort, req.DockerHTTPSPort); err != nil {
+			rejections = append(rejections, rejection{ReasonInvalidDockerSettings, err.Error()})
 		}
+	} else if req.DockerHTTPPort != 0 || req.DockerHTTPSPort != 0 {
+		rejections = append(rejections, rejection{ReasonDockerPortsNotAllowed, "docker ports are only allowed for docker repositories"})
+	}
 
-		// 2. Validate AppID/Shared Combinations
-		// If Action is Create: Shared=true MUST have Empty AppID.
-		// If Action is Delete: Shared=true MUST have AppID (Offboarding Mode).
-		if action == MethodCreate {
-			if req.Shared && req.AppID != "" {
-				validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
-					Request: req,
-					Reasons: []string{"appid not allowed for shared repos on create"},
-				})
-				continue
-			}
-		} else if action == MethodDelete {
-			if req.Shared && req.AppID == "" {
-				validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
-					Request: req,
-					Reasons: []string{"appid required for shared repos on delete (offboarding)"},
-				})
-				continue
-			}
+	if format == config.FormatMaven {
+		if _, err := manager.MavenPolicies(req.MavenVersionPolicy, req.MavenLayoutPolicy); err != nil {
+			rejections = append(rejections, rejection{ReasonInvalidMavenPolicy, err.Error()})
 		}
+	} else if req.MavenVersionPolicy != "" || req.MavenLayoutPolicy != "" {
+		rejections = append(rejections, rejection{ReasonMavenPoliciesNotAllowed, "maven policies are only allowed for maven repositories"})
+	}
+	return rejections
+}
 
-		if !req.Shared && req.AppID == "" {
-			validationResult.InvalidRequests = append(validationResult.InvalidRequests, ValidationError{
-				Request: req,
-				Reasons: []string{"appid required for non-shared repos"},
-			})
-			continue
+// validateRequest returns every validation failure for a single request.
+func (h *Handler) validateRequest(req config.RepositoryRequest, action string) []string {
+	return rejectionMessages(h.checkRequest(req, action))
+}
+
+// validateSubmission is validateRequest for a request submitted for processing: each
+// rejection is also counted by reason in the validation metrics.
+func (h *Handler) validateSubmission(req config.RepositoryRequest, action string) []string {
+	rejections := h.checkRequest(req, action)
+	h.recordRejections(rejections...)
+	return rejectionMessages(rejections)
+}
+
+// recordRejections counts the rejections in the validation metrics.
+func (h *Handler) recordRejections(rejections ...rejection) {
+	if h.batchManager == nil {
+		return
+	}
+	for _, r := range rejections {
+		h.batchManager.metrics.recordRejection(r.Reason)
+	}
+}
+
+// checkRequest returns every reason the request fails validation.
+func (h *Handler) checkRequest(req config.RepositoryRequest, action string) []rejection {
+	var rejections []rejection
+
+	// 1. Validate OrganizationName against the configured organizations. Any
+	// organization may be named when AUTO_CREATE_ORG is set; it is resolved in IQ Server
+	autoResolved := req.OrganizationName != "" && h.cfg.AutoCreatesOrganizations()
+	if _, ok := h.cfg.Orgs[req.OrganizationName]; !ok && !autoResolved {
+		rejections = append(rejections, rejection{ReasonUnknownOrganization,
+			fmt.Sprintf("organization '%s' is not configured", req.OrganizationName)})
+	}
+
+	// 2. Validate PackageManager
+	// Case A: Delete + Shared = Offboarding. PackageManager MUST be empty.
+	// Case B: All other cases. PackageManager MUST be present.
+	if action == MethodDelete && req.Shared {
+		if req.PackageManager != "" {
+			rejections = append(rejections, rejection{ReasonPackageManagerNotAllowed,
+				"packageManager must be empty for shared delete operations"})
+		}
+	} else {
+		if req.PackageManager == "" {
+			rejections = append(rejections, rejection{ReasonMissingPackageManager,
+				"packageManager is required for this operation type"})
+		} else if !h.cfg.HasPackageManager(req.PackageManager) {
+			rejections = append(rejections, rejection{ReasonUnsupportedPackageManager,
+				fmt.Sprintf("packageManager '%s' is not supported (supported: %s)",
+					req.PackageManager, strings.Join(h.cfg.SupportedPackageManagers(), ", "))})
 		}
+	}
 
-		validationResult.ValidRequests = append(validationResult.ValidRequests, req)
+	// 3. Validate AppID/Shared Combinations
+	// If Action is Create: Shared=true MUST have Empty AppID.
+	// If Action is Delete: Shared=true MUST have AppID (Offboarding Mode).
+	if action == MethodCreate {
+		if req.Shared && req.AppID != "" {
+			rejections = append(rejections, rejection{ReasonAppIDNotAllowed,
+				"appid not allowed for shared repos on create"})
+		}
+	} else if action == MethodDelete {
+		if req.Shared && len(req.AppIDs()) == 0 {
+			rejections = append(rejections, rejection{ReasonMissingAppID,
+				"appid required for shared repos on delete (offboarding)"})
+		}
 	}
-	return validationResult
+
+	if !req.Shared && req.AppID == "" {
+		rejections = append(rejections, rejection{ReasonMissingAppID, "appid required for non-shared repos"})
+	}
+
+	// 4. Format-specific settings must suit the repository format
+	if action == MethodCreate {
+		rejections = append(rejections, h.validateFormatSettings(req)...)
+	}
+
+	// 5. Remote credentials come as a pair
+	if (req.RemoteUsername == "") != (req.RemotePassword == "") {
+		rejections = append(rejections, rejection{ReasonIncompleteRemoteCredentials,
+			"remoteUsername and remotePassword must be provided together"})
+	}
+	// A password copied back from a stored job is only the redaction placeholder
+	if req.RemotePassword == utils.RedactedValue {
+		rejections = append(rejections, rejection{ReasonRedactedRemotePassword,
+			"remotePassword is redacted; supply the real password again"})
+	}
+
+	// 6. A remote URL override must be usable as the proxy's upstream
+	if req.RemoteURL != "" {
+		if err := config.ValidateRemoteURL(req.RemoteURL); err != nil {
+			rejections = append(rejections, rejection{ReasonInvalidRemoteURL, err.Error()})
+		}
+	}
+
+	// A repository name override must be a valid Nexus name, and only names a new
+	// repository: a deletion may only target the generated, managed name
+	if req.RepositoryName != "" {
+		if action != MethodCreate {
+			rejections = append(rejections, rejection{ReasonRepositoryNameNotAllowed,
+				"repositoryName is only allowed for create operations"})
+		} else if err := config.ValidateRepositoryName(req.RepositoryName); err != nil {
+			rejections = append(rejections, rejection{ReasonInvalidRepositoryName, err.Error()})
+		}
+	}
+
+	// 7. Privilege access must be a known level
+	if req.PrivilegeAccess != "" && !slices.Contains(config.PrivilegeAccessLevels, req.PrivilegeAccess) {
+		rejections = append(rejections, rejection{ReasonInvalidPrivilegeAccess,
+			fmt.Sprintf("privilegeAccess '%s' is invalid (allowed: %s)",
+				req.PrivilegeAccess, strings.Join(config.PrivilegeAccessLevels, ", "))})
+	}
+
+	// 8. Only deletions can be forced
+	if req.Force && action != MethodDelete {
+		rejections = append(rejections, rejection{ReasonForceNotAllowed, "force is only allowed for delete operations"})
+	}
+
+	// Only offboarding accepts a comma-separated list of AppIDs
+	if strings.Contains(req.AppID, ",") && !(action == MethodDelete && req.Shared) {
+		rejections = append(rejections, rejection{ReasonMultipleAppIDsNotAllowed,
+			"multiple appids are only allowed for offboarding"})
+	}
+
+	return rejections
 }
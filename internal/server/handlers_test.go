@@ -2,14 +2,30 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"github.com/anmicius0/sonatype-resource-automation/internal/version"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func setupRouter(bm *BatchManager) (*gin.Engine, *Handler) {
@@ -18,8 +34,8 @@ func setupRouter(bm *BatchManager) (*gin.Engine, *Handler) {
 
 	cfg := &config.Config{
 		APIToken: "test-token",
-		Orgs: map[string]string{
-			"org1": "org-id-1",
+		Orgs: map[string]config.Organization{
+			"org1": {ID: "org-id-1"},
 		},
 		PackageManagers: map[string]config.PackageManager{
 			"npm": {DefaultURL: "https://registry.npmjs.org"},
@@ -48,6 +64,162 @@ func TestHealth(t *testing.T) {
 	assert.Equal(t, "healthy", resp["status"])
 }
 
+func TestReady(t *testing.T) {
+	newRouter := func(nexusErr, iqErr error) *gin.Engine {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("Ping").Return(nexusErr)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("Ping").Return(iqErr)
+		bm := NewBatchManager(&config.Config{}, config.NewJobStore(), mockNexus, mockIQ)
+		bm.MarkStarted()
+		r, h := setupRouter(bm)
+		r.GET("/ready", h.ready)
+		return r
+	}
+
+	t.Run("Starting", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		bm := NewBatchManager(&config.Config{}, config.NewJobStore(), mockNexus, new(MockIQClient))
+		r, h := setupRouter(bm)
+		r.GET("/ready", h.ready)
+
+		req, _ := http.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.JSONEq(t, `{"success":false,"status":"starting","backends":[]}`, w.Body.String())
+		// Backends are not probed before startup completes
+		mockNexus.AssertNotCalled(t, "Ping")
+	})
+
+	t.Run("All backends reachable", func(t *testing.T) {
+		r := newRouter(nil, nil)
+		req, _ := http.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "ready", resp["status"])
+		assert.Len(t, resp["backends"], 2)
+	})
+
+	t.Run("IQ Server unavailable", func(t *testing.T) {
+		r := newRouter(nil, errors.New("connection refused"))
+		req, _ := http.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "unavailable", resp["status"])
+
+		backends := resp["backends"].([]any)
+		nexus := backends[0].(map[string]any)
+		iq := backends[1].(map[string]any)
+		assert.Equal(t, "nexus", nexus["name"])
+		assert.Equal(t, "ready", nexus["status"])
+		assert.Contains(t, nexus, "latencyMs")
+		assert.Equal(t, "iq_server", iq["name"])
+		assert.Equal(t, "unavailable", iq["status"])
+		assert.Equal(t, "connection refused", iq["error"])
+	})
+
+	t.Run("Typed ping failure reports only the cause", func(t *testing.T) {
+		nexusErr := &client.BackendUnavailableError{Backend: client.BackendNexus, Err: &client.HTTPError{StatusCode: 503, Body: "starting"}}
+		r := newRouter(nexusErr, nil)
+		req, _ := http.NewRequest("GET", "/ready", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		nexus := resp["backends"].([]any)[0].(map[string]any)
+		assert.Equal(t, "HTTP 503: starting", nexus["error"])
+	})
+}
+
+func TestVersion(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.GET("/version", h.version)
+
+	req, _ := http.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, version.Version, resp["version"])
+	assert.Equal(t, version.Commit, resp["commit"])
+	assert.Equal(t, version.BuildTime, resp["buildTime"])
+	assert.Equal(t, runtime.Version(), resp["goVersion"])
+}
+
+func TestListPackageManagers(t *testing.T) {
+	r, h := setupRouter(nil)
+	h.cfg.PackageManagers = map[string]config.PackageManager{
+		"npm":   {DefaultURL: "https://registry.npmjs.org", APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/npm/proxy"}},
+		"maven": {DefaultURL: "https://repo1.maven.org/maven2/", APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/maven/proxy"}},
+	}
+	h.cfg.DefaultPackageManager = "npm"
+	r.GET("/package-managers", h.listPackageManagers)
+
+	req, _ := http.NewRequest("GET", "/package-managers", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []any{
+		map[string]any{"name": "maven", "format": "maven", "defaultUrl": "https://repo1.maven.org/maven2/", "default": false},
+		map[string]any{"name": "npm", "format": "npm", "defaultUrl": "https://registry.npmjs.org", "default": true},
+	}, resp["packageManagers"])
+}
+
+func TestListOrganizations(t *testing.T) {
+	list := func(cfg func(*config.Config)) []any {
+		r, h := setupRouter(nil)
+		h.cfg.Orgs["Department B"] = config.Organization{ID: "org-id-2"}
+		cfg(h.cfg)
+		r.GET("/organizations", h.listOrganizations)
+
+		req, _ := http.NewRequest("GET", "/organizations", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp["organizations"].([]any)
+	}
+
+	t.Run("IDs are hidden by default", func(t *testing.T) {
+		orgs := list(func(*config.Config) {})
+		assert.Equal(t, []any{
+			map[string]any{"name": "Department B", "usesIQServer": true, "id": ""},
+			map[string]any{"name": "org1", "usesIQServer": true, "id": ""},
+		}, orgs)
+	})
+
+	t.Run("IDs are exposed when enabled", func(t *testing.T) {
+		orgs := list(func(cfg *config.Config) {
+			cfg.ExposeOrganizationIDs = true
+			cfg.IQDisabled = true
+		})
+		assert.Equal(t, []any{
+			map[string]any{"name": "Department B", "usesIQServer": false, "id": "org-id-2"},
+			map[string]any{"name": "org1", "usesIQServer": false, "id": "org-id-1"},
+		}, orgs)
+	})
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	r, _ := setupRouter(nil)
 	r.Use(authMiddleware("test-token"))
@@ -79,6 +251,163 @@ func TestAuthMiddleware(t *testing.T) {
 	})
 }
 
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	originalProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(originalProvider)
+
+	r, _ := setupRouter(nil)
+	r.Use(tracingMiddleware())
+	r.GET("/jobs/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	req, _ := http.NewRequest("GET", "/jobs/job-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /jobs/:id", spans[0].Name())
+
+	attrs := make(map[string]any)
+	for _, kv := range spans[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, "/jobs/:id", attrs["http.route"])
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.response.status_code"])
+}
+
+func TestRequireJSONMiddleware(t *testing.T) {
+	r, _ := setupRouter(nil)
+	r.POST("/batch", requireJSONMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+	})
+
+	send := func(contentType string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/batch", bytes.NewBufferString(`{"Requests":[]}`))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "Application/JSON"} {
+		assert.Equal(t, http.StatusAccepted, send(contentType).Code, contentType)
+	}
+
+	for _, contentType := range []string{"", "application/x-www-form-urlencoded", "text/plain", "application/jsonp", "not a media type;;"} {
+		w := send(contentType)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, contentType)
+
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeUnsupportedMediaType, resp["error"])
+		assert.Equal(t, MessageUnsupportedMediaType, resp["message"])
+		details, ok := resp["details"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, contentType, details["contentType"])
+	}
+}
+
+func TestAllowlistMiddleware(t *testing.T) {
+	newRouter := func(allowed []netip.Prefix, trustedProxies []string) *gin.Engine {
+		r, _ := setupRouter(nil)
+		assert.NoError(t, r.SetTrustedProxies(trustedProxies))
+		r.Use(allowlistMiddleware(allowed))
+		r.GET("/ping", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+	send := func(r *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/ping", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	runners := []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("2001:db8::/32")}
+
+	t.Run("Empty list allows every client", func(t *testing.T) {
+		r := newRouter(nil, nil)
+		assert.Equal(t, http.StatusOK, send(r, "203.0.113.7:4000", "").Code)
+	})
+
+	t.Run("Allowed and denied client IPs", func(t *testing.T) {
+		r := newRouter(runners, nil)
+		assert.Equal(t, http.StatusOK, send(r, "10.20.3.4:4000", "").Code)
+		assert.Equal(t, http.StatusOK, send(r, "[2001:db8::1]:4000", "").Code)
+
+		w := send(r, "10.21.0.1:4000", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeForbidden, resp["error"])
+		assert.Equal(t, MessageClientNotAllowed, resp["message"])
+		details, ok := resp["details"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, "10.21.0.1", details["clientIP"])
+	})
+
+	t.Run("Forwarded header from an untrusted peer is ignored", func(t *testing.T) {
+		r := newRouter(runners, nil)
+		assert.Equal(t, http.StatusForbidden, send(r, "203.0.113.7:4000", "10.20.3.4").Code)
+	})
+
+	t.Run("Forwarded header from a trusted proxy names the client", func(t *testing.T) {
+		r := newRouter(runners, []string{"192.0.2.10"})
+		assert.Equal(t, http.StatusOK, send(r, "192.0.2.10:4000", "10.20.3.4").Code)
+		assert.Equal(t, http.StatusForbidden, send(r, "192.0.2.10:4000", "203.0.113.7").Code)
+		// The proxy itself is not in the allowlist
+		assert.Equal(t, http.StatusForbidden, send(r, "192.0.2.10:4000", "").Code)
+	})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	r, _ := setupRouter(nil)
+	r.Use(requestIDMiddleware())
+	var seen string
+	r.GET("/ping", func(c *gin.Context) {
+		seen = utils.RequestIDFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	send := func(header string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/ping", nil)
+		if header != "" {
+			req.Header.Set(RequestIDHeader, header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Incoming ID is honored", func(t *testing.T) {
+		w := send("abc-123")
+		assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+		assert.Equal(t, "abc-123", seen)
+	})
+
+	t.Run("Missing ID is generated", func(t *testing.T) {
+		w := send("")
+		assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+		assert.Equal(t, w.Header().Get(RequestIDHeader), seen)
+	})
+
+	t.Run("Malformed ID is replaced", func(t *testing.T) {
+		w := send("bad id\twith spaces")
+		assert.NotEqual(t, "bad id\twith spaces", w.Header().Get(RequestIDHeader))
+		assert.Equal(t, w.Header().Get(RequestIDHeader), seen)
+	})
+}
+
 func TestGetJobStatus(t *testing.T) {
 	r, h := setupRouter(nil)
 	r.GET("/jobs/:id", h.getJobStatus)
@@ -107,6 +436,78 @@ func TestGetJobStatus(t *testing.T) {
 	})
 }
 
+func TestGetStats(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.GET(StatsPath, h.getStats)
+
+	h.jobStore.CreateJob("job-1", "create", 4)
+	assert.NoError(t, h.jobStore.UpdateJob("job-1", func(j *config.Job) {
+		j.Status = config.JobStatusCompleted
+		j.SuccessfulOperations = 3
+		j.FailedOperations = 1
+		j.DurationMs = 1500
+	}))
+	h.jobStore.CreateJob("job-2", "delete", 2)
+
+	req, _ := http.NewRequest("GET", StatsPath, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(2), resp["totalJobs"])
+	assert.Equal(t, float64(1), resp["pendingJobs"])
+	assert.Equal(t, float64(1), resp["completedJobs"])
+	assert.Equal(t, float64(1), resp["jobSuccessRate"])
+	assert.Equal(t, float64(4), resp["processedOperations"])
+	assert.Equal(t, 0.75, resp["operationSuccessRate"])
+	assert.Equal(t, 0.25, resp["operationFailureRate"])
+	assert.Equal(t, float64(1500), resp["averageDurationMs"])
+}
+
+func TestGetFailedRequests(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.GET("/jobs/:id/failed", h.getFailedRequests)
+
+	h.jobStore.CreateJob("job-1", "create", 3)
+	failed := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+	_ = h.jobStore.UpdateJob("job-1", func(j *config.Job) {
+		j.FailedRequests = []config.FailedRequest{{Request: failed, Reason: "nexus unavailable"}}
+	})
+	h.jobStore.CreateJob("job-2", "create", 1)
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]any) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("Failed requests as a batch body", func(t *testing.T) {
+		w, _ := get("/jobs/job-1/failed")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The body decodes straight back into a batch request
+		var batch batchRepositoryRequest
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+		assert.Equal(t, []config.RepositoryRequest{failed}, batch.Requests)
+	})
+
+	t.Run("No failures", func(t *testing.T) {
+		w, resp := get("/jobs/job-2/failed")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []any{}, resp["requests"])
+	})
+
+	t.Run("Job Not Found", func(t *testing.T) {
+		w, _ := get("/jobs/job-999/failed")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
 func TestHandleBatch_Validation(t *testing.T) {
 	r, h := setupRouter(nil)
 	r.POST("/batch", h.createBatch)
@@ -132,11 +533,14 @@ func TestCreateBatch_Success(t *testing.T) {
 	mockNexus := new(MockNexusClient)
 	mockIQ := new(MockIQClient)
 	cfg := &config.Config{
-		Orgs: map[string]string{"org1": "org-id-1"},
+		Orgs: map[string]config.Organization{"org1": {ID: "org-id-1"}},
 		PackageManagers: map[string]config.PackageManager{
 			"npm": {DefaultURL: "https://registry.npmjs.org"},
 		},
 	}
+	// The job runs in the background and may outlive the test; fail it fast.
+	mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil).Maybe()
+	mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("not under test")).Maybe()
 	jobStore := config.NewJobStore()
 	bm := NewBatchManager(cfg, jobStore, mockNexus, mockIQ)
 
@@ -183,11 +587,13 @@ func TestDeleteBatch_Success(t *testing.T) {
 	mockNexus := new(MockNexusClient)
 	mockIQ := new(MockIQClient)
 	cfg := &config.Config{
-		Orgs: map[string]string{"org1": "org-id-1"},
+		Orgs: map[string]config.Organization{"org1": {ID: "org-id-1"}},
 		PackageManagers: map[string]config.PackageManager{
 			"npm": {DefaultURL: "https://registry.npmjs.org"},
 		},
 	}
+	// The job runs in the background and may outlive the test; fail it fast.
+	mockNexus.On("DeleteRepository", mock.Anything).Return(errors.New("not under test")).Maybe()
 	jobStore := config.NewJobStore()
 	bm := NewBatchManager(cfg, jobStore, mockNexus, mockIQ)
 
@@ -213,3 +619,1324 @@ func TestDeleteBatch_Success(t *testing.T) {
 
 	assert.Equal(t, http.StatusAccepted, w.Code)
 }
+
+func TestValidateBatchRequest_CollectsAllReasons(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	t.Run("Shared delete with package manager and no AppID", func(t *testing.T) {
+		batch := batchRepositoryRequest{
+			Requests: []config.RepositoryRequest{
+				{
+					OrganizationName: "org1",
+					LdapUsername:     "user1",
+					PackageManager:   "npm",
+					Shared:           true,
+				},
+			},
+		}
+
+		result := h.validateBatchRequest(batch, MethodDelete)
+
+		assert.Empty(t, result.ValidRequests)
+		assert.Len(t, result.InvalidRequests, 1)
+		assert.ElementsMatch(t, []string{
+			"packageManager must be empty for shared delete operations",
+			"appid required for shared repos on delete (offboarding)",
+		}, result.InvalidRequests[0].Reasons)
+	})
+
+	t.Run("Non-shared create with no package manager and no AppID", func(t *testing.T) {
+		batch := batchRepositoryRequest{
+			Requests: []config.RepositoryRequest{
+				{
+					OrganizationName: "org1",
+					LdapUsername:     "user1",
+				},
+			},
+		}
+
+		result := h.validateBatchRequest(batch, MethodCreate)
+
+		assert.Len(t, result.InvalidRequests, 1)
+		assert.ElementsMatch(t, []string{
+			"packageManager is required for this operation type",
+			"appid required for non-shared repos",
+		}, result.InvalidRequests[0].Reasons)
+	})
+}
+
+func TestValidateBatchRequest_UnsupportedPackageManager(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "maven3", AppID: "app1"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "NPM", AppID: "app1"},
+		},
+	}
+
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Equal(t, "npm", result.ValidRequests[0].PackageManager)
+	assert.Len(t, result.InvalidRequests, 1)
+	assert.Equal(t, []string{"packageManager 'maven3' is not supported (supported: npm)"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_AutoCreateOrg(t *testing.T) {
+	_, h := setupRouter(nil)
+	h.cfg.AutoCreateOrg = true
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "new-team", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+		},
+	}
+
+	result := h.validateBatchRequest(batch, MethodCreate)
+	assert.Len(t, result.ValidRequests, 1)
+
+	// Deletion can clean up what creation set up under the organization
+	result = h.validateBatchRequest(batch, MethodDelete)
+	assert.Len(t, result.ValidRequests, 1)
+
+	// Without IQ Server there is nowhere to create the organization
+	h.cfg.IQDisabled = true
+	result = h.validateBatchRequest(batch, MethodCreate)
+	assert.Len(t, result.InvalidRequests, 1)
+}
+
+func TestValidateBatchRequest_NormalizesPackageManager(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "Npm", AppID: "app1"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: " NPM ", AppID: "app1"},
+		},
+	}
+
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Empty(t, result.InvalidRequests)
+	assert.Len(t, result.ValidRequests, 3)
+	for _, req := range result.ValidRequests {
+		assert.Equal(t, "npm", req.PackageManager)
+		opConfig, err := h.cfg.CreateOpConfig(req, MethodCreate)
+		assert.NoError(t, err)
+		assert.Equal(t, "npm-release-app1", opConfig.RepositoryName)
+		assert.Equal(t, "npm-release-app1", opConfig.PrivilegeName)
+	}
+}
+
+func TestCreateBatch_UnsupportedPackageManager(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.POST("/batch", h.createBatch)
+
+	reqBody := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "maven3", AppID: "app1"},
+		},
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/batch", bytes.NewBuffer(jsonBody))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "packageManager 'maven3' is not supported")
+}
+
+func TestValidateBatchRequest_OrganizationName(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	t.Run("Known organization", func(t *testing.T) {
+		batch := batchRepositoryRequest{
+			Requests: []config.RepositoryRequest{
+				{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+			},
+		}
+
+		result := h.validateBatchRequest(batch, MethodCreate)
+
+		assert.Len(t, result.ValidRequests, 1)
+		assert.Empty(t, result.InvalidRequests)
+	})
+
+	t.Run("Unknown organization", func(t *testing.T) {
+		batch := batchRepositoryRequest{
+			Requests: []config.RepositoryRequest{
+				{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+				{OrganizationName: "Unknown Dept", LdapUsername: "user2", PackageManager: "npm", AppID: "app2"},
+			},
+		}
+
+		result := h.validateBatchRequest(batch, MethodCreate)
+
+		assert.Len(t, result.ValidRequests, 1)
+		assert.Len(t, result.InvalidRequests, 1)
+		assert.Equal(t, "Unknown Dept", result.InvalidRequests[0].Request.OrganizationName)
+		assert.Equal(t, []string{"organization 'Unknown Dept' is not configured"}, result.InvalidRequests[0].Reasons)
+	})
+}
+
+func TestValidateBatchRequest_MultipleAppIDs(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	offboarding := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", Shared: true, AppID: "app1, app2"},
+			{OrganizationName: "org1", LdapUsername: "user1", Shared: true, AppID: " , "},
+		},
+	}
+	result := h.validateBatchRequest(offboarding, MethodDelete)
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Len(t, result.InvalidRequests, 1)
+	assert.Equal(t, []string{"appid required for shared repos on delete (offboarding)"}, result.InvalidRequests[0].Reasons)
+
+	create := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1,app2"},
+		},
+	}
+	result = h.validateBatchRequest(create, MethodCreate)
+	assert.Empty(t, result.ValidRequests)
+	assert.Equal(t, []string{"multiple appids are only allowed for offboarding"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_PrivilegeAccess(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", PrivilegeAccess: config.PrivilegeAccessReadOnly},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app2", PrivilegeAccess: "admin"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Len(t, result.InvalidRequests, 1)
+	assert.Equal(t, []string{"privilegeAccess 'admin' is invalid (allowed: full, read-only)"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_Indexes(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app2"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "pip", AppID: "app3"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app4"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Equal(t, []int{1, 3}, result.ValidIndexes)
+	assert.Len(t, result.InvalidRequests, 2)
+	assert.Equal(t, 0, result.InvalidRequests[0].Index)
+	assert.Equal(t, 2, result.InvalidRequests[1].Index)
+}
+
+func TestValidateBatchRequest_ForceOnlyOnDelete(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", Force: true},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodDelete)
+	assert.Len(t, result.ValidRequests, 1)
+
+	result = h.validateBatchRequest(batch, MethodCreate)
+	assert.Empty(t, result.ValidRequests)
+	assert.Equal(t, []string{"force is only allowed for delete operations"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_DockerPorts(t *testing.T) {
+	_, h := setupRouter(nil)
+	h.cfg.PackageManagers["docker"] = config.PackageManager{
+		DefaultURL: "https://registry-1.docker.io/",
+		APIEndpoint: &config.APIEndpoint{
+			Path:                 "/v1/repositories/docker/proxy",
+			FormatSpecificConfig: map[string]any{"docker": map[string]any{"httpPort": nil, "httpsPort": nil}},
+		},
+	}
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "docker", AppID: "app1", DockerHTTPPort: 8082},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "docker", AppID: "app2"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app3", DockerHTTPPort: 8082},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Equal(t, "app1", result.ValidRequests[0].AppID)
+	assert.Len(t, result.InvalidRequests, 2)
+	assert.Equal(t, []string{"docker repositories need an httpPort or httpsPort connector"}, result.InvalidRequests[0].Reasons)
+	assert.Equal(t, []string{"docker ports are only allowed for docker repositories"}, result.InvalidRequests[1].Reasons)
+}
+
+func TestValidateBatchRequest_MavenPolicies(t *testing.T) {
+	_, h := setupRouter(nil)
+	h.cfg.PackageManagers["maven"] = config.PackageManager{
+		DefaultURL:  "https://repo1.maven.org/maven2/",
+		APIEndpoint: &config.APIEndpoint{Path: "/v1/repositories/maven/proxy"},
+	}
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "maven", AppID: "app1", MavenVersionPolicy: "SNAPSHOT"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "maven", AppID: "app2", MavenLayoutPolicy: "LOOSE"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app3", MavenVersionPolicy: "SNAPSHOT"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Equal(t, "app1", result.ValidRequests[0].AppID)
+	assert.Len(t, result.InvalidRequests, 2)
+	assert.Equal(t, []string{"maven layoutPolicy 'LOOSE' is invalid (allowed: STRICT, PERMISSIVE)"}, result.InvalidRequests[0].Reasons)
+	assert.Equal(t, []string{"maven policies are only allowed for maven repositories"}, result.InvalidRequests[1].Reasons)
+}
+
+func TestValidateBatchRequest_RemoteCredentials(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", RemoteUsername: "mirror", RemotePassword: "s3cret"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app2", RemoteUsername: "mirror"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Len(t, result.InvalidRequests, 1)
+	assert.Equal(t, []string{"remoteUsername and remotePassword must be provided together"}, result.InvalidRequests[0].Reasons)
+
+	// A request copied from a job's stored failures must not configure the placeholder
+	batch = batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", RemoteUsername: "mirror", RemotePassword: utils.RedactedValue},
+		},
+	}
+	result = h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Empty(t, result.ValidRequests)
+	assert.Equal(t, []string{"remotePassword is redacted; supply the real password again"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_RemoteURL(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", RemoteURL: "https://npm.eu.example.com", RepositoryName: "npm-eu-mirror"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app2", RemoteURL: "npm.eu.example.com"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Len(t, result.InvalidRequests, 1)
+	assert.Equal(t, []string{"remote URL 'npm.eu.example.com' is invalid (expected an absolute http or https URL)"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatchRequest_RepositoryName(t *testing.T) {
+	_, h := setupRouter(nil)
+
+	batch := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", RepositoryName: "npm_eu.mirror-1"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app2", RepositoryName: "../security/users"},
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app3", RepositoryName: "-mirror"},
+		},
+	}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.ValidRequests, 1)
+	assert.Len(t, result.InvalidRequests, 2)
+	assert.Contains(t, result.InvalidRequests[0].Reasons[0], "repository name '../security/users' is invalid")
+	assert.Contains(t, result.InvalidRequests[1].Reasons[0], "repository name '-mirror' is invalid")
+
+	// A deletion cannot be pointed at an arbitrary repository
+	batch.Requests = []config.RepositoryRequest{
+		{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1", RepositoryName: "hand-made-proxy"},
+	}
+	result = h.validateBatchRequest(batch, MethodDelete)
+
+	assert.Empty(t, result.ValidRequests)
+	assert.Equal(t, []string{"repositoryName is only allowed for create operations"}, result.InvalidRequests[0].Reasons)
+}
+
+func TestValidateBatch(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.POST(ValidatePath, h.validateBatch)
+
+	post := func(body string) (*httptest.ResponseRecorder, map[string]any) {
+		req, _ := http.NewRequest("POST", ValidatePath, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("Valid batch", func(t *testing.T) {
+		w, resp := post(`{"action":"create","requests":[
+			{"organizationName":"org1","ldapUsername":"user1","packageManager":"npm","appId":"app1"},
+			{"organizationName":"org1","ldapUsername":"user2","packageManager":"npm","shared":true}]}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, resp["success"])
+		assert.Equal(t, "create", resp["action"])
+		validation := resp["validation"].(map[string]any)
+		assert.Equal(t, float64(2), validation["totalRequests"])
+		assert.Equal(t, float64(2), validation["validRequests"])
+		assert.Equal(t, float64(0), validation["invalidRequests"])
+		assert.Empty(t, validation["failedValidations"])
+	})
+
+	t.Run("Mixed batch", func(t *testing.T) {
+		w, resp := post(`{"action":"delete","requests":[
+			{"organizationName":"org1","ldapUsername":"user1","packageManager":"npm","appId":"app1"},
+			{"organizationName":"unknown","ldapUsername":"user2","shared":true}]}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, false, resp["success"])
+		validation := resp["validation"].(map[string]any)
+		assert.Equal(t, float64(1), validation["validRequests"])
+		assert.Equal(t, float64(1), validation["invalidRequests"])
+		failed := validation["failedValidations"].([]any)[0].(map[string]any)
+		assert.Equal(t, []any{
+			"organization 'unknown' is not configured",
+			"appid required for shared repos on delete (offboarding)",
+		}, failed["validationErrors"])
+	})
+
+	t.Run("Invalid action", func(t *testing.T) {
+		w, _ := post(`{"action":"update","requests":[{"organizationName":"org1","ldapUsername":"user1","packageManager":"npm","appId":"app1"}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		w, _ := post(`{"action":"create","requests":[]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+}
+
+func TestValidateBatchRequest_DefaultPackageManager(t *testing.T) {
+	_, h := setupRouter(nil)
+	h.cfg.DefaultPackageManager = "npm"
+
+	create := h.validateBatchRequest(batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{{OrganizationName: "org1", LdapUsername: "user1", AppID: "app1"}},
+	}, MethodCreate)
+	assert.Empty(t, create.InvalidRequests)
+	assert.Equal(t, "npm", create.ValidRequests[0].PackageManager)
+
+	// Offboarding still requires an empty package manager and is left untouched
+	offboard := h.validateBatchRequest(batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{{OrganizationName: "org1", LdapUsername: "user1", AppID: "app1", Shared: true}},
+	}, MethodDelete)
+	assert.Empty(t, offboard.InvalidRequests)
+	assert.Empty(t, offboard.ValidRequests[0].PackageManager)
+}
+
+func TestCreateBatch_ExceedsMaxBatchSize(t *testing.T) {
+	r, h := setupRouter(nil)
+	h.cfg.MaxBatchSize = 2
+	r.POST("/batch", h.createBatch)
+
+	request := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+	reqBody := batchRepositoryRequest{
+		Requests: []config.RepositoryRequest{request, request, request},
+	}
+	jsonBody, _ := json.Marshal(reqBody)
+	req, _ := http.NewRequest("POST", "/batch", bytes.NewBuffer(jsonBody))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrorCodeBatchTooLarge, resp["error"])
+	details, ok := resp["details"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, float64(3), details["submittedCount"])
+	assert.Equal(t, float64(2), details["maxBatchSize"])
+}
+
+func TestCreateBatch_NDJSON(t *testing.T) {
+	mockNexus := new(MockNexusClient)
+	mockIQ := new(MockIQClient)
+	cfg := &config.Config{
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	// The job runs in the background and may outlive the test; fail it fast.
+	mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil).Maybe()
+	mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("not under test")).Maybe()
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+	r, h := setupRouter(bm)
+	r.POST("/batch", requireJSONMiddleware(MIMENDJSON), h.createBatch)
+
+	post := func(body string) (*httptest.ResponseRecorder, map[string]any) {
+		req, _ := http.NewRequest("POST", "/batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", MIMENDJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	t.Run("Valid and invalid lines", func(t *testing.T) {
+		w, resp := post(`{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}
+{"OrganizationName":"org9","LdapUsername":"user2","PackageManager":"npm","AppID":"app2"}
+
+{"OrganizationName":"org1","LdapUsername":"user3",
+{"OrganizationName":"org1","LdapUsername":"user4","PackageManager":"npm","AppID":"app4","Typo":true}
+{"OrganizationName":"org1","LdapUsername":"user5","PackageManager":"npm","AppID":"app5"}
+`)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		validation, ok := resp["validation"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, float64(5), validation["totalRequests"])
+		assert.Equal(t, float64(2), validation["validRequests"])
+		assert.Equal(t, float64(3), validation["invalidRequests"])
+
+		failed, _ := validation["failedValidations"].([]any)
+		assert.Len(t, failed, 3)
+		var reasons []string
+		var indexes []float64
+		for _, f := range failed {
+			indexes = append(indexes, f.(map[string]any)["index"].(float64))
+			for _, reason := range f.(map[string]any)["validationErrors"].([]any) {
+				reasons = append(reasons, reason.(string))
+			}
+		}
+		assert.Contains(t, reasons, "organization 'org9' is not configured")
+		assert.Contains(t, reasons[1], "line 4: ")
+		assert.Equal(t, "line 5: unknown field 'Typo'", reasons[2])
+		// Blank lines are not requests, so they do not count towards the index
+		assert.Equal(t, []float64{1, 2, 3}, indexes)
+	})
+
+	t.Run("All lines invalid", func(t *testing.T) {
+		w, _ := post("not json\n")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("Empty body", func(t *testing.T) {
+		w, resp := post("\n\n")
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, MessageBatchEmpty, resp["message"])
+	})
+
+	t.Run("Oversized batch is rejected without reading the rest", func(t *testing.T) {
+		h.cfg.MaxBatchSize = 2
+		defer func() { h.cfg.MaxBatchSize = 0 }()
+		malformedBefore := bm.metrics.rejectionCount(ReasonMalformedRequest)
+		line := `{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}` + "\n"
+		// Reading past the third line would fail the request with 400
+		body := io.MultiReader(strings.NewReader("not json\n"+line+line), iotest.ErrReader(errors.New("read past the limit")))
+
+		req, _ := http.NewRequest("POST", "/batch", body)
+		req.Header.Set("Content-Type", MIMENDJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		details, _ := resp["details"].(map[string]any)
+		assert.Equal(t, float64(3), details["submittedCount"])
+		// The valid line before the limit was already submitted to a job
+		assert.NotEmpty(t, details["jobId"])
+		// The rejected body counts once, not once per invalid line
+		assert.Equal(t, malformedBefore, bm.metrics.rejectionCount(ReasonMalformedRequest))
+		assert.Equal(t, int64(1), bm.metrics.rejectionCount(ReasonBatchTooLarge))
+	})
+}
+
+func TestCreateBatch_NDJSONQueuesWhileReading(t *testing.T) {
+	mockNexus := new(MockNexusClient)
+	mockIQ := new(MockIQClient)
+	cfg := &config.Config{
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	started := make(chan struct{}, 1)
+	mockNexus.On("RepositoryExists", mock.Anything).Run(func(mock.Arguments) {
+		started <- struct{}{}
+	}).Return(false, nil)
+	mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("not under test"))
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+	r, h := setupRouter(bm)
+	r.POST("/batch", requireJSONMiddleware(MIMENDJSON), h.createBatch)
+
+	body, writer := io.Pipe()
+	req, _ := http.NewRequest("POST", "/batch", body)
+	req.Header.Set("Content-Type", MIMENDJSON)
+	w := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		r.ServeHTTP(w, req)
+	}()
+
+	_, err := writer.Write([]byte(`{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}` + "\n"))
+	assert.NoError(t, err)
+	// The first request is processed while the body is still open
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not processed before the body was read")
+	}
+	assert.NoError(t, writer.Close())
+	<-served
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	validation, _ := resp["validation"].(map[string]any)
+	assert.Equal(t, float64(1), validation["validRequests"])
+	assert.Eventually(t, func() bool { return bm.RunningJobs() == 0 }, 5*time.Second, 10*time.Millisecond)
+	job, ok := bm.jobStore.GetJob(resp["jobId"].(string))
+	assert.True(t, ok)
+	assert.Equal(t, config.JobStatusFailed, job.Status)
+	assert.Equal(t, 1, job.TotalRequests)
+}
+
+func TestCreateBatch_TooManyConcurrentJobs(t *testing.T) {
+	mockNexus := new(MockNexusClient)
+	mockIQ := new(MockIQClient)
+	cfg := &config.Config{
+		Orgs:              map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:   map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		MaxConcurrentJobs: 1,
+	}
+	mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil).Maybe()
+	mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("not under test")).Maybe()
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+	r, h := setupRouter(bm)
+	h.cfg.MaxConcurrentJobs = 1
+	r.POST("/batch", h.createBatch)
+
+	submit := func(path string) *httptest.ResponseRecorder {
+		reqBody := batchRepositoryRequest{Requests: []config.RepositoryRequest{
+			{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+		}}
+		jsonBody, _ := json.Marshal(reqBody)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonBody))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Hold the only slot as if another job were still running
+	assert.NoError(t, bm.acquireJobSlot())
+
+	for _, path := range []string{"/batch", "/batch?sync=true"} {
+		w := submit(path)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, path)
+		assert.Equal(t, "30", w.Header().Get(RetryAfterHeader), path)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeQueueFull, resp["error"])
+		assert.Equal(t, MessageQueueFull, resp["message"])
+		details, ok := resp["details"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, float64(1), details["runningJobs"])
+		assert.Equal(t, float64(1), details["maxConcurrentJobs"])
+		assert.Equal(t, float64(30), details["retryAfterSeconds"])
+	}
+
+	// Once jobs have finished, Retry-After is their average duration, rounded up
+	bm.metrics.observeJob(MethodCreate, 2*time.Second)
+	bm.metrics.observeJob(MethodCreate, 3*time.Second)
+	w := submit("/batch")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get(RetryAfterHeader))
+
+	// Once the slot frees up the batch is accepted, and its slot is released when it finishes
+	bm.releaseJobSlot()
+	assert.Equal(t, http.StatusAccepted, submit("/batch").Code)
+	assert.Eventually(t, func() bool { return bm.RunningJobs() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestCreateBatch_UnknownField(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.POST("/batch", h.createBatch)
+
+	body := `{"Requests":[{"OrganizationName":"org1","LdapUsername":"user1","pkgManager":"npm","AppID":"app1"}]}`
+	req, _ := http.NewRequest("POST", "/batch", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrorCodeUnknownField, resp["error"])
+	assert.Equal(t, "Unknown field 'pkgManager' in request body", resp["message"])
+	details, ok := resp["details"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, "pkgManager", details["field"])
+}
+
+func TestCreateBatch_MissingRequiredField(t *testing.T) {
+	r, h := setupRouter(nil)
+	r.POST("/batch", h.createBatch)
+
+	// Strict decoding must still apply the `binding` tag validation
+	body := `{"Requests":[{"OrganizationName":"org1","PackageManager":"npm","AppID":"app1"}]}`
+	req, _ := http.NewRequest("POST", "/batch", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrorCodeInvalidRequestBody, resp["error"])
+	assert.Contains(t, resp["details"], "LdapUsername")
+}
+
+func TestGetRepository(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient) *gin.Engine {
+		bm := NewBatchManager(&config.Config{}, config.NewJobStore(), mockNexus, new(MockIQClient))
+		r, h := setupRouter(bm)
+		r.GET("/repositories/:name", h.getRepository)
+		return r
+	}
+
+	t.Run("Found", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("GetRepository", "npm-release-app1").Return(&client.Repository{
+			Name:   "npm-release-app1",
+			Format: "npm",
+			Type:   "proxy",
+			Url:    "http://nexus/repository/npm-release-app1",
+			Online: true,
+		}, nil)
+
+		req, _ := http.NewRequest("GET", "/repositories/npm-release-app1", nil)
+		w := httptest.NewRecorder()
+		newRouter(mockNexus).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "npm-release-app1", resp["name"])
+		assert.Equal(t, "proxy", resp["type"])
+		assert.Equal(t, "http://nexus/repository/npm-release-app1", resp["url"])
+		assert.Equal(t, true, resp["online"])
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("GetRepository", "missing").Return(nil, fmt.Errorf("get repository 'missing': %w", &client.HTTPError{StatusCode: 404, Body: "not found"}))
+
+		req, _ := http.NewRequest("GET", "/repositories/missing", nil)
+		w := httptest.NewRecorder()
+		newRouter(mockNexus).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeNotFound, resp["error"])
+	})
+
+	t.Run("Backend error", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("GetRepository", "npm-release-app1").Return(nil, &client.HTTPError{StatusCode: 500, Body: "boom"})
+
+		req, _ := http.NewRequest("GET", "/repositories/npm-release-app1", nil)
+		w := httptest.NewRecorder()
+		newRouter(mockNexus).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeBackendError, resp["error"])
+	})
+
+	t.Run("Invalid name is rejected before reaching Nexus", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+
+		for _, path := range []string{"/repositories/.hidden", "/repositories/npm%3Fx=1"} {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			newRouter(mockNexus).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+			var resp map[string]any
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, ErrorCodeValidationFailed, resp["error"])
+		}
+		mockNexus.AssertNotCalled(t, "GetRepository", mock.Anything)
+	})
+}
+
+func TestSetRepositoryOnline(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient) *gin.Engine {
+		bm := NewBatchManager(&config.Config{}, config.NewJobStore(), mockNexus, new(MockIQClient))
+		r, h := setupRouter(bm)
+		r.PUT("/repositories/:name/online", h.setRepositoryOnline)
+		return r
+	}
+	put := func(r *gin.Engine, name, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/repositories/"+name+"/online", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Offline", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("SetRepositoryOnline", "npm-release-app1", false).Return(nil)
+
+		w := put(newRouter(mockNexus), "npm-release-app1", `{"online": false}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "npm-release-app1", resp["name"])
+		assert.Equal(t, false, resp["online"])
+		mockNexus.AssertExpectations(t)
+	})
+
+	t.Run("Missing online flag", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+
+		w := put(newRouter(mockNexus), "npm-release-app1", `{}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		mockNexus.AssertNotCalled(t, "SetRepositoryOnline", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("SetRepositoryOnline", "missing", true).Return(fmt.Errorf("set repository 'missing' online=true: %w", &client.HTTPError{StatusCode: 404, Body: "not found"}))
+
+		w := put(newRouter(mockNexus), "missing", `{"online": true}`)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeNotFound, resp["error"])
+	})
+
+	t.Run("Backend error", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("SetRepositoryOnline", "npm-release-app1", true).Return(&client.HTTPError{StatusCode: 500, Body: "boom"})
+
+		w := put(newRouter(mockNexus), "npm-release-app1", `{"online": true}`)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeBackendError, resp["error"])
+	})
+}
+
+func TestInvalidateCache(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient) *gin.Engine {
+		bm := NewBatchManager(&config.Config{}, config.NewJobStore(), mockNexus, new(MockIQClient))
+		r, h := setupRouter(bm)
+		r.POST("/repositories/:name/invalidate-cache", h.invalidateCache)
+		return r
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("InvalidateCache", "npm-release-app1").Return(nil)
+
+		req, _ := http.NewRequest("POST", "/repositories/npm-release-app1/invalidate-cache", nil)
+		w := httptest.NewRecorder()
+		newRouter(mockNexus).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "npm-release-app1", resp["name"])
+		mockNexus.AssertExpectations(t)
+	})
+
+	t.Run("Backend error", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("InvalidateCache", "npm-hosted").Return(&client.HTTPError{StatusCode: 400, Body: "not a proxy"})
+
+		req, _ := http.NewRequest("POST", "/repositories/npm-hosted/invalidate-cache", nil)
+		w := httptest.NewRecorder()
+		newRouter(mockNexus).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeBackendError, resp["error"])
+	})
+}
+
+func TestInvalidateIQRoleCache(t *testing.T) {
+	mockIQ := new(MockIQClient)
+	mockIQ.On("InvalidateRoleCache").Return()
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), mockIQ)
+	r, h := setupRouter(bm)
+	h.cfg.IQOwnerRoleName = "Owner"
+	r.POST(IQRoleCachePath, h.invalidateIQRoleCache)
+
+	req, _ := http.NewRequest("POST", IQRoleCachePath, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"success":true,"roleName":"Owner"}`, w.Body.String())
+	mockIQ.AssertExpectations(t)
+}
+
+// deadlineRecorder records the write deadline a handler sets through
+// http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (w *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+func TestCreateSingle(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient, mockIQ *MockIQClient) *gin.Engine {
+		cfg := &config.Config{
+			Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+			PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		}
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+		r, h := setupRouter(bm)
+		r.POST("/repositories/single", h.createSingle)
+		return r
+	}
+	body := `{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}`
+
+	t.Run("Success returns 200 synchronously", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", mock.Anything).Return(nil, nil)
+		mockNexus.On("CreateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("AddOwnerRoleToUser", mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest("POST", "/repositories/single", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, mockIQ).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, true, resp["success"])
+		assert.Equal(t, MethodCreate, resp["action"])
+		result, ok := resp["result"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, []any{"user1"}, result["createdRoles"])
+		mockNexus.AssertExpectations(t)
+		mockIQ.AssertExpectations(t)
+	})
+
+	t.Run("Write deadline covers the operation timeout", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("nexus down"))
+		cfg := &config.Config{
+			Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+			PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		}
+		r, h := setupRouter(NewBatchManager(cfg, config.NewJobStore(), mockNexus, new(MockIQClient)))
+		h.cfg.OperationTimeout = time.Minute
+		r.POST("/repositories/single", gzipMiddleware(), h.createSingle)
+
+		req, _ := http.NewRequest("POST", "/repositories/single", bytes.NewBufferString(body))
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.WithinDuration(t, time.Now().Add(time.Minute+config.DefaultWriteTimeout), w.deadline, 5*time.Second)
+	})
+
+	t.Run("Remote password is not echoed back", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", mock.Anything).Return(nil, nil)
+		mockNexus.On("CreateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("AddOwnerRoleToUser", mock.Anything).Return(nil)
+		withCredentials := `{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1","RemoteUsername":"mirror","RemotePassword":"s3cret"}`
+
+		req, _ := http.NewRequest("POST", "/repositories/single", bytes.NewBufferString(withCredentials))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, mockIQ).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "s3cret")
+		assert.Contains(t, w.Body.String(), utils.RedactedValue)
+	})
+
+	t.Run("Operation failure returns 422", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("nexus down"))
+
+		req, _ := http.NewRequest("POST", "/repositories/single", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeOperationFailed, resp["error"])
+		assert.Contains(t, resp["details"], "nexus down")
+	})
+
+	t.Run("Validation failure returns 422 without calling backends", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		invalid := `{"OrganizationName":"unknown-org","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}`
+
+		req, _ := http.NewRequest("POST", "/repositories/single", bytes.NewBufferString(invalid))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, ErrorCodeValidationFailed, resp["error"])
+		mockNexus.AssertNotCalled(t, "RepositoryExists", mock.Anything)
+	})
+}
+
+func TestPreviewOffboarding(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient, mockIQ *MockIQClient) *gin.Engine {
+		cfg := &config.Config{
+			Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+			PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+			BaseRoles:       []string{"base-role"},
+		}
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+		r, h := setupRouter(bm)
+		r.POST("/offboarding/preview", h.previewOffboarding)
+		return r
+	}
+	body := `{"OrganizationName":"org1","LdapUsername":"user1","Shared":true,"AppID":"app1"}`
+
+	t.Run("Returns the plan without mutating anything", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1", Roles: []string{"user1", "base-role"}}, nil)
+		mockNexus.On("RoleExists", "user1").Return(true, nil)
+		mockNexus.On("GetRepositories").Return([]client.Repository{{Name: "npm-release-app1"}, {Name: "other"}}, nil)
+		mockNexus.On("GetPrivileges").Return([]client.Privilege{{Name: "npm-release-app1"}}, nil)
+		mockIQ := new(MockIQClient)
+
+		req, _ := http.NewRequest("POST", "/offboarding/preview", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, mockIQ).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		plan, ok := resp["plan"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, []any{"app1"}, plan["appIds"])
+		assert.Equal(t, []any{"npm-release-app1"}, plan["repositories"])
+		assert.Equal(t, []any{"npm-release-app1"}, plan["privileges"])
+		assert.Equal(t, []any{"user1"}, plan["roles"])
+		assert.Equal(t, []any{"base-role"}, plan["userRoles"])
+		assert.Equal(t, true, plan["removeOwnerRole"])
+		mockNexus.AssertNotCalled(t, "UpdateUser", mock.Anything)
+		mockNexus.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+		mockIQ.AssertNotCalled(t, "RemoveOwnerRoleFromUser", mock.Anything)
+	})
+
+	t.Run("Non-offboarding request returns 422", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		invalid := `{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"}`
+
+		req, _ := http.NewRequest("POST", "/offboarding/preview", bytes.NewBufferString(invalid))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		mockNexus.AssertNotCalled(t, "GetUser", mock.Anything)
+	})
+
+	t.Run("Backend failure returns 502", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("GetUser", "user1").Return(nil, errors.New("nexus down"))
+
+		req, _ := http.NewRequest("POST", "/offboarding/preview", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+	})
+}
+
+func TestNewRouter_RegistersRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(&config.Config{APIToken: "test-token"}, config.NewJobStore(), nil)
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	assert.True(t, routes["POST /repositories/single"])
+	assert.True(t, routes["DELETE /repositories/single"])
+	assert.True(t, routes["GET /repositories/:name"])
+	assert.True(t, routes["POST /offboarding/preview"])
+	assert.True(t, routes["POST /batch/validate"])
+	assert.True(t, routes["GET /jobs/:id/failed"])
+}
+
+func TestNewRouter_ReadinessGate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockIQ := new(MockIQClient)
+	mockIQ.On("InvalidateRoleCache").Return()
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), mockIQ)
+	router := NewRouter(&config.Config{APIToken: "test-token"}, config.NewJobStore(), bm)
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", IQRoleCachePath)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrorCodeNotReady, resp["error"])
+	mockIQ.AssertNotCalled(t, "InvalidateRoleCache")
+	// Read-only endpoints are not gated
+	assert.Equal(t, http.StatusOK, send("GET", PackageManagersPath).Code)
+
+	bm.MarkStarted()
+
+	assert.Equal(t, http.StatusOK, send("POST", IQRoleCachePath).Code)
+	mockIQ.AssertExpectations(t)
+}
+
+func TestNewRouter_Pprof(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	send := func(router *gin.Engine, token string) int {
+		req, _ := http.NewRequest("GET", PprofPath+"/goroutine?debug=1", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	off := NewRouter(&config.Config{APIToken: "test-token"}, config.NewJobStore(), nil)
+	for _, route := range off.Routes() {
+		assert.NotContains(t, route.Path, PprofPath)
+	}
+	assert.Equal(t, http.StatusNotFound, send(off, "test-token"))
+
+	on := NewRouter(&config.Config{APIToken: "test-token", EnablePprof: true}, config.NewJobStore(), nil)
+	assert.Equal(t, http.StatusUnauthorized, send(on, ""))
+	assert.Equal(t, http.StatusOK, send(on, "test-token"))
+}
+
+func TestNewRouter_RoutePrefix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter(&config.Config{APIToken: "test-token", RoutePrefix: "/api/v1"}, config.NewJobStore(), nil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("/api/v1/health").Code)
+	assert.Equal(t, http.StatusOK, send("/api/v1/version").Code)
+	assert.Equal(t, http.StatusNotFound, send("/health").Code)
+	assert.Equal(t, http.StatusNotFound, send("/version").Code)
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	assert.True(t, routes["POST /api/v1/repositories"])
+	assert.True(t, routes["GET /api/v1/metrics"])
+	assert.False(t, routes["POST /repositories"])
+}
+
+func TestCreateBatch_Sync(t *testing.T) {
+	newRouter := func(mockNexus *MockNexusClient, mockIQ *MockIQClient) *gin.Engine {
+		cfg := &config.Config{
+			Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+			PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		}
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+		r, h := setupRouter(bm)
+		r.POST("/batch", h.createBatch)
+		return r
+	}
+	forApp := func(appID string) any {
+		return mock.MatchedBy(func(o *config.OperationConfig) bool { return o.AppID == appID })
+	}
+	body := `{"Requests":[
+		{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"},
+		{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app2"}]}`
+
+	t.Run("Mixed results return 207", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", forApp("app1")).Return("", nil)
+		mockNexus.On("CreateProxyRepository", forApp("app2")).Return("", errors.New("nexus down"))
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", mock.Anything).Return(nil, nil)
+		mockNexus.On("CreateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("AddOwnerRoleToUser", mock.Anything).Return(nil)
+
+		req, _ := http.NewRequest("POST", "/batch?sync=true", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, mockIQ).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "partial", resp["outcome"])
+		assert.NotEmpty(t, resp["jobId"])
+
+		outcomes := map[string]bool{}
+		for _, r := range resp["results"].([]any) {
+			result := r.(map[string]any)
+			outcomes[result["appId"].(string)] = result["success"].(bool)
+		}
+		assert.Equal(t, map[string]bool{"app1": true, "app2": false}, outcomes)
+	})
+
+	t.Run("Results carry the index of their request", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("nexus down"))
+		indexedBody := `{"Requests":[
+			{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app1"},
+			{"OrganizationName":"missing","LdapUsername":"user1","PackageManager":"npm","AppID":"app2"},
+			{"OrganizationName":"org1","LdapUsername":"user1","PackageManager":"npm","AppID":"app3"}]}`
+
+		req, _ := http.NewRequest("POST", "/batch?sync=true", bytes.NewBufferString(indexedBody))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		indexes := map[string]float64{}
+		for _, r := range resp["results"].([]any) {
+			result := r.(map[string]any)
+			indexes[result["appId"].(string)] = result["index"].(float64)
+		}
+		assert.Equal(t, map[string]float64{"app1": 0, "app3": 2}, indexes)
+		failed := resp["validation"].(map[string]any)["failedValidations"].([]any)
+		assert.Len(t, failed, 1)
+		assert.Equal(t, float64(1), failed[0].(map[string]any)["index"])
+	})
+
+	t.Run("All failures return 502", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", mock.Anything).Return("", errors.New("nexus down"))
+
+		req, _ := http.NewRequest("POST", "/batch?sync=true", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "failed", resp["outcome"])
+		assert.Equal(t, false, resp["success"])
+	})
+
+	t.Run("Transient failures are flagged retriable", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", forApp("app1")).Return("", &client.RetriableError{Err: &client.HTTPError{StatusCode: 503}})
+		mockNexus.On("CreateProxyRepository", forApp("app2")).Return("", &client.HTTPError{StatusCode: 400})
+
+		req, _ := http.NewRequest("POST", "/batch?sync=true", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		newRouter(mockNexus, new(MockIQClient)).ServeHTTP(w, req)
+
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		retriable := map[string]bool{}
+		for _, r := range resp["results"].([]any) {
+			result := r.(map[string]any)
+			retriable[result["appId"].(string)] = result["retriable"].(bool)
+		}
+		assert.Equal(t, map[string]bool{"app1": true, "app2": false}, retriable)
+	})
+
+	t.Run("Job outlasting the wait returns 202 and keeps running", func(t *testing.T) {
+		release := make(chan struct{})
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(false, nil)
+		mockNexus.On("CreateProxyRepository", mock.Anything).Run(func(mock.Arguments) { <-release }).Return("", errors.New("nexus down"))
+		cfg := &config.Config{
+			Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+			PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+		}
+		jobStore := config.NewJobStore()
+		bm := NewBatchManager(cfg, jobStore, mockNexus, new(MockIQClient))
+		bm.syncWait = 10 * time.Millisecond
+		r, h := setupRouter(bm)
+		r.POST("/batch", h.createBatch)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		req, _ := http.NewRequestWithContext(ctx, "POST", "/batch?sync=true", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		// The client going away does not cancel the job
+		cancel()
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var resp map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		jobID, _ := resp["jobId"].(string)
+		assert.NotEmpty(t, jobID)
+		assert.True(t, bm.Busy())
+
+		close(release)
+		assert.Eventually(t, func() bool { return bm.RunningJobs() == 0 }, time.Second, 5*time.Millisecond)
+		job, ok := jobStore.GetJob(jobID)
+		assert.True(t, ok)
+		assert.Equal(t, 2, job.FailedOperations)
+	})
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"go.uber.org/zap"
+)
+
+// OperationEvent describes one finished create or delete operation for an OperationHook.
+type OperationEvent struct {
+	Action           string    `json:"action"`
+	Success          bool      `json:"success"`
+	OrganizationName string    `json:"organizationName"`
+	OrganizationID   string    `json:"organizationId,omitempty"`
+	LdapUsername     string    `json:"ldapUsername"`
+	PackageManager   string    `json:"packageManager"`
+	AppID            string    `json:"appId,omitempty"`
+	Shared           bool      `json:"shared"`
+	RepositoryName   string    `json:"repositoryName,omitempty"`
+	RoleName         string    `json:"roleName,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	ErrorCode        string    `json:"errorCode,omitempty"`
+	CompletedSteps   []string  `json:"completedSteps"`
+	FailedStep       string    `json:"failedStep,omitempty"`
+	RequestID        string    `json:"requestId,omitempty"`
+	CompletedAt      time.Time `json:"completedAt"`
+}
+
+// OperationHook is notified after every operation, successful or not, so external
+// systems can react to it. A hook's error is logged and never changes the outcome.
+type OperationHook interface {
+	OnOperationComplete(ctx context.Context, event OperationEvent) error
+}
+
+// noopHook is the OperationHook used when none is configured.
+type noopHook struct{}
+
+func (noopHook) OnOperationComplete(context.Context, OperationEvent) error { return nil }
+
+// httpHook POSTs each event as JSON to a URL, such as an event bus ingestion endpoint.
+type httpHook struct {
+	url    string
+	client *http.Client
+}
+
+// newOperationHook returns the hook configured by OPERATION_HOOK_URL, or a no-op hook.
+func newOperationHook(cfg *config.Config) OperationHook {
+	if cfg.OperationHookURL == "" {
+		return noopHook{}
+	}
+	timeout := cfg.OperationHookTimeout
+	if timeout == 0 {
+		timeout = config.DefaultOperationHookTimeout
+	}
+	return &httpHook{url: cfg.OperationHookURL, client: &http.Client{Timeout: timeout}}
+}
+
+// OnOperationComplete sends the event, failing on a transport error or a non-2xx status.
+func (h *httpHook) OnOperationComplete(ctx context.Context, event OperationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode operation event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build operation hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if event.RequestID != "" {
+		req.Header.Set(RequestIDHeader, event.RequestID)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post operation event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("post operation event: unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// hookDelivery is an event waiting to be redelivered to the operation hook. jobID is
+// empty for operations outside a job.
+type hookDelivery struct {
+	event    OperationEvent
+	jobID    string
+	attempts int
+}
+
+// hookRetryQueue holds the events the hook failed to accept until they are due for
+// another delivery. The wait doubles with every failed attempt. At most size
+// deliveries wait at once, so ready never blocks.
+type hookRetryQueue struct {
+	ready       chan *hookDelivery
+	size        int
+	maxAttempts int
+	backoff     time.Duration
+
+	mu      sync.Mutex
+	waiting int
+}
+
+// newHookRetryQueue sizes the queue from the OPERATION_HOOK_* settings, using the
+// defaults for unset ones.
+func newHookRetryQueue(cfg *config.Config) *hookRetryQueue {
+	q := &hookRetryQueue{
+		size:        cfg.OperationHookQueueSize,
+		maxAttempts: cfg.OperationHookMaxAttempts,
+		backoff:     cfg.OperationHookRetryBackoff,
+	}
+	if q.size <= 0 {
+		q.size = config.DefaultOperationHookQueueSize
+	}
+	if q.maxAttempts <= 0 {
+		q.maxAttempts = config.DefaultOperationHookMaxAttempts
+	}
+	if q.backoff <= 0 {
+		q.backoff = config.DefaultOperationHookRetryBackoff
+	}
+	q.ready = make(chan *hookDelivery, q.size)
+	return q
+}
+
+// enqueue schedules a failed delivery for its next attempt, reporting false when the
+// queue is full.
+func (q *hookRetryQueue) enqueue(d *hookDelivery) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiting >= q.size {
+		return false
+	}
+	q.waiting++
+	q.schedule(d)
+	return true
+}
+
+// schedule hands the delivery to the worker once its backoff has elapsed. The
+// delivery keeps its place in the queue.
+func (q *hookRetryQueue) schedule(d *hookDelivery) {
+	time.AfterFunc(q.delay(d.attempts), func() { q.ready <- d })
+}
+
+// delay is the wait after the given number of failed attempts.
+func (q *hookRetryQueue) delay(attempts int) time.Duration {
+	delay := q.backoff
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= config.MaxOperationHookRetryBackoff {
+			return config.MaxOperationHookRetryBackoff
+		}
+	}
+	return delay
+}
+
+// release frees the place of a delivery that was delivered or given up on.
+func (q *hookRetryQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.waiting--
+}
+
+// RunHookRetries redelivers the events the operation hook failed to accept until ctx
+// is done, recording each outcome on the event's job. Events still waiting when ctx
+// ends are not delivered.
+func (bm *BatchManager) RunHookRetries(ctx context.Context) {
+	q := bm.hookRetries
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-q.ready:
+			err := bm.hook.OnOperationComplete(ctx, d.event)
+			d.attempts++
+			logger := utils.Logger.With(
+				zap.String(utils.FieldAction, d.event.Action),
+				zap.String(utils.FieldRepo, d.event.RepositoryName),
+				zap.Int("attempts", d.attempts))
+			switch {
+			case err == nil:
+				q.release()
+				bm.recordHookDelivery(d.jobID, config.HookDeliveryPending, config.HookDeliveryDelivered)
+				logger.Info("Operation hook redelivery succeeded")
+			case d.attempts >= q.maxAttempts:
+				q.release()
+				bm.recordHookDelivery(d.jobID, config.HookDeliveryPending, config.HookDeliveryFailed)
+				logger.Error("Operation hook delivery failed; giving up", zap.Error(err))
+			default:
+				logger.Warn("Operation hook redelivery failed; retrying",
+					zap.Duration("retry_in", q.delay(d.attempts)),
+					zap.Error(err))
+				q.schedule(d)
+			}
+		}
+	}
+}
+
+// recordHookDelivery records a delivery's status change on its job, if it has one.
+func (bm *BatchManager) recordHookDelivery(jobID, from, to string) {
+	if jobID == "" {
+		return
+	}
+	if err := bm.jobStore.RecordHookDelivery(jobID, from, to); err != nil {
+		utils.Logger.Debug("Could not record operation hook delivery",
+			zap.String(utils.FieldJobID, jobID),
+			zap.Error(err))
+	}
+}
+
+// SetOperationHook replaces the hook notified after each operation; nil restores the no-op hook.
+func (bm *BatchManager) SetOperationHook(hook OperationHook) {
+	if hook == nil {
+		hook = noopHook{}
+	}
+	bm.hook = hook
+}
+
+// notifyHook builds the event for one operation and hands it to the hook. The hook
+// runs even if ctx was cancelled or timed out, and its failure is only logged and the
+// event queued for redelivery by RunHookRetries.
+func (bm *BatchManager) notifyHook(ctx context.Context, action string, req config.RepositoryRequest, opConfig *config.OperationConfig, res operationResult) {
+	event := OperationEvent{
+		Action:           action,
+		Success:          res.Success,
+		OrganizationName: req.OrganizationName,
+		LdapUsername:     req.LdapUsername,
+		PackageManager:   req.PackageManager,
+		AppID:            req.AppID,
+		Shared:           req.Shared,
+		Error:            res.Error,
+		ErrorCode:        res.ErrorCode,
+		CompletedSteps:   res.CompletedSteps,
+		FailedStep:       res.FailedStep,
+		RequestID:        utils.RequestIDFromContext(ctx),
+		CompletedAt:      time.Now().UTC(),
+	}
+	if opConfig != nil {
+		event.OrganizationID = opConfig.OrganizationID
+		event.RepositoryName = opConfig.RepositoryName
+		event.RoleName = opConfig.RoleName
+	}
+	if _, ok := bm.hook.(noopHook); ok {
+		return
+	}
+
+	jobID := utils.JobIDFromContext(ctx)
+	err := bm.hook.OnOperationComplete(context.WithoutCancel(ctx), event)
+	if err == nil {
+		bm.recordHookDelivery(jobID, "", config.HookDeliveryDelivered)
+		return
+	}
+	logger := utils.LoggerFromContext(ctx).With(
+		zap.String(utils.FieldAction, action),
+		zap.String(utils.FieldRepo, event.RepositoryName),
+		zap.Error(err))
+	if bm.hookRetries.maxAttempts <= 1 {
+		logger.Warn("Operation hook failed")
+		bm.recordHookDelivery(jobID, "", config.HookDeliveryFailed)
+		return
+	}
+	if !bm.hookRetries.enqueue(&hookDelivery{event: event, jobID: jobID, attempts: 1}) {
+		logger.Warn("Operation hook failed and the retry queue is full; dropping the event",
+			zap.Int("queue_size", bm.hookRetries.size))
+		bm.recordHookDelivery(jobID, "", config.HookDeliveryFailed)
+		return
+	}
+	logger.Warn("Operation hook failed; queued for retry",
+		zap.Duration("retry_in", bm.hookRetries.delay(1)))
+	bm.recordHookDelivery(jobID, "", config.HookDeliveryPending)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPHook(t *testing.T) {
+	var received map[string]any
+	var requestID string
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(RequestIDHeader)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hook := newOperationHook(&config.Config{OperationHookURL: srv.URL})
+	event := OperationEvent{Action: MethodDelete, Success: true, LdapUsername: "user1", RequestID: "req-1"}
+
+	assert.NoError(t, hook.OnOperationComplete(t.Context(), event))
+	assert.Equal(t, "req-1", requestID)
+	assert.Equal(t, MethodDelete, received["action"])
+	assert.Equal(t, "user1", received["ldapUsername"])
+
+	status = http.StatusInternalServerError
+	assert.ErrorContains(t, hook.OnOperationComplete(t.Context(), event), "unexpected status code 500")
+
+	assert.IsType(t, noopHook{}, newOperationHook(&config.Config{}))
+}
+
+func TestHookRetries_ReceiverRecovers(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The receiver is down for the first two deliveries
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{OperationHookURL: srv.URL, OperationHookRetryBackoff: time.Millisecond}
+	jobStore := config.NewJobStore()
+	jobStore.CreateJob("job-1", MethodCreate, 1)
+	bm := NewBatchManager(cfg, jobStore, new(MockNexusClient), new(MockIQClient))
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go bm.RunHookRetries(ctx)
+
+	bm.notifyHook(utils.ContextWithJobID(t.Context(), "job-1"), MethodCreate, config.RepositoryRequest{LdapUsername: "user1"}, nil, operationResult{Success: true})
+	assert.Equal(t, config.HookDeliveries{Status: config.HookDeliveryPending, Pending: 1}, hookDeliveries(t, jobStore, "job-1"))
+
+	assert.Eventually(t, func() bool {
+		return hookDeliveries(t, jobStore, "job-1").Status == config.HookDeliveryDelivered
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, config.HookDeliveries{Status: config.HookDeliveryDelivered, Delivered: 1}, hookDeliveries(t, jobStore, "job-1"))
+}
+
+func TestHookRetries_GivesUpAndDrops(t *testing.T) {
+	cfg := &config.Config{OperationHookMaxAttempts: 2, OperationHookRetryBackoff: time.Millisecond, OperationHookQueueSize: 1}
+	jobStore := config.NewJobStore()
+	jobStore.CreateJob("job-1", MethodCreate, 2)
+	bm := NewBatchManager(cfg, jobStore, new(MockNexusClient), new(MockIQClient))
+	hook := &recordingHook{err: errors.New("receiver down")}
+	bm.SetOperationHook(hook)
+	ctx := utils.ContextWithJobID(t.Context(), "job-1")
+
+	// The queue holds one event, so the second failure is dropped
+	bm.notifyHook(ctx, MethodCreate, config.RepositoryRequest{LdapUsername: "user1"}, nil, operationResult{Success: true})
+	bm.notifyHook(ctx, MethodCreate, config.RepositoryRequest{LdapUsername: "user2"}, nil, operationResult{Success: true})
+	assert.Equal(t, config.HookDeliveries{Status: config.HookDeliveryPending, Pending: 1, Failed: 1}, hookDeliveries(t, jobStore, "job-1"))
+
+	// The queued event fails its second and last attempt
+	workerCtx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go bm.RunHookRetries(workerCtx)
+	assert.Eventually(t, func() bool {
+		return hookDeliveries(t, jobStore, "job-1").Status == config.HookDeliveryFailed
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, config.HookDeliveries{Status: config.HookDeliveryFailed, Failed: 2}, hookDeliveries(t, jobStore, "job-1"))
+}
+
+func TestHookRetryQueue_Delay(t *testing.T) {
+	q := newHookRetryQueue(&config.Config{OperationHookRetryBackoff: time.Second})
+	assert.Equal(t, time.Second, q.delay(1))
+	assert.Equal(t, 4*time.Second, q.delay(3))
+	assert.Equal(t, config.MaxOperationHookRetryBackoff, q.delay(20))
+}
+
+// hookDeliveries reads a job's hook deliveries under the store's lock.
+func hookDeliveries(t *testing.T, jobStore *config.JobStore, jobID string) config.HookDeliveries {
+	t.Helper()
+	var deliveries config.HookDeliveries
+	assert.NoError(t, jobStore.UpdateJob(jobID, func(job *config.Job) {
+		if job.HookDeliveries != nil {
+			deliveries = *job.HookDeliveries
+		}
+	}))
+	return deliveries
+}
@@ -0,0 +1,179 @@
+// internal/server/metrics.go
+package server
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OperationsMetricName counts processed repository operations.
+	OperationsMetricName = "sonatype_automation_operations_total"
+
+	// JobDurationMetricName summarizes how long finished batch jobs were processing.
+	JobDurationMetricName = "sonatype_automation_job_duration_seconds"
+
+	// ValidationRejectionsMetricName counts the reasons submitted requests failed validation.
+	ValidationRejectionsMetricName = "sonatype_automation_validation_rejections_total"
+
+	// ResultSuccess and ResultFailure label the outcome of an operation.
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	// noPackageManager labels operations without a package manager, such as offboarding.
+	noPackageManager = "none"
+)
+
+// operationKey identifies one operation counter.
+type operationKey struct {
+	PackageManager string
+	Action         string
+	Result         string
+}
+
+// durationSummary accumulates the count and total of observed durations.
+type durationSummary struct {
+	Count int64
+	Sum   time.Duration
+}
+
+// operationMetrics counts processed operations by package manager, action and result,
+// summarizes job durations by action and counts validation rejections by reason. It is
+// safe for concurrent use by the job workers.
+type operationMetrics struct {
+	mu           sync.Mutex
+	counts       map[operationKey]int64
+	jobDurations map[string]durationSummary
+	rejections   map[string]int64
+}
+
+func newOperationMetrics() *operationMetrics {
+	return &operationMetrics{
+		counts:       make(map[operationKey]int64),
+		jobDurations: make(map[string]durationSummary),
+		rejections:   make(map[string]int64),
+	}
+}
+
+// recordRejection increments the counter for one validation rejection reason.
+func (m *operationMetrics) recordRejection(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejections[reason]++
+}
+
+// rejectionCount returns the current value of one rejection counter.
+func (m *operationMetrics) rejectionCount(reason string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rejections[reason]
+}
+
+// observeJob adds the processing time of one finished job to its action's summary.
+func (m *operationMetrics) observeJob(action string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summary := m.jobDurations[action]
+	summary.Count++
+	summary.Sum += duration
+	m.jobDurations[action] = summary
+}
+
+// averageJobDuration returns the mean processing time of the finished jobs of every
+// action, reporting false before any job has finished.
+func (m *operationMetrics) averageJobDuration() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var total durationSummary
+	for _, summary := range m.jobDurations {
+		total.Count += summary.Count
+		total.Sum += summary.Sum
+	}
+	if total.Count == 0 {
+		return 0, false
+	}
+	return total.Sum / time.Duration(total.Count), true
+}
+
+// record increments the counter for one finished operation.
+func (m *operationMetrics) record(packageManager, action string, success bool) {
+	if packageManager == "" {
+		packageManager = noPackageManager
+	}
+	result := ResultFailure
+	if success {
+		result = ResultSuccess
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[operationKey{PackageManager: packageManager, Action: action, Result: result}]++
+}
+
+// count returns the current value of one counter.
+func (m *operationMetrics) count(packageManager, action, result string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[operationKey{PackageManager: packageManager, Action: action, Result: result}]
+}
+
+// render writes the counters in the Prometheus text exposition format, sorted by label.
+func (m *operationMetrics) render() string {
+	m.mu.Lock()
+	keys := make([]operationKey, 0, len(m.counts))
+	for k := range m.counts {
+		keys = append(keys, k)
+	}
+	counts := make(map[operationKey]int64, len(m.counts))
+	for k, v := range m.counts {
+		counts[k] = v
+	}
+	jobDurations := make(map[string]durationSummary, len(m.jobDurations))
+	for k, v := range m.jobDurations {
+		jobDurations[k] = v
+	}
+	rejections := maps.Clone(m.rejections)
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].PackageManager != keys[j].PackageManager {
+			return keys[i].PackageManager < keys[j].PackageManager
+		}
+		if keys[i].Action != keys[j].Action {
+			return keys[i].Action < keys[j].Action
+		}
+		return keys[i].Result < keys[j].Result
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Repository operations processed, by package manager, action and result.\n", OperationsMetricName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", OperationsMetricName)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s{package_manager=%q,action=%q,result=%q} %d\n",
+			OperationsMetricName, k.PackageManager, k.Action, k.Result, counts[k])
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Processing time of finished batch jobs, by action.\n", JobDurationMetricName)
+	fmt.Fprintf(&b, "# TYPE %s summary\n", JobDurationMetricName)
+	actions := make([]string, 0, len(jobDurations))
+	for action := range jobDurations {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	for _, action := range actions {
+		summary := jobDurations[action]
+		fmt.Fprintf(&b, "%s_sum{action=%q} %g\n", JobDurationMetricName, action, summary.Sum.Seconds())
+		fmt.Fprintf(&b, "%s_count{action=%q} %d\n", JobDurationMetricName, action, summary.Count)
+	}
+
+	fmt.Fprintf(&b, "# HELP %s Submitted requests rejected by validation, by reason.\n", ValidationRejectionsMetricName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", ValidationRejectionsMetricName)
+	for _, reason := range slices.Sorted(maps.Keys(rejections)) {
+		fmt.Fprintf(&b, "%s{reason=%q} %d\n", ValidationRejectionsMetricName, reason, rejections[reason])
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptOperation_RecordsMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+
+	// An unknown organization fails before any backend call
+	req := config.RepositoryRequest{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+	bm.attemptOperation(t.Context(), MethodCreate, req)
+	bm.attemptOperation(t.Context(), MethodCreate, req)
+
+	assert.Equal(t, int64(2), bm.metrics.count("npm", MethodCreate, ResultFailure))
+	assert.Equal(t, int64(0), bm.metrics.count("npm", MethodCreate, ResultSuccess))
+	assert.Equal(t, int64(0), bm.metrics.count("npm", MethodDelete, ResultFailure))
+}
+
+func TestValidateBatchRequest_RecordsRejections(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	_, h := setupRouter(bm)
+
+	batch := batchRepositoryRequest{Requests: []config.RepositoryRequest{
+		// Valid
+		{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"},
+		// Missing AppID
+		{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm"},
+		// Unsupported package manager and missing AppID
+		{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "maven3"},
+	}}
+	result := h.validateBatchRequest(batch, MethodCreate)
+
+	assert.Len(t, result.InvalidRequests, 2)
+	assert.Equal(t, int64(2), bm.metrics.rejectionCount(ReasonMissingAppID))
+	assert.Equal(t, int64(1), bm.metrics.rejectionCount(ReasonUnsupportedPackageManager))
+	assert.Equal(t, int64(0), bm.metrics.rejectionCount(ReasonUnknownOrganization))
+}
+
+func TestValidateBatch_DoesNotRecordRejections(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	r, h := setupRouter(bm)
+	r.POST(ValidatePath, h.validateBatch)
+
+	req, _ := http.NewRequest("POST", ValidatePath, bytes.NewBufferString(`{"action":"create","requests":[
+		{"organizationName":"org1","ldapUsername":"user1","packageManager":"npm"}]}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), bm.metrics.rejectionCount(ReasonMissingAppID))
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	bm.metrics.record("npm", MethodCreate, true)
+	bm.metrics.record("npm", MethodCreate, false)
+	bm.metrics.record("", MethodDelete, true)
+	bm.metrics.observeJob(MethodCreate, 1500*time.Millisecond)
+	bm.metrics.observeJob(MethodCreate, 500*time.Millisecond)
+	bm.metrics.recordRejection(ReasonUnknownOrganization)
+	bm.metrics.recordRejection(ReasonMissingAppID)
+	bm.metrics.recordRejection(ReasonMissingAppID)
+
+	r, h := setupRouter(bm)
+	r.GET(MetricsEndpoint, h.metrics)
+
+	req, _ := http.NewRequest("GET", MetricsEndpoint, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "# HELP sonatype_automation_operations_total Repository operations processed, by package manager, action and result.\n"+
+		"# TYPE sonatype_automation_operations_total counter\n"+
+		`sonatype_automation_operations_total{package_manager="none",action="delete",result="success"} 1`+"\n"+
+		`sonatype_automation_operations_total{package_manager="npm",action="create",result="failure"} 1`+"\n"+
+		`sonatype_automation_operations_total{package_manager="npm",action="create",result="success"} 1`+"\n"+
+		"# HELP sonatype_automation_job_duration_seconds Processing time of finished batch jobs, by action.\n"+
+		"# TYPE sonatype_automation_job_duration_seconds summary\n"+
+		`sonatype_automation_job_duration_seconds_sum{action="create"} 2`+"\n"+
+		`sonatype_automation_job_duration_seconds_count{action="create"} 2`+"\n"+
+		"# HELP sonatype_automation_validation_rejections_total Submitted requests rejected by validation, by reason.\n"+
+		"# TYPE sonatype_automation_validation_rejections_total counter\n"+
+		`sonatype_automation_validation_rejections_total{reason="missing_appid"} 2`+"\n"+
+		`sonatype_automation_validation_rejections_total{reason="unknown_organization"} 1`+"\n",
+		w.Body.String())
+}
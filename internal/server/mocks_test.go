@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/stretchr/testify/mock"
@@ -10,7 +11,7 @@ type MockNexusClient struct {
 	mock.Mock
 }
 
-func (m *MockNexusClient) GetRepository(name string) (*client.Repository, error) {
+func (m *MockNexusClient) GetRepository(ctx context.Context, name string) (*client.Repository, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -18,7 +19,12 @@ func (m *MockNexusClient) GetRepository(name string) (*client.Repository, error)
 	return args.Get(0).(*client.Repository), args.Error(1)
 }
 
-func (m *MockNexusClient) GetRepositories() ([]client.Repository, error) {
+func (m *MockNexusClient) RepositoryExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetRepositories(ctx context.Context) ([]client.Repository, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -26,17 +32,35 @@ func (m *MockNexusClient) GetRepositories() ([]client.Repository, error) {
 	return args.Get(0).([]client.Repository), args.Error(1)
 }
 
-func (m *MockNexusClient) CreateProxyRepository(config *config.OperationConfig) error {
+func (m *MockNexusClient) GetRepositoriesByFormat(ctx context.Context, format string) ([]client.Repository, error) {
+	args := m.Called(format)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.Repository), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateProxyRepository(ctx context.Context, config *config.OperationConfig) (string, error) {
 	args := m.Called(config)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNexusClient) SetRepositoryOnline(ctx context.Context, name string, online bool) error {
+	args := m.Called(name, online)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) InvalidateCache(ctx context.Context, repositoryName string) error {
+	args := m.Called(repositoryName)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeleteRepository(name string) error {
+func (m *MockNexusClient) DeleteRepository(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetPrivilege(name string) (*client.Privilege, error) {
+func (m *MockNexusClient) GetPrivilege(ctx context.Context, name string) (*client.Privilege, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -44,7 +68,12 @@ func (m *MockNexusClient) GetPrivilege(name string) (*client.Privilege, error) {
 	return args.Get(0).(*client.Privilege), args.Error(1)
 }
 
-func (m *MockNexusClient) GetPrivileges() ([]client.Privilege, error) {
+func (m *MockNexusClient) PrivilegeExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetPrivileges(ctx context.Context) ([]client.Privilege, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -52,17 +81,17 @@ func (m *MockNexusClient) GetPrivileges() ([]client.Privilege, error) {
 	return args.Get(0).([]client.Privilege), args.Error(1)
 }
 
-func (m *MockNexusClient) CreatePrivilege(config *config.OperationConfig) error {
+func (m *MockNexusClient) CreatePrivilege(ctx context.Context, config *config.OperationConfig) error {
 	args := m.Called(config)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeletePrivilege(name string) error {
+func (m *MockNexusClient) DeletePrivilege(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetRole(name string) (*client.Role, error) {
+func (m *MockNexusClient) GetRole(ctx context.Context, name string) (*client.Role, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -70,22 +99,35 @@ func (m *MockNexusClient) GetRole(name string) (*client.Role, error) {
 	return args.Get(0).(*client.Role), args.Error(1)
 }
 
-func (m *MockNexusClient) CreateRole(config *config.OperationConfig) error {
+func (m *MockNexusClient) RoleExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetRolesByPrivilege(ctx context.Context, privilegeName string) ([]client.Role, error) {
+	args := m.Called(privilegeName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.Role), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateRole(ctx context.Context, config *config.OperationConfig) error {
 	args := m.Called(config)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) UpdateRole(role *client.Role) error {
+func (m *MockNexusClient) UpdateRole(ctx context.Context, role *client.Role) error {
 	args := m.Called(role)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeleteRole(name string) error {
+func (m *MockNexusClient) DeleteRole(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetUser(username string) (*client.User, error) {
+func (m *MockNexusClient) GetUser(ctx context.Context, username string) (*client.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -93,31 +135,81 @@ func (m *MockNexusClient) GetUser(username string) (*client.User, error) {
 	return args.Get(0).(*client.User), args.Error(1)
 }
 
-func (m *MockNexusClient) UpdateUser(user *client.User) error {
+func (m *MockNexusClient) GetUsersByRole(ctx context.Context, roleName string) ([]client.User, error) {
+	args := m.Called(roleName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.User), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateUser(ctx context.Context, user *client.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) UpdateUser(ctx context.Context, user *client.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
+func (m *MockNexusClient) DeleteUser(ctx context.Context, userID string) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) Ping(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 type MockIQClient struct {
 	mock.Mock
 }
 
-func (m *MockIQClient) GetRoles() ([]client.IQRole, error) {
+func (m *MockIQClient) GetRoles(ctx context.Context) ([]client.IQRole, error) {
 	args := m.Called()
 	return args.Get(0).([]client.IQRole), args.Error(1)
 }
 
-func (m *MockIQClient) FindOwnerRoleID() (string, error) {
+func (m *MockIQClient) FindOwnerRoleID(ctx context.Context) (string, error) {
 	args := m.Called()
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockIQClient) AddOwnerRoleToUser(opConfig *config.OperationConfig) error {
+func (m *MockIQClient) InvalidateRoleCache() {
+	m.Called()
+}
+
+func (m *MockIQClient) AddOwnerRoleToUser(ctx context.Context, opConfig *config.OperationConfig) error {
 	args := m.Called(opConfig)
 	return args.Error(0)
 }
 
-func (m *MockIQClient) RemoveOwnerRoleFromUser(opConfig *config.OperationConfig) error {
+func (m *MockIQClient) RemoveOwnerRoleFromUser(ctx context.Context, opConfig *config.OperationConfig) (bool, error) {
 	args := m.Called(opConfig)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIQClient) GetOrganizationAncestors(ctx context.Context, organizationID string) ([]string, error) {
+	args := m.Called(organizationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockIQClient) FindOrganization(ctx context.Context, name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockIQClient) CreateOrganization(ctx context.Context, name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockIQClient) Ping(ctx context.Context) error {
+	args := m.Called()
 	return args.Error(0)
 }
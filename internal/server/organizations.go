@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"go.uber.org/zap"
+)
+
+// organizationCache remembers the IQ Server IDs of the organizations that requests
+// use without them being in the mapping, so each is resolved once per process.
+type organizationCache struct {
+	mu  sync.Mutex
+	ids map[string]string
+	// resolving holds a channel for each organization being resolved, closed once done
+	resolving map[string]chan struct{}
+}
+
+func newOrganizationCache() *organizationCache {
+	return &organizationCache{
+		ids:       make(map[string]string),
+		resolving: make(map[string]chan struct{}),
+	}
+}
+
+// resolve returns the ID of the named organization. When create is set, it is created
+// in IQ Server if it does not exist; otherwise it is only looked up, and a missing
+// organization is an error. Only one request resolves a given organization at a time,
+// so concurrent requests for a new organization create it only once; the others wait
+// for its result, and resolve it themselves if it failed. The lock only guards the
+// maps, so other organizations are resolved in parallel.
+func (oc *organizationCache) resolve(ctx context.Context, iq client.IQClient, name string, create bool) (string, error) {
+	for {
+		oc.mu.Lock()
+		if id, ok := oc.ids[name]; ok {
+			oc.mu.Unlock()
+			return id, nil
+		}
+		wait, busy := oc.resolving[name]
+		if !busy {
+			done := make(chan struct{})
+			oc.resolving[name] = done
+			oc.mu.Unlock()
+			defer func() {
+				oc.mu.Lock()
+				delete(oc.resolving, name)
+				oc.mu.Unlock()
+				close(done)
+			}()
+			break
+		}
+		oc.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	var id string
+	var err error
+	if create {
+		id, err = iq.CreateOrganization(ctx, name)
+	} else {
+		id, err = iq.FindOrganization(ctx, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("organization '%s' not found", name)
+	}
+	utils.LoggerFromContext(ctx).Info("Resolved organization missing from the mapping",
+		zap.String("organization_name", name),
+		zap.String("organization_id", id))
+	oc.mu.Lock()
+	oc.ids[name] = id
+	oc.mu.Unlock()
+	return id, nil
+}
+
+// opConfigFor builds the OperationConfig of a request. With AUTO_CREATE_ORG set, a
+// request naming an organization missing from the mapping uses the IQ Server
+// organization of that name with the global roles. Creation creates the organization
+// on demand; deletion and offboarding only look it up, so that whatever creation set
+// up under it can be cleaned up again.
+func (bm *BatchManager) opConfigFor(ctx context.Context, req config.RepositoryRequest, action string) (*config.OperationConfig, error) {
+	if _, ok := bm.cfg.Orgs[req.OrganizationName]; ok || !bm.cfg.AutoCreatesOrganizations() {
+		return bm.cfg.CreateOpConfig(req, action)
+	}
+	id, err := bm.orgs.resolve(ctx, bm.iq, req.OrganizationName, action == MethodCreate)
+	if err != nil {
+		return nil, err
+	}
+	cfg := *bm.cfg
+	cfg.Orgs = make(map[string]config.Organization, len(bm.cfg.Orgs)+1)
+	maps.Copy(cfg.Orgs, bm.cfg.Orgs)
+	cfg.Orgs[req.OrganizationName] = config.Organization{ID: id}
+	return cfg.CreateOpConfig(req, action)
+}
@@ -0,0 +1,26 @@
+// internal/server/pprof.go
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pprofProfiles are the runtime profiles served by name under PprofPath.
+var pprofProfiles = []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"}
+
+// registerPprof mounts the net/http/pprof handlers on group. Named profiles get a
+// route each, since pprof.Index only finds them under the fixed /debug/pprof/ path
+// and the group may sit under a route prefix.
+func registerPprof(group *gin.RouterGroup) {
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range pprofProfiles {
+		group.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
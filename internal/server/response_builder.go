@@ -4,18 +4,36 @@ package server
 import (
 	"reflect"
 	"strings"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/service"
 )
 
 // ResponseBuilder provides utilities for constructing consistent API responses.
-type ResponseBuilder struct{}
+type ResponseBuilder struct {
+	// omitEmpty drops nil, empty and zero-value fields from built responses
+	omitEmpty bool
+}
 
 // newResponseBuilder creates a new response builder instance.
 func newResponseBuilder() *ResponseBuilder { return &ResponseBuilder{} }
 
+// WithOmitEmpty enables or disables dropping empty fields from built responses.
+// It is off by default so consumers that expect every key keep receiving them.
+func (rb *ResponseBuilder) WithOmitEmpty(enabled bool) *ResponseBuilder {
+	rb.omitEmpty = enabled
+	return rb
+}
+
+// convert applies the builder's options while converting data to camelCase.
+func (rb *ResponseBuilder) convert(data any) any {
+	return convertToCamelCase(data, rb.omitEmpty)
+}
+
 // AcceptedResponse is the payload returned for accepted batch requests.
 type AcceptedResponse struct {
 	Success    bool
@@ -25,6 +43,33 @@ type AcceptedResponse struct {
 	Validation ValidationSummary
 }
 
+// SyncBatchResponse is the payload returned for batches processed with ?sync=true.
+type SyncBatchResponse struct {
+	Success    bool
+	JobID      string
+	Outcome    string
+	Results    []RequestResultResponse
+	Validation ValidationSummary
+}
+
+// RequestResultResponse reports the outcome of one processed batch request.
+type RequestResultResponse struct {
+	// Index is the zero-based position of the request in the submitted batch
+	Index            int
+	OrganizationName string
+	LdapUsername     string
+	PackageManager   string
+	Shared           bool
+	AppID            string
+	Success          bool
+	Error            string
+	Retriable        bool
+	ErrorCode        string
+	CompletedSteps   []string
+	FailedStep       string
+	Result           map[string]interface{}
+}
+
 // ValidationSummary contains batch validation counts and details.
 type ValidationSummary struct {
 	TotalRequests     int
@@ -35,6 +80,8 @@ type ValidationSummary struct {
 
 // InvalidRequestResponse holds a single invalid batch request's details.
 type InvalidRequestResponse struct {
+	// Index is the zero-based position of the request in the submitted batch
+	Index            int
 	OrganizationName string
 	LdapUsername     string
 	PackageManager   string
@@ -43,6 +90,79 @@ type InvalidRequestResponse struct {
 	ValidationErrors []string
 }
 
+// SingleOperationResponse is returned when a synchronous single request succeeds.
+type SingleOperationResponse struct {
+	Success bool
+	Message string
+	Action  string
+	Request config.RepositoryRequest
+	Result  map[string]interface{}
+}
+
+// BatchValidationResponse reports how a batch would be validated, without queueing a job.
+// Success is true only when every request is valid.
+type BatchValidationResponse struct {
+	Success    bool
+	Action     string
+	Validation ValidationSummary
+}
+
+// OffboardingPreviewResponse lists what an offboarding request would change.
+type OffboardingPreviewResponse struct {
+	Success bool
+	Request config.RepositoryRequest
+	Plan    *service.OffboardingPlan
+}
+
+// RepositoryStateResponse reports the online state a repository was put in.
+type RepositoryStateResponse struct {
+	Success bool
+	Name    string
+	Online  bool
+}
+
+// CacheInvalidatedResponse reports the repository whose caches were invalidated.
+type CacheInvalidatedResponse struct {
+	Success bool
+	Name    string
+}
+
+// RoleCacheInvalidatedResponse reports the IQ Server role whose cached ID was dropped.
+type RoleCacheInvalidatedResponse struct {
+	Success  bool
+	RoleName string
+}
+
+// PackageManagersResponse lists the configured package managers.
+type PackageManagersResponse struct {
+	Success         bool
+	PackageManagers []PackageManagerInfo
+}
+
+// PackageManagerInfo describes one package manager. Format is the Nexus repository
+// format it creates and Default marks DEFAULT_PACKAGE_MANAGER.
+type PackageManagerInfo struct {
+	Name       string
+	Format     string
+	DefaultURL string
+	Default    bool
+}
+
+// OrganizationsResponse lists the configured organizations.
+type OrganizationsResponse struct {
+	Success       bool
+	Organizations []OrganizationInfo
+}
+
+// OrganizationInfo describes one organization. UsesIQServer reports whether requests for
+// it are granted the Owner role in IQ Server. ID is the IQ Server organization ID,
+// empty unless EXPOSE_ORGANIZATION_IDS is set.
+type OrganizationInfo struct {
+	Name         string
+	UsesIQServer bool
+	ID           string
+}
+
 // ErrorResponse standardizes error responses.
 type ErrorResponse struct {
 	Success bool
@@ -51,6 +171,59 @@ type ErrorResponse struct {
 	Details any
 }
 
+// BatchSizeDetails reports the submitted batch size against the configured limit.
+type BatchSizeDetails struct {
+	SubmittedCount int
+	MaxBatchSize   int
+}
+
+// StreamedBatchSizeDetails is BatchSizeDetails for an NDJSON batch whose requests were
+// submitted while it was read. JobID is the job running the requests submitted before
+// the limit was reached.
+type StreamedBatchSizeDetails struct {
+	SubmittedCount int
+	MaxBatchSize   int
+	JobID          string
+}
+
+// ConcurrencyDetails reports the running jobs against the configured limit, and the
+// seconds a client should wait before resubmitting, as sent in Retry-After.
+type ConcurrencyDetails struct {
+	RunningJobs       int
+	MaxConcurrentJobs int
+	RetryAfterSeconds int
+}
+
+// ContentTypeDetails reports the Content-Type a request was rejected for.
+type ContentTypeDetails struct {
+	ContentType string
+}
+
+// ClientIPDetails reports the client IP a request was rejected for.
+type ClientIPDetails struct {
+	ClientIP string
+}
+
+// UnknownFieldDetails names a request body field that the API does not recognize.
+type UnknownFieldDetails struct {
+	Field string
+}
+
+// ReadinessResponse reports whether the service can reach its backends.
+type ReadinessResponse struct {
+	Success  bool
+	Status   string
+	Backends []BackendStatus
+}
+
+// BackendStatus is the outcome of a single backend ping.
+type BackendStatus struct {
+	Name      string
+	Status    string
+	LatencyMs int64
+	Error     string
+}
+
 // ValidationFailedResponse is returned when all requests are invalid.
 type ValidationFailedResponse struct {
 	Success         bool
@@ -67,7 +240,71 @@ type ValidationFailedResponseDetails struct {
 
 // BuildJobResponse constructs the job status response with all metrics, converting keys to camelCase.
 func (rb *ResponseBuilder) BuildJobResponse(job *config.Job) any {
-	return toCamelCaseMap(job)
+	return rb.convert(job)
+}
+
+// BuildStatsResponse constructs the aggregate job statistics response, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildStatsResponse(stats config.JobStats) any {
+	return rb.convert(stats)
+}
+
+// BuildFailedRequestsResponse constructs a batch body holding the job's failed requests,
+// ready to be edited and resubmitted, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildFailedRequestsResponse(job *config.Job) any {
+	return rb.convert(batchRepositoryRequest{Requests: job.FailedRepositoryRequests()})
+}
+
+// BuildRepositoryResponse constructs the repository details response, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildRepositoryResponse(repo *client.Repository) any {
+	return rb.convert(repo)
+}
+
+// BuildRepositoryStateResponse constructs the response to an online state change, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildRepositoryStateResponse(name string, online bool) any {
+	return rb.convert(RepositoryStateResponse{Success: true, Name: name, Online: online})
+}
+
+// BuildCacheInvalidatedResponse constructs the response to a cache invalidation, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildCacheInvalidatedResponse(name string) any {
+	return rb.convert(CacheInvalidatedResponse{Success: true, Name: name})
+}
+
+// BuildRoleCacheInvalidatedResponse constructs the response to an IQ Server role cache
+// invalidation, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildRoleCacheInvalidatedResponse(roleName string) any {
+	return rb.convert(RoleCacheInvalidatedResponse{Success: true, RoleName: roleName})
+}
+
+// BuildPackageManagersResponse lists the configured package managers in name order,
+// converting keys to camelCase. Only public settings are included.
+func (rb *ResponseBuilder) BuildPackageManagersResponse(cfg *config.Config) any {
+	names := cfg.SupportedPackageManagers()
+	managers := make([]PackageManagerInfo, 0, len(names))
+	for _, name := range names {
+		manager := cfg.PackageManagers[name]
+		managers = append(managers, PackageManagerInfo{
+			Name:       name,
+			Format:     manager.Format(),
+			DefaultURL: manager.DefaultURL,
+			Default:    name == cfg.DefaultPackageManager,
+		})
+	}
+	return rb.convert(PackageManagersResponse{Success: true, PackageManagers: managers})
+}
+
+// BuildOrganizationsResponse lists the configured organizations in name order,
+// converting keys to camelCase.
+func (rb *ResponseBuilder) BuildOrganizationsResponse(cfg *config.Config) any {
+	names := cfg.OrganizationNames()
+	orgs := make([]OrganizationInfo, 0, len(names))
+	for _, name := range names {
+		info := OrganizationInfo{Name: name, UsesIQServer: !cfg.IQDisabled}
+		if cfg.ExposeOrganizationIDs {
+			info.ID = cfg.Orgs[name].ID
+		}
+		orgs = append(orgs, info)
+	}
+	return rb.convert(OrganizationsResponse{Success: true, Organizations: orgs})
 }
 
 // BuildAcceptedResponse constructs an AcceptedResponse with validation details, converting keys to camelCase.
@@ -84,7 +321,76 @@ func (rb *ResponseBuilder) BuildAcceptedResponse(jobID string, totalRequests, va
 			FailedValidations: rb.ConvertValidationErrorsToResponse(validationResult.InvalidRequests),
 		},
 	}
-	return toCamelCaseMap(response)
+	return rb.convert(response)
+}
+
+// BuildBatchValidationResponse constructs the preflight validation result, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildBatchValidationResponse(action string, validationResult *ValidationResult) any {
+	invalidCount := len(validationResult.InvalidRequests)
+	return rb.convert(BatchValidationResponse{
+		Success: invalidCount == 0,
+		Action:  action,
+		Validation: ValidationSummary{
+			TotalRequests:     len(validationResult.ValidRequests) + invalidCount,
+			ValidRequests:     len(validationResult.ValidRequests),
+			InvalidRequests:   invalidCount,
+			FailedValidations: rb.ConvertValidationErrorsToResponse(validationResult.InvalidRequests),
+		},
+	})
+}
+
+// BuildSingleOperationResponse constructs the success payload for a synchronous single request.
+func (rb *ResponseBuilder) BuildSingleOperationResponse(req config.RepositoryRequest, action string, result map[string]interface{}) any {
+	return rb.convert(SingleOperationResponse{
+		Success: true,
+		Message: MessageOperationSucceeded,
+		Action:  action,
+		Request: req.Redacted(),
+		Result:  result,
+	})
+}
+
+// BuildOffboardingPreviewResponse constructs the dry-run plan for an offboarding request, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildOffboardingPreviewResponse(req config.RepositoryRequest, plan *service.OffboardingPlan) any {
+	return rb.convert(OffboardingPreviewResponse{
+		Success: true,
+		Request: req.Redacted(),
+		Plan:    plan,
+	})
+}
+
+// BuildSyncBatchResponse constructs the per-request results of a synchronous batch, converting keys to camelCase.
+func (rb *ResponseBuilder) BuildSyncBatchResponse(jobID string, totalRequests int, outcome service.Outcome, outcomes []requestOutcome, validationResult *ValidationResult) any {
+	results := make([]RequestResultResponse, 0, len(outcomes))
+	for _, o := range outcomes {
+		results = append(results, RequestResultResponse{
+			Index:            validationResult.batchIndex(o.Position),
+			OrganizationName: o.Request.OrganizationName,
+			LdapUsername:     o.Request.LdapUsername,
+			PackageManager:   o.Request.PackageManager,
+			Shared:           o.Request.Shared,
+			AppID:            o.Request.AppID,
+			Success:          o.Result.Success,
+			Error:            o.Result.Error,
+			Retriable:        o.Result.Retriable,
+			ErrorCode:        o.Result.ErrorCode,
+			CompletedSteps:   o.Result.CompletedSteps,
+			FailedStep:       o.Result.FailedStep,
+			Result:           o.Result.Result,
+		})
+	}
+	return rb.convert(SyncBatchResponse{
+		Success: outcome == service.OutcomeSucceeded,
+		JobID:   jobID,
+		Outcome: string(outcome),
+		Results: results,
+		Validation: ValidationSummary{
+			TotalRequests:     totalRequests,
+			ValidRequests:     len(validationResult.ValidRequests),
+			InvalidRequests:   len(validationResult.InvalidRequests),
+			FailedValidations: rb.ConvertValidationErrorsToResponse(validationResult.InvalidRequests),
+		},
+	})
 }
 
 // BuildErrorResponse constructs a standardized error response, converting keys to camelCase.
@@ -95,7 +401,7 @@ func (rb *ResponseBuilder) BuildErrorResponse(errorCode, errorMessage string, de
 		Message: errorMessage,
 		Details: details,
 	}
-	return toCamelCaseMap(response)
+	return rb.convert(response)
 }
 
 // BuildValidationFailedResponse constructs a response for validation failures, converting keys to camelCase.
@@ -109,7 +415,37 @@ func (rb *ResponseBuilder) BuildValidationFailedResponse(validationResult *Valid
 			Details: rb.ConvertValidationErrorsToResponse(validationResult.InvalidRequests),
 		},
 	}
-	return toCamelCaseMap(response)
+	return rb.convert(response)
+}
+
+// BuildReadinessResponse constructs the readiness payload, converting keys to camelCase.
+// The service is ready only when every backend is available.
+func (rb *ResponseBuilder) BuildReadinessResponse(backends []BackendStatus) (any, bool) {
+	ready := true
+	for _, b := range backends {
+		if b.Status != StatusReady {
+			ready = false
+		}
+	}
+	status := StatusReady
+	if !ready {
+		status = StatusUnavailable
+	}
+	return rb.convert(ReadinessResponse{
+		Success:  ready,
+		Status:   status,
+		Backends: backends,
+	}), ready
+}
+
+// BuildStartingResponse constructs the readiness payload reported before the startup
+// checks complete, when no backend has been probed yet.
+func (rb *ResponseBuilder) BuildStartingResponse() any {
+	return rb.convert(ReadinessResponse{
+		Success:  false,
+		Status:   StatusStarting,
+		Backends: []BackendStatus{},
+	})
 }
 
 // ConvertValidationErrorsToResponse transforms validation errors to response format.
@@ -117,6 +453,7 @@ func (rb *ResponseBuilder) ConvertValidationErrorsToResponse(validationErrors []
 	response := make([]InvalidRequestResponse, 0, len(validationErrors))
 	for _, ve := range validationErrors {
 		response = append(response, InvalidRequestResponse{
+			Index:            ve.Index,
 			OrganizationName: ve.Request.OrganizationName,
 			LdapUsername:     ve.Request.LdapUsername,
 			PackageManager:   ve.Request.PackageManager,
@@ -128,7 +465,16 @@ func (rb *ResponseBuilder) ConvertValidationErrorsToResponse(validationErrors []
 	return response
 }
 
+// toCamelCaseMap converts structs and string-keyed maps into maps with lowerCamelCase
+// keys, recursing through pointers, slices and nested values. time.Time values are
+// rendered as RFC3339 strings.
 func toCamelCaseMap(data any) any {
+	return convertToCamelCase(data, false)
+}
+
+// convertToCamelCase implements toCamelCaseMap. When omitEmpty is set, struct fields
+// and map entries holding nil, empty or zero values are left out of the output.
+func convertToCamelCase(data any, omitEmpty bool) any {
 	val := reflect.ValueOf(data)
 
 	// Handle Pointers
@@ -139,11 +485,32 @@ func toCamelCaseMap(data any) any {
 		val = val.Elem()
 	}
 
+	// Handle time.Time before the generic struct case; its fields are unexported
+	if val.IsValid() && val.Type() == reflect.TypeOf(time.Time{}) {
+		return val.Interface().(time.Time).Format(time.RFC3339)
+	}
+
 	// Handle Slices/Arrays
 	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
 		out := make([]any, val.Len())
 		for i := 0; i < val.Len(); i++ {
-			out[i] = toCamelCaseMap(val.Index(i).Interface())
+			out[i] = convertToCamelCase(val.Index(i).Interface(), omitEmpty)
+		}
+		return out
+	}
+
+	// Handle Maps with string keys, e.g. the map[string]interface{} results of service managers
+	if val.Kind() == reflect.Map && val.Type().Key().Kind() == reflect.String {
+		if val.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			if omitEmpty && isEmptyValue(iter.Value()) {
+				continue
+			}
+			out[camelCaseKey(iter.Key().String())] = convertToCamelCase(iter.Value().Interface(), omitEmpty)
 		}
 		return out
 	}
@@ -159,35 +526,12 @@ func toCamelCaseMap(data any) any {
 				continue
 			}
 
-			// Recursively convert the field value
-			fieldVal := toCamelCaseMap(val.Field(i).Interface())
-
-			// Determine the new key name
-			key := field.Name
-
-			// Handle common acronyms manually for cleaner API design
-			if key == "ID" || strings.HasSuffix(key, "ID") {
-				// e.g., "ID" -> "id", "JobID" -> "jobId", "AppID" -> "appId"
-				if key == "ID" {
-					key = "id"
-				} else {
-					// Convert "JobID" -> "jobId"
-					prefix := key[:len(key)-2]
-					key = lowerFirst(prefix) + "Id"
-				}
-			} else if key == "URL" || strings.HasSuffix(key, "URL") {
-				if key == "URL" {
-					key = "url"
-				} else {
-					prefix := key[:len(key)-3]
-					key = lowerFirst(prefix) + "Url"
-				}
-			} else {
-				// Default camelCase conversion (lower first letter)
-				key = lowerFirst(key)
+			if omitEmpty && isEmptyValue(val.Field(i)) {
+				continue
 			}
 
-			out[key] = fieldVal
+			// Recursively convert the field value
+			out[camelCaseKey(field.Name)] = convertToCamelCase(val.Field(i).Interface(), omitEmpty)
 		}
 		return out
 	}
@@ -196,6 +540,61 @@ func toCamelCaseMap(data any) any {
 	return data
 }
 
+// isEmptyValue reports whether v is nil, an empty slice/map/array, or a zero value.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return v.IsNil()
+	case reflect.Interface:
+		return v.IsNil() || isEmptyValue(v.Elem())
+	default:
+		return v.IsZero()
+	}
+}
+
+// camelCaseKey converts a Go field name (e.g. "JobID") or a snake_case map key
+// (e.g. "ldap_username") into lowerCamelCase.
+func camelCaseKey(key string) string {
+	if strings.Contains(key, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(key, "_") {
+			if part == "" {
+				continue
+			}
+			if b.Len() == 0 {
+				b.WriteString(lowerFirst(part))
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(part)
+			b.WriteString(string(unicode.ToUpper(r)) + part[size:])
+		}
+		return b.String()
+	}
+
+	// Handle common acronyms manually for cleaner API design
+	switch {
+	case key == "ID":
+		return "id"
+	case strings.HasSuffix(key, "ID"):
+		// e.g., "JobID" -> "jobId", "AppID" -> "appId"
+		return lowerFirst(key[:len(key)-2]) + "Id"
+	case strings.HasSuffix(key, "IDs"):
+		// e.g., "AppIDs" -> "appIds"
+		return lowerFirst(key[:len(key)-3]) + "Ids"
+	case key == "URL":
+		return "url"
+	case strings.HasSuffix(key, "URL"):
+		return lowerFirst(key[:len(key)-3]) + "Url"
+	default:
+		// Default camelCase conversion (lower first letter)
+		return lowerFirst(key)
+	}
+}
+
 // lowerFirst lowers the first rune of a string
 func lowerFirst(s string) string {
 	if s == "" {
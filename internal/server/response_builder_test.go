@@ -2,6 +2,7 @@ package server
 
 import (
 	"testing"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/stretchr/testify/assert"
@@ -83,3 +84,81 @@ func TestToCamelCaseMap(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, 42, nested["innerField"])
 }
+
+func TestToCamelCaseMap_NestedMap(t *testing.T) {
+	input := struct {
+		Result map[string]interface{}
+	}{
+		Result: map[string]interface{}{
+			"ldap_username":   "john.doe",
+			"organization_id": "org-1",
+			"details": map[string]interface{}{
+				"repository_name": "npm-release-app",
+			},
+		},
+	}
+
+	outMap := toCamelCaseMap(input).(map[string]interface{})
+	result, ok := outMap["result"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "john.doe", result["ldapUsername"])
+	assert.Equal(t, "org-1", result["organizationId"])
+
+	details, ok := result["details"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "npm-release-app", details["repositoryName"])
+}
+
+func TestToCamelCaseMap_Time(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	job := &config.Job{ID: "job-1", CreatedAt: createdAt}
+
+	outMap := toCamelCaseMap(job).(map[string]interface{})
+	assert.Equal(t, "2024-05-01T12:30:00Z", outMap["createdAt"])
+}
+
+func TestBuildJobResponse_OmitEmpty(t *testing.T) {
+	job := &config.Job{
+		ID:             "job-123",
+		Status:         config.JobStatusCompleted,
+		FailedRequests: []config.FailedRequest{},
+	}
+
+	t.Run("Off by default keeps empty fields", func(t *testing.T) {
+		respMap := newResponseBuilder().BuildJobResponse(job).(map[string]interface{})
+		assert.Contains(t, respMap, "failedRequests")
+		assert.Contains(t, respMap, "message")
+		assert.Equal(t, []any{}, respMap["failedRequests"])
+	})
+
+	t.Run("Enabled drops empty fields", func(t *testing.T) {
+		respMap := newResponseBuilder().WithOmitEmpty(true).BuildJobResponse(job).(map[string]interface{})
+		assert.NotContains(t, respMap, "failedRequests")
+		assert.NotContains(t, respMap, "message")
+		assert.NotContains(t, respMap, "totalRequests")
+		assert.Equal(t, "job-123", respMap["id"])
+		assert.Equal(t, config.JobStatusCompleted, respMap["status"])
+	})
+}
+
+func TestBuildJobResponse_ErrorSummary(t *testing.T) {
+	failed := []config.FailedRequest{
+		{Reason: "organization not found"},
+		{Reason: "organization not found"},
+		{Reason: "nexus unavailable"},
+	}
+	job := &config.Job{
+		ID:             "job-123",
+		Status:         config.JobStatusCompleted,
+		ErrorSummary:   config.SummarizeFailures(failed),
+		FailedRequests: failed,
+	}
+
+	respMap := newResponseBuilder().BuildJobResponse(job).(map[string]interface{})
+	assert.Equal(t, []any{
+		map[string]any{"reason": "organization not found", "count": 2},
+		map[string]any{"reason": "nexus unavailable", "count": 1},
+	}, respMap["errorSummary"])
+	// The detailed list is still returned
+	assert.Len(t, respMap["failedRequests"], 3)
+}
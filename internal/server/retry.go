@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+)
+
+// retryBudget is a bucket of retry attempts shared by the operations of one batch
+// job, so that a degraded backend cannot turn every failure of a large batch into a
+// storm of retries. Once it is empty, failures are returned without retrying.
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+func newRetryBudget(size int) *retryBudget {
+	b := &retryBudget{}
+	b.remaining.Store(int64(size))
+	return b
+}
+
+// take consumes one retry, reporting false when none is left. A nil budget has none.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return false
+	}
+	for {
+		n := b.remaining.Load()
+		if n <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+type retryBudgetKey struct{}
+
+// contextWithRetryBudget returns ctx carrying the retry budget of its job.
+func contextWithRetryBudget(ctx context.Context, b *retryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, b)
+}
+
+// retryBudgetFromContext returns the retry budget carried by ctx, or nil outside a
+// job, where operations are not retried.
+func retryBudgetFromContext(ctx context.Context) *retryBudget {
+	b, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return b
+}
+
+// retryDelay is the wait after the given number of failed attempts of an operation.
+func (bm *BatchManager) retryDelay(attempts int) time.Duration {
+	delay := bm.cfg.OperationRetryBackoff
+	if delay <= 0 {
+		delay = config.DefaultOperationRetryBackoff
+	}
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= config.MaxOperationRetryBackoff {
+			return config.MaxOperationRetryBackoff
+		}
+	}
+	return delay
+}
@@ -2,20 +2,50 @@ package server
 
 import (
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // NewRouter builds the Gin router with the configured API handlers.
 func NewRouter(cfg *config.Config, jobStore *config.JobStore, batchManager *BatchManager) *gin.Engine {
 	router := gin.Default()
+	// Only trusted proxies may name the client in X-Forwarded-For; the list is validated by config.Load
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		utils.Logger.Error("Invalid trusted proxies", zap.Error(err))
+	}
 	router.Use(gin.Logger())
+	router.Use(tracingMiddleware())
+	router.Use(requestIDMiddleware())
+	router.Use(gzipMiddleware())
+	router.Use(allowlistMiddleware(cfg.AllowedCIDRs))
 
 	handler := newHandler(cfg, jobStore, batchManager)
+	ready := readinessMiddleware(batchManager)
+	api := router.Group(cfg.RoutePrefix)
 
-	router.GET(HealthEndpoint, handler.health)
-	router.POST(RepositoriesPath, authMiddleware(cfg.APIToken), handler.createBatch)
-	router.DELETE(RepositoriesPath, authMiddleware(cfg.APIToken), handler.deleteBatch)
-	router.GET(JobsPath+"/:id", authMiddleware(cfg.APIToken), handler.getJobStatus)
+	api.GET(HealthEndpoint, handler.health)
+	api.GET(ReadyEndpoint, handler.ready)
+	api.GET(VersionEndpoint, handler.version)
+	api.GET(MetricsEndpoint, handler.metrics)
+	api.POST(RepositoriesPath, authMiddleware(cfg.APIToken), ready, requireJSONMiddleware(MIMENDJSON), handler.createBatch)
+	api.DELETE(RepositoriesPath, authMiddleware(cfg.APIToken), ready, requireJSONMiddleware(MIMENDJSON), handler.deleteBatch)
+	api.POST(SinglePath, authMiddleware(cfg.APIToken), ready, requireJSONMiddleware(), handler.createSingle)
+	api.DELETE(SinglePath, authMiddleware(cfg.APIToken), ready, requireJSONMiddleware(), handler.deleteSingle)
+	api.POST(ValidatePath, authMiddleware(cfg.APIToken), requireJSONMiddleware(), handler.validateBatch)
+	api.POST(PreviewPath, authMiddleware(cfg.APIToken), requireJSONMiddleware(), handler.previewOffboarding)
+	api.GET(RepositoriesPath+"/:name", authMiddleware(cfg.APIToken), handler.getRepository)
+	api.PUT(RepositoriesPath+"/:name"+OnlinePath, authMiddleware(cfg.APIToken), ready, requireJSONMiddleware(), handler.setRepositoryOnline)
+	api.POST(RepositoriesPath+"/:name"+InvalidateCachePath, authMiddleware(cfg.APIToken), ready, handler.invalidateCache)
+	api.POST(IQRoleCachePath, authMiddleware(cfg.APIToken), ready, handler.invalidateIQRoleCache)
+	api.GET(PackageManagersPath, authMiddleware(cfg.APIToken), handler.listPackageManagers)
+	api.GET(OrganizationsPath, authMiddleware(cfg.APIToken), handler.listOrganizations)
+	api.GET(StatsPath, authMiddleware(cfg.APIToken), handler.getStats)
+	api.GET(JobsPath+"/:id", authMiddleware(cfg.APIToken), handler.getJobStatus)
+	api.GET(JobsPath+"/:id/failed", authMiddleware(cfg.APIToken), handler.getFailedRequests)
+	if cfg.EnablePprof {
+		registerPprof(api.Group(PprofPath, authMiddleware(cfg.APIToken)))
+	}
 
 	return router
 }
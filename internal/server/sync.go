@@ -4,196 +4,743 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/anmicius0/sonatype-resource-automation/internal/service"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// ErrTooManyJobs is returned when cfg.MaxConcurrentJobs jobs are already running.
+var ErrTooManyJobs = errors.New("too many concurrent jobs")
+
 // BatchManager encapsulates async job execution for repository requests.
 type BatchManager struct {
 	cfg      *config.Config
 	jobStore *config.JobStore
 	nexus    client.NexusClient
 	iq       client.IQClient
+	metrics  *operationMetrics
+	hook     OperationHook
+	// hookRetries holds the events waiting for redelivery to hook
+	hookRetries *hookRetryQueue
+	// orgs holds the organizations created on demand with AUTO_CREATE_ORG
+	orgs *organizationCache
+
+	mu          sync.Mutex
+	runningJobs int
+	// jobs tracks every job holding a slot so shutdown can wait for them
+	jobs sync.WaitGroup
+	// started is set by MarkStarted once the startup checks have completed
+	started atomic.Bool
+	// operations counts the operations in flight, in jobs and single requests alike
+	operations atomic.Int64
+	// syncWait bounds how long ProcessBatchSync waits for its job
+	syncWait time.Duration
 }
 
 type operationResult struct {
 	Success bool
 	Error   string
+	// Retriable is set when the failure came from a network error, timeout, 429 or 5xx
+	Retriable bool
+	// ErrorCode classifies a failure for clients, e.g. user_not_found or backend_error
+	ErrorCode string
+	// Result is the Nexus manager's result, including the names of resources it changed
+	Result map[string]interface{}
+	// CompletedSteps lists the steps that finished, in order; FailedStep is the step
+	// that stopped the operation, empty if none did
+	CompletedSteps []string
+	FailedStep     string
+}
+
+// requestOutcome pairs a processed request with its result. Position is the request's
+// index in the requests of the job, as outcomes arrive in completion order.
+type requestOutcome struct {
+	Position int
+	Request  config.RepositoryRequest
+	Result   operationResult
 }
 
 // NewBatchManager constructs a BatchManager with the required dependencies.
 func NewBatchManager(cfg *config.Config, jobStore *config.JobStore, nexus client.NexusClient, iq client.IQClient) *BatchManager {
-	return &BatchManager{cfg, jobStore, nexus, iq}
+	return &BatchManager{
+		cfg:         cfg,
+		jobStore:    jobStore,
+		nexus:       nexus,
+		iq:          iq,
+		metrics:     newOperationMetrics(),
+		hook:        newOperationHook(cfg),
+		hookRetries: newHookRetryQueue(cfg),
+		orgs:        newOrganizationCache(),
+		syncWait:    config.SyncBatchWait,
+	}
+}
+
+// acquireJobSlot reserves a slot for a new job, failing with ErrTooManyJobs once
+// cfg.MaxConcurrentJobs jobs are in flight. A limit of zero means unlimited.
+func (bm *BatchManager) acquireJobSlot() error {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	if bm.cfg.MaxConcurrentJobs > 0 && bm.runningJobs >= bm.cfg.MaxConcurrentJobs {
+		return ErrTooManyJobs
+	}
+	bm.runningJobs++
+	bm.jobs.Add(1)
+	return nil
+}
+
+// releaseJobSlot frees a slot reserved by acquireJobSlot.
+func (bm *BatchManager) releaseJobSlot() {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.runningJobs--
+	bm.jobs.Done()
+}
+
+// RetryAfter estimates how long a batch rejected with ErrTooManyJobs should wait
+// before resubmitting: the average duration of the finished jobs, at least a second
+// and at most config.MaxQueueRetryAfter. Before any job has finished it is
+// config.DefaultQueueRetryAfter.
+func (bm *BatchManager) RetryAfter() time.Duration {
+	average, ok := bm.metrics.averageJobDuration()
+	if !ok {
+		return config.DefaultQueueRetryAfter
+	}
+	return min(max(average, time.Second), config.MaxQueueRetryAfter)
+}
+
+// RunningJobs returns the number of jobs currently in flight.
+func (bm *BatchManager) RunningJobs() int {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.runningJobs
+}
+
+// Busy reports whether any job or single operation is in flight. The orphan scanner
+// stays away while it is, since a repository created moments ago has no role yet.
+func (bm *BatchManager) Busy() bool {
+	return bm.RunningJobs() > 0 || bm.operations.Load() > 0
+}
+
+// MarkStarted records that the startup checks completed, so mutating endpoints stop
+// answering 503 and /ready starts probing the backends.
+func (bm *BatchManager) MarkStarted() {
+	if !bm.started.Swap(true) {
+		utils.Logger.Info("Startup checks completed; accepting requests")
+	}
+}
+
+// Started reports whether MarkStarted has been called.
+func (bm *BatchManager) Started() bool {
+	return bm.started.Load()
+}
+
+// AwaitBackends pings the backends every interval until all of them answer, logging
+// each unavailable one, and returns ctx's error if ctx ends first.
+func (bm *BatchManager) AwaitBackends(ctx context.Context, interval time.Duration) error {
+	for {
+		unavailable := 0
+		for _, backend := range bm.CheckBackends(ctx) {
+			if backend.Status != StatusReady {
+				unavailable++
+				utils.Logger.Warn("Backend not reachable at startup; retrying",
+					zap.String("backend", backend.Name),
+					zap.String("error", backend.Error),
+					zap.Duration("retry_in", interval))
+			}
+		}
+		if unavailable == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Drain waits for the running jobs to finish, returning ctx's error if they are still
+// running when ctx is done. It is meant for shutdown, once no new jobs can be submitted.
+func (bm *BatchManager) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		bm.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backendProbe names a backend and the call that checks it.
+type backendProbe struct {
+	name string
+	ping func(context.Context) error
+}
+
+// CheckBackends pings Nexus and IQ Server concurrently, each ping bounded by
+// config.ReadinessTimeout, and reports per-backend status and latency. IQ Server is
+// left out when the integration is disabled.
+func (bm *BatchManager) CheckBackends(ctx context.Context) []BackendStatus {
+	probes := []backendProbe{{BackendNexus, bm.nexus.Ping}}
+	if !bm.cfg.IQDisabled {
+		probes = append(probes, backendProbe{BackendIQServer, bm.iq.Ping})
+	}
+
+	results := make([]BackendStatus, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := probe.ping(ctx)
+			status := BackendStatus{
+				Name:      probe.name,
+				Status:    StatusReady,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				status.Status = StatusUnavailable
+				status.Error = err.Error()
+				// The backend is already named, so report only the underlying cause
+				var unavailable *client.BackendUnavailableError
+				if errors.As(err, &unavailable) {
+					status.Error = unavailable.Err.Error()
+				}
+			}
+			results[i] = status
+		}()
+	}
+	wg.Wait()
+	return results
 }
 
 // ProcessBatchAsync creates a job and processes the valid requests in the background.
 // This function combines the logic of the previous QueueJob and processBatch.
-func (bm *BatchManager) ProcessBatchAsync(validationResult *ValidationResult, batchRequest batchRepositoryRequest, action string) (string, int, int, int) {
-	totalRequests := len(batchRequest.Requests)
+// The trace context in ctx is carried into the background work, but its
+// cancellation is not, since the job outlives the HTTP request.
+// It returns ErrTooManyJobs without creating a job when the concurrency limit is reached.
+func (bm *BatchManager) ProcessBatchAsync(ctx context.Context, validationResult *ValidationResult, action string) (string, int, int, error) {
 	validCount := len(validationResult.ValidRequests)
 	invalidCount := len(validationResult.InvalidRequests)
+	if err := bm.acquireJobSlot(); err != nil {
+		return "", validCount, invalidCount, err
+	}
 	jobID := uuid.New().String()
 
 	// 1. Create the job in the store.
 	bm.jobStore.CreateJob(jobID, action, validCount)
 
-	utils.Logger.Debug("Queued job",
+	utils.LoggerFromContext(ctx).Debug("Queued job",
 		zap.String(utils.FieldJobID, jobID),
 		zap.String(utils.FieldAction, action),
-		zap.Int("total_requests", totalRequests),
+		zap.Int("total_requests", validCount+invalidCount),
 		zap.Int("valid_count", validCount),
 		zap.Int("invalid_count", invalidCount))
 
 	// 2. Launch the background processor.
 	go func() {
-		ctx := context.Background()
-		requests := validationResult.ValidRequests
-		tracker := service.NewJobProgressTracker(bm.jobStore, jobID)
+		defer bm.releaseJobSlot()
+		bm.runJob(context.WithoutCancel(ctx), jobID, validationResult.ValidRequests, action, false)
+	}()
 
-		utils.Logger.Debug("Starting batch processing",
-			zap.String(utils.FieldJobID, jobID),
-			zap.Int("request_count", len(requests)),
-			zap.String(utils.FieldAction, action))
-		tracker.SetProcessing()
+	return jobID, validCount, invalidCount, nil
+}
+
+// syncJobResult carries the outcome of a job run for ProcessBatchSync.
+type syncJobResult struct {
+	outcomes []requestOutcome
+	outcome  service.Outcome
+}
+
+// ProcessBatchSync creates a job and waits up to bm.syncWait for it to process the
+// valid requests, reporting the per-request outcomes along with the aggregate outcome
+// of the job. The job runs detached from ctx's cancellation, as in ProcessBatchAsync,
+// so a client that disconnects or stops waiting does not cut operations short. When
+// the job outlasts the wait, or ctx is done first, it keeps running in the background
+// and finished is false; its results are then polled like any other job's.
+// Like ProcessBatchAsync, it returns ErrTooManyJobs when the concurrency limit is reached.
+func (bm *BatchManager) ProcessBatchSync(ctx context.Context, validationResult *ValidationResult, action string) (jobID string, outcomes []requestOutcome, outcome service.Outcome, finished bool, err error) {
+	if err = bm.acquireJobSlot(); err != nil {
+		return "", nil, "", false, err
+	}
+
+	jobID = uuid.New().String()
+	bm.jobStore.CreateJob(jobID, action, len(validationResult.ValidRequests))
+
+	utils.LoggerFromContext(ctx).Debug("Processing job synchronously",
+		zap.String(utils.FieldJobID, jobID),
+		zap.String(utils.FieldAction, action),
+		zap.Int("valid_count", len(validationResult.ValidRequests)),
+		zap.Int("invalid_count", len(validationResult.InvalidRequests)))
+
+	done := make(chan syncJobResult, 1)
+	go func() {
+		defer bm.releaseJobSlot()
+		outcomes, outcome := bm.runJob(context.WithoutCancel(ctx), jobID, validationResult.ValidRequests, action, true)
+		done <- syncJobResult{outcomes: outcomes, outcome: outcome}
+	}()
+
+	timer := time.NewTimer(bm.syncWait)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return jobID, result.outcomes, result.outcome, true, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	utils.LoggerFromContext(ctx).Info("Synchronous job still running; answering with the job to poll",
+		zap.String(utils.FieldJobID, jobID),
+		zap.Duration("waited", bm.syncWait))
+	return jobID, nil, "", false, nil
+}
+
+// streamingJob is a job that takes its requests while they are still being read, from
+// StartStreamingJob. Requests are handed to the workers one at a time by Submit, and
+// Close ends the job once the last one has been submitted.
+type streamingJob struct {
+	ID       string
+	jobStore *config.JobStore
+	pending  chan queuedRequest
+	queued   int
+}
+
+// queuedRequest is a request of a job waiting for a worker, with its position in the
+// requests of the job.
+type queuedRequest struct {
+	Position int
+	Request  config.RepositoryRequest
+}
+
+// StartStreamingJob creates a job and starts its workers before its requests are known,
+// so a batch can be processed while it is read instead of being collected first. Like
+// ProcessBatchAsync, the job runs detached from ctx's cancellation, and
+// ErrTooManyJobs is returned when the concurrency limit is reached.
+func (bm *BatchManager) StartStreamingJob(ctx context.Context, action string) (*streamingJob, error) {
+	if err := bm.acquireJobSlot(); err != nil {
+		return nil, err
+	}
+	job := &streamingJob{
+		ID:       uuid.New().String(),
+		jobStore: bm.jobStore,
+		pending:  make(chan queuedRequest),
+	}
+	bm.jobStore.CreateJob(job.ID, action, 0)
+
+	utils.LoggerFromContext(ctx).Debug("Queued streaming job",
+		zap.String(utils.FieldJobID, job.ID),
+		zap.String(utils.FieldAction, action))
 
-		type batchResult struct {
-			request config.RepositoryRequest
-			result  operationResult
+	// The size of the batch is unknown, so the job gets the full worker pool
+	go func() {
+		defer bm.releaseJobSlot()
+		bm.processQueue(context.WithoutCancel(ctx), job.ID, job.pending, bm.jobWorkers(math.MaxInt), action, false)
+	}()
+	return job, nil
+}
+
+// Submit adds req to the job's requests and hands it to the next free worker, blocking
+// until one takes it. This holds back reading the rest of the batch while every worker
+// is busy, so at most one request per worker is held in memory.
+func (j *streamingJob) Submit(req config.RepositoryRequest) {
+	_ = j.jobStore.UpdateJob(j.ID, func(job *config.Job) {
+		job.TotalRequests++
+		job.NotProcessedOperations++
+	})
+	j.pending <- queuedRequest{Position: j.queued, Request: req}
+	j.queued++
+}
+
+// Queued returns the number of requests submitted so far.
+func (j *streamingJob) Queued() int {
+	return j.queued
+}
+
+// Close tells the job no more requests follow. It is finalized once the workers have
+// finished the submitted ones.
+func (j *streamingJob) Close() {
+	close(j.pending)
+}
+
+// runJob fans the requests out to a bounded pool of workers, then records the
+// aggregated results on the job, as described for processQueue.
+func (bm *BatchManager) runJob(ctx context.Context, jobID string, requests []config.RepositoryRequest, action string, keepOutcomes bool) ([]requestOutcome, service.Outcome) {
+	pending := make(chan queuedRequest)
+	go func() {
+		for position, req := range requests {
+			pending <- queuedRequest{Position: position, Request: req}
 		}
+		close(pending)
+	}()
+	return bm.processQueue(ctx, jobID, pending, bm.jobWorkers(len(requests)), action, keepOutcomes)
+}
+
+// processQueue runs the requests arriving on pending on the given number of workers
+// until pending is closed, then records the aggregated results on the job. The
+// results are drained while the workers run, so memory grows with the number of
+// workers rather than the size of the batch; the per-request outcomes are only kept
+// when keepOutcomes is set. The job ID is added to ctx so every log line of the job
+// carries it, together with the job's retry budget of cfg.BatchRetryBudget retries.
+func (bm *BatchManager) processQueue(ctx context.Context, jobID string, pending <-chan queuedRequest, workers int, action string, keepOutcomes bool) ([]requestOutcome, service.Outcome) {
+	ctx = utils.ContextWithJobID(ctx, jobID)
+	ctx = contextWithRetryBudget(ctx, newRetryBudget(bm.cfg.BatchRetryBudget))
+	logger := utils.LoggerFromContext(ctx)
+	tracker := service.NewJobProgressTracker(bm.jobStore, jobID)
+
+	logger.Debug("Starting batch processing",
+		zap.Int("workers", workers),
+		zap.String(utils.FieldAction, action))
+	tracker.SetProcessing()
 
-		results := make(chan batchResult, len(requests))
-		var wg sync.WaitGroup
+	results := make(chan requestOutcome, workers)
+	var wg sync.WaitGroup
 
-		// 3. Fan out: Start a worker goroutine for each request.
-		for _, repositoryRequest := range requests {
-			wg.Add(1)
-			go func(req config.RepositoryRequest) {
-				defer wg.Done()
-				utils.Logger.Debug("Attempting operation for repository",
+	// Fan out: each worker takes the next pending request until none are left.
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for queued := range pending {
+				req := queued.Request
+				logger.Debug("Attempting operation for repository",
 					zap.String("ldap_username", req.LdapUsername),
 					zap.String("package_manager", req.PackageManager),
 					zap.String("organization_name", req.OrganizationName),
 					zap.String(utils.FieldAction, action))
 				opResult := bm.attemptOperation(ctx, action, req)
-				results <- batchResult{request: req, result: opResult}
-			}(repositoryRequest)
-		}
+				results <- requestOutcome{Position: queued.Position, Request: req, Result: opResult}
+			}
+		}()
+	}
 
-		// Wait for all workers to finish, then close the results channel.
+	// Close the results channel once every worker has finished.
+	go func() {
 		wg.Wait()
 		close(results)
+	}()
 
-		// 4. Fan in: Aggregate results and finalize the job.
-		successfulOps := 0
-		failedOps := 0
-		failedRequests := make([]config.FailedRequest, 0, len(requests))
+	// Fan in: Aggregate results as they arrive and finalize the job.
+	successfulOps := 0
+	failedOps := 0
+	failures := config.NewFailureLog(bm.cfg.MaxFailedRequestDetails)
+	var outcomes []requestOutcome
 
-		for res := range results {
-			if res.result.Success {
-				successfulOps++
-			} else {
-				failedOps++
-				failedRequests = append(failedRequests, config.FailedRequest{
-					Request: res.request,
-					Reason:  res.result.Error,
-				})
-			}
+	for res := range results {
+		if keepOutcomes {
+			outcomes = append(outcomes, res)
+		}
+		if res.Result.Success {
+			successfulOps++
+		} else {
+			failedOps++
+			failures.Add(config.FailedRequest{
+				Request:        res.Request.Redacted(),
+				Reason:         res.Result.Error,
+				Retriable:      res.Result.Retriable,
+				ErrorCode:      res.Result.ErrorCode,
+				CompletedSteps: res.Result.CompletedSteps,
+				FailedStep:     res.Result.FailedStep,
+			})
 		}
+	}
 
-		tracker.Finalize(successfulOps, failedOps, 0, len(requests), failedRequests)
-		utils.Logger.Debug("Finished batch processing",
-			zap.String(utils.FieldJobID, jobID),
-			zap.Int("successful_ops", successfulOps),
-			zap.Int("failed_ops", failedOps))
-	}()
+	outcome := tracker.Finalize(successfulOps, failedOps, 0, successfulOps+failedOps, failures)
+	if job, ok := bm.jobStore.GetJob(jobID); ok {
+		bm.metrics.observeJob(action, time.Duration(job.DurationMs)*time.Millisecond)
+	}
+	logger.Debug("Finished batch processing",
+		zap.Int("successful_ops", successfulOps),
+		zap.Int("failed_ops", failedOps))
+	return outcomes, outcome
+}
 
-	return jobID, totalRequests, validCount, invalidCount
+// jobWorkers returns the number of workers for a job of n requests: cfg.JobWorkers,
+// or config.DefaultJobWorkers when unset, but never more than there are requests.
+func (bm *BatchManager) jobWorkers(n int) int {
+	workers := bm.cfg.JobWorkers
+	if workers <= 0 {
+		workers = config.DefaultJobWorkers
+	}
+	return max(min(workers, n), 1)
+}
+
+// ProcessSingle runs one already-validated request synchronously, bypassing the job store.
+func (bm *BatchManager) ProcessSingle(ctx context.Context, req config.RepositoryRequest, action string) operationResult {
+	utils.LoggerFromContext(ctx).Debug("Processing single request",
+		zap.String("ldap_username", req.LdapUsername),
+		zap.String("package_manager", req.PackageManager),
+		zap.String("organization_name", req.OrganizationName),
+		zap.String(utils.FieldAction, action))
+	return bm.attemptOperation(ctx, action, req)
+}
+
+// PreviewOffboarding evaluates an already-validated offboarding request against
+// Nexus and IQ Server rules without changing anything.
+func (bm *BatchManager) PreviewOffboarding(ctx context.Context, req config.RepositoryRequest) (*service.OffboardingPlan, error) {
+	opConfig, err := bm.opConfigFor(ctx, req, MethodDelete)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := service.NewDeletionManager(opConfig, bm.nexus).PlanOffboarding(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bm.cfg.IQDisabled {
+		return plan, nil
+	}
+	// IQ cleanup runs after the user's roles are reset, so evaluate it against those roles
+	plan.RemoveOwnerRole, err = service.NewIQServerCleaner(opConfig, bm.iq, bm.nexus).PlanOwnerRemoval(ctx, plan.UserRoles)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
 }
 
 // attemptOperation performs the actual create/delete logic for a single request.
-// This function accepts a context for cancellation support.
-func (bm *BatchManager) attemptOperation(ctx context.Context, action string, req config.RepositoryRequest) operationResult {
+// This function accepts a context for cancellation support. Within a batch job, a
+// retriable failure, including a timeout, is retried up to cfg.OperationMaxRetries
+// times while the job's retry budget lasts. Each attempt gets its own
+// cfg.OperationTimeout. Every outcome, not every attempt, is counted in the operation
+// metrics under the request's package manager and written to the audit log.
+func (bm *BatchManager) attemptOperation(ctx context.Context, action string, req config.RepositoryRequest) (res operationResult) {
+	bm.operations.Add(1)
+	defer bm.operations.Add(-1)
+	var opConfig *config.OperationConfig
+	defer func() {
+		bm.metrics.record(req.PackageManager, action, res.Success)
+		auditOperation(ctx, action, req, opConfig, res)
+		bm.notifyHook(ctx, action, req, opConfig, res)
+	}()
+
+	ctx, span := utils.Tracer().Start(ctx, "operation "+action, trace.WithAttributes(
+		attribute.String("operation.action", action),
+		attribute.String("operation.organization_name", req.OrganizationName),
+		attribute.String("operation.ldap_username", req.LdapUsername),
+		attribute.String("operation.package_manager", req.PackageManager),
+		attribute.String("operation.app_id", req.AppID),
+		attribute.Bool("operation.shared", req.Shared),
+	))
+	defer span.End()
+	logger := utils.LoggerFromContext(ctx)
+
+	budget := retryBudgetFromContext(ctx)
+	for attempt := 1; ; attempt++ {
+		opConfig, res = bm.attemptOnce(ctx, action, req)
+		if res.Success || !res.Retriable || budget == nil || attempt > bm.cfg.OperationMaxRetries || ctx.Err() != nil {
+			return res
+		}
+		if !budget.take() {
+			logger.Warn("Batch retry budget exhausted; not retrying",
+				zap.String(utils.FieldAction, action),
+				zap.Int("attempt", attempt))
+			return res
+		}
+		delay := bm.retryDelay(attempt)
+		logger.Warn("Operation failed with a retriable error; retrying",
+			zap.String(utils.FieldAction, action),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", delay),
+			zap.String("error", res.Error))
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attemptOnce runs one attempt of an operation within its own OPERATION_TIMEOUT, so a
+// retry after a timeout gets the full time again.
+func (bm *BatchManager) attemptOnce(ctx context.Context, action string, req config.RepositoryRequest) (*config.OperationConfig, operationResult) {
+	// Bound the whole multi-step operation, not just each backend request
+	if bm.cfg.OperationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bm.cfg.OperationTimeout)
+		defer cancel()
+	}
+	return bm.performOperation(ctx, action, req)
+}
+
+// performOperation runs one attempt of an operation, returning the OperationConfig it
+// resolved, nil if it got no further, and the result.
+func (bm *BatchManager) performOperation(ctx context.Context, action string, req config.RepositoryRequest) (*config.OperationConfig, operationResult) {
+	span := trace.SpanFromContext(ctx)
+	logger := utils.LoggerFromContext(ctx)
+
 	// Check for cancellation before starting
 	select {
 	case <-ctx.Done():
-		return operationResult{Success: false, Error: fmt.Sprintf("request cancelled: %v", ctx.Err())}
+		return nil, operationResult{Success: false, Error: fmt.Sprintf("request cancelled: %v", ctx.Err()), ErrorCode: ErrorCodeCancelled}
 	default:
 	}
 
-	opConfig, err := bm.cfg.CreateOpConfig(req, action)
+	opConfig, err := bm.opConfigFor(ctx, req, action)
 	if err != nil {
-		utils.Logger.Error("Failed to create operation config",
+		logger.Error("Failed to create operation config",
 			zap.Error(err),
 			zap.String(utils.FieldAction, action))
-		return operationResult{Success: false, Error: err.Error()}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, operationResult{Success: false, Error: err.Error(), Retriable: client.IsRetriable(err), ErrorCode: errorCodeFor(err, false)}
 	}
 
-	utils.Logger.Debug("Created operation config",
+	logger.Debug("Created operation config",
 		zap.String(utils.FieldRepo, opConfig.RepositoryName),
 		zap.String(utils.FieldAction, opConfig.Action),
-		zap.String("package_manager", opConfig.PackageManager))
+		zap.String("package_manager", opConfig.PackageManager),
+		zap.String("remote_username", opConfig.RemoteUsername),
+		utils.Secret("remote_password", opConfig.RemotePassword))
 
 	var opErr error
+	var result map[string]interface{}
+	var progress service.StepProgress
 
 	switch action {
 	case MethodCreate:
 		// Step 1: Create Nexus resources. If it fails, stop.
 		repoManager := service.NewCreationManager(opConfig, bm.nexus)
-		if _, opErr = repoManager.Run(); opErr != nil {
+		result, opErr = repoManager.Run(ctx)
+		progress = repoManager.Progress()
+		if opErr != nil {
 			break
 		}
 
 		// Step 2: If the first step succeeded, add owner role in IQ Server.
-		if opConfig.OrganizationID != "" {
-			opErr = bm.iq.AddOwnerRoleToUser(opConfig)
+		if opErr = ctx.Err(); opErr != nil {
+			break
+		}
+		if bm.cfg.IQDisabled {
+			logger.Debug("IQ Server integration disabled; skipping role assignment",
+				zap.String("ldap_username", opConfig.LdapUsername))
+		} else if opConfig.OrganizationID != "" {
+			opErr = progress.Record(service.StepIQ, bm.iq.AddOwnerRoleToUser(ctx, opConfig))
 			if opErr != nil {
-				utils.Logger.Error("Failed to assign Owner role in IQ Server",
+				logger.Error("Failed to assign Owner role in IQ Server",
 					zap.String("ldap_username", opConfig.LdapUsername),
 					zap.String("organization_id", opConfig.OrganizationID),
 					zap.Error(opErr))
 			} else {
-				utils.Logger.Info("Successfully assigned Owner role in IQ Server",
+				logger.Info("Successfully assigned Owner role in IQ Server",
 					zap.String("ldap_username", opConfig.LdapUsername),
 					zap.String("organization_id", opConfig.OrganizationID))
 			}
 		} else {
-			utils.Logger.Warn("No organization_id; skipping IQ Server role assignment",
+			logger.Warn("No organization_id; skipping IQ Server role assignment",
 				zap.String("ldap_username", opConfig.LdapUsername))
 		}
 
 	case MethodDelete:
 		// Step 1: Delete Nexus resources. If it fails, stop.
 		repoManager := service.NewDeletionManager(opConfig, bm.nexus)
-		if _, opErr = repoManager.Run(); opErr != nil {
+		result, opErr = repoManager.Run(ctx)
+		progress = repoManager.Progress()
+		if opErr != nil {
 			break
 		}
 
 		// Step 2: If the first step succeeded, clean up from IQ Server.
+		if opErr = ctx.Err(); opErr != nil {
+			break
+		}
+		if bm.cfg.IQDisabled {
+			logger.Debug("IQ Server integration disabled; skipping cleanup",
+				zap.String("ldap_username", opConfig.LdapUsername))
+			break
+		}
 		iqManager := service.NewIQDeletionManager(opConfig, bm.iq, bm.nexus)
-		_, opErr = iqManager.Run()
+		_, err := iqManager.Run(ctx)
+		opErr = progress.Record(service.StepIQ, err)
 
 	default:
 		opErr = fmt.Errorf("unsupported action: %s", action)
 	}
 
+	// An operation that outlived OPERATION_TIMEOUT fails with a timeout reason, even if
+	// a backend answered after the deadline. Resubmitting it may succeed.
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		if opErr == nil {
+			opErr = ctx.Err()
+		}
+		opErr = fmt.Errorf("operation timed out after %s: %w", bm.cfg.OperationTimeout, opErr)
+	}
+
 	// Centralized error handling for the entire operation
 	if opErr != nil {
-		utils.Logger.Error("Operation failed",
+		logger.Error("Operation failed",
 			zap.Error(opErr),
 			zap.String(utils.FieldAction, action),
 			zap.String(utils.FieldRepo, opConfig.RepositoryName))
-		return operationResult{Success: false, Error: opErr.Error()}
+		span.RecordError(opErr)
+		span.SetStatus(codes.Error, opErr.Error())
+		return opConfig, operationResult{
+			Success:        false,
+			Error:          opErr.Error(),
+			Retriable:      timedOut || client.IsRetriable(opErr),
+			ErrorCode:      errorCodeFor(opErr, timedOut),
+			CompletedSteps: progress.CompletedSteps,
+			FailedStep:     progress.FailedStep,
+		}
 	}
 
-	utils.Logger.Info("Operation succeeded",
+	logger.Info("Operation succeeded",
 		zap.String(utils.FieldAction, action),
 		zap.String(utils.FieldRepo, opConfig.RepositoryName))
-	return operationResult{Success: true}
+	return opConfig, operationResult{Success: true, Result: result, CompletedSteps: progress.CompletedSteps}
+}
+
+// errorCodeFor classifies a failed operation so that clients can tell a missing user
+// or a timeout from a broken backend without parsing the message.
+func errorCodeFor(err error, timedOut bool) string {
+	switch {
+	case timedOut:
+		return ErrorCodeTimeout
+	case errors.Is(err, service.ErrUserNotFound):
+		return ErrorCodeUserNotFound
+	case client.IsConflict(err):
+		return ErrorCodeConflict
+	case client.IsRetriable(err):
+		return ErrorCodeBackendError
+	default:
+		return ErrorCodeOperationFailed
+	}
+}
+
+// auditOperation writes the outcome of one operation to the audit log. opConfig is
+// nil when the request could not be resolved into an operation.
+func auditOperation(ctx context.Context, action string, req config.RepositoryRequest, opConfig *config.OperationConfig, res operationResult) {
+	record := utils.AuditRecord{
+		Action:         action,
+		User:           req.LdapUsername,
+		PackageManager: req.PackageManager,
+		AppID:          req.AppID,
+		Result:         ResultSuccess,
+	}
+	if opConfig != nil {
+		record.Repository = opConfig.RepositoryName
+		record.Role = opConfig.RoleName
+		record.OrganizationID = opConfig.OrganizationID
+	}
+	if !res.Success {
+		record.Result = ResultFailure
+		record.Error = res.Error
+	}
+	utils.Audit(ctx, record)
 }
@@ -0,0 +1,591 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/service"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAttemptOperation_WritesAuditRecord(t *testing.T) {
+	var buf bytes.Buffer
+	originalAudit := utils.AuditLogger
+	utils.AuditLogger = utils.NewAuditLogger(&buf)
+	defer func() { utils.AuditLogger = originalAudit }()
+
+	cfg := &config.Config{
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+
+	ctx := utils.ContextWithCaller(utils.ContextWithRequestID(t.Context(), "req-1"), "10.0.0.5")
+	req := config.RepositoryRequest{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+	bm.attemptOperation(ctx, MethodCreate, req)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record))
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "10.0.0.5", record["caller"])
+	assert.Equal(t, MethodCreate, record["action"])
+	assert.Equal(t, "user1", record["user"])
+	assert.Equal(t, "npm", record["package_manager"])
+	assert.Equal(t, "app1", record["app_id"])
+	assert.Equal(t, ResultFailure, record["result"])
+	assert.Equal(t, "organization 'missing' not found", record["error"])
+	// The request never resolved into an operation, so there is no repository or role
+	assert.Equal(t, "", record["repo"])
+	assert.Equal(t, "", record["role"])
+}
+
+func TestAttemptOperation_IQDisabled(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:      true,
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+
+	t.Run("Create skips the Owner role assignment", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockIQ := new(MockIQClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", "user1").Return(&client.Role{ID: "user1"}, nil)
+		mockNexus.On("UpdateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+		assert.True(t, res.Success, res.Error)
+		assert.Empty(t, mockIQ.Calls)
+		mockNexus.AssertExpectations(t)
+	})
+
+	t.Run("Delete skips the IQ cleanup", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockIQ := new(MockIQClient)
+		mockNexus.On("DeleteRepository", mock.Anything).Return(nil)
+		mockNexus.On("GetRolesByPrivilege", mock.Anything).Return([]client.Role{}, nil)
+		mockNexus.On("DeletePrivilege", mock.Anything).Return(nil)
+		mockNexus.On("GetRole", "user1").Return(nil, nil)
+		mockNexus.On("GetUser", "user1").Return(nil, nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodDelete, req)
+
+		assert.True(t, res.Success, res.Error)
+		assert.Empty(t, mockIQ.Calls)
+		mockNexus.AssertExpectations(t)
+	})
+
+	t.Run("Readiness does not ping IQ Server", func(t *testing.T) {
+		mockNexus := new(MockNexusClient)
+		mockIQ := new(MockIQClient)
+		mockNexus.On("Ping").Return(nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		backends := bm.CheckBackends(t.Context())
+
+		assert.Len(t, backends, 1)
+		assert.Equal(t, BackendNexus, backends[0].Name)
+		assert.Empty(t, mockIQ.Calls)
+	})
+}
+
+func TestAttemptOperation_AutoCreateOrg(t *testing.T) {
+	cfg := &config.Config{
+		AutoCreateOrg:   true,
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	newNexus := func() *MockNexusClient {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", "user1").Return(&client.Role{ID: "user1"}, nil)
+		mockNexus.On("UpdateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		return mockNexus
+	}
+	req := config.RepositoryRequest{OrganizationName: "new-team", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+
+	t.Run("Creation resolves the organization once", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("CreateOrganization", "new-team").Return("org-id-new", nil).Once()
+		mockIQ.On("AddOwnerRoleToUser", mock.MatchedBy(func(op *config.OperationConfig) bool {
+			return op.OrganizationID == "org-id-new"
+		})).Return(nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), newNexus(), mockIQ)
+
+		for range 2 {
+			res := bm.attemptOperation(t.Context(), MethodCreate, req)
+			assert.True(t, res.Success, res.Error)
+		}
+		mockIQ.AssertExpectations(t)
+		// The mapping itself is left untouched
+		assert.NotContains(t, cfg.Orgs, "new-team")
+	})
+
+	t.Run("A failed lookup fails the operation", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("CreateOrganization", "new-team").Return("", &client.RetriableError{Err: &client.HTTPError{StatusCode: 503}})
+		mockNexus := new(MockNexusClient)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+		assert.False(t, res.Success)
+		assert.True(t, res.Retriable)
+		assert.Equal(t, ErrorCodeBackendError, res.ErrorCode)
+		assert.Empty(t, mockNexus.Calls)
+	})
+
+	t.Run("Deletion looks the organization up without creating it", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("FindOrganization", "new-team").Return("org-id-new", nil).Once()
+		bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), mockIQ)
+
+		opConfig, err := bm.opConfigFor(t.Context(), req, MethodDelete)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "org-id-new", opConfig.OrganizationID)
+		mockIQ.AssertExpectations(t)
+		mockIQ.AssertNotCalled(t, "CreateOrganization", mock.Anything)
+	})
+
+	t.Run("Deletion reuses the organization creation resolved", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("CreateOrganization", "new-team").Return("org-id-new", nil).Once()
+		bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), mockIQ)
+
+		_, err := bm.opConfigFor(t.Context(), req, MethodCreate)
+		assert.NoError(t, err)
+		offboarding := config.RepositoryRequest{OrganizationName: "new-team", LdapUsername: "user1", AppID: "app1", Shared: true}
+		opConfig, err := bm.opConfigFor(t.Context(), offboarding, MethodDelete)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "org-id-new", opConfig.OrganizationID)
+		mockIQ.AssertNotCalled(t, "FindOrganization", mock.Anything)
+	})
+
+	t.Run("Organizations resolve in parallel, each once", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		release := make(chan struct{})
+		// new-team blocks until other-team has resolved, which it could not while holding a global lock
+		mockIQ.On("CreateOrganization", "new-team").Run(func(mock.Arguments) { <-release }).Return("org-id-new", nil).Once()
+		mockIQ.On("CreateOrganization", "other-team").Return("org-id-other", nil).Once()
+		bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), mockIQ)
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id, err := bm.orgs.resolve(t.Context(), mockIQ, "new-team", true)
+				assert.NoError(t, err)
+				assert.Equal(t, "org-id-new", id)
+			}()
+		}
+		id, err := bm.orgs.resolve(t.Context(), mockIQ, "other-team", true)
+		close(release)
+		wg.Wait()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "org-id-other", id)
+		mockIQ.AssertExpectations(t)
+	})
+
+	t.Run("Deletion of an organization IQ Server lacks fails", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("FindOrganization", "new-team").Return("", nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), new(MockNexusClient), mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodDelete, req)
+
+		assert.False(t, res.Success)
+		assert.Equal(t, "organization 'new-team' not found", res.Error)
+		mockIQ.AssertNotCalled(t, "CreateOrganization", mock.Anything)
+	})
+}
+
+func TestAttemptOperation_Timeout(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:       true,
+		OperationTimeout: 20 * time.Millisecond,
+		Orgs:             map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:  map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+
+	// The slow backend answers after the deadline; the remaining steps must not run
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("RepositoryExists", mock.Anything).After(100*time.Millisecond).Return(true, nil)
+	mockIQ := new(MockIQClient)
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+	start := time.Now()
+	res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+	assert.False(t, res.Success)
+	assert.True(t, res.Retriable)
+	assert.Equal(t, ErrorCodeTimeout, res.ErrorCode)
+	assert.Contains(t, res.Error, "operation timed out after 20ms")
+	assert.Less(t, time.Since(start), time.Second)
+	mockNexus.AssertNotCalled(t, "PrivilegeExists", mock.Anything)
+	assert.Empty(t, mockIQ.Calls)
+}
+
+func TestErrorCodeFor(t *testing.T) {
+	missingUser := fmt.Errorf("add role to user 'user1': %w", service.ErrUserNotFound)
+
+	assert.Equal(t, ErrorCodeTimeout, errorCodeFor(missingUser, true))
+	assert.Equal(t, ErrorCodeUserNotFound, errorCodeFor(missingUser, false))
+	assert.Equal(t, ErrorCodeConflict, errorCodeFor(&client.ConflictError{Err: errors.New("exists")}, false))
+	assert.Equal(t, ErrorCodeBackendError, errorCodeFor(&client.RetriableError{Err: errors.New("503")}, false))
+	assert.Equal(t, ErrorCodeOperationFailed, errorCodeFor(errors.New("bad input"), false))
+}
+
+func TestAttemptOperation_ReportsSteps(t *testing.T) {
+	cfg := &config.Config{
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+	setupNexus := func() *MockNexusClient {
+		mockNexus := new(MockNexusClient)
+		mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+		mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+		mockNexus.On("GetRole", "user1").Return(&client.Role{ID: "user1"}, nil)
+		mockNexus.On("UpdateRole", mock.Anything).Return(nil)
+		mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		return mockNexus
+	}
+
+	t.Run("Failed user step after repository, privilege and role", func(t *testing.T) {
+		mockNexus := setupNexus()
+		mockNexus.On("UpdateUser", mock.Anything).Return(errors.New("nexus down"))
+		mockIQ := new(MockIQClient)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+		assert.False(t, res.Success)
+		assert.Equal(t, []string{service.StepRepository, service.StepPrivilege, service.StepRole}, res.CompletedSteps)
+		assert.Equal(t, service.StepUser, res.FailedStep)
+		assert.Empty(t, mockIQ.Calls)
+	})
+
+	t.Run("Failed IQ step after every Nexus step", func(t *testing.T) {
+		mockNexus := setupNexus()
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("AddOwnerRoleToUser", mock.Anything).Return(errors.New("iq down"))
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+		assert.False(t, res.Success)
+		assert.Equal(t, []string{service.StepRepository, service.StepPrivilege, service.StepRole, service.StepUser}, res.CompletedSteps)
+		assert.Equal(t, service.StepIQ, res.FailedStep)
+	})
+
+	t.Run("Success completes every step", func(t *testing.T) {
+		mockNexus := setupNexus()
+		mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+		mockIQ := new(MockIQClient)
+		mockIQ.On("AddOwnerRoleToUser", mock.Anything).Return(nil)
+		bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, mockIQ)
+
+		res := bm.attemptOperation(t.Context(), MethodCreate, req)
+
+		assert.True(t, res.Success, res.Error)
+		assert.Equal(t, []string{service.StepRepository, service.StepPrivilege, service.StepRole, service.StepUser, service.StepIQ}, res.CompletedSteps)
+		assert.Empty(t, res.FailedStep)
+	})
+}
+
+// recordingHook records the events it receives, failing with err.
+type recordingHook struct {
+	events []OperationEvent
+	err    error
+}
+
+func (h *recordingHook) OnOperationComplete(ctx context.Context, event OperationEvent) error {
+	h.events = append(h.events, event)
+	return h.err
+}
+
+func TestAttemptOperation_NotifiesHook(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:      true,
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil)
+	mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+	mockNexus.On("GetRole", "user1").Return(&client.Role{ID: "user1"}, nil)
+	mockNexus.On("UpdateRole", mock.Anything).Return(nil)
+	mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+	mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, new(MockIQClient))
+	// A failing hook must not change the outcome
+	hook := &recordingHook{err: errors.New("event bus down")}
+	bm.SetOperationHook(hook)
+	ctx := utils.ContextWithRequestID(t.Context(), "req-1")
+
+	res := bm.attemptOperation(ctx, MethodCreate, config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"})
+	assert.True(t, res.Success, res.Error)
+	bm.attemptOperation(ctx, MethodCreate, config.RepositoryRequest{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"})
+
+	assert.Len(t, hook.events, 2)
+	created := hook.events[0]
+	assert.Equal(t, MethodCreate, created.Action)
+	assert.True(t, created.Success)
+	assert.Equal(t, "org-id-1", created.OrganizationID)
+	assert.Equal(t, "npm-release-app1", created.RepositoryName)
+	assert.Equal(t, "user1", created.RoleName)
+	assert.Equal(t, "req-1", created.RequestID)
+	assert.Equal(t, []string{service.StepRepository, service.StepPrivilege, service.StepRole, service.StepUser}, created.CompletedSteps)
+
+	failed := hook.events[1]
+	assert.False(t, failed.Success)
+	assert.Equal(t, "organization 'missing' not found", failed.Error)
+	assert.Equal(t, ErrorCodeOperationFailed, failed.ErrorCode)
+	assert.Empty(t, failed.RepositoryName)
+}
+
+func TestRunJob_BoundsWorkers(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:      true,
+		JobWorkers:      2,
+		Orgs:            map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers: map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	requests := make([]config.RepositoryRequest, 10)
+	for i := range requests {
+		requests[i] = config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: fmt.Sprintf("app%d", i)}
+	}
+
+	var active, peak atomic.Int32
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("RepositoryExists", mock.Anything).Run(func(mock.Arguments) {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+	}).Return(false, errors.New("nexus down"))
+	jobStore := config.NewJobStore()
+	bm := NewBatchManager(cfg, jobStore, mockNexus, new(MockIQClient))
+	jobStore.CreateJob("job-1", MethodCreate, len(requests))
+
+	outcomes, _ := bm.runJob(t.Context(), "job-1", requests, MethodCreate, true)
+
+	assert.Len(t, outcomes, len(requests))
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+	job, ok := jobStore.GetJob("job-1")
+	assert.True(t, ok)
+	assert.Len(t, job.FailedRequests, len(requests))
+	assert.NotNil(t, job.StartedAt)
+	assert.Equal(t, int64(1), bm.metrics.jobDurations[MethodCreate].Count)
+}
+
+func TestRunJob_RetryBudget(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:            true,
+		JobWorkers:            4,
+		OperationMaxRetries:   3,
+		OperationRetryBackoff: time.Millisecond,
+		BatchRetryBudget:      5,
+		Orgs:                  map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:       map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	requests := make([]config.RepositoryRequest, 10)
+	for i := range requests {
+		requests[i] = config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: fmt.Sprintf("app%d", i)}
+	}
+
+	var attempts atomic.Int32
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("RepositoryExists", mock.Anything).Run(func(mock.Arguments) {
+		attempts.Add(1)
+	}).Return(false, &client.RetriableError{Err: &client.HTTPError{StatusCode: 503}})
+	jobStore := config.NewJobStore()
+	bm := NewBatchManager(cfg, jobStore, mockNexus, new(MockIQClient))
+	jobStore.CreateJob("job-1", MethodCreate, len(requests))
+
+	outcomes, _ := bm.runJob(t.Context(), "job-1", requests, MethodCreate, true)
+
+	// Every request is tried once; the retries of all of them together stop at the budget
+	assert.Equal(t, int32(len(requests)+5), attempts.Load())
+	assert.Len(t, outcomes, len(requests))
+	for _, o := range outcomes {
+		assert.False(t, o.Result.Success)
+		assert.True(t, o.Result.Retriable)
+	}
+	// Each request is counted once, however many attempts it took
+	assert.Contains(t, bm.metrics.render(), `result="failure"} 10`)
+
+	t.Run("Single requests are not retried", func(t *testing.T) {
+		attempts.Store(0)
+
+		res := bm.ProcessSingle(t.Context(), requests[0], MethodCreate)
+
+		assert.False(t, res.Success)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestAttemptOperation_RetryAfterTimeout(t *testing.T) {
+	cfg := &config.Config{
+		IQDisabled:            true,
+		OperationTimeout:      20 * time.Millisecond,
+		OperationMaxRetries:   1,
+		OperationRetryBackoff: time.Millisecond,
+		Orgs:                  map[string]config.Organization{"org1": {ID: "org-id-1"}},
+		PackageManagers:       map[string]config.PackageManager{"npm": {DefaultURL: "https://registry.npmjs.org"}},
+	}
+	req := config.RepositoryRequest{OrganizationName: "org1", LdapUsername: "user1", PackageManager: "npm", AppID: "app1"}
+
+	// The first attempt outlives its timeout; the retry must get a fresh one
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("RepositoryExists", mock.Anything).After(50*time.Millisecond).Return(true, nil).Once()
+	mockNexus.On("RepositoryExists", mock.Anything).Return(true, nil).Once()
+	mockNexus.On("PrivilegeExists", mock.Anything).Return(true, nil)
+	mockNexus.On("GetRole", "user1").Return(&client.Role{ID: "user1"}, nil)
+	mockNexus.On("UpdateRole", mock.Anything).Return(nil)
+	mockNexus.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+	mockNexus.On("UpdateUser", mock.Anything).Return(nil)
+	bm := NewBatchManager(cfg, config.NewJobStore(), mockNexus, new(MockIQClient))
+
+	ctx := contextWithRetryBudget(t.Context(), newRetryBudget(1))
+	res := bm.attemptOperation(ctx, MethodCreate, req)
+
+	assert.True(t, res.Success, res.Error)
+	mockNexus.AssertNumberOfCalls(t, "RepositoryExists", 2)
+}
+
+func TestRunJob_CapsFailedRequestDetails(t *testing.T) {
+	cfg := &config.Config{IQDisabled: true, MaxFailedRequestDetails: 3}
+	requests := make([]config.RepositoryRequest, 10)
+	for i := range requests {
+		// An unknown organization fails every request before any backend call
+		requests[i] = config.RepositoryRequest{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: fmt.Sprintf("app%d", i)}
+	}
+	jobStore := config.NewJobStore()
+	bm := NewBatchManager(cfg, jobStore, new(MockNexusClient), new(MockIQClient))
+	jobStore.CreateJob("job-1", MethodCreate, len(requests))
+
+	bm.runJob(t.Context(), "job-1", requests, MethodCreate, false)
+
+	job, ok := jobStore.GetJob("job-1")
+	assert.True(t, ok)
+	assert.Equal(t, 10, job.FailedOperations)
+	assert.Len(t, job.FailedRequests, 3)
+	assert.Equal(t, 7, job.OmittedFailedRequests)
+	// The summary still covers every failure
+	assert.Equal(t, []config.FailureReasonCount{{Reason: "organization 'missing' not found", Count: 10}}, job.ErrorSummary)
+	assert.Equal(t, "All 10 requests failed; details kept for the first 3 failures only", job.Message)
+}
+
+// BenchmarkRunJob compares the bounded worker pool with one worker per request,
+// which is how every request of a batch used to be started at once.
+func BenchmarkRunJob(b *testing.B) {
+	const batchSize = 10000
+	requests := make([]config.RepositoryRequest, batchSize)
+	for i := range requests {
+		// An unknown organization fails before any backend call, leaving only the fan-out cost
+		requests[i] = config.RepositoryRequest{OrganizationName: "missing", LdapUsername: "user1", PackageManager: "npm", AppID: fmt.Sprintf("app%d", i)}
+	}
+
+	for _, workers := range []int{config.DefaultJobWorkers, batchSize} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := &config.Config{IQDisabled: true, JobWorkers: workers}
+			jobStore := config.NewJobStore()
+			bm := NewBatchManager(cfg, jobStore, new(MockNexusClient), new(MockIQClient))
+			b.ReportAllocs()
+			for b.Loop() {
+				jobStore.CreateJob("job-1", MethodCreate, batchSize)
+				bm.runJob(context.Background(), "job-1", requests, MethodCreate, false)
+			}
+		})
+	}
+}
+
+func TestDrain_WaitsForRunningJobs(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	assert.NoError(t, bm.Drain(t.Context()))
+
+	assert.NoError(t, bm.acquireJobSlot())
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bm.Drain(ctx), context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		bm.releaseJobSlot()
+	}()
+	assert.NoError(t, bm.Drain(t.Context()))
+	assert.Equal(t, 0, bm.RunningJobs())
+}
+
+func TestBusy(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	assert.False(t, bm.Busy())
+
+	assert.NoError(t, bm.acquireJobSlot())
+	assert.True(t, bm.Busy())
+	bm.releaseJobSlot()
+
+	bm.operations.Add(1)
+	assert.True(t, bm.Busy())
+	bm.operations.Add(-1)
+	assert.False(t, bm.Busy())
+}
+
+func TestRetryAfter(t *testing.T) {
+	bm := NewBatchManager(&config.Config{}, config.NewJobStore(), new(MockNexusClient), new(MockIQClient))
+	assert.Equal(t, config.DefaultQueueRetryAfter, bm.RetryAfter())
+
+	bm.metrics.observeJob(MethodCreate, 10*time.Millisecond)
+	assert.Equal(t, time.Second, bm.RetryAfter())
+
+	bm.metrics.observeJob(MethodCreate, time.Hour)
+	assert.Equal(t, config.MaxQueueRetryAfter, bm.RetryAfter())
+}
+
+func TestAwaitBackends_RetriesUntilReachable(t *testing.T) {
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("Ping").Return(errors.New("connection refused")).Once()
+	mockNexus.On("Ping").Return(nil).Once()
+	bm := NewBatchManager(&config.Config{IQDisabled: true}, config.NewJobStore(), mockNexus, new(MockIQClient))
+
+	assert.NoError(t, bm.AwaitBackends(t.Context(), time.Millisecond))
+	mockNexus.AssertNumberOfCalls(t, "Ping", 2)
+
+	mockNexus.On("Ping").Return(errors.New("connection refused"))
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bm.AwaitBackends(ctx, time.Millisecond), context.DeadlineExceeded)
+}
@@ -4,18 +4,45 @@ import "github.com/anmicius0/sonatype-resource-automation/internal/config"
 
 // ValidationError represents validation errors for a single request with detailed context.
 type ValidationError struct {
+	// Index is the zero-based position of the request in the submitted batch
+	Index   int
 	Request config.RepositoryRequest
 	Reasons []string // List of all validation error messages
 }
 
 // ValidationResult contains validation results for an entire batch.
 type ValidationResult struct {
-	ValidRequests   []config.RepositoryRequest
+	ValidRequests []config.RepositoryRequest
+	// ValidIndexes holds the position in the submitted batch of each ValidRequests entry
+	ValidIndexes    []int
 	InvalidRequests []ValidationError
 }
 
+// batchIndex returns the position in the submitted batch of ValidRequests[position],
+// or position itself when the indexes were not recorded.
+func (v *ValidationResult) batchIndex(position int) int {
+	if position < len(v.ValidIndexes) {
+		return v.ValidIndexes[position]
+	}
+	return position
+}
+
 // batchRepositoryRequest holds a batch of repository requests for bulk processing.
 type batchRepositoryRequest struct {
 	// Requests is the list of repository operation requests to process
 	Requests []config.RepositoryRequest `binding:"required,dive"`
 }
+
+// batchValidateRequest is a batch to validate for the given action without processing it.
+type batchValidateRequest struct {
+	// Action is the operation the batch would be submitted for: create or delete
+	Action string `binding:"required,oneof=create delete"`
+	// Requests is the list of repository operation requests to validate
+	Requests []config.RepositoryRequest `binding:"required,dive"`
+}
+
+// repositoryOnlineRequest takes a repository online or offline.
+type repositoryOnlineRequest struct {
+	// Online is the state to put the repository in; a pointer so that false is not mistaken for missing
+	Online *bool `binding:"required"`
+}
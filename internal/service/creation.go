@@ -1,11 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"maps"
 	"slices"
-	"sync"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
@@ -13,59 +13,80 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxRoleUpdateAttempts bounds the re-fetch/re-apply loop on conflicting role updates.
+const maxRoleUpdateAttempts = 3
+
 // NexusCreator handles idempotent creation of Nexus resources like repositories, privileges, and roles.
 type NexusCreator struct {
 	opConfig *config.OperationConfig
 	nexus    client.NexusClient
+	changes  creationChanges
 }
 
-var roleModificationLock sync.Mutex
+// creationChanges records what a NexusCreator actually changed, as opposed to
+// resources that already existed, so that only its own work is rolled back.
+type creationChanges struct {
+	repositoryCreated  bool
+	privilegeCreated   bool
+	roleCreated        bool
+	privilegeAddedRole bool
+}
 
 // NewNexusCreator creates a new NexusCreator instance.
 func NewNexusCreator(opConfig *config.OperationConfig, nexus client.NexusClient) *NexusCreator {
-	return &NexusCreator{opConfig, nexus}
+	return &NexusCreator{opConfig: opConfig, nexus: nexus}
 }
 
 // CreateRepository creates a proxy repository if it does not exist.
-func (nc *NexusCreator) CreateRepository() error {
-	utils.WithComponent("nexus_creator").Debug("CreateRepository called",
+func (nc *NexusCreator) CreateRepository(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_creator").Debug("CreateRepository called",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("repository_name", nc.opConfig.RepositoryName))
 
-	_, err := nc.nexus.GetRepository(nc.opConfig.RepositoryName)
-	if err == nil {
+	exists, err := nc.nexus.RepositoryExists(ctx, nc.opConfig.RepositoryName)
+	if err != nil {
+		return fmt.Errorf("create repository '%s': %w", nc.opConfig.RepositoryName, err)
+	}
+	if exists {
 		// Repository exists, idempotent skip
-		utils.WithComponent("nexus_creator").Debug("Repository already exists, skipping creation",
+		utils.WithComponentContext(ctx, "nexus_creator").Debug("Repository already exists, skipping creation",
 			zap.String("repository_name", nc.opConfig.RepositoryName))
 		return nil
 	}
-	if err := nc.nexus.CreateProxyRepository(nc.opConfig); err != nil {
+	location, err := nc.nexus.CreateProxyRepository(ctx, nc.opConfig)
+	if err != nil {
 		return fmt.Errorf("create proxy repository '%s' (package_manager='%s', remote_url='%s'): %w", nc.opConfig.RepositoryName, nc.opConfig.PackageManager, nc.opConfig.RemoteURL, err)
 	}
-	utils.WithComponent("nexus_creator").Info("Successfully created proxy repository",
+	nc.changes.repositoryCreated = true
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Successfully created proxy repository",
 		zap.String("repository_name", nc.opConfig.RepositoryName),
 		zap.String("package_manager", nc.opConfig.PackageManager),
-		zap.String("remote_url", nc.opConfig.RemoteURL))
+		zap.String("remote_url", nc.opConfig.RemoteURL),
+		zap.String("location", location))
 	return nil
 }
 
 // CreatePrivilege creates a repository privilege if it does not exist.
-func (nc *NexusCreator) CreatePrivilege() error {
-	utils.WithComponent("nexus_creator").Debug("CreatePrivilege called",
+func (nc *NexusCreator) CreatePrivilege(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_creator").Debug("CreatePrivilege called",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("privilege_name", nc.opConfig.PrivilegeName))
 
-	_, err := nc.nexus.GetPrivilege(nc.opConfig.PrivilegeName)
-	if err == nil {
+	exists, err := nc.nexus.PrivilegeExists(ctx, nc.opConfig.PrivilegeName)
+	if err != nil {
+		return fmt.Errorf("create privilege '%s': %w", nc.opConfig.PrivilegeName, err)
+	}
+	if exists {
 		// Privilege exists, idempotent skip
-		utils.WithComponent("nexus_creator").Warn("Privilege already exists, skipping creation",
+		utils.WithComponentContext(ctx, "nexus_creator").Warn("Privilege already exists, skipping creation",
 			zap.String("privilege_name", nc.opConfig.PrivilegeName))
 		return nil
 	}
-	if err := nc.nexus.CreatePrivilege(nc.opConfig); err != nil {
+	if err := nc.nexus.CreatePrivilege(ctx, nc.opConfig); err != nil {
 		return fmt.Errorf("create privilege '%s' for repository '%s': %w", nc.opConfig.PrivilegeName, nc.opConfig.RepositoryName, err)
 	}
-	utils.WithComponent("nexus_creator").Info("Successfully created repository privilege",
+	nc.changes.privilegeCreated = true
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Successfully created repository privilege",
 		zap.String("privilege_name", nc.opConfig.PrivilegeName),
 		zap.String("repository_name", nc.opConfig.RepositoryName),
 		zap.String("package_manager", nc.opConfig.PackageManager))
@@ -73,49 +94,56 @@ func (nc *NexusCreator) CreatePrivilege() error {
 }
 
 // AddPrivilegeToRole adds the repository privilege to the role, creating the role if necessary.
-func (nc *NexusCreator) AddPrivilegeToRole() error {
-	roleModificationLock.Lock()
-	defer roleModificationLock.Unlock()
-
-	utils.WithComponent("nexus_creator").Debug("AddPrivilegeToRole called",
+// Concurrent additions to the same role are coalesced into one update; see
+// AddPrivilegesToRole for how conflicting updates are retried.
+func (nc *NexusCreator) AddPrivilegeToRole(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_creator").Debug("AddPrivilegeToRole called",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("role_name", nc.opConfig.RoleName),
 		zap.String("privilege_name", nc.opConfig.PrivilegeName))
 
-	role, err := nc.nexus.GetRole(nc.opConfig.RoleName)
+	added, err := privilegeAdds.add(ctx, nc.nexus, nc.opConfig.RoleName, nc.opConfig.PrivilegeName)
+	if errors.Is(err, ErrRoleNotFound) {
+		return nc.createRoleWithPrivilege(ctx)
+	}
 	if err != nil {
-		var httpErr *client.HTTPError
-		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
-			// Role doesn't exist; continue to create
-			role = nil
-		} else {
-			return fmt.Errorf("add privilege '%s' to role '%s': get role failed: %w", nc.opConfig.PrivilegeName, nc.opConfig.RoleName, err)
-		}
+		return fmt.Errorf("add privilege '%s' to role '%s': %w", nc.opConfig.PrivilegeName, nc.opConfig.RoleName, err)
 	}
-	if role != nil {
-		privileges := role.Privileges
-		if slices.Contains(privileges, nc.opConfig.PrivilegeName) {
-			utils.WithComponent("nexus_creator").Debug("Privilege already in role, skipping addition",
-				zap.String("role_name", nc.opConfig.RoleName),
-				zap.String("privilege_name", nc.opConfig.PrivilegeName))
-			return nil
-		}
-		privileges = append(privileges, nc.opConfig.PrivilegeName)
-		role.Privileges = privileges
-		if err := nc.nexus.UpdateRole(role); err != nil {
-			return fmt.Errorf("add privilege to role '%s': update role failed: %w", nc.opConfig.RoleName, err)
-		}
-		utils.WithComponent("nexus_creator").Info("Successfully added privilege to existing role",
+	if !slices.Contains(added, nc.opConfig.PrivilegeName) {
+		utils.WithComponentContext(ctx, "nexus_creator").Debug("Privilege already in role, skipping addition",
 			zap.String("role_name", nc.opConfig.RoleName),
-			zap.String("privilege_name", nc.opConfig.PrivilegeName),
-			zap.String("repository_name", nc.opConfig.RepositoryName))
+			zap.String("privilege_name", nc.opConfig.PrivilegeName))
 		return nil
 	}
-	// Role does not exist; create it with the privilege
-	if err := nc.nexus.CreateRole(nc.opConfig); err != nil {
+	nc.changes.privilegeAddedRole = true
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Successfully added privilege to existing role",
+		zap.String("role_name", nc.opConfig.RoleName),
+		zap.String("privilege_name", nc.opConfig.PrivilegeName),
+		zap.String("repository_name", nc.opConfig.RepositoryName))
+	return nil
+}
+
+// createRoleWithPrivilege creates the role holding the repository privilege. Another
+// creation may have created the role since it was found missing, in which case the
+// privilege is added to it instead.
+func (nc *NexusCreator) createRoleWithPrivilege(ctx context.Context) error {
+	unlock := roleLocks.Lock(nc.opConfig.RoleName)
+	defer unlock()
+
+	added, err := addPrivilegesToRoleLocked(ctx, nc.nexus, nc.opConfig.RoleName, []string{nc.opConfig.PrivilegeName})
+	switch {
+	case err == nil:
+		nc.changes.privilegeAddedRole = len(added) > 0
+		return nil
+	case !errors.Is(err, ErrRoleNotFound):
+		return fmt.Errorf("add privilege '%s' to role '%s': %w", nc.opConfig.PrivilegeName, nc.opConfig.RoleName, err)
+	}
+
+	if err := nc.nexus.CreateRole(ctx, nc.opConfig); err != nil {
 		return fmt.Errorf("add privilege '%s' to role '%s': create role failed: %w", nc.opConfig.PrivilegeName, nc.opConfig.RoleName, err)
 	}
-	utils.WithComponent("nexus_creator").Info("Successfully created role with privilege",
+	nc.changes.roleCreated = true
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Successfully created role with privilege",
 		zap.String("role_name", nc.opConfig.RoleName),
 		zap.String("privilege_name", nc.opConfig.PrivilegeName),
 		zap.String("repository_name", nc.opConfig.RepositoryName))
@@ -123,22 +151,56 @@ func (nc *NexusCreator) AddPrivilegeToRole() error {
 }
 
 // AddRoleToUser adds the role and extra roles to the user, deduplicating existing roles.
-func (nc *NexusCreator) AddRoleToUser() error {
-	utils.WithComponent("nexus_creator").Debug("AddRoleToUser called",
+// A missing user is an error unless CreateMissingUsers is set, in which case the user
+// is created with those roles.
+func (nc *NexusCreator) AddRoleToUser(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_creator").Debug("AddRoleToUser called",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("role_name", nc.opConfig.RoleName),
 		zap.String("username", nc.opConfig.LdapUsername))
 
-	user, err := nc.nexus.GetUser(nc.opConfig.LdapUsername)
+	user, err := nc.nexus.GetUser(ctx, nc.opConfig.LdapUsername)
 	if err != nil {
 		return fmt.Errorf("add role to user '%s': get user failed: %w", nc.opConfig.LdapUsername, err)
 	}
 	if user == nil {
-		return fmt.Errorf("user '%s' not found", nc.opConfig.LdapUsername)
+		if !nc.opConfig.CreateMissingUsers {
+			return fmt.Errorf("add role to user '%s': %w", nc.opConfig.LdapUsername, ErrUserNotFound)
+		}
+		return nc.createUser(ctx)
 	}
 
-	currentRoles := user.Roles
+	user.Roles = nc.withTargetRoles(user.Roles)
+	if err := nc.nexus.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("add role to user '%s': update user failed: %w", nc.opConfig.LdapUsername, err)
+	}
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Successfully updated user roles",
+		zap.String("username", nc.opConfig.LdapUsername),
+		zap.String("role_name", nc.opConfig.RoleName),
+		zap.Int("extra_roles_count", len(nc.opConfig.ExtraRoles)))
+	return nil
+}
 
+// createUser creates the missing user as an active local user holding the target roles.
+func (nc *NexusCreator) createUser(ctx context.Context) error {
+	user := &client.User{
+		UserID: nc.opConfig.LdapUsername,
+		Source: "default",
+		Status: "active",
+		Roles:  nc.withTargetRoles(nil),
+	}
+	if err := nc.nexus.CreateUser(ctx, user); err != nil {
+		return fmt.Errorf("add role to user '%s': create user failed: %w", nc.opConfig.LdapUsername, err)
+	}
+	utils.WithComponentContext(ctx, "nexus_creator").Info("Created missing user with roles",
+		zap.String("username", nc.opConfig.LdapUsername),
+		zap.String("role_name", nc.opConfig.RoleName),
+		zap.Strings("roles", user.Roles))
+	return nil
+}
+
+// withTargetRoles returns currentRoles plus the role, extra roles and base roles.
+func (nc *NexusCreator) withTargetRoles(currentRoles []string) []string {
 	// Add target role if not present
 	if !slices.Contains(currentRoles, nc.opConfig.RoleName) {
 		currentRoles = append(currentRoles, nc.opConfig.RoleName)
@@ -155,15 +217,102 @@ func (nc *NexusCreator) AddRoleToUser() error {
 			currentRoles = append(currentRoles, baseRole)
 		}
 	}
+	return currentRoles
+}
 
-	user.Roles = currentRoles
-	if err := nc.nexus.UpdateUser(user); err != nil {
-		return fmt.Errorf("add role to user '%s': update user failed: %w", nc.opConfig.LdapUsername, err)
+// Rollback best-effort undoes the changes this creator made, in reverse order: the
+// role change, then the privilege, then the repository. Every step is attempted;
+// failures are joined into the returned error.
+func (nc *NexusCreator) Rollback(ctx context.Context) error {
+	logger := utils.WithComponentContext(ctx, "nexus_creator")
+	var errs []error
+
+	switch {
+	case nc.changes.roleCreated:
+		logger.Warn("Rolling back: removing privilege from created role, deleting it if left empty",
+			zap.String("role_name", nc.opConfig.RoleName),
+			zap.String("privilege_name", nc.opConfig.PrivilegeName))
+		if err := removePrivilegeFromCreatedRole(ctx, nc.nexus, nc.opConfig.RoleName, nc.opConfig.PrivilegeName); err != nil {
+			errs = append(errs, fmt.Errorf("rollback role '%s': %w", nc.opConfig.RoleName, err))
+		}
+	case nc.changes.privilegeAddedRole:
+		logger.Warn("Rolling back: removing privilege from role",
+			zap.String("role_name", nc.opConfig.RoleName),
+			zap.String("privilege_name", nc.opConfig.PrivilegeName))
+		if err := removePrivilegeFromRole(ctx, nc.nexus, nc.opConfig.RoleName, nc.opConfig.PrivilegeName); err != nil {
+			errs = append(errs, fmt.Errorf("rollback role '%s': %w", nc.opConfig.RoleName, err))
+		}
+	}
+
+	if nc.changes.privilegeCreated {
+		logger.Warn("Rolling back: deleting created privilege",
+			zap.String("privilege_name", nc.opConfig.PrivilegeName))
+		if err := nc.nexus.DeletePrivilege(ctx, nc.opConfig.PrivilegeName); err != nil {
+			errs = append(errs, fmt.Errorf("rollback privilege '%s': %w", nc.opConfig.PrivilegeName, err))
+		}
+	}
+
+	if nc.changes.repositoryCreated {
+		logger.Warn("Rolling back: deleting created repository",
+			zap.String("repository_name", nc.opConfig.RepositoryName))
+		if err := nc.nexus.DeleteRepository(ctx, nc.opConfig.RepositoryName); err != nil {
+			errs = append(errs, fmt.Errorf("rollback repository '%s': %w", nc.opConfig.RepositoryName, err))
+		}
+	}
+
+	nc.changes = creationChanges{}
+	return errors.Join(errs...)
+}
+
+// removePrivilegeFromRole removes privilegeName from the role, if both exist. It reverts
+// AddPrivilegeToRole on a role that already existed, and strips a privilege from the
+// roles referencing it before the privilege is deleted.
+func removePrivilegeFromRole(ctx context.Context, nexus client.NexusClient, roleName, privilegeName string) error {
+	unlock := roleLocks.Lock(roleName)
+	defer unlock()
+
+	role, err := nexus.GetRole(ctx, roleName)
+	if err != nil {
+		return fmt.Errorf("get role failed: %w", err)
+	}
+	if role == nil {
+		return nil
+	}
+	role.Privileges = slices.DeleteFunc(role.Privileges, func(p string) bool {
+		return p == privilegeName
+	})
+	if err := nexus.UpdateRole(ctx, role); err != nil {
+		return fmt.Errorf("update role failed: %w", err)
+	}
+	return nil
+}
+
+// removePrivilegeFromCreatedRole reverts the creation of a role holding privilegeName.
+// Concurrent creations may have added their own privileges to the role since, so only
+// privilegeName is removed, and the role is deleted only when that leaves it empty.
+func removePrivilegeFromCreatedRole(ctx context.Context, nexus client.NexusClient, roleName, privilegeName string) error {
+	unlock := roleLocks.Lock(roleName)
+	defer unlock()
+
+	role, err := nexus.GetRole(ctx, roleName)
+	if err != nil {
+		return fmt.Errorf("get role failed: %w", err)
+	}
+	if role == nil {
+		return nil
+	}
+	role.Privileges = slices.DeleteFunc(role.Privileges, func(p string) bool {
+		return p == privilegeName
+	})
+	if len(role.Privileges) == 0 && len(role.Roles) == 0 {
+		if err := nexus.DeleteRole(ctx, roleName); err != nil {
+			return fmt.Errorf("delete role failed: %w", err)
+		}
+		return nil
+	}
+	if err := nexus.UpdateRole(ctx, role); err != nil {
+		return fmt.Errorf("update role failed: %w", err)
 	}
-	utils.WithComponent("nexus_creator").Info("Successfully updated user roles",
-		zap.String("username", nc.opConfig.LdapUsername),
-		zap.String("role_name", nc.opConfig.RoleName),
-		zap.Int("extra_roles_count", len(nc.opConfig.ExtraRoles)))
 	return nil
 }
 
@@ -171,6 +320,7 @@ func (nc *NexusCreator) AddRoleToUser() error {
 type CreationManager struct {
 	opConfig     *config.OperationConfig
 	nexusCreator *NexusCreator
+	progress     StepProgress
 }
 
 // NewCreationManager creates a new CreationManager instance.
@@ -181,29 +331,83 @@ func NewCreationManager(opConfig *config.OperationConfig, nexusClient client.Nex
 	}
 }
 
+// CreatedResources returns the names of the resources this creator created or changed,
+// keyed for inclusion in a Run result. Lists are never nil.
+func (nc *NexusCreator) CreatedResources() map[string]interface{} {
+	repositories, privileges, createdRoles, updatedRoles := []string{}, []string{}, []string{}, []string{}
+	if nc.changes.repositoryCreated {
+		repositories = append(repositories, nc.opConfig.RepositoryName)
+	}
+	if nc.changes.privilegeCreated {
+		privileges = append(privileges, nc.opConfig.PrivilegeName)
+	}
+	if nc.changes.roleCreated {
+		createdRoles = append(createdRoles, nc.opConfig.RoleName)
+	}
+	if nc.changes.privilegeAddedRole {
+		updatedRoles = append(updatedRoles, nc.opConfig.RoleName)
+	}
+	return map[string]interface{}{
+		"created_repositories": repositories,
+		"created_privileges":   privileges,
+		"created_roles":        createdRoles,
+		"updated_roles":        updatedRoles,
+	}
+}
+
+// rollback undoes the partial creation after cause. Rollback failures are logged
+// rather than returned so they never mask the original error.
+func (cm *CreationManager) rollback(ctx context.Context, cause error) {
+	utils.WithComponentContext(ctx, "creation_manager").Warn("Creation failed, rolling back created resources",
+		zap.String("repository_name", cm.opConfig.RepositoryName),
+		zap.Error(cause))
+	if err := cm.nexusCreator.Rollback(ctx); err != nil {
+		utils.WithComponentContext(ctx, "creation_manager").Error("Rollback incomplete; manual cleanup may be required",
+			zap.String("repository_name", cm.opConfig.RepositoryName),
+			zap.Error(err))
+	}
+}
+
+// Progress reports the steps Run completed and the step it failed on, if any.
+func (cm *CreationManager) Progress() StepProgress {
+	return cm.progress.snapshot()
+}
+
 // Run executes the creation workflow: repository, privilege, role, and user assignment.
-func (cm *CreationManager) Run() (map[string]interface{}, error) {
-	utils.Logger.Debug("CreationManager.Run invoked",
+// When opConfig.Rollback is set, a failing step triggers a best-effort rollback of the
+// resources created by earlier steps.
+func (cm *CreationManager) Run(ctx context.Context) (map[string]interface{}, error) {
+	utils.LoggerFromContext(ctx).Debug("CreationManager.Run invoked",
 		zap.String("repository_name", cm.opConfig.RepositoryName),
 		zap.String("action", cm.opConfig.Action),
 		zap.String("ldap_username", cm.opConfig.LdapUsername))
 
-	if err := cm.nexusCreator.CreateRepository(); err != nil {
-		return nil, err
+	steps := []namedStep{
+		{StepRepository, cm.nexusCreator.CreateRepository},
+		{StepPrivilege, cm.nexusCreator.CreatePrivilege},
+		{StepRole, cm.nexusCreator.AddPrivilegeToRole},
+		{StepUser, cm.nexusCreator.AddRoleToUser},
 	}
-	if err := cm.nexusCreator.CreatePrivilege(); err != nil {
-		return nil, err
-	}
-	if err := cm.nexusCreator.AddPrivilegeToRole(); err != nil {
-		return nil, err
-	}
-	if err := cm.nexusCreator.AddRoleToUser(); err != nil {
-		return nil, err
+	for _, step := range steps {
+		// Stop between steps once the operation has been cancelled or run out of time
+		err := ctx.Err()
+		if err == nil {
+			err = step.run(ctx)
+		}
+		if cm.progress.Record(step.name, err) != nil {
+			if cm.opConfig.Rollback {
+				// Roll back even when ctx is what stopped the creation
+				cm.rollback(context.WithoutCancel(ctx), err)
+			}
+			return nil, err
+		}
 	}
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"action":          cm.opConfig.Action,
 		"repository_name": cm.opConfig.RepositoryName,
 		"ldap_username":   cm.opConfig.LdapUsername,
 		"organization_id": cm.opConfig.OrganizationID,
-	}, nil
+	}
+	maps.Copy(result, cm.nexusCreator.CreatedResources())
+	return result, nil
 }
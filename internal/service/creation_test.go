@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
@@ -15,7 +19,7 @@ type MockNexusClient struct {
 	mock.Mock
 }
 
-func (m *MockNexusClient) GetRepository(name string) (*client.Repository, error) {
+func (m *MockNexusClient) GetRepository(ctx context.Context, name string) (*client.Repository, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -23,7 +27,12 @@ func (m *MockNexusClient) GetRepository(name string) (*client.Repository, error)
 	return args.Get(0).(*client.Repository), args.Error(1)
 }
 
-func (m *MockNexusClient) GetRepositories() ([]client.Repository, error) {
+func (m *MockNexusClient) RepositoryExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetRepositories(ctx context.Context) ([]client.Repository, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -31,17 +40,35 @@ func (m *MockNexusClient) GetRepositories() ([]client.Repository, error) {
 	return args.Get(0).([]client.Repository), args.Error(1)
 }
 
-func (m *MockNexusClient) CreateProxyRepository(config *config.OperationConfig) error {
+func (m *MockNexusClient) GetRepositoriesByFormat(ctx context.Context, format string) ([]client.Repository, error) {
+	args := m.Called(format)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.Repository), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateProxyRepository(ctx context.Context, config *config.OperationConfig) (string, error) {
 	args := m.Called(config)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockNexusClient) SetRepositoryOnline(ctx context.Context, name string, online bool) error {
+	args := m.Called(name, online)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeleteRepository(name string) error {
+func (m *MockNexusClient) InvalidateCache(ctx context.Context, repositoryName string) error {
+	args := m.Called(repositoryName)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) DeleteRepository(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetPrivilege(name string) (*client.Privilege, error) {
+func (m *MockNexusClient) GetPrivilege(ctx context.Context, name string) (*client.Privilege, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -49,7 +76,12 @@ func (m *MockNexusClient) GetPrivilege(name string) (*client.Privilege, error) {
 	return args.Get(0).(*client.Privilege), args.Error(1)
 }
 
-func (m *MockNexusClient) GetPrivileges() ([]client.Privilege, error) {
+func (m *MockNexusClient) PrivilegeExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetPrivileges(ctx context.Context) ([]client.Privilege, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -57,17 +89,17 @@ func (m *MockNexusClient) GetPrivileges() ([]client.Privilege, error) {
 	return args.Get(0).([]client.Privilege), args.Error(1)
 }
 
-func (m *MockNexusClient) CreatePrivilege(config *config.OperationConfig) error {
+func (m *MockNexusClient) CreatePrivilege(ctx context.Context, config *config.OperationConfig) error {
 	args := m.Called(config)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeletePrivilege(name string) error {
+func (m *MockNexusClient) DeletePrivilege(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetRole(name string) (*client.Role, error) {
+func (m *MockNexusClient) GetRole(ctx context.Context, name string) (*client.Role, error) {
 	args := m.Called(name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -75,22 +107,35 @@ func (m *MockNexusClient) GetRole(name string) (*client.Role, error) {
 	return args.Get(0).(*client.Role), args.Error(1)
 }
 
-func (m *MockNexusClient) CreateRole(config *config.OperationConfig) error {
+func (m *MockNexusClient) RoleExists(ctx context.Context, name string) (bool, error) {
+	args := m.Called(name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNexusClient) GetRolesByPrivilege(ctx context.Context, privilegeName string) ([]client.Role, error) {
+	args := m.Called(privilegeName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.Role), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateRole(ctx context.Context, config *config.OperationConfig) error {
 	args := m.Called(config)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) UpdateRole(role *client.Role) error {
+func (m *MockNexusClient) UpdateRole(ctx context.Context, role *client.Role) error {
 	args := m.Called(role)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) DeleteRole(name string) error {
+func (m *MockNexusClient) DeleteRole(ctx context.Context, name string) error {
 	args := m.Called(name)
 	return args.Error(0)
 }
 
-func (m *MockNexusClient) GetUser(username string) (*client.User, error) {
+func (m *MockNexusClient) GetUser(ctx context.Context, username string) (*client.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -98,11 +143,34 @@ func (m *MockNexusClient) GetUser(username string) (*client.User, error) {
 	return args.Get(0).(*client.User), args.Error(1)
 }
 
-func (m *MockNexusClient) UpdateUser(user *client.User) error {
+func (m *MockNexusClient) GetUsersByRole(ctx context.Context, roleName string) ([]client.User, error) {
+	args := m.Called(roleName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]client.User), args.Error(1)
+}
+
+func (m *MockNexusClient) CreateUser(ctx context.Context, user *client.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
+func (m *MockNexusClient) UpdateUser(ctx context.Context, user *client.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) DeleteUser(ctx context.Context, userID string) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockNexusClient) Ping(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func TestCreateRepository(t *testing.T) {
 	opConfig := &config.OperationConfig{
 		RepositoryName: "test-repo",
@@ -113,10 +181,10 @@ func TestCreateRepository(t *testing.T) {
 
 	t.Run("Repository already exists", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetRepository", "test-repo").Return(&client.Repository{Name: "test-repo"}, nil)
+		mockClient.On("RepositoryExists", "test-repo").Return(true, nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreateRepository()
+		err := creator.CreateRepository(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -124,11 +192,11 @@ func TestCreateRepository(t *testing.T) {
 
 	t.Run("Repository does not exist, create success", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetRepository", "test-repo").Return(nil, &client.HTTPError{StatusCode: 404, Body: "not found"})
-		mockClient.On("CreateProxyRepository", opConfig).Return(nil)
+		mockClient.On("RepositoryExists", "test-repo").Return(false, nil)
+		mockClient.On("CreateProxyRepository", opConfig).Return("", nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreateRepository()
+		err := creator.CreateRepository(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -136,11 +204,11 @@ func TestCreateRepository(t *testing.T) {
 
 	t.Run("Create failure", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetRepository", "test-repo").Return(nil, &client.HTTPError{StatusCode: 404, Body: "not found"})
-		mockClient.On("CreateProxyRepository", opConfig).Return(errors.New("create error"))
+		mockClient.On("RepositoryExists", "test-repo").Return(false, nil)
+		mockClient.On("CreateProxyRepository", opConfig).Return("", errors.New("create error"))
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreateRepository()
+		err := creator.CreateRepository(context.Background())
 
 		assert.Error(t, err)
 		mockClient.AssertExpectations(t)
@@ -157,10 +225,10 @@ func TestCreatePrivilege(t *testing.T) {
 
 	t.Run("Privilege already exists", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetPrivilege", "test-privilege").Return(&client.Privilege{Name: "test-privilege"}, nil)
+		mockClient.On("PrivilegeExists", "test-privilege").Return(true, nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreatePrivilege()
+		err := creator.CreatePrivilege(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -168,11 +236,11 @@ func TestCreatePrivilege(t *testing.T) {
 
 	t.Run("Create privilege success", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetPrivilege", "test-privilege").Return(nil, errors.New("not found"))
+		mockClient.On("PrivilegeExists", "test-privilege").Return(false, nil)
 		mockClient.On("CreatePrivilege", opConfig).Return(nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreatePrivilege()
+		err := creator.CreatePrivilege(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -180,11 +248,11 @@ func TestCreatePrivilege(t *testing.T) {
 
 	t.Run("Create privilege failure", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
-		mockClient.On("GetPrivilege", "test-privilege").Return(nil, errors.New("not found"))
+		mockClient.On("PrivilegeExists", "test-privilege").Return(false, nil)
 		mockClient.On("CreatePrivilege", opConfig).Return(errors.New("create error"))
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.CreatePrivilege()
+		err := creator.CreatePrivilege(context.Background())
 
 		assert.Error(t, err)
 		mockClient.AssertExpectations(t)
@@ -207,7 +275,7 @@ func TestAddPrivilegeToRole(t *testing.T) {
 		mockClient.On("GetRole", "test-role").Return(role, nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.AddPrivilegeToRole()
+		err := creator.AddPrivilegeToRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -224,12 +292,57 @@ func TestAddPrivilegeToRole(t *testing.T) {
 		})).Return(nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.AddPrivilegeToRole()
+		err := creator.AddPrivilegeToRole(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Role update conflicts, retry against latest role succeeds", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"other-privilege"}}, nil).Once()
+		mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"other-privilege", "concurrent-privilege"}}, nil).Once()
+		mockClient.On("UpdateRole", mock.Anything).Return(&client.ConflictError{Err: &client.HTTPError{StatusCode: 409, Body: "conflict"}}).Once()
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+			return slices.Equal(r.Privileges, []string{"other-privilege", "concurrent-privilege", "test-privilege"})
+		})).Return(nil).Once()
+
+		creator := NewNexusCreator(opConfig, mockClient)
+		err := creator.AddPrivilegeToRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("Stale ETag is rejected, retry with the fresh ETag succeeds", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"other-privilege"}, ETag: `"v1"`}, nil).Once()
+		mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"other-privilege"}, ETag: `"v2"`}, nil).Once()
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool { return r.ETag == `"v1"` })).
+			Return(&client.ConflictError{Err: &client.HTTPError{StatusCode: 412, Body: "precondition failed"}}).Once()
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool { return r.ETag == `"v2"` })).Return(nil).Once()
+
+		creator := NewNexusCreator(opConfig, mockClient)
+		err := creator.AddPrivilegeToRole(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Role update keeps conflicting, gives up", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		for i := 0; i < maxRoleUpdateAttempts; i++ {
+			mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"other-privilege"}}, nil).Once()
+		}
+		mockClient.On("UpdateRole", mock.Anything).Return(&client.ConflictError{Err: &client.HTTPError{StatusCode: 412, Body: "precondition failed"}})
+
+		creator := NewNexusCreator(opConfig, mockClient)
+		err := creator.AddPrivilegeToRole(context.Background())
+
+		assert.Error(t, err)
+		mockClient.AssertNumberOfCalls(t, "UpdateRole", maxRoleUpdateAttempts)
+	})
+
 	t.Run("Role does not exist, create role success", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
 		// Simulate 404 Not Found
@@ -238,7 +351,7 @@ func TestAddPrivilegeToRole(t *testing.T) {
 		mockClient.On("CreateRole", opConfig).Return(nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.AddPrivilegeToRole()
+		err := creator.AddPrivilegeToRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -259,10 +372,56 @@ func TestAddRoleToUser(t *testing.T) {
 		mockClient.On("GetUser", "test-user").Return(nil, nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.AddRoleToUser()
+		err := creator.AddRoleToUser(context.Background())
+
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.EqualError(t, err, "add role to user 'test-user': user not found")
+		mockClient.AssertNotCalled(t, "CreateUser", mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Get user failure is not a missing user", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetUser", "test-user").Return(nil, errors.New("nexus down"))
+
+		creator := NewNexusCreator(opConfig, mockClient)
+		err := creator.AddRoleToUser(context.Background())
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUserNotFound)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Missing user is created when enabled", func(t *testing.T) {
+		createConfig := *opConfig
+		createConfig.CreateMissingUsers = true
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetUser", "test-user").Return(nil, nil)
+		mockClient.On("CreateUser", mock.MatchedBy(func(u *client.User) bool {
+			return u.UserID == "test-user" && u.Source == "default" && u.Status == "active" &&
+				assert.ObjectsAreEqual([]string{"test-role", "extra-role", "base-role"}, u.Roles)
+		})).Return(nil)
+
+		creator := NewNexusCreator(&createConfig, mockClient)
+		err := creator.AddRoleToUser(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertNotCalled(t, "UpdateUser", mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Create missing user failure", func(t *testing.T) {
+		createConfig := *opConfig
+		createConfig.CreateMissingUsers = true
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetUser", "test-user").Return(nil, nil)
+		mockClient.On("CreateUser", mock.Anything).Return(errors.New("forbidden"))
+
+		creator := NewNexusCreator(&createConfig, mockClient)
+		err := creator.AddRoleToUser(context.Background())
 
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "user 'test-user' not found")
+		assert.Contains(t, err.Error(), "create user failed")
 		mockClient.AssertExpectations(t)
 	})
 
@@ -292,9 +451,340 @@ func TestAddRoleToUser(t *testing.T) {
 		})).Return(nil)
 
 		creator := NewNexusCreator(opConfig, mockClient)
-		err := creator.AddRoleToUser()
+		err := creator.AddRoleToUser(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+}
+
+func TestAddPrivilegeToRole_DistinctRolesDoNotBlock(t *testing.T) {
+	opConfigA := &config.OperationConfig{RoleName: "role-a", PrivilegeName: "priv-a", Action: "create"}
+	opConfigB := &config.OperationConfig{RoleName: "role-b", PrivilegeName: "priv-b", Action: "create"}
+
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	mockClient := new(MockNexusClient)
+	// role-a's lookup blocks until released, holding role-a's lock
+	mockClient.On("GetRole", "role-a").Run(func(mock.Arguments) {
+		close(entered)
+		<-release
+	}).Return(&client.Role{Privileges: []string{"priv-a"}}, nil)
+	mockClient.On("GetRole", "role-b").Return(&client.Role{Privileges: []string{"priv-b"}}, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, NewNexusCreator(opConfigA, mockClient).AddPrivilegeToRole(context.Background()))
+	}()
+	<-entered
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewNexusCreator(opConfigB, mockClient).AddPrivilegeToRole(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("update of role-b blocked behind role-a")
+	}
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 0, roleLocks.size())
+	mockClient.AssertExpectations(t)
+}
 
+func TestAddPrivilegesToRole(t *testing.T) {
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"priv-a"}}, nil).Once()
+	mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+		return slices.Equal(r.Privileges, []string{"priv-a", "priv-b", "priv-c"})
+	})).Return(nil).Once()
+
+	added, err := AddPrivilegesToRole(context.Background(), mockClient, "test-role", []string{"priv-a", "priv-b", "priv-c", "priv-b"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"priv-b", "priv-c"}, added)
+	mockClient.AssertExpectations(t)
+
+	missing := new(MockNexusClient)
+	missing.On("GetRole", "new-role").Return(nil, &client.HTTPError{StatusCode: 404})
+	_, err = AddPrivilegesToRole(context.Background(), missing, "new-role", []string{"priv-a"})
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+}
+
+func TestAddPrivilegeToRole_CoalescesConcurrentAdditions(t *testing.T) {
+	privileges := []string{"priv-a", "priv-b", "priv-c"}
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetRole", "shared-role").Return(&client.Role{ID: "shared-role", Privileges: []string{"existing"}}, nil).Once()
+	mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+		return len(r.Privileges) == 4 && slices.Contains(r.Privileges, "existing") &&
+			slices.Contains(r.Privileges, "priv-a") && slices.Contains(r.Privileges, "priv-b") && slices.Contains(r.Privileges, "priv-c")
+	})).Return(nil).Once()
+
+	// Hold the role's lock so every addition queues up behind it
+	unlock := roleLocks.Lock("shared-role")
+	creators := make([]*NexusCreator, len(privileges))
+	var wg sync.WaitGroup
+	for i, privilege := range privileges {
+		creators[i] = NewNexusCreator(&config.OperationConfig{RoleName: "shared-role", PrivilegeName: privilege, Action: "create"}, mockClient)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, creators[i].AddPrivilegeToRole(context.Background()))
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		privilegeAdds.mu.Lock()
+		defer privilegeAdds.mu.Unlock()
+		batch := privilegeAdds.pending["shared-role"]
+		return batch != nil && len(batch.privileges) == len(privileges)
+	}, time.Second, time.Millisecond)
+	unlock()
+	wg.Wait()
+
+	// One GET and one PUT added all three, and each creation can roll back its own
+	mockClient.AssertExpectations(t)
+	for _, creator := range creators {
+		assert.True(t, creator.changes.privilegeAddedRole)
+	}
+}
+
+// liveContextNexus fails GetRole on a cancelled context, as the real client does.
+type liveContextNexus struct{ *MockNexusClient }
+
+func (n liveContextNexus) GetRole(ctx context.Context, name string) (*client.Role, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return n.MockNexusClient.GetRole(ctx, name)
+}
+
+func TestAddPrivilegeToRole_CoalescedCallerCancellation(t *testing.T) {
+	mockClient := liveContextNexus{new(MockNexusClient)}
+	mockClient.On("GetRole", "team-role").Return(&client.Role{ID: "team-role"}, nil).Once()
+	mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+		return slices.Equal(r.Privileges, []string{"priv-a", "priv-b"})
+	})).Return(nil).Once()
+
+	unlock := roleLocks.Lock("team-role")
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := privilegeAdds.add(leaderCtx, mockClient, "team-role", "priv-a")
+		leaderErr <- err
+	}()
+	assert.Eventually(t, func() bool {
+		privilegeAdds.mu.Lock()
+		defer privilegeAdds.mu.Unlock()
+		return privilegeAdds.pending["team-role"] != nil
+	}, time.Second, time.Millisecond)
+	joinerResult := make(chan []string, 1)
+	go func() {
+		added, err := privilegeAdds.add(context.Background(), mockClient, "team-role", "priv-b")
 		assert.NoError(t, err)
+		joinerResult <- added
+	}()
+	assert.Eventually(t, func() bool {
+		privilegeAdds.mu.Lock()
+		defer privilegeAdds.mu.Unlock()
+		batch := privilegeAdds.pending["team-role"]
+		return batch != nil && len(batch.privileges) == 2
+	}, time.Second, time.Millisecond)
+
+	// The caller that started the batch gives up without failing the other
+	cancelLeader()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	unlock()
+
+	assert.Equal(t, []string{"priv-a", "priv-b"}, <-joinerResult)
+	mockClient.AssertExpectations(t)
+}
+
+func TestKeyedMutex_SameKeySerializes(t *testing.T) {
+	km := newKeyedMutex()
+	unlock := km.Lock("repositories.share")
+
+	acquired := make(chan struct{})
+	go func() {
+		unlockSecond := km.Lock("repositories.share")
+		close(acquired)
+		unlockSecond()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lock on the same key acquired while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	<-acquired
+	assert.Equal(t, 0, km.size())
+}
+
+func TestCreationManager_Run_Rollback(t *testing.T) {
+	newOpConfig := func(rollback bool) *config.OperationConfig {
+		return &config.OperationConfig{
+			Action:         "create",
+			RepositoryName: "npm-release-app1",
+			PrivilegeName:  "npm-release-app1",
+			RoleName:       "user1",
+			LdapUsername:   "user1",
+			Rollback:       rollback,
+		}
+	}
+	userErr := errors.New("update user failed")
+
+	setupCreatedResources := func(mockClient *MockNexusClient) {
+		mockClient.On("RepositoryExists", "npm-release-app1").Return(false, nil)
+		mockClient.On("CreateProxyRepository", mock.Anything).Return("", nil)
+		mockClient.On("PrivilegeExists", "npm-release-app1").Return(false, nil)
+		mockClient.On("CreatePrivilege", mock.Anything).Return(nil)
+		mockClient.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+		mockClient.On("UpdateUser", mock.Anything).Return(userErr)
+	}
+
+	t.Run("Disabled leaves created resources in place", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		setupCreatedResources(mockClient)
+		mockClient.On("GetRole", "user1").Return(nil, nil)
+		mockClient.On("CreateRole", mock.Anything).Return(nil)
+
+		cm := NewCreationManager(newOpConfig(false), mockClient)
+		_, err := cm.Run(context.Background())
+
+		assert.ErrorIs(t, err, userErr)
+		assert.Equal(t, StepProgress{CompletedSteps: []string{StepRepository, StepPrivilege, StepRole}, FailedStep: StepUser}, cm.Progress())
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+		mockClient.AssertNotCalled(t, "DeletePrivilege", mock.Anything)
+		mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	})
+
+	t.Run("Enabled deletes created resources in reverse order", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		setupCreatedResources(mockClient)
+		mockClient.On("GetRole", "user1").Return(nil, nil).Twice()
+		mockClient.On("CreateRole", mock.Anything).Return(nil)
+		mockClient.On("GetRole", "user1").Return(&client.Role{ID: "user1", Privileges: []string{"npm-release-app1"}}, nil).Once()
+		mockClient.On("DeleteRole", "user1").Return(nil)
+		mockClient.On("DeletePrivilege", "npm-release-app1").Return(nil)
+		mockClient.On("DeleteRepository", "npm-release-app1").Return(nil)
+
+		_, err := NewCreationManager(newOpConfig(true), mockClient).Run(context.Background())
+
+		assert.ErrorIs(t, err, userErr)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Created role keeps the privileges concurrent creations added", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		setupCreatedResources(mockClient)
+		mockClient.On("GetRole", "user1").Return(nil, nil).Twice()
+		mockClient.On("CreateRole", mock.Anything).Return(nil)
+		mockClient.On("GetRole", "user1").Return(&client.Role{ID: "user1", Privileges: []string{"npm-release-app1", "maven-release-app1"}}, nil).Once()
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+			return slices.Equal(r.Privileges, []string{"maven-release-app1"})
+		})).Return(nil).Once()
+		mockClient.On("DeletePrivilege", "npm-release-app1").Return(nil)
+		mockClient.On("DeleteRepository", "npm-release-app1").Return(nil)
+
+		_, err := NewCreationManager(newOpConfig(true), mockClient).Run(context.Background())
+
+		assert.ErrorIs(t, err, userErr)
+		mockClient.AssertExpectations(t)
+		mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	})
+
+	t.Run("Existing role has only the added privilege removed", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		setupCreatedResources(mockClient)
+		mockClient.On("GetRole", "user1").Return(&client.Role{ID: "user1", Privileges: []string{"other"}}, nil).Once()
+		mockClient.On("UpdateRole", mock.Anything).Return(nil).Once()
+		mockClient.On("GetRole", "user1").Return(&client.Role{ID: "user1", Privileges: []string{"other", "npm-release-app1"}}, nil).Once()
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+			return len(r.Privileges) == 1 && r.Privileges[0] == "other"
+		})).Return(nil).Once()
+		mockClient.On("DeletePrivilege", "npm-release-app1").Return(nil)
+		mockClient.On("DeleteRepository", "npm-release-app1").Return(nil)
+
+		_, err := NewCreationManager(newOpConfig(true), mockClient).Run(context.Background())
+
+		assert.ErrorIs(t, err, userErr)
+		mockClient.AssertExpectations(t)
+		mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	})
+
+	t.Run("Pre-existing resources are not deleted and rollback errors don't mask the cause", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("RepositoryExists", "npm-release-app1").Return(true, nil)
+		mockClient.On("PrivilegeExists", "npm-release-app1").Return(false, nil)
+		mockClient.On("CreatePrivilege", mock.Anything).Return(nil)
+		mockClient.On("GetRole", "user1").Return(nil, nil)
+		mockClient.On("CreateRole", mock.Anything).Return(errors.New("create role failed"))
+		mockClient.On("DeletePrivilege", "npm-release-app1").Return(errors.New("delete failed"))
+
+		_, err := NewCreationManager(newOpConfig(true), mockClient).Run(context.Background())
+
+		assert.ErrorContains(t, err, "create role failed")
 		mockClient.AssertExpectations(t)
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
 	})
 }
+
+func TestCreationManager_Run_StopsWhenContextDone(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "create",
+		RepositoryName: "npm-release-app1",
+		PrivilegeName:  "npm-release-app1",
+		RoleName:       "user1",
+		LdapUsername:   "user1",
+		Rollback:       true,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	mockClient := new(MockNexusClient)
+	mockClient.On("RepositoryExists", "npm-release-app1").Return(false, nil)
+	// The context is cancelled while the first step is in flight
+	mockClient.On("CreateProxyRepository", mock.Anything).Run(func(mock.Arguments) { cancel() }).Return("", nil)
+	mockClient.On("DeleteRepository", "npm-release-app1").Return(nil)
+
+	_, err := NewCreationManager(opConfig, mockClient).Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNotCalled(t, "PrivilegeExists", mock.Anything)
+}
+
+func TestCreationManager_Run_ReportsCreatedResources(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "create",
+		RepositoryName: "npm-release-app1",
+		PrivilegeName:  "npm-release-app1",
+		RoleName:       "user1",
+		LdapUsername:   "user1",
+	}
+
+	mockClient := new(MockNexusClient)
+	// The repository already exists, so only the privilege and role change are reported
+	mockClient.On("RepositoryExists", "npm-release-app1").Return(true, nil)
+	mockClient.On("PrivilegeExists", "npm-release-app1").Return(false, nil)
+	mockClient.On("CreatePrivilege", mock.Anything).Return(nil)
+	mockClient.On("GetRole", "user1").Return(&client.Role{ID: "user1", Privileges: []string{"other"}}, nil)
+	mockClient.On("UpdateRole", mock.Anything).Return(nil)
+	mockClient.On("GetUser", "user1").Return(&client.User{UserID: "user1"}, nil)
+	mockClient.On("UpdateUser", mock.Anything).Return(nil)
+
+	cm := NewCreationManager(opConfig, mockClient)
+	result, err := cm.Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, StepProgress{CompletedSteps: []string{StepRepository, StepPrivilege, StepRole, StepUser}}, cm.Progress())
+	assert.Equal(t, []string{}, result["created_repositories"])
+	assert.Equal(t, []string{"npm-release-app1"}, result["created_privileges"])
+	assert.Equal(t, []string{}, result["created_roles"])
+	assert.Equal(t, []string{"user1"}, result["updated_roles"])
+}
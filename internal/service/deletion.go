@@ -1,10 +1,15 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"path"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
@@ -12,10 +17,24 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxDeletionWorkers bounds concurrent deletions during offboarding.
+const maxDeletionWorkers = 4
+
 // NexusCleaner handles cleanup of Nexus resources like repositories, privileges, and roles.
+// It is safe for concurrent deletions.
 type NexusCleaner struct {
 	opConfig    *config.OperationConfig
 	nexusClient client.NexusClient
+
+	mu      sync.Mutex
+	deleted deletedResources
+}
+
+// deletedResources records the names of resources a NexusCleaner deleted.
+type deletedResources struct {
+	repositories []string
+	privileges   []string
+	roles        []string
 }
 
 // NewNexusCleaner creates a new NexusCleaner instance.
@@ -24,202 +43,408 @@ func NewNexusCleaner(opConfig *config.OperationConfig, nexusClient client.NexusC
 }
 
 // DeleteRepository deletes the specified proxy repository.
-func (nc *NexusCleaner) DeleteRepository() error {
-	return nc.DeleteRepositoryByName(nc.opConfig.RepositoryName)
+func (nc *NexusCleaner) DeleteRepository(ctx context.Context) error {
+	return nc.DeleteRepositoryByName(ctx, nc.opConfig.RepositoryName)
 }
 
 // DeleteRepositoryByName deletes a repository by its name.
-func (nc *NexusCleaner) DeleteRepositoryByName(name string) error {
-	utils.WithComponent("nexus_cleaner").Debug("Starting repository deletion",
+func (nc *NexusCleaner) DeleteRepositoryByName(ctx context.Context, name string) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Starting repository deletion",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("repository_name", name),
 		zap.String("username", nc.opConfig.LdapUsername))
-	if err := nc.nexusClient.DeleteRepository(name); err != nil {
+	if err := nc.nexusClient.DeleteRepository(ctx, name); err != nil {
 		return fmt.Errorf("delete repository '%s': %w", name, err)
 	}
-	utils.WithComponent("nexus_cleaner").Info("Successfully deleted proxy repository",
+	nc.recordDeleted(&nc.deleted.repositories, name)
+	utils.WithComponentContext(ctx, "nexus_cleaner").Info("Successfully deleted proxy repository",
 		zap.String("repository_name", name))
 	return nil
 }
 
 // DeletePrivilege deletes the specified repository privilege.
-func (nc *NexusCleaner) DeletePrivilege() error {
-	return nc.DeletePrivilegeByName(nc.opConfig.PrivilegeName)
+func (nc *NexusCleaner) DeletePrivilege(ctx context.Context) error {
+	return nc.DeletePrivilegeByName(ctx, nc.opConfig.PrivilegeName)
 }
 
-// DeletePrivilegeByName deletes a privilege by its name.
-func (nc *NexusCleaner) DeletePrivilegeByName(name string) error {
-	utils.WithComponent("nexus_cleaner").Debug("Starting privilege deletion",
+// DeletePrivilegeByName deletes a privilege by its name. Roles that still reference
+// it are logged, or have it removed first when StripPrivilegeRefs is set.
+func (nc *NexusCleaner) DeletePrivilegeByName(ctx context.Context, name string) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Starting privilege deletion",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("privilege_name", name),
 		zap.String("username", nc.opConfig.LdapUsername))
-	if err := nc.nexusClient.DeletePrivilege(name); err != nil {
+	if err := nc.releasePrivilegeReferences(ctx, name); err != nil {
+		return fmt.Errorf("delete privilege '%s': %w", name, err)
+	}
+	if err := nc.nexusClient.DeletePrivilege(ctx, name); err != nil {
 		return fmt.Errorf("delete privilege '%s': %w", name, err)
 	}
-	utils.WithComponent("nexus_cleaner").Info("Successfully deleted repository privilege",
+	nc.recordDeleted(&nc.deleted.privileges, name)
+	utils.WithComponentContext(ctx, "nexus_cleaner").Info("Successfully deleted repository privilege",
 		zap.String("privilege_name", name))
 	return nil
 }
 
-// CleanupRole deletes the role if it has no privileges; otherwise skips.
-func (nc *NexusCleaner) CleanupRole() error {
-	utils.WithComponent("nexus_cleaner").Debug("Starting role cleanup",
+// releasePrivilegeReferences finds the roles referencing the privilege and either
+// warns about each one or, with StripPrivilegeRefs, removes the privilege from it.
+func (nc *NexusCleaner) releasePrivilegeReferences(ctx context.Context, name string) error {
+	roles, err := nc.nexusClient.GetRolesByPrivilege(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if !nc.opConfig.StripPrivilegeRefs {
+			utils.WithComponentContext(ctx, "nexus_cleaner").Warn("Deleting privilege still referenced by role",
+				zap.String("privilege_name", name),
+				zap.String("role_name", role.ID))
+			continue
+		}
+		if err := removePrivilegeFromRole(ctx, nc.nexusClient, role.ID, name); err != nil {
+			return fmt.Errorf("strip privilege from role '%s': %w", role.ID, err)
+		}
+		utils.WithComponentContext(ctx, "nexus_cleaner").Info("Removed privilege from referencing role",
+			zap.String("privilege_name", name),
+			zap.String("role_name", role.ID))
+	}
+	return nil
+}
+
+// CleanupRole applies the configured RoleCleanupMode to the role: by default it is
+// deleted only if it has no privileges and no user other than the one being
+// offboarded holds it, skip never deletes it and force-delete deletes it regardless.
+// A forced request always uses force-delete.
+func (nc *NexusCleaner) CleanupRole(ctx context.Context) error {
+	mode := roleCleanupMode(nc.opConfig)
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Starting role cleanup",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("role_name", nc.opConfig.RoleName),
-		zap.String("username", nc.opConfig.LdapUsername))
+		zap.String("username", nc.opConfig.LdapUsername),
+		zap.String("mode", mode))
+
+	if mode == config.RoleCleanupSkip {
+		utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Role cleanup disabled, skipping",
+			zap.String("role_name", nc.opConfig.RoleName))
+		return nil
+	}
 
-	role, err := nc.nexusClient.GetRole(nc.opConfig.RoleName)
+	role, err := nc.nexusClient.GetRole(ctx, nc.opConfig.RoleName)
 	if err != nil {
 		return fmt.Errorf("cleanup role '%s': get role failed: %w", nc.opConfig.RoleName, err)
 	}
 	if role == nil {
 		// Role not found; nothing to clean
-		utils.WithComponent("nexus_cleaner").Debug("Role not found, nothing to cleanup",
+		utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Role not found, nothing to cleanup",
 			zap.String("role_name", nc.opConfig.RoleName))
 		return nil
 	}
 	privileges := role.Privileges
+	if mode == config.RoleCleanupForceDelete {
+		if err := nc.ForceDeleteRole(ctx, nc.opConfig.RoleName); err != nil {
+			return fmt.Errorf("cleanup role: %w", err)
+		}
+		utils.WithComponentContext(ctx, "nexus_cleaner").Info("Force deleted role",
+			zap.String("role_name", nc.opConfig.RoleName),
+			zap.Int("privilege_count", len(privileges)))
+		return nil
+	}
 	if len(privileges) == 0 {
-		// Empty role; safe to delete
-		if err := nc.nexusClient.DeleteRole(nc.opConfig.RoleName); err != nil {
+		// Empty role; safe to delete unless other users still hold it
+		holders, err := nc.otherRoleHolders(ctx, nc.opConfig.RoleName)
+		if err != nil {
+			return fmt.Errorf("cleanup role '%s': %w", nc.opConfig.RoleName, err)
+		}
+		if len(holders) > 0 {
+			utils.WithComponentContext(ctx, "nexus_cleaner").Warn("Role is still held by other users, skipping deletion",
+				zap.String("role_name", nc.opConfig.RoleName),
+				zap.Strings("users", holders))
+			return nil
+		}
+		if err := nc.nexusClient.DeleteRole(ctx, nc.opConfig.RoleName); err != nil {
 			return fmt.Errorf("cleanup role '%s': delete empty role failed: %w", nc.opConfig.RoleName, err)
 		}
-		utils.WithComponent("nexus_cleaner").Info("Successfully deleted empty role",
+		nc.recordDeleted(&nc.deleted.roles, nc.opConfig.RoleName)
+		utils.WithComponentContext(ctx, "nexus_cleaner").Info("Successfully deleted empty role",
 			zap.String("role_name", nc.opConfig.RoleName),
 			zap.String("privilege_name", nc.opConfig.PrivilegeName))
 	} else {
 		// Role has privileges; skip deletion to avoid breaking access
-		utils.WithComponent("nexus_cleaner").Debug("Role has privileges, skipping deletion",
+		utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Role has privileges, skipping deletion",
 			zap.String("role_name", nc.opConfig.RoleName),
 			zap.Int("privilege_count", len(privileges)))
 	}
 	return nil
 }
 
+// otherRoleHolders returns the IDs of the users other than the one being offboarded
+// who hold roleName.
+func (nc *NexusCleaner) otherRoleHolders(ctx context.Context, roleName string) ([]string, error) {
+	users, err := nc.nexusClient.GetUsersByRole(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("get role holders failed: %w", err)
+	}
+	var holders []string
+	for _, user := range users {
+		if user.UserID != nc.opConfig.LdapUsername {
+			holders = append(holders, user.UserID)
+		}
+	}
+	return holders, nil
+}
+
 // ForceDeleteRole unconditionally deletes a role, ignoring 404 Not Found errors.
-func (nc *NexusCleaner) ForceDeleteRole(roleName string) error {
-	utils.WithComponent("nexus_cleaner").Debug("Force deleting role", zap.String("role_name", roleName))
-	if err := nc.nexusClient.DeleteRole(roleName); err != nil {
+func (nc *NexusCleaner) ForceDeleteRole(ctx context.Context, roleName string) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Force deleting role", zap.String("role_name", roleName))
+	if err := nc.nexusClient.DeleteRole(ctx, roleName); err != nil {
 		// If the role is not found (404), it is already deleted, so we treat it as success.
 		var httpErr *client.HTTPError
 		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
-			utils.WithComponent("nexus_cleaner").Debug("Role not found during force delete, ignoring",
+			utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Role not found during force delete, ignoring",
 				zap.String("role_name", roleName))
 			return nil
 		}
 		return fmt.Errorf("force delete role '%s': %w", roleName, err)
 	}
+	nc.recordDeleted(&nc.deleted.roles, roleName)
 	return nil
 }
 
-// DisableUserAndResetRoles resets the user's roles to BaseRoles only and sets status to disabled.
-func (nc *NexusCleaner) DisableUserAndResetRoles() error {
-	utils.WithComponent("nexus_cleaner").Debug("Disabling user and resetting roles",
-		zap.String("username", nc.opConfig.LdapUsername))
+// OffboardUser applies the configured OffboardingUserAction to the user: disable
+// (the default), reset-only or delete.
+func (nc *NexusCleaner) OffboardUser(ctx context.Context) error {
+	switch offboardingUserAction(nc.opConfig) {
+	case config.OffboardingUserResetOnly:
+		return nc.ResetUserRoles(ctx)
+	case config.OffboardingUserDelete:
+		return nc.DeleteUser(ctx)
+	default:
+		return nc.DisableUserAndResetRoles(ctx)
+	}
+}
+
+// offboardingUserAction returns the configured offboarding user action, defaulting to disable.
+func offboardingUserAction(opConfig *config.OperationConfig) string {
+	if opConfig.OffboardingUserAction == "" {
+		return config.DefaultOffboardingUserAction
+	}
+	return opConfig.OffboardingUserAction
+}
+
+// roleCleanupMode returns the configured role cleanup mode, defaulting to delete-if-empty.
+// A forced request overrides it with force-delete.
+func roleCleanupMode(opConfig *config.OperationConfig) string {
+	if opConfig.Force {
+		return config.RoleCleanupForceDelete
+	}
+	if opConfig.RoleCleanupMode == "" {
+		return config.DefaultRoleCleanupMode
+	}
+	return opConfig.RoleCleanupMode
+}
+
+// DisableUserAndResetRoles resets the user's roles to BaseRoles (plus any protected roles) and sets status to disabled.
+func (nc *NexusCleaner) DisableUserAndResetRoles(ctx context.Context) error {
+	return nc.resetUser(ctx, true)
+}
+
+// ResetUserRoles resets the user's roles to BaseRoles (plus any protected roles),
+// leaving the account active for orgs that manage it through LDAP.
+func (nc *NexusCleaner) ResetUserRoles(ctx context.Context) error {
+	return nc.resetUser(ctx, false)
+}
+
+func (nc *NexusCleaner) resetUser(ctx context.Context, disable bool) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Resetting user roles",
+		zap.String("username", nc.opConfig.LdapUsername),
+		zap.Bool("disable", disable))
 
-	user, err := nc.nexusClient.GetUser(nc.opConfig.LdapUsername)
+	user, err := nc.nexusClient.GetUser(ctx, nc.opConfig.LdapUsername)
 	if err != nil {
-		return fmt.Errorf("disable user '%s': get user failed: %w", nc.opConfig.LdapUsername, err)
+		return fmt.Errorf("reset user '%s': get user failed: %w", nc.opConfig.LdapUsername, err)
 	}
 	if user == nil {
-		return fmt.Errorf("user '%s' not found", nc.opConfig.LdapUsername)
+		return fmt.Errorf("reset user '%s': %w", nc.opConfig.LdapUsername, ErrUserNotFound)
+	}
+
+	user.Roles = nc.offboardedRoles(user.Roles)
+	if disable && !isLocalUser(user) && !nc.opConfig.DisableExternalUsers {
+		utils.WithComponentContext(ctx, "nexus_cleaner").Info("User status is managed by an external source, only resetting roles",
+			zap.String("username", nc.opConfig.LdapUsername),
+			zap.String("source", user.Source))
+		disable = false
+	}
+	if disable {
+		user.Status = "disabled"
 	}
 
-	// Set to BaseRoles only
-	user.Roles = nc.opConfig.BaseRoles
-	user.Status = "disabled"
+	if err := nc.nexusClient.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("reset user '%s': update failed: %w", nc.opConfig.LdapUsername, err)
+	}
+	utils.WithComponentContext(ctx, "nexus_cleaner").Info("User roles reset",
+		zap.String("username", nc.opConfig.LdapUsername),
+		zap.Bool("disabled", disable))
+	return nil
+}
+
+// isLocalUser reports whether the user belongs to Nexus's own realm. A user without a
+// source is treated as local.
+func isLocalUser(user *client.User) bool {
+	return user.Source == "" || user.Source == client.LocalUserSource
+}
 
-	if err := nc.nexusClient.UpdateUser(user); err != nil {
-		return fmt.Errorf("disable user '%s': update failed: %w", nc.opConfig.LdapUsername, err)
+// DeleteUser removes the Nexus user, treating an already deleted user as success.
+func (nc *NexusCleaner) DeleteUser(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Deleting user",
+		zap.String("username", nc.opConfig.LdapUsername))
+	if err := nc.nexusClient.DeleteUser(ctx, nc.opConfig.LdapUsername); err != nil {
+		var httpErr *client.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			utils.WithComponentContext(ctx, "nexus_cleaner").Debug("User not found during delete, ignoring",
+				zap.String("username", nc.opConfig.LdapUsername))
+			return nil
+		}
+		return err
 	}
-	utils.WithComponent("nexus_cleaner").Info("User disabled and roles reset",
+	utils.WithComponentContext(ctx, "nexus_cleaner").Info("User deleted",
 		zap.String("username", nc.opConfig.LdapUsername))
 	return nil
 }
 
+// offboardedRoles returns the roles an offboarded user keeps: BaseRoles, plus any
+// protected roles they already hold. A role differing only in case from one already
+// kept is not added again.
+func (nc *NexusCleaner) offboardedRoles(current []string) []string {
+	roles := slices.Clone(nc.opConfig.BaseRoles)
+	for _, r := range current {
+		kept := slices.ContainsFunc(roles, func(role string) bool { return strings.EqualFold(role, r) })
+		if kept {
+			continue
+		}
+		for _, protected := range nc.opConfig.ProtectedRoles {
+			if roleNamesEqual(protected, r, nc.opConfig.CaseInsensitiveRoles) {
+				roles = append(roles, r)
+				break
+			}
+		}
+	}
+	return roles
+}
+
 // CleanupUserRoles removes the target role from the user, applying the new logic based on remaining role combinations.
-func (nc *NexusCleaner) CleanupUserRoles() error {
-	utils.WithComponent("nexus_cleaner").Debug("Starting user roles cleanup",
+func (nc *NexusCleaner) CleanupUserRoles(ctx context.Context) error {
+	utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Starting user roles cleanup",
 		zap.String("action", nc.opConfig.Action),
 		zap.String("username", nc.opConfig.LdapUsername),
 		zap.String("rolename", nc.opConfig.RoleName))
 
-	user, err := nc.nexusClient.GetUser(nc.opConfig.LdapUsername)
+	user, err := nc.nexusClient.GetUser(ctx, nc.opConfig.LdapUsername)
 	if err != nil {
 		return fmt.Errorf("cleanup user roles for '%s': get user failed: %w", nc.opConfig.LdapUsername, err)
 	}
 	if user == nil {
-		utils.WithComponent("nexus_cleaner").Warn("User not found, skipping role cleanup",
+		utils.WithComponentContext(ctx, "nexus_cleaner").Warn("User not found, skipping role cleanup",
 			zap.String("username", nc.opConfig.LdapUsername))
 		return nil
 	}
 
 	roles := user.Roles
 
-	// Remove target role only if the role itself is empty.
+	// Remove target role only if the role itself is empty, unless the request forces it.
 	// If the role still contains privileges (something still inside the role),
 	// do not remove it from the user's roles because it's still providing access.
 	if nc.opConfig.RoleName != "" {
-		roleInfo, err := nc.nexusClient.GetRole(nc.opConfig.RoleName)
-		if err != nil {
-			return fmt.Errorf("cleanup user roles for '%s': get role '%s' failed: %w", nc.opConfig.LdapUsername, nc.opConfig.RoleName, err)
-		}
 		// If role not found or role has no privileges, it's safe to remove from user.
 		canRemove := true
-		if roleInfo != nil {
-			if len(roleInfo.Privileges) > 0 {
+		if !nc.opConfig.Force {
+			roleInfo, err := nc.nexusClient.GetRole(ctx, nc.opConfig.RoleName)
+			if err != nil {
+				return fmt.Errorf("cleanup user roles for '%s': get role '%s' failed: %w", nc.opConfig.LdapUsername, nc.opConfig.RoleName, err)
+			}
+			if roleInfo != nil && len(roleInfo.Privileges) > 0 {
 				// Role still has privileges -> do not remove it from the user
 				canRemove = false
 			}
 		}
+		// Protected roles are never taken away by automation
+		for _, protected := range nc.opConfig.ProtectedRoles {
+			if roleNamesEqual(protected, nc.opConfig.RoleName, nc.opConfig.CaseInsensitiveRoles) {
+				canRemove = false
+			}
+		}
 		if canRemove {
 			// Remove the role from the slice
-			for i, r := range roles {
-				if r == nc.opConfig.RoleName {
-					roles = append(roles[:i], roles[i+1:]...)
-					break
-				}
-			}
+			roles = removeRole(roles, nc.opConfig.RoleName, nc.opConfig.CaseInsensitiveRoles)
 		} else {
-			utils.WithComponent("nexus_cleaner").Debug("Role still contains privileges; keeping role on user",
+			utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Role still contains privileges; keeping role on user",
 				zap.String("username", nc.opConfig.LdapUsername),
 				zap.String("role_name", nc.opConfig.RoleName))
 		}
 	}
 
 	// Use RoleDecisionEngine to determine final roles
-	roleEngine := NewRoleDecisionEngine(nc.opConfig.BaseRoles, nc.opConfig.ExtraRoles)
+	roleEngine := NewRoleDecisionEngine(nc.opConfig.BaseRoles, nc.opConfig.ExtraRoles, nc.opConfig.ProtectedRoles)
+	roleEngine.SetCaseInsensitive(nc.opConfig.CaseInsensitiveRoles)
+	roleEngine.SetSharedRole(nc.opConfig.SharedRole())
 	roleEngine.SetAfterRemovalRoles(roles)
 	finalRoles := roleEngine.DecideFinalRoles()
 
 	// Log the decision
 	if roleEngine.HasOtherRoles() {
-		utils.WithComponent("nexus_cleaner").Debug("Other roles present, keeping all remaining roles",
+		utils.WithComponentContext(ctx, "nexus_cleaner").Debug("Other roles present, keeping all remaining roles",
 			zap.String("username", nc.opConfig.LdapUsername))
 	} else {
 		removedExtra := roleEngine.GetRemovedExtraRoles()
-		utils.WithComponent("nexus_cleaner").Info("No other roles, removed extra roles",
+		utils.WithComponentContext(ctx, "nexus_cleaner").Info("No other roles, removed extra roles",
 			zap.String("username", nc.opConfig.LdapUsername),
 			zap.Strings("removed_extra_roles", removedExtra))
 	}
 
 	user.Roles = finalRoles
-	if err := nc.nexusClient.UpdateUser(user); err != nil {
+	if err := nc.nexusClient.UpdateUser(ctx, user); err != nil {
 		return fmt.Errorf("cleanup user roles for '%s': update user failed: %w", nc.opConfig.LdapUsername, err)
 	}
 
-	utils.WithComponent("nexus_cleaner").Info("Successfully updated user roles after cleanup",
+	utils.WithComponentContext(ctx, "nexus_cleaner").Info("Successfully updated user roles after cleanup",
 		zap.String("username", nc.opConfig.LdapUsername),
 		zap.String("removedrole", nc.opConfig.RoleName))
 
 	return nil
 }
 
+// recordDeleted appends name to list under the cleaner's lock.
+func (nc *NexusCleaner) recordDeleted(list *[]string, name string) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	*list = append(*list, name)
+}
+
+// DeletedResources returns the sorted names of the repositories, privileges and roles
+// deleted so far, keyed for inclusion in a Run result. Lists are never nil.
+func (nc *NexusCleaner) DeletedResources() map[string]interface{} {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return map[string]interface{}{
+		"deleted_repositories": sortedNames(nc.deleted.repositories),
+		"deleted_privileges":   sortedNames(nc.deleted.privileges),
+		"deleted_roles":        sortedNames(nc.deleted.roles),
+	}
+}
+
+// sortedNames returns a sorted copy of names, or an empty slice when it is nil, so
+// results are deterministic and serialize as [].
+func sortedNames(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return slices.Sorted(slices.Values(names))
+}
+
 // DeletionManager orchestrates the full deletion workflow for repositories and roles.
 type DeletionManager struct {
 	opConfig     *config.OperationConfig
 	nexusClient  client.NexusClient
 	nexusCleaner *NexusCleaner
+	progress     StepProgress
 }
 
 // NewDeletionManager creates a new DeletionManager instance.
@@ -231,95 +456,275 @@ func NewDeletionManager(opConfig *config.OperationConfig, nexusClient client.Nex
 	}
 }
 
+// offboardingPatterns returns the globs matching each AppID's resources, deriving them
+// from the default naming template when the operation config doesn't carry them.
+func (dm *DeletionManager) offboardingPatterns() []string {
+	if len(dm.opConfig.OffboardingPatterns) > 0 {
+		return dm.opConfig.OffboardingPatterns
+	}
+	var patterns []string
+	for _, appID := range config.SplitAppIDs(dm.opConfig.AppID) {
+		patterns = append(patterns, config.OffboardingPatterns(config.DefaultRepositoryNameTemplate, "", appID, nil)...)
+	}
+	return patterns
+}
+
+// discoverOffboardingResources lists the repositories and privileges that match any
+// of the AppIDs' offboarding patterns.
+func (dm *DeletionManager) discoverOffboardingResources(ctx context.Context) ([]string, []string, error) {
+	patterns := dm.offboardingPatterns()
+
+	allRepos, err := dm.nexusClient.GetRepositories(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("offboarding: failed to list repositories: %w", err)
+	}
+	repoNames := []string{}
+	for _, repo := range allRepos {
+		if matchesAnyPattern(patterns, repo.Name) {
+			repoNames = append(repoNames, repo.Name)
+		}
+	}
+
+	allPrivs, err := dm.nexusClient.GetPrivileges(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("offboarding: failed to list privileges: %w", err)
+	}
+	privNames := []string{}
+	for _, priv := range allPrivs {
+		if matchesAnyPattern(patterns, priv.Name) {
+			privNames = append(privNames, priv.Name)
+		}
+	}
+	return repoNames, privNames, nil
+}
+
+// OffboardingPlan describes what an offboarding run would change, without changing it.
+type OffboardingPlan struct {
+	LdapUsername string
+	AppIDs       []string
+	// UserAction is the configured OffboardingUserAction
+	UserAction string
+	// UserFound is false when the Nexus user does not exist; a real run would fail
+	// unless the user is being deleted.
+	UserFound bool
+	// UserRoles are the roles the user keeps; empty when the user is deleted
+	UserRoles    []string
+	Repositories []string
+	Privileges   []string
+	// Roles lists the roles that would be deleted; empty if the user's role is already gone
+	Roles []string
+	// RemoveOwnerRole reports whether the IQ Server Owner role would be removed; the
+	// caller fills it in from IQServerCleaner.PlanOwnerRemoval
+	RemoveOwnerRole bool
+}
+
+// PlanOffboarding runs offboarding discovery without mutating Nexus. It matches
+// resources exactly the way Run does, so the plan lists what Run would delete.
+func (dm *DeletionManager) PlanOffboarding(ctx context.Context) (*OffboardingPlan, error) {
+	if !dm.opConfig.Shared || dm.opConfig.AppID == "" {
+		return nil, fmt.Errorf("offboarding plan requires a shared request with an app_id")
+	}
+	plan := &OffboardingPlan{
+		LdapUsername: dm.opConfig.LdapUsername,
+		AppIDs:       config.SplitAppIDs(dm.opConfig.AppID),
+		UserAction:   offboardingUserAction(dm.opConfig),
+		UserRoles:    []string{},
+		Roles:        []string{},
+	}
+
+	user, err := dm.nexusClient.GetUser(ctx, dm.opConfig.LdapUsername)
+	if err != nil {
+		return nil, fmt.Errorf("get user '%s': %w", dm.opConfig.LdapUsername, err)
+	}
+	if user != nil {
+		plan.UserFound = true
+		if plan.UserAction != config.OffboardingUserDelete {
+			plan.UserRoles = dm.nexusCleaner.offboardedRoles(user.Roles)
+		}
+	}
+
+	roleExists, err := dm.nexusClient.RoleExists(ctx, dm.opConfig.LdapUsername)
+	if err != nil {
+		return nil, err
+	}
+	if roleExists {
+		plan.Roles = append(plan.Roles, dm.opConfig.LdapUsername)
+	}
+
+	plan.Repositories, plan.Privileges, err = dm.discoverOffboardingResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.Repositories = sortedNames(plan.Repositories)
+	plan.Privileges = sortedNames(plan.Privileges)
+	return plan, nil
+}
+
+// FailedDeletion records a resource that could not be deleted and why.
+type FailedDeletion struct {
+	Name  string
+	Error string
+}
+
+// deleteConcurrently deletes the named resources with at most maxDeletionWorkers in
+// flight. Every deletion is attempted; failures are logged and returned rather than
+// stopping the remaining work, both as FailedDeletions, never nil, and joined into
+// one error, nil if every deletion succeeded.
+func deleteConcurrently(ctx context.Context, kind string, names []string, del func(context.Context, string) error) ([]FailedDeletion, error) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = []FailedDeletion{}
+		errs   []error
+		sem    = make(chan struct{}, maxDeletionWorkers)
+	)
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := del(ctx, name); err != nil {
+				utils.WithComponentContext(ctx, "deletion_manager").Warn("Failed to delete "+kind+" during offboarding",
+					zap.String(kind, name), zap.Error(err))
+				mu.Lock()
+				failed = append(failed, FailedDeletion{Name: name, Error: err.Error()})
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(failed, func(a, b FailedDeletion) int { return strings.Compare(a.Name, b.Name) })
+	return failed, errors.Join(errs...)
+}
+
+// deletePrivilegesOfDeletedRepositories deletes the named privileges like
+// deleteConcurrently, except those whose repository, which shares the privilege's
+// name, failed to delete: without its privilege nobody could reach that repository
+// any more. Those are reported as failed without being attempted.
+func deletePrivilegesOfDeletedRepositories(ctx context.Context, names []string, failedRepos []FailedDeletion, del func(context.Context, string) error) ([]FailedDeletion, error) {
+	var kept []FailedDeletion
+	names = slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		if !slices.ContainsFunc(failedRepos, func(f FailedDeletion) bool { return f.Name == name }) {
+			return false
+		}
+		kept = append(kept, FailedDeletion{Name: name, Error: "kept because its repository could not be deleted"})
+		return true
+	})
+	failed, err := deleteConcurrently(ctx, "privilege", names, del)
+	failed = append(failed, kept...)
+	slices.SortFunc(failed, func(a, b FailedDeletion) int { return strings.Compare(a.Name, b.Name) })
+	return failed, err
+}
+
+// matchesPattern reports whether name matches the glob pattern; a malformed pattern matches nothing.
+func matchesPattern(pattern, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// matchesAnyPattern reports whether name matches at least one of patterns.
+func matchesAnyPattern(patterns []string, name string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		return matchesPattern(pattern, name)
+	})
+}
+
+// Progress reports the steps Run completed and the step it failed on, if any. During
+// offboarding a step whose deletions partly failed is not reported as completed.
+func (dm *DeletionManager) Progress() StepProgress {
+	return dm.progress.snapshot()
+}
+
 // Run executes the deletion workflow: conditional on shared role or full cleanup.
-func (dm *DeletionManager) Run() (map[string]interface{}, error) {
+func (dm *DeletionManager) Run(ctx context.Context) (map[string]interface{}, error) {
 	// Special Offboarding Mode: Shared=true AND AppID is present (during delete)
 	if dm.opConfig.Shared && dm.opConfig.AppID != "" {
-		utils.WithComponent("deletion_manager").Info("Executing Offboarding Mode (Delete Shared+AppID)",
+		utils.WithComponentContext(ctx, "deletion_manager").Info("Executing Offboarding Mode (Delete Shared+AppID)",
 			zap.String("username", dm.opConfig.LdapUsername),
 			zap.String("app_id", dm.opConfig.AppID))
 
-		// Reset User: Keep only base roles, set status disabled
-		if err := dm.nexusCleaner.DisableUserAndResetRoles(); err != nil {
+		// Reset the user's roles, then disable or delete the account as configured
+		if err := dm.progress.Record(StepUser, dm.nexusCleaner.OffboardUser(ctx)); err != nil {
 			return nil, err
 		}
 
 		// Remove the Role named after the LDAP username
-		if err := dm.nexusCleaner.ForceDeleteRole(dm.opConfig.LdapUsername); err != nil {
+		if err := dm.nexusCleaner.ForceDeleteRole(ctx, dm.opConfig.LdapUsername); err != nil {
 			// We log but continue, as the role might not exist
-			utils.WithComponent("deletion_manager").Warn("Failed to delete user role during offboarding",
+			utils.WithComponentContext(ctx, "deletion_manager").Warn("Failed to delete user role during offboarding",
 				zap.Error(err), zap.String("role", dm.opConfig.LdapUsername))
+		} else {
+			dm.progress.Complete(StepRole)
 		}
 
-		// Remove ALL repositories and privileges associated with this AppID.
-		// We assume the naming convention *-release-[appID]
-		// Fetch all repositories
-		allRepos, err := dm.nexusClient.GetRepositories()
+		// Remove ALL repositories and privileges associated with this AppID,
+		// matched by the same naming scheme used to create them.
+		repoNames, privNames, err := dm.discoverOffboardingResources(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("offboarding: failed to list repositories: %w", err)
-		}
-
-		suffix := fmt.Sprintf("-release-%s", dm.opConfig.AppID)
-
-		// Filter and delete matching repositories
-		for _, repo := range allRepos {
-			if strings.HasSuffix(repo.Name, suffix) {
-				if err := dm.nexusCleaner.DeleteRepositoryByName(repo.Name); err != nil {
-					utils.WithComponent("deletion_manager").Warn("Failed to delete repository during offboarding",
-						zap.String("repository", repo.Name), zap.Error(err))
-				}
-			}
+			dm.progress.Fail(StepRepository)
+			return nil, err
 		}
-
-		// Fetch all privileges
-		allPrivs, err := dm.nexusClient.GetPrivileges()
-		if err != nil {
-			return nil, fmt.Errorf("offboarding: failed to list privileges: %w", err)
+		failedRepos, repoErr := deleteConcurrently(ctx, "repository", repoNames, dm.nexusCleaner.DeleteRepositoryByName)
+		if len(failedRepos) == 0 {
+			dm.progress.Complete(StepRepository)
+		} else {
+			dm.progress.Fail(StepRepository)
 		}
 
-		// 4. Filter and delete matching privileges
-		for _, priv := range allPrivs {
-			if strings.HasSuffix(priv.Name, suffix) {
-				if err := dm.nexusCleaner.DeletePrivilegeByName(priv.Name); err != nil {
-					utils.WithComponent("deletion_manager").Warn("Failed to delete privilege during offboarding",
-						zap.String("privilege", priv.Name), zap.Error(err))
-				}
-			}
+		// Privileges are removed after the repositories they refer to
+		failedPrivs, privErr := deletePrivilegesOfDeletedRepositories(ctx, privNames, failedRepos, dm.nexusCleaner.DeletePrivilegeByName)
+		if len(failedPrivs) == 0 {
+			dm.progress.Complete(StepPrivilege)
+		} else if len(failedRepos) == 0 {
+			dm.progress.Fail(StepPrivilege)
 		}
 
-		return map[string]interface{}{
+		result := map[string]interface{}{
 			"action":        dm.opConfig.Action,
 			"mode":          "offboarding",
 			"ldap_username": dm.opConfig.LdapUsername,
 			"app_id":        dm.opConfig.AppID,
-		}, nil
+			"app_ids":       config.SplitAppIDs(dm.opConfig.AppID),
+			"user_action":   offboardingUserAction(dm.opConfig),
+		}
+		maps.Copy(result, dm.nexusCleaner.DeletedResources())
+		result["deleted_repository_count"] = len(repoNames) - len(failedRepos)
+		result["deleted_privilege_count"] = len(privNames) - len(failedPrivs)
+		result["failed_repositories"] = failedRepos
+		result["failed_privileges"] = failedPrivs
+		if len(failedRepos) > 0 || len(failedPrivs) > 0 {
+			// A partial offboarding fails the request, so it is counted and can be retried
+			return result, fmt.Errorf("offboarding: %d repositories and %d privileges could not be deleted: %w",
+				len(failedRepos), len(failedPrivs), errors.Join(repoErr, privErr))
+		}
+		return result, nil
 	}
 
 	// Standard Deletion Logic
-	if dm.opConfig.RoleName == "repositories.share" {
-		// Shared role: only cleanup user roles
-		if err := dm.nexusCleaner.CleanupUserRoles(); err != nil {
-			return nil, err
-		}
-	} else {
+	// Shared role: only cleanup user roles
+	steps := []namedStep{{StepUser, dm.nexusCleaner.CleanupUserRoles}}
+	if dm.opConfig.RoleName != dm.opConfig.SharedRole() {
 		// Full cleanup: repo, privilege, role, user
-		if err := dm.nexusCleaner.DeleteRepository(); err != nil {
-			return nil, err
-		}
-		if err := dm.nexusCleaner.DeletePrivilege(); err != nil {
-			return nil, err
+		steps = []namedStep{
+			{StepRepository, dm.nexusCleaner.DeleteRepository},
+			{StepPrivilege, dm.nexusCleaner.DeletePrivilege},
+			{StepRole, dm.nexusCleaner.CleanupRole},
+			{StepUser, dm.nexusCleaner.CleanupUserRoles},
 		}
-		if err := dm.nexusCleaner.CleanupRole(); err != nil {
-			return nil, err
-		}
-		if err := dm.nexusCleaner.CleanupUserRoles(); err != nil {
+	}
+	for _, step := range steps {
+		if err := dm.progress.Record(step.name, step.run(ctx)); err != nil {
 			return nil, err
 		}
 	}
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"action":          dm.opConfig.Action,
 		"repository_name": dm.opConfig.RepositoryName,
 		"ldap_username":   dm.opConfig.LdapUsername,
 		"organization_id": dm.opConfig.OrganizationID,
-	}, nil
+	}
+	maps.Copy(result, dm.nexusCleaner.DeletedResources())
+	return result, nil
 }
@@ -1,8 +1,13 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
@@ -21,7 +26,7 @@ func TestDeleteRepository(t *testing.T) {
 		mockClient.On("DeleteRepository", "test-repo").Return(nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.DeleteRepository()
+		err := cleaner.DeleteRepository(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -32,7 +37,7 @@ func TestDeleteRepository(t *testing.T) {
 		mockClient.On("DeleteRepository", "test-repo").Return(errors.New("delete error"))
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.DeleteRepository()
+		err := cleaner.DeleteRepository(context.Background())
 
 		assert.Error(t, err)
 		mockClient.AssertExpectations(t)
@@ -47,14 +52,57 @@ func TestDeletePrivilege(t *testing.T) {
 
 	t.Run("Delete success", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
+		mockClient.On("GetRolesByPrivilege", "test-privilege").Return([]client.Role{}, nil)
 		mockClient.On("DeletePrivilege", "test-privilege").Return(nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.DeletePrivilege()
+		err := cleaner.DeletePrivilege(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
 	})
+
+	t.Run("Referencing roles are only warned about by default", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRolesByPrivilege", "test-privilege").Return([]client.Role{{ID: "other-role", Privileges: []string{"test-privilege"}}}, nil)
+		mockClient.On("DeletePrivilege", "test-privilege").Return(nil)
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		err := cleaner.DeletePrivilege(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertNotCalled(t, "UpdateRole", mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Referencing roles are stripped when configured", func(t *testing.T) {
+		stripConfig := *opConfig
+		stripConfig.StripPrivilegeRefs = true
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRolesByPrivilege", "test-privilege").Return([]client.Role{{ID: "other-role", Privileges: []string{"test-privilege"}}}, nil)
+		mockClient.On("GetRole", "other-role").Return(&client.Role{ID: "other-role", Privileges: []string{"keep", "test-privilege"}}, nil)
+		mockClient.On("UpdateRole", mock.MatchedBy(func(r *client.Role) bool {
+			return r.ID == "other-role" && slices.Equal(r.Privileges, []string{"keep"})
+		})).Return(nil)
+		mockClient.On("DeletePrivilege", "test-privilege").Return(nil)
+
+		cleaner := NewNexusCleaner(&stripConfig, mockClient)
+		err := cleaner.DeletePrivilege(context.Background())
+
+		assert.NoError(t, err)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Lookup failure stops the deletion", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRolesByPrivilege", "test-privilege").Return(nil, errors.New("nexus down"))
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		err := cleaner.DeletePrivilege(context.Background())
+
+		assert.Error(t, err)
+		mockClient.AssertNotCalled(t, "DeletePrivilege", mock.Anything)
+	})
 }
 
 func TestCleanupRole(t *testing.T) {
@@ -68,7 +116,7 @@ func TestCleanupRole(t *testing.T) {
 		mockClient.On("GetRole", "test-role").Return(nil, nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.CleanupRole()
+		err := cleaner.CleanupRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -80,15 +128,58 @@ func TestCleanupRole(t *testing.T) {
 			Privileges: []string{},
 		}
 		mockClient.On("GetRole", "test-role").Return(role, nil)
+		mockClient.On("GetUsersByRole", "test-role").Return([]client.User{}, nil)
 		mockClient.On("DeleteRole", "test-role").Return(nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.CleanupRole()
+		err := cleaner.CleanupRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
 	})
 
+	t.Run("Role empty, held only by the offboarded user, delete success", func(t *testing.T) {
+		opConfig := &config.OperationConfig{RoleName: "test-role", Action: "delete", LdapUsername: "user1"}
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{Privileges: []string{}}, nil)
+		mockClient.On("GetUsersByRole", "test-role").Return([]client.User{{UserID: "user1", Roles: []string{"test-role"}}}, nil)
+		mockClient.On("DeleteRole", "test-role").Return(nil)
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		assert.NoError(t, cleaner.CleanupRole(context.Background()))
+
+		assert.Equal(t, []string{"test-role"}, cleaner.DeletedResources()["deleted_roles"])
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Role empty, held by other users, skip delete", func(t *testing.T) {
+		opConfig := &config.OperationConfig{RoleName: "test-role", Action: "delete", LdapUsername: "user1"}
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{Privileges: []string{}}, nil)
+		mockClient.On("GetUsersByRole", "test-role").Return([]client.User{
+			{UserID: "user1", Roles: []string{"test-role"}},
+			{UserID: "user2", Roles: []string{"test-role"}},
+		}, nil)
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		assert.NoError(t, cleaner.CleanupRole(context.Background()))
+
+		mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("Role holders lookup fails", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{Privileges: []string{}}, nil)
+		mockClient.On("GetUsersByRole", "test-role").Return(nil, errors.New("nexus unavailable"))
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		err := cleaner.CleanupRole(context.Background())
+
+		assert.ErrorContains(t, err, "get role holders failed")
+		mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	})
+
 	t.Run("Role has privileges, skip delete", func(t *testing.T) {
 		mockClient := new(MockNexusClient)
 		role := &client.Role{
@@ -97,13 +188,63 @@ func TestCleanupRole(t *testing.T) {
 		mockClient.On("GetRole", "test-role").Return(role, nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.CleanupRole()
+		err := cleaner.CleanupRole(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
 	})
 }
 
+func TestCleanupRole_Modes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		privileges []string
+		wantDelete bool
+	}{
+		{config.RoleCleanupSkip, nil, false},
+		{config.RoleCleanupSkip, []string{"other-privilege"}, false},
+		{config.RoleCleanupDeleteIfEmpty, nil, true},
+		{config.RoleCleanupDeleteIfEmpty, []string{"other-privilege"}, false},
+		{config.RoleCleanupForceDelete, nil, true},
+		{config.RoleCleanupForceDelete, []string{"other-privilege"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s with %d privileges", tt.mode, len(tt.privileges)), func(t *testing.T) {
+			opConfig := &config.OperationConfig{RoleName: "test-role", Action: "delete", RoleCleanupMode: tt.mode}
+			mockClient := new(MockNexusClient)
+			if tt.mode != config.RoleCleanupSkip {
+				mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: tt.privileges}, nil)
+			}
+			if tt.mode == config.RoleCleanupDeleteIfEmpty && len(tt.privileges) == 0 {
+				mockClient.On("GetUsersByRole", "test-role").Return([]client.User{}, nil)
+			}
+			if tt.wantDelete {
+				mockClient.On("DeleteRole", "test-role").Return(nil)
+			}
+
+			cleaner := NewNexusCleaner(opConfig, mockClient)
+			assert.NoError(t, cleaner.CleanupRole(context.Background()))
+
+			if tt.wantDelete {
+				assert.Equal(t, []string{"test-role"}, cleaner.DeletedResources()["deleted_roles"])
+			} else {
+				mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+			}
+			mockClient.AssertExpectations(t)
+		})
+	}
+
+	t.Run("force-delete treats an already deleted role as success", func(t *testing.T) {
+		opConfig := &config.OperationConfig{RoleName: "test-role", Action: "delete", RoleCleanupMode: config.RoleCleanupForceDelete}
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRole", "test-role").Return(&client.Role{ID: "test-role", Privileges: []string{"p"}}, nil)
+		mockClient.On("DeleteRole", "test-role").Return(&client.HTTPError{StatusCode: 404})
+
+		cleaner := NewNexusCleaner(opConfig, mockClient)
+		assert.NoError(t, cleaner.CleanupRole(context.Background()))
+	})
+}
+
 func TestCleanupUserRoles(t *testing.T) {
 	opConfig := &config.OperationConfig{
 		LdapUsername: "test-user",
@@ -118,7 +259,7 @@ func TestCleanupUserRoles(t *testing.T) {
 		mockClient.On("GetUser", "test-user").Return(nil, nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.CleanupUserRoles()
+		err := cleaner.CleanupUserRoles(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -138,7 +279,7 @@ func TestCleanupUserRoles(t *testing.T) {
 		mockClient.On("UpdateUser", mock.Anything).Return(nil)
 
 		cleaner := NewNexusCleaner(opConfig, mockClient)
-		err := cleaner.CleanupUserRoles()
+		err := cleaner.CleanupUserRoles(context.Background())
 
 		assert.NoError(t, err)
 		mockClient.AssertExpectations(t)
@@ -182,18 +323,83 @@ func TestDeletionManager_Run_OffboardingMode(t *testing.T) {
 	// Mock deleting discovered resources
 	mockClient.On("DeleteRepository", "npm-release-app-123").Return(nil)
 	mockClient.On("DeleteRepository", "maven-release-app-123").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "npm-release-app-123").Return([]client.Role{}, nil)
 	mockClient.On("DeletePrivilege", "npm-release-app-123").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "maven-release-app-123").Return([]client.Role{}, nil)
 	mockClient.On("DeletePrivilege", "maven-release-app-123").Return(nil)
 
 	dm := NewDeletionManager(opConfig, mockClient)
-	result, err := dm.Run()
+	result, err := dm.Run(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "offboarding", result["mode"])
 	assert.Equal(t, "offboard-user", result["ldap_username"])
+	assert.Equal(t, []string{"maven-release-app-123", "npm-release-app-123"}, result["deleted_repositories"])
+	assert.Equal(t, []string{"maven-release-app-123", "npm-release-app-123"}, result["deleted_privileges"])
+	assert.Equal(t, []string{"offboard-user"}, result["deleted_roles"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeletionManager_PlanOffboarding_MatchesRun(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "delete",
+		Shared:         true,
+		AppID:          "app-123",
+		LdapUsername:   "offboard-user",
+		RoleName:       "offboard-user",
+		BaseRoles:      []string{"base-role"},
+		ProtectedRoles: []string{"security-admin"},
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"offboard-user", "security-admin"}}, nil)
+	mockClient.On("RoleExists", "offboard-user").Return(true, nil)
+	mockClient.On("GetRepositories").Return([]client.Repository{
+		{Name: "npm-release-app-123"},
+		{Name: "maven-release-app-123"},
+		{Name: "npm-release-app-1234"},
+	}, nil)
+	mockClient.On("GetPrivileges").Return([]client.Privilege{
+		{Name: "npm-release-app-123"},
+		{Name: "other-priv"},
+	}, nil)
+
+	dm := NewDeletionManager(opConfig, mockClient)
+	plan, err := dm.PlanOffboarding(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, plan.UserFound)
+	assert.Equal(t, []string{"base-role", "security-admin"}, plan.UserRoles)
+	assert.Equal(t, []string{"offboard-user"}, plan.Roles)
+	mockClient.AssertNotCalled(t, "UpdateUser", mock.Anything)
+	mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+	mockClient.AssertNotCalled(t, "DeletePrivilege", mock.Anything)
+
+	// A real run deletes exactly what the plan listed
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return slices.Equal(u.Roles, plan.UserRoles)
+	})).Return(nil)
+	mockClient.On("DeleteRole", "offboard-user").Return(nil)
+	mockClient.On("DeleteRepository", mock.Anything).Return(nil)
+	mockClient.On("GetRolesByPrivilege", mock.Anything).Return([]client.Role{}, nil)
+	mockClient.On("DeletePrivilege", mock.Anything).Return(nil)
+
+	result, err := NewDeletionManager(opConfig, mockClient).Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, plan.Repositories, result["deleted_repositories"])
+	assert.Equal(t, plan.Privileges, result["deleted_privileges"])
+	assert.Equal(t, plan.Roles, result["deleted_roles"])
 	mockClient.AssertExpectations(t)
 }
 
+func TestDeletionManager_PlanOffboarding_RequiresOffboardingRequest(t *testing.T) {
+	dm := NewDeletionManager(&config.OperationConfig{Action: "delete", LdapUsername: "u"}, new(MockNexusClient))
+	_, err := dm.PlanOffboarding(context.Background())
+	assert.Error(t, err)
+}
+
 func TestDeletionManager_Run_SharedRoleCleanup(t *testing.T) {
 	opConfig := &config.OperationConfig{
 		Action:         "delete",
@@ -213,7 +419,7 @@ func TestDeletionManager_Run_SharedRoleCleanup(t *testing.T) {
 	})).Return(nil)
 
 	dm := NewDeletionManager(opConfig, mockClient)
-	result, err := dm.Run()
+	result, err := dm.Run(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "delete", result["action"])
@@ -222,6 +428,32 @@ func TestDeletionManager_Run_SharedRoleCleanup(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestDeletionManager_Run_CustomSharedRoleCleanup(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "delete",
+		RoleName:       "team.shared",
+		SharedRoleName: "team.shared",
+		Shared:         true,
+		LdapUsername:   "shared-user",
+		BaseRoles:      []string{"base-role"},
+		ExtraRoles:     []string{"extra-role"},
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "shared-user").Return(&client.User{Roles: []string{"team.shared", "extra-role"}}, nil)
+	mockClient.On("GetRole", "team.shared").Return(&client.Role{Privileges: []string{}}, nil)
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return len(u.Roles) == 1 && u.Roles[0] == "base-role"
+	})).Return(nil)
+
+	_, err := NewDeletionManager(opConfig, mockClient).Run(context.Background())
+
+	assert.NoError(t, err)
+	// Only the user's roles are cleaned up; the shared repository stays
+	mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+	mockClient.AssertExpectations(t)
+}
+
 func TestDeletionManager_Run_FullCleanup(t *testing.T) {
 	opConfig := &config.OperationConfig{
 		Action:         "delete",
@@ -236,6 +468,7 @@ func TestDeletionManager_Run_FullCleanup(t *testing.T) {
 
 	mockClient := new(MockNexusClient)
 	mockClient.On("DeleteRepository", "app-role-repo").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "app-role-repo").Return([]client.Role{}, nil)
 	mockClient.On("DeletePrivilege", "app-role-repo").Return(nil)
 	mockClient.On("GetRole", "app-role").Return(nil, nil).Twice()
 	mockClient.On("GetUser", "app-user").Return(&client.User{Roles: []string{"app-role", "base-role"}}, nil)
@@ -244,12 +477,441 @@ func TestDeletionManager_Run_FullCleanup(t *testing.T) {
 	})).Return(nil)
 
 	dm := NewDeletionManager(opConfig, mockClient)
-	result, err := dm.Run()
+	result, err := dm.Run(context.Background())
 
 	assert.NoError(t, err)
+	assert.Equal(t, []string{StepRepository, StepPrivilege, StepRole, StepUser}, dm.Progress().CompletedSteps)
+	assert.Empty(t, dm.Progress().FailedStep)
 	assert.Equal(t, "delete", result["action"])
 	assert.Equal(t, "app-role-repo", result["repository_name"])
 	assert.Equal(t, "app-user", result["ldap_username"])
 	assert.Equal(t, "org-b", result["organization_id"])
+	assert.Equal(t, []string{"app-role-repo"}, result["deleted_repositories"])
+	assert.Equal(t, []string{"app-role-repo"}, result["deleted_privileges"])
+	// The role was already gone, so nothing is reported as deleted
+	assert.Equal(t, []string{}, result["deleted_roles"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeletionManager_Run_ReportsFailedStep(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "delete",
+		RoleName:       "app-role",
+		RepositoryName: "app-role-repo",
+		PrivilegeName:  "app-role-repo",
+		LdapUsername:   "app-user",
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("DeleteRepository", "app-role-repo").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "app-role-repo").Return(nil, errors.New("nexus down"))
+
+	dm := NewDeletionManager(opConfig, mockClient)
+	_, err := dm.Run(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, StepProgress{CompletedSteps: []string{StepRepository}, FailedStep: StepPrivilege}, dm.Progress())
+}
+
+func TestCleanupUserRoles_CaseInsensitive(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		LdapUsername:         "test-user",
+		RoleName:             "test-role",
+		BaseRoles:            []string{"nx-admin"},
+		ExtraRoles:           []string{"extra-role"},
+		CaseInsensitiveRoles: true,
+		Action:               "delete",
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "test-user").Return(&client.User{Roles: []string{"Test-Role", "Nx-Admin", "Extra-Role"}}, nil)
+	mockClient.On("GetRole", "test-role").Return(nil, nil)
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return len(u.Roles) == 1 && u.Roles[0] == "Nx-Admin"
+	})).Return(nil)
+
+	cleaner := NewNexusCleaner(opConfig, mockClient)
+	err := cleaner.CleanupUserRoles(context.Background())
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestCleanupUserRoles_ProtectedRoleSurvives(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		LdapUsername:   "test-user",
+		RoleName:       "test-role",
+		BaseRoles:      []string{"base-role"},
+		ExtraRoles:     []string{"extra-role"},
+		ProtectedRoles: []string{"security-admin"},
+		Action:         "delete",
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "test-user").Return(&client.User{Roles: []string{"test-role", "base-role", "extra-role", "security-admin"}}, nil)
+	mockClient.On("GetRole", "test-role").Return(nil, nil)
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return slices.Equal(u.Roles, []string{"base-role", "security-admin"})
+	})).Return(nil)
+
+	cleaner := NewNexusCleaner(opConfig, mockClient)
+	err := cleaner.CleanupUserRoles(context.Background())
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestCleanup_ForcedVsConservative(t *testing.T) {
+	// The role still grants another application's privilege
+	role := &client.Role{ID: "test-user", Privileges: []string{"npm-release-other-app"}}
+
+	for _, force := range []bool{false, true} {
+		t.Run(fmt.Sprintf("force=%t", force), func(t *testing.T) {
+			opConfig := &config.OperationConfig{
+				LdapUsername: "test-user",
+				RoleName:     "test-user",
+				BaseRoles:    []string{"base-role"},
+				Action:       "delete",
+				Force:        force,
+			}
+			mockClient := new(MockNexusClient)
+			mockClient.On("GetRole", "test-user").Return(role, nil)
+			mockClient.On("GetUser", "test-user").Return(&client.User{Roles: []string{"test-user", "base-role"}}, nil)
+			mockClient.On("DeleteRole", "test-user").Return(nil)
+			var updated []string
+			mockClient.On("UpdateUser", mock.Anything).Run(func(args mock.Arguments) {
+				updated = args.Get(0).(*client.User).Roles
+			}).Return(nil)
+
+			cleaner := NewNexusCleaner(opConfig, mockClient)
+			assert.NoError(t, cleaner.CleanupRole(context.Background()))
+			assert.NoError(t, cleaner.CleanupUserRoles(context.Background()))
+
+			if force {
+				mockClient.AssertCalled(t, "DeleteRole", "test-user")
+				assert.Equal(t, []string{"test-user"}, cleaner.DeletedResources()["deleted_roles"])
+				assert.Equal(t, []string{"base-role"}, updated)
+			} else {
+				mockClient.AssertNotCalled(t, "DeleteRole", mock.Anything)
+				assert.Equal(t, []string{}, cleaner.DeletedResources()["deleted_roles"])
+				assert.ElementsMatch(t, []string{"test-user", "base-role"}, updated)
+			}
+		})
+	}
+}
+
+func TestDisableUserAndResetRoles_UserNotFound(t *testing.T) {
+	opConfig := &config.OperationConfig{LdapUsername: "test-user", Action: "delete"}
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "test-user").Return(nil, nil)
+
+	cleaner := NewNexusCleaner(opConfig, mockClient)
+	err := cleaner.DisableUserAndResetRoles(context.Background())
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	mockClient.AssertNotCalled(t, "UpdateUser", mock.Anything)
+}
+
+func TestDisableUserAndResetRoles_KeepsProtectedRoles(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		LdapUsername:   "offboard-user",
+		BaseRoles:      []string{"base-role"},
+		ProtectedRoles: []string{"security-admin"},
+		Action:         "delete",
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"app-role", "security-admin"}}, nil)
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return u.Status == "disabled" && slices.Equal(u.Roles, []string{"base-role", "security-admin"})
+	})).Return(nil)
+
+	cleaner := NewNexusCleaner(opConfig, mockClient)
+	err := cleaner.DisableUserAndResetRoles(context.Background())
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestDisableUserAndResetRoles_ProtectedBaseRoleDiffersInCase(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		LdapUsername:         "offboard-user",
+		BaseRoles:            []string{"base-role", "security-admin"},
+		ProtectedRoles:       []string{"security-admin"},
+		CaseInsensitiveRoles: true,
+		Action:               "delete",
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"app-role", "Security-Admin"}}, nil)
+	mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+		return slices.Equal(u.Roles, []string{"base-role", "security-admin"})
+	})).Return(nil)
+
+	cleaner := NewNexusCleaner(opConfig, mockClient)
+	err := cleaner.DisableUserAndResetRoles(context.Background())
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestDisableUserAndResetRoles_UserSource(t *testing.T) {
+	tests := []struct {
+		name                 string
+		source               string
+		disableExternalUsers bool
+		wantStatus           string
+	}{
+		{"Local user is disabled", client.LocalUserSource, false, "disabled"},
+		{"User without a source is disabled", "", false, "disabled"},
+		{"LDAP user only has roles reset", "LDAP", false, "active"},
+		{"LDAP user is disabled when forced", "LDAP", true, "disabled"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opConfig := &config.OperationConfig{
+				LdapUsername:         "offboard-user",
+				BaseRoles:            []string{"base-role"},
+				Action:               "delete",
+				DisableExternalUsers: tc.disableExternalUsers,
+			}
+			mockClient := new(MockNexusClient)
+			mockClient.On("GetUser", "offboard-user").Return(&client.User{
+				UserID: "offboard-user",
+				Source: tc.source,
+				Status: "active",
+				Roles:  []string{"base-role", "offboard-user"},
+			}, nil)
+			mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+				return u.Status == tc.wantStatus && slices.Equal(u.Roles, []string{"base-role"})
+			})).Return(nil)
+
+			cleaner := NewNexusCleaner(opConfig, mockClient)
+			assert.NoError(t, cleaner.DisableUserAndResetRoles(context.Background()))
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeletionManager_Run_OffboardingUserActions(t *testing.T) {
+	newOpConfig := func(action string) *config.OperationConfig {
+		return &config.OperationConfig{
+			Action:                "delete",
+			Shared:                true,
+			AppID:                 "app-123",
+			LdapUsername:          "offboard-user",
+			RoleName:              "offboard-user",
+			BaseRoles:             []string{"base-role"},
+			OffboardingUserAction: action,
+		}
+	}
+	newClient := func() *MockNexusClient {
+		mockClient := new(MockNexusClient)
+		mockClient.On("DeleteRole", "offboard-user").Return(nil)
+		mockClient.On("GetRepositories").Return([]client.Repository{}, nil)
+		mockClient.On("GetPrivileges").Return([]client.Privilege{}, nil)
+		return mockClient
+	}
+
+	t.Run("disable resets roles and disables the account", func(t *testing.T) {
+		mockClient := newClient()
+		mockClient.On("GetUser", "offboard-user").Return(&client.User{UserID: "offboard-user", Status: "active", Roles: []string{"app-role"}}, nil)
+		mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+			return u.Status == "disabled" && slices.Equal(u.Roles, []string{"base-role"})
+		})).Return(nil)
+
+		result, err := NewDeletionManager(newOpConfig(config.OffboardingUserDisable), mockClient).Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.OffboardingUserDisable, result["user_action"])
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("reset-only resets roles and keeps the account active", func(t *testing.T) {
+		mockClient := newClient()
+		mockClient.On("GetUser", "offboard-user").Return(&client.User{UserID: "offboard-user", Status: "active", Roles: []string{"app-role"}}, nil)
+		mockClient.On("UpdateUser", mock.MatchedBy(func(u *client.User) bool {
+			return u.Status == "active" && slices.Equal(u.Roles, []string{"base-role"})
+		})).Return(nil)
+
+		result, err := NewDeletionManager(newOpConfig(config.OffboardingUserResetOnly), mockClient).Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.OffboardingUserResetOnly, result["user_action"])
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("delete removes the user instead of updating it", func(t *testing.T) {
+		mockClient := newClient()
+		mockClient.On("DeleteUser", "offboard-user").Return(nil)
+
+		result, err := NewDeletionManager(newOpConfig(config.OffboardingUserDelete), mockClient).Run(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, config.OffboardingUserDelete, result["user_action"])
+		mockClient.AssertNotCalled(t, "UpdateUser", mock.Anything)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("delete treats an already deleted user as success", func(t *testing.T) {
+		mockClient := newClient()
+		mockClient.On("DeleteUser", "offboard-user").Return(&client.HTTPError{StatusCode: 404})
+
+		_, err := NewDeletionManager(newOpConfig(config.OffboardingUserDelete), mockClient).Run(context.Background())
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestDeletionManager_Run_OffboardingMultipleAppIDs(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:       "delete",
+		Shared:       true,
+		AppID:        "app-1,app-2",
+		LdapUsername: "offboard-user",
+		RoleName:     "offboard-user",
+		BaseRoles:    []string{"base-role"},
+	}
+
+	mockClient := new(MockNexusClient)
+	// The user is reset and their role removed once, however many AppIDs there are
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"some-role"}}, nil).Once()
+	mockClient.On("UpdateUser", mock.Anything).Return(nil).Once()
+	mockClient.On("DeleteRole", "offboard-user").Return(nil).Once()
+	mockClient.On("GetRepositories").Return([]client.Repository{
+		{Name: "npm-release-app-1"},
+		{Name: "maven-release-app-2"},
+		{Name: "npm-release-app-3"},
+	}, nil)
+	mockClient.On("GetPrivileges").Return([]client.Privilege{
+		{Name: "npm-release-app-1"},
+		{Name: "maven-release-app-2"},
+		{Name: "npm-release-app-3"},
+	}, nil)
+	mockClient.On("DeleteRepository", "npm-release-app-1").Return(nil)
+	mockClient.On("DeleteRepository", "maven-release-app-2").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "npm-release-app-1").Return([]client.Role{}, nil)
+	mockClient.On("DeletePrivilege", "npm-release-app-1").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "maven-release-app-2").Return([]client.Role{}, nil)
+	mockClient.On("DeletePrivilege", "maven-release-app-2").Return(nil)
+
+	result, err := NewDeletionManager(opConfig, mockClient).Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app-1", "app-2"}, result["app_ids"])
+	assert.Equal(t, []string{"maven-release-app-2", "npm-release-app-1"}, result["deleted_repositories"])
+	assert.Equal(t, []string{"maven-release-app-2", "npm-release-app-1"}, result["deleted_privileges"])
+	mockClient.AssertNotCalled(t, "DeleteRepository", "npm-release-app-3")
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeletionManager_Run_OffboardingCustomNaming(t *testing.T) {
+	// With "{appId}-{packageManager}-proxy" naming, the old hardcoded
+	// "-release-<appID>" suffix would have matched nothing.
+	opConfig := &config.OperationConfig{
+		Action:              "delete",
+		Shared:              true,
+		AppID:               "app-123",
+		LdapUsername:        "offboard-user",
+		BaseRoles:           []string{"base-role"},
+		OffboardingPatterns: config.OffboardingPatterns("{appId}-{packageManager}-proxy", "", "app-123", []string{"npm", "maven2"}),
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"some-role"}}, nil)
+	mockClient.On("UpdateUser", mock.Anything).Return(nil)
+	mockClient.On("DeleteRole", "offboard-user").Return(nil)
+	mockClient.On("GetRepositories").Return([]client.Repository{
+		{Name: "app-123-npm-proxy"},
+		{Name: "app-123-maven2-proxy"},
+		{Name: "app-1234-npm-proxy"},
+		{Name: "npm-release-app-123"},
+	}, nil)
+	mockClient.On("GetPrivileges").Return([]client.Privilege{
+		{Name: "app-123-npm-proxy"},
+		{Name: "other-priv"},
+	}, nil)
+	mockClient.On("DeleteRepository", "app-123-npm-proxy").Return(nil)
+	mockClient.On("DeleteRepository", "app-123-maven2-proxy").Return(nil)
+	mockClient.On("GetRolesByPrivilege", "app-123-npm-proxy").Return([]client.Role{}, nil)
+	mockClient.On("DeletePrivilege", "app-123-npm-proxy").Return(nil)
+
+	result, err := NewDeletionManager(opConfig, mockClient).Run(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app-123-maven2-proxy", "app-123-npm-proxy"}, result["deleted_repositories"])
+	assert.Equal(t, []string{"app-123-npm-proxy"}, result["deleted_privileges"])
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeletionManager_Run_OffboardingDeletesConcurrently(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:       "delete",
+		Shared:       true,
+		AppID:        "app-123",
+		LdapUsername: "offboard-user",
+		BaseRoles:    []string{"base-role"},
+	}
+
+	repos := []client.Repository{
+		{Name: "npm-release-app-123"},
+		{Name: "maven-release-app-123"},
+		{Name: "pypi-release-app-123"},
+		{Name: "docker-release-app-123"},
+	}
+
+	// Every repository deletion blocks until all of them are in flight together,
+	// which can only happen if they run concurrently.
+	var inFlight sync.WaitGroup
+	inFlight.Add(len(repos))
+	waitForAll := func(mock.Arguments) {
+		inFlight.Done()
+		inFlight.Wait()
+	}
+
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"some-role"}}, nil)
+	mockClient.On("UpdateUser", mock.Anything).Return(nil)
+	mockClient.On("DeleteRole", "offboard-user").Return(nil)
+	mockClient.On("GetRepositories").Return(repos, nil)
+	mockClient.On("DeleteRepository", "npm-release-app-123").Run(waitForAll).Return(nil)
+	mockClient.On("DeleteRepository", "maven-release-app-123").Run(waitForAll).Return(errors.New("locked"))
+	mockClient.On("DeleteRepository", "pypi-release-app-123").Run(waitForAll).Return(nil)
+	mockClient.On("DeleteRepository", "docker-release-app-123").Run(waitForAll).Return(errors.New("locked"))
+	mockClient.On("GetPrivileges").Return([]client.Privilege{{Name: "npm-release-app-123"}, {Name: "maven-release-app-123"}}, nil)
+	mockClient.On("GetRolesByPrivilege", "npm-release-app-123").Return([]client.Role{}, nil)
+	mockClient.On("DeletePrivilege", "npm-release-app-123").Return(nil)
+
+	done := make(chan struct{})
+	var (
+		result map[string]interface{}
+		err    error
+	)
+	go func() {
+		defer close(done)
+		result, err = NewDeletionManager(opConfig, mockClient).Run(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("repository deletions did not run concurrently")
+	}
+
+	// The partial failure fails the request, keeping the retriable cause
+	assert.ErrorContains(t, err, "2 repositories and 1 privileges could not be deleted")
+	assert.ErrorContains(t, err, "locked")
+	assert.Equal(t, 2, result["deleted_repository_count"])
+	assert.Equal(t, []string{"npm-release-app-123", "pypi-release-app-123"}, result["deleted_repositories"])
+	failed := result["failed_repositories"].([]FailedDeletion)
+	assert.Len(t, failed, 2)
+	assert.Equal(t, "docker-release-app-123", failed[0].Name)
+	assert.Contains(t, failed[0].Error, "locked")
+	assert.Equal(t, "maven-release-app-123", failed[1].Name)
+	assert.Equal(t, 1, result["deleted_privilege_count"])
+	// The privilege of a repository that is still there is kept
+	assert.Equal(t, []FailedDeletion{{Name: "maven-release-app-123", Error: "kept because its repository could not be deleted"}},
+		result["failed_privileges"])
+	mockClient.AssertNotCalled(t, "DeletePrivilege", "maven-release-app-123")
 	mockClient.AssertExpectations(t)
 }
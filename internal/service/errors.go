@@ -0,0 +1,11 @@
+// internal/service/errors.go
+package service
+
+import "errors"
+
+// Sentinel errors for failures that callers may want to tell apart from a broken
+// backend. The managers wrap them with context, so test for them with errors.Is.
+var (
+	// ErrUserNotFound reports that the Nexus user an operation targets does not exist.
+	ErrUserNotFound = errors.New("user not found")
+)
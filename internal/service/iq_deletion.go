@@ -1,8 +1,8 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"slices"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
@@ -23,71 +23,141 @@ func NewIQServerCleaner(opConfig *config.OperationConfig, iqClient client.IQClie
 }
 
 // CleanupUserFromOrganization removes the Owner role from the user in the organization.
-func (ic IQServerCleaner) CleanupUserFromOrganization() error {
+func (ic IQServerCleaner) CleanupUserFromOrganization(ctx context.Context) error {
 	if ic.opConfig.Shared && ic.opConfig.AppID != "" {
-		utils.WithComponent("iq_cleaner").Debug("Offboarding mode detected; including IQ Server owner cleanup",
+		utils.WithComponentContext(ctx, "iq_cleaner").Debug("Offboarding mode detected; including IQ Server owner cleanup",
 			zap.String("username", ic.opConfig.LdapUsername),
 			zap.String("app_id", ic.opConfig.AppID))
 	}
 
-	utils.WithComponent("iq_cleaner").Debug("Starting IQ Server user cleanup from organization",
+	utils.WithComponentContext(ctx, "iq_cleaner").Debug("Starting IQ Server user cleanup from organization",
 		zap.String("action", ic.opConfig.Action),
 		zap.String("username", ic.opConfig.LdapUsername),
 		zap.String("organization_id", ic.opConfig.OrganizationID))
 	if ic.opConfig.OrganizationID == "" {
-		utils.WithComponent("iq_cleaner").Debug("No organization_id; skipping IQ Server cleanup",
+		utils.WithComponentContext(ctx, "iq_cleaner").Debug("No organization_id; skipping IQ Server cleanup",
 			zap.String("username", ic.opConfig.LdapUsername))
 		return nil
 	}
-	removeOwner, err := ic.shouldRemoveOwnerRole()
-	if err != nil {
-		return err
+	// Once the Nexus account is deleted, nothing justifies keeping Owner
+	removeOwner := ic.userDeleted()
+	if !removeOwner {
+		var err error
+		if removeOwner, err = ic.shouldRemoveOwnerRole(ctx); err != nil {
+			return err
+		}
 	}
 	if !removeOwner {
-		utils.WithComponent("iq_cleaner").Debug("Skipping IQ Server Owner role removal (conditions not met)",
+		utils.WithComponentContext(ctx, "iq_cleaner").Debug("Skipping IQ Server Owner role removal (conditions not met)",
 			zap.String("username", ic.opConfig.LdapUsername))
 		return nil
 	}
-	if err := ic.iqClient.RemoveOwnerRoleFromUser(ic.opConfig); err != nil {
+	removed, err := ic.iqClient.RemoveOwnerRoleFromUser(ctx, ic.opConfig)
+	if err != nil {
 		return fmt.Errorf("remove owner role: %w", err)
 	}
-	utils.WithComponent("iq_cleaner").Info("Successfully removed Owner role from user in IQ Server organization",
+	var removedAt string
+	switch {
+	case removed:
+		removedAt = ic.opConfig.OrganizationID
+	case ic.opConfig.IQOwnerAncestorFallback:
+		if removedAt, err = ic.removeOwnerFromAncestors(ctx); err != nil {
+			return err
+		}
+	}
+	if removedAt == "" {
+		utils.WithComponentContext(ctx, "iq_cleaner").Info("User held no Owner role in IQ Server organization; nothing removed",
+			zap.String("username", ic.opConfig.LdapUsername),
+			zap.String("organization_id", ic.opConfig.OrganizationID))
+		return nil
+	}
+	utils.WithComponentContext(ctx, "iq_cleaner").Info("Successfully removed Owner role from user in IQ Server organization",
 		zap.String("username", ic.opConfig.LdapUsername),
-		zap.String("organization_id", ic.opConfig.OrganizationID))
+		zap.String("organization_id", ic.opConfig.OrganizationID),
+		zap.String("removed_at_organization_id", removedAt))
 	return nil
 }
 
-func (ic IQServerCleaner) shouldRemoveOwnerRole() (bool, error) {
+// removeOwnerFromAncestors tries Owner removal at each ancestor of the organization,
+// nearest first, and returns the ID of the one it took effect on, or "" if the user
+// held Owner at none of them.
+func (ic IQServerCleaner) removeOwnerFromAncestors(ctx context.Context) (string, error) {
+	ancestors, err := ic.iqClient.GetOrganizationAncestors(ctx, ic.opConfig.OrganizationID)
+	if err != nil {
+		return "", fmt.Errorf("remove owner role: %w", err)
+	}
+	for _, ancestor := range ancestors {
+		utils.WithComponentContext(ctx, "iq_cleaner").Debug("No Owner membership found; trying ancestor organization",
+			zap.String("username", ic.opConfig.LdapUsername),
+			zap.String("organization_id", ancestor))
+		ancestorConfig := *ic.opConfig
+		ancestorConfig.OrganizationID = ancestor
+		removed, err := ic.iqClient.RemoveOwnerRoleFromUser(ctx, &ancestorConfig)
+		if err != nil {
+			return "", fmt.Errorf("remove owner role: %w", err)
+		}
+		if removed {
+			return ancestor, nil
+		}
+	}
+	return "", nil
+}
+
+func (ic IQServerCleaner) shouldRemoveOwnerRole(ctx context.Context) (bool, error) {
 	if ic.nexusClient == nil {
 		return false, fmt.Errorf("evaluate owner role removal: nexus client not configured")
 	}
-	user, err := ic.nexusClient.GetUser(ic.opConfig.LdapUsername)
+	user, err := ic.nexusClient.GetUser(ctx, ic.opConfig.LdapUsername)
 	if err != nil {
 		return false, fmt.Errorf("evaluate owner role removal: get user '%s' failed: %w", ic.opConfig.LdapUsername, err)
 	}
 	if user == nil {
-		utils.WithComponent("iq_cleaner").Debug("User not found while evaluating IQ Server owner removal",
+		utils.WithComponentContext(ctx, "iq_cleaner").Debug("User not found while evaluating IQ Server owner removal",
 			zap.String("username", ic.opConfig.LdapUsername))
 		return false, nil
 	}
-	roles := user.Roles
+	return ic.decideOwnerRemoval(ctx, user.Roles)
+}
+
+// PlanOwnerRemoval reports whether cleanup would remove the Owner role, assuming
+// the user ends up holding roles. It reads from Nexus but changes nothing.
+func (ic IQServerCleaner) PlanOwnerRemoval(ctx context.Context, roles []string) (bool, error) {
+	if ic.opConfig.OrganizationID == "" {
+		return false, nil
+	}
+	if ic.userDeleted() {
+		return true, nil
+	}
+	if ic.nexusClient == nil {
+		return false, fmt.Errorf("evaluate owner role removal: nexus client not configured")
+	}
+	return ic.decideOwnerRemoval(ctx, roles)
+}
+
+// userDeleted reports whether offboarding deletes the Nexus user.
+func (ic IQServerCleaner) userDeleted() bool {
+	return ic.opConfig.Shared && ic.opConfig.AppID != "" &&
+		offboardingUserAction(ic.opConfig) == config.OffboardingUserDelete
+}
+
+// decideOwnerRemoval applies the Owner removal rules to the user's current roles.
+func (ic IQServerCleaner) decideOwnerRemoval(ctx context.Context, roles []string) (bool, error) {
+	caseInsensitive := ic.opConfig.CaseInsensitiveRoles
 	if ic.opConfig.RoleName != "" {
-		for i, r := range roles {
-			if r == ic.opConfig.RoleName {
-				roles = append(roles[:i], roles[i+1:]...)
-				break
-			}
-		}
+		roles = removeRole(roles, ic.opConfig.RoleName, caseInsensitive)
 	}
-	roleEngine := NewRoleDecisionEngine(ic.opConfig.BaseRoles, ic.opConfig.ExtraRoles)
+	roleEngine := NewRoleDecisionEngine(ic.opConfig.BaseRoles, ic.opConfig.ExtraRoles, ic.opConfig.ProtectedRoles)
+	roleEngine.SetCaseInsensitive(caseInsensitive)
+	sharedRole := ic.opConfig.SharedRole()
+	roleEngine.SetSharedRole(sharedRole)
 	roleEngine.SetAfterRemovalRoles(roles)
 	hasOtherRoles := roleEngine.HasOtherRoles()
-	shareRoleAssigned := slices.Contains(roles, "repositories.share")
+	shareRoleAssigned := roleEngine.contains(roles, sharedRole)
 	shareRoleEmpty := true
 	if shareRoleAssigned {
-		shareRole, err := ic.nexusClient.GetRole("repositories.share")
+		shareRole, err := ic.nexusClient.GetRole(ctx, sharedRole)
 		if err != nil {
-			return false, fmt.Errorf("evaluate owner role removal: get repositories.share role failed: %w", err)
+			return false, fmt.Errorf("evaluate owner role removal: get %s role failed: %w", sharedRole, err)
 		}
 		if shareRole != nil {
 			if len(shareRole.Privileges) > 0 {
@@ -101,14 +171,14 @@ func (ic IQServerCleaner) shouldRemoveOwnerRole() (bool, error) {
 	onlyBaseRole := len(ic.opConfig.BaseRoles) > 0 && len(roles) > 0
 	if onlyBaseRole {
 		for _, r := range roles {
-			if !slices.Contains(ic.opConfig.BaseRoles, r) {
+			if !roleEngine.contains(ic.opConfig.BaseRoles, r) && !roleEngine.IsProtected(r) {
 				onlyBaseRole = false
 				break
 			}
 		}
 	}
 	shouldRemove := !hasOtherRoles && shareRoleEmpty && onlyBaseRole
-	utils.WithComponent("iq_cleaner").Debug("IQ Server owner removal decision",
+	utils.WithComponentContext(ctx, "iq_cleaner").Debug("IQ Server owner removal decision",
 		zap.String("username", ic.opConfig.LdapUsername),
 		zap.Bool("has_other_roles", hasOtherRoles),
 		zap.Bool("share_role_assigned", shareRoleAssigned),
@@ -134,8 +204,8 @@ func NewIQDeletionManager(opConfig *config.OperationConfig, iqClient client.IQCl
 }
 
 // Run executes the cleanup workflow.
-func (dm IQDeletionManager) Run() (map[string]interface{}, error) {
-	if err := dm.cleaner.CleanupUserFromOrganization(); err != nil {
+func (dm IQDeletionManager) Run(ctx context.Context) (map[string]interface{}, error) {
+	if err := dm.cleaner.CleanupUserFromOrganization(ctx); err != nil {
 		return nil, err
 	}
 	return map[string]interface{}{
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
@@ -14,23 +15,50 @@ type MockIQClient struct {
 	mock.Mock
 }
 
-func (m *MockIQClient) GetRoles() ([]client.IQRole, error) {
+func (m *MockIQClient) GetRoles(ctx context.Context) ([]client.IQRole, error) {
 	args := m.Called()
 	return args.Get(0).([]client.IQRole), args.Error(1)
 }
 
-func (m *MockIQClient) FindOwnerRoleID() (string, error) {
+func (m *MockIQClient) FindOwnerRoleID(ctx context.Context) (string, error) {
 	args := m.Called()
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockIQClient) AddOwnerRoleToUser(opConfig *config.OperationConfig) error {
+func (m *MockIQClient) InvalidateRoleCache() {
+	m.Called()
+}
+
+func (m *MockIQClient) AddOwnerRoleToUser(ctx context.Context, opConfig *config.OperationConfig) error {
 	args := m.Called(opConfig)
 	return args.Error(0)
 }
 
-func (m *MockIQClient) RemoveOwnerRoleFromUser(opConfig *config.OperationConfig) error {
+func (m *MockIQClient) RemoveOwnerRoleFromUser(ctx context.Context, opConfig *config.OperationConfig) (bool, error) {
 	args := m.Called(opConfig)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIQClient) GetOrganizationAncestors(ctx context.Context, organizationID string) ([]string, error) {
+	args := m.Called(organizationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockIQClient) FindOrganization(ctx context.Context, name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockIQClient) CreateOrganization(ctx context.Context, name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockIQClient) Ping(ctx context.Context) error {
+	args := m.Called()
 	return args.Error(0)
 }
 
@@ -50,12 +78,143 @@ func TestIQServerCleaner_RemovesOwnerDuringOffboarding(t *testing.T) {
 	mockNexus.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"offboard-user", "base-role"}}, nil)
 
 	mockIQ := new(MockIQClient)
-	mockIQ.On("RemoveOwnerRoleFromUser", opConfig).Return(nil)
+	mockIQ.On("RemoveOwnerRoleFromUser", opConfig).Return(true, nil)
 
 	cleaner := NewIQServerCleaner(opConfig, mockIQ, mockNexus)
-	err := cleaner.CleanupUserFromOrganization()
+	err := cleaner.CleanupUserFromOrganization(context.Background())
+
+	assert.NoError(t, err)
+	mockNexus.AssertExpectations(t)
+	mockIQ.AssertExpectations(t)
+}
+
+func TestIQServerCleaner_PlanOwnerRemoval(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "delete",
+		LdapUsername:   "offboard-user",
+		OrganizationID: "org-123",
+		RoleName:       "offboard-user",
+		BaseRoles:      []string{"base-role"},
+	}
+
+	mockNexus := new(MockNexusClient)
+	mockIQ := new(MockIQClient)
+	cleaner := NewIQServerCleaner(opConfig, mockIQ, mockNexus)
+
+	remove, err := cleaner.PlanOwnerRemoval(context.Background(), []string{"base-role"})
+	assert.NoError(t, err)
+	assert.True(t, remove)
+
+	remove, err = cleaner.PlanOwnerRemoval(context.Background(), []string{"base-role", "project-role"})
+	assert.NoError(t, err)
+	assert.False(t, remove)
+
+	// Planning never touches IQ Server or looks the user up again
+	mockIQ.AssertNotCalled(t, "RemoveOwnerRoleFromUser", mock.Anything)
+	mockNexus.AssertNotCalled(t, "GetUser", mock.Anything)
+}
 
+func TestIQServerCleaner_CustomSharedRole(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:         "delete",
+		LdapUsername:   "offboard-user",
+		OrganizationID: "org-123",
+		RoleName:       "offboard-user",
+		SharedRoleName: "team.shared",
+		Shared:         true,
+		AppID:          "app-99",
+		BaseRoles:      []string{"base-role"},
+	}
+
+	mockNexus := new(MockNexusClient)
+	mockNexus.On("GetUser", "offboard-user").Return(&client.User{Roles: []string{"offboard-user", "base-role", "team.shared"}}, nil)
+	mockNexus.On("GetRole", "team.shared").Return(&client.Role{Privileges: []string{"shared-priv"}}, nil)
+	mockIQ := new(MockIQClient)
+
+	err := NewIQServerCleaner(opConfig, mockIQ, mockNexus).CleanupUserFromOrganization(context.Background())
+
+	// The configured shared role still grants access, so Owner is kept
 	assert.NoError(t, err)
 	mockNexus.AssertExpectations(t)
+	mockIQ.AssertNotCalled(t, "RemoveOwnerRoleFromUser", mock.Anything)
+}
+
+func TestIQServerCleaner_RemovesOwnerWhenUserDeleted(t *testing.T) {
+	opConfig := &config.OperationConfig{
+		Action:                "delete",
+		LdapUsername:          "offboard-user",
+		OrganizationID:        "org-123",
+		RoleName:              "offboard-user",
+		Shared:                true,
+		AppID:                 "app-99",
+		BaseRoles:             []string{"base-role"},
+		OffboardingUserAction: config.OffboardingUserDelete,
+	}
+
+	mockNexus := new(MockNexusClient)
+	mockIQ := new(MockIQClient)
+	mockIQ.On("RemoveOwnerRoleFromUser", opConfig).Return(true, nil)
+
+	err := NewIQServerCleaner(opConfig, mockIQ, mockNexus).CleanupUserFromOrganization(context.Background())
+
+	assert.NoError(t, err)
+	// The user no longer exists in Nexus, so their roles are not consulted
+	mockNexus.AssertNotCalled(t, "GetUser", mock.Anything)
 	mockIQ.AssertExpectations(t)
 }
+
+func TestIQServerCleaner_OwnerAncestorFallback(t *testing.T) {
+	newConfig := func() *config.OperationConfig {
+		return &config.OperationConfig{
+			Action:                  "delete",
+			LdapUsername:            "offboard-user",
+			OrganizationID:          "team",
+			Shared:                  true,
+			AppID:                   "app-99",
+			OffboardingUserAction:   config.OffboardingUserDelete,
+			IQOwnerAncestorFallback: true,
+		}
+	}
+	atOrg := func(orgID string) any {
+		return mock.MatchedBy(func(c *config.OperationConfig) bool { return c.OrganizationID == orgID })
+	}
+
+	t.Run("Leaf hit", func(t *testing.T) {
+		mockIQ := new(MockIQClient)
+		mockIQ.On("RemoveOwnerRoleFromUser", atOrg("team")).Return(true, nil)
+
+		err := NewIQServerCleaner(newConfig(), mockIQ, new(MockNexusClient)).CleanupUserFromOrganization(context.Background())
+
+		assert.NoError(t, err)
+		mockIQ.AssertExpectations(t)
+		mockIQ.AssertNotCalled(t, "GetOrganizationAncestors", mock.Anything)
+	})
+
+	t.Run("Ancestor fallback", func(t *testing.T) {
+		opConfig := newConfig()
+		mockIQ := new(MockIQClient)
+		mockIQ.On("RemoveOwnerRoleFromUser", atOrg("team")).Return(false, nil)
+		mockIQ.On("GetOrganizationAncestors", "team").Return([]string{"division", "company"}, nil)
+		mockIQ.On("RemoveOwnerRoleFromUser", atOrg("division")).Return(false, nil)
+		mockIQ.On("RemoveOwnerRoleFromUser", atOrg("company")).Return(true, nil)
+
+		err := NewIQServerCleaner(opConfig, mockIQ, new(MockNexusClient)).CleanupUserFromOrganization(context.Background())
+
+		assert.NoError(t, err)
+		mockIQ.AssertExpectations(t)
+		// The request's own config is left pointing at the leaf organization
+		assert.Equal(t, "team", opConfig.OrganizationID)
+	})
+
+	t.Run("Fallback disabled", func(t *testing.T) {
+		opConfig := newConfig()
+		opConfig.IQOwnerAncestorFallback = false
+		mockIQ := new(MockIQClient)
+		mockIQ.On("RemoveOwnerRoleFromUser", atOrg("team")).Return(false, nil)
+
+		err := NewIQServerCleaner(opConfig, mockIQ, new(MockNexusClient)).CleanupUserFromOrganization(context.Background())
+
+		assert.NoError(t, err)
+		mockIQ.AssertNotCalled(t, "GetOrganizationAncestors", mock.Anything)
+	})
+}
@@ -0,0 +1,155 @@
+// internal/service/orphans.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"go.uber.org/zap"
+)
+
+// OrphanScanner finds repositories named by the naming convention whose privilege no
+// role grants any more, which is what a failed or partial operation leaves behind.
+// Resources outside the convention are never looked at, let alone deleted.
+//
+// A repository is only deleted once two consecutive scans have both found it
+// orphaned, and no scan runs while busy reports operations in flight: a repository a
+// running operation has just created has no role yet either.
+type OrphanScanner struct {
+	nexusClient client.NexusClient
+	pattern     string
+	delete      bool
+	busy        func() bool
+
+	mu sync.Mutex
+	// suspects holds the orphans the previous scan found
+	suspects map[string]bool
+}
+
+// NewOrphanScanner creates an OrphanScanner for repositories matching pattern (see
+// config.Config.RepositoryPattern). With deleteOrphans the orphans are deleted,
+// otherwise they are only reported. busy, which may be nil, reports whether any
+// operation is in flight; scans are skipped while it does.
+func NewOrphanScanner(nexusClient client.NexusClient, pattern string, deleteOrphans bool, busy func() bool) *OrphanScanner {
+	return &OrphanScanner{nexusClient: nexusClient, pattern: pattern, delete: deleteOrphans, busy: busy}
+}
+
+// OrphanReport is the outcome of one scan. Orphans lists the orphaned repositories;
+// their privileges share their names. The slices are never nil.
+type OrphanReport struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	// Skipped is set when the scan did not run because operations were in flight
+	Skipped bool
+	// Scanned counts the repositories matching the naming convention
+	Scanned int
+	Orphans []string
+	// Pending lists the orphans the previous scan did not find; they are left alone
+	// until the next scan confirms them. Only filled in when deleting.
+	Pending []string
+	// Deleted reports whether the confirmed orphans were deleted rather than only reported
+	Deleted            bool
+	FailedRepositories []FailedDeletion
+	FailedPrivileges   []FailedDeletion
+}
+
+// Scan lists the repositories matching the naming convention and reports those that
+// no role references through their privilege. When configured to, it deletes those
+// the previous scan found orphaned too.
+func (s *OrphanScanner) Scan(ctx context.Context) (*OrphanReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := &OrphanReport{
+		StartedAt:          time.Now(),
+		Orphans:            []string{},
+		Pending:            []string{},
+		FailedRepositories: []FailedDeletion{},
+		FailedPrivileges:   []FailedDeletion{},
+	}
+	if s.busy != nil && s.busy() {
+		report.Skipped = true
+		return report, nil
+	}
+	repos, err := s.nexusClient.GetRepositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("orphan scan: failed to list repositories: %w", err)
+	}
+	for _, repo := range repos {
+		if !matchesPattern(s.pattern, repo.Name) {
+			continue
+		}
+		report.Scanned++
+		roles, err := s.nexusClient.GetRolesByPrivilege(ctx, repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("orphan scan: %w", err)
+		}
+		if len(roles) == 0 {
+			report.Orphans = append(report.Orphans, repo.Name)
+		}
+	}
+
+	previous := s.suspects
+	s.suspects = make(map[string]bool, len(report.Orphans))
+	var confirmed []string
+	for _, name := range report.Orphans {
+		s.suspects[name] = true
+		if previous[name] {
+			confirmed = append(confirmed, name)
+		} else if s.delete {
+			report.Pending = append(report.Pending, name)
+		}
+	}
+
+	// An operation may have started while the roles were being read
+	if s.delete && len(confirmed) > 0 && (s.busy == nil || !s.busy()) {
+		cleaner := NewNexusCleaner(&config.OperationConfig{Action: "orphan-cleanup"}, s.nexusClient)
+		report.Deleted = true
+		report.FailedRepositories, _ = deleteConcurrently(ctx, "repository", confirmed, cleaner.DeleteRepositoryByName)
+		report.FailedPrivileges, _ = deletePrivilegesOfDeletedRepositories(ctx, confirmed, report.FailedRepositories, cleaner.DeletePrivilegeByName)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// Run scans every interval until ctx is done, logging each report. A failed scan is
+// logged and retried at the next interval.
+func (s *OrphanScanner) Run(ctx context.Context, interval time.Duration) {
+	logger := utils.WithComponentContext(ctx, "orphan_scanner")
+	logger.Info("Orphan scan scheduled",
+		zap.Duration("interval", interval),
+		zap.String("pattern", s.pattern),
+		zap.Bool("delete", s.delete))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := s.Scan(ctx)
+		if err != nil {
+			logger.Error("Orphan scan failed", zap.Error(err))
+			continue
+		}
+		if report.Skipped {
+			logger.Info("Orphan scan skipped; operations are in flight")
+			continue
+		}
+		logger.Info("Orphan scan completed",
+			zap.Int("scanned", report.Scanned),
+			zap.Strings("orphans", report.Orphans),
+			zap.Strings("pending", report.Pending),
+			zap.Bool("deleted", report.Deleted),
+			zap.Any("failed_repositories", report.FailedRepositories),
+			zap.Any("failed_privileges", report.FailedPrivileges),
+			zap.Duration("duration", report.Duration))
+	}
+}
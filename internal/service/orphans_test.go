@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrphanScanner_Scan(t *testing.T) {
+	repos := []client.Repository{
+		{Name: "npm-release-app1"},
+		{Name: "maven-release-app2"},
+		{Name: "hand-made-proxy"},
+	}
+	setup := func() *MockNexusClient {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRepositories").Return(repos, nil)
+		mockClient.On("GetRolesByPrivilege", "npm-release-app1").Return([]client.Role{{ID: "user1"}}, nil)
+		mockClient.On("GetRolesByPrivilege", "maven-release-app2").Return([]client.Role{}, nil)
+		return mockClient
+	}
+
+	t.Run("Orphans are reported but not deleted by default", func(t *testing.T) {
+		mockClient := setup()
+
+		report, err := NewOrphanScanner(mockClient, "*-release-*", false, nil).Scan(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Scanned)
+		assert.Equal(t, []string{"maven-release-app2"}, report.Orphans)
+		assert.False(t, report.Deleted)
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+		mockClient.AssertNotCalled(t, "DeletePrivilege", mock.Anything)
+		// Resources outside the naming convention are never inspected
+		mockClient.AssertNotCalled(t, "GetRolesByPrivilege", "hand-made-proxy")
+	})
+
+	t.Run("Orphans are deleted once a second scan confirms them", func(t *testing.T) {
+		mockClient := setup()
+		mockClient.On("DeleteRepository", "maven-release-app2").Return(nil)
+		mockClient.On("DeletePrivilege", "maven-release-app2").Return(errors.New("nexus down"))
+		scanner := NewOrphanScanner(mockClient, "*-release-*", true, nil)
+
+		report, err := scanner.Scan(context.Background())
+
+		assert.NoError(t, err)
+		assert.False(t, report.Deleted)
+		assert.Equal(t, []string{"maven-release-app2"}, report.Pending)
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+
+		report, err = scanner.Scan(context.Background())
+
+		assert.NoError(t, err)
+		assert.True(t, report.Deleted)
+		assert.Empty(t, report.Pending)
+		assert.Empty(t, report.FailedRepositories)
+		assert.Equal(t, []FailedDeletion{{Name: "maven-release-app2", Error: "delete privilege 'maven-release-app2': nexus down"}}, report.FailedPrivileges)
+		mockClient.AssertNotCalled(t, "DeleteRepository", "npm-release-app1")
+		mockClient.AssertNotCalled(t, "DeleteRepository", "hand-made-proxy")
+	})
+
+	t.Run("An orphan a later scan no longer finds is not deleted", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRepositories").Return([]client.Repository{{Name: "npm-release-app1"}}, nil)
+		mockClient.On("GetRolesByPrivilege", "npm-release-app1").Return([]client.Role{}, nil).Once()
+		mockClient.On("GetRolesByPrivilege", "npm-release-app1").Return([]client.Role{{ID: "user1"}}, nil)
+		scanner := NewOrphanScanner(mockClient, "*-release-*", true, nil)
+
+		_, err := scanner.Scan(context.Background())
+		assert.NoError(t, err)
+		report, err := scanner.Scan(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, report.Orphans)
+		assert.False(t, report.Deleted)
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+	})
+
+	t.Run("Scan is skipped while operations are in flight", func(t *testing.T) {
+		mockClient := setup()
+		busy := true
+		scanner := NewOrphanScanner(mockClient, "*-release-*", true, func() bool { return busy })
+
+		report, err := scanner.Scan(context.Background())
+
+		assert.NoError(t, err)
+		assert.True(t, report.Skipped)
+		mockClient.AssertNotCalled(t, "GetRepositories")
+
+		busy = false
+		_, err = scanner.Scan(context.Background())
+		assert.NoError(t, err)
+		// An operation starting after the first scan holds off the deletion
+		busy = true
+		report, err = scanner.Scan(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, report.Skipped)
+		mockClient.AssertNotCalled(t, "DeleteRepository", mock.Anything)
+	})
+
+	t.Run("Listing failure fails the scan", func(t *testing.T) {
+		mockClient := new(MockNexusClient)
+		mockClient.On("GetRepositories").Return(nil, errors.New("nexus down"))
+
+		_, err := NewOrphanScanner(mockClient, "*-release-*", true, nil).Scan(context.Background())
+
+		assert.ErrorContains(t, err, "orphan scan")
+	})
+}
+
+func TestOrphanScanner_Run_StopsWithContext(t *testing.T) {
+	scanned := make(chan struct{}, 1)
+	mockClient := new(MockNexusClient)
+	mockClient.On("GetRepositories").Run(func(mock.Arguments) {
+		select {
+		case scanned <- struct{}{}:
+		default:
+		}
+	}).Return([]client.Repository{}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		NewOrphanScanner(mockClient, "*-release-*", false, nil).Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-scanned:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not scan")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
@@ -3,6 +3,7 @@ package service
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
@@ -23,40 +24,85 @@ func NewJobProgressTracker(jobStore *config.JobStore, jobID string) *JobProgress
 	}
 }
 
-// SetProcessing marks the job as processing.
+// SetProcessing marks the job as processing and records when processing started.
 func (jpt *JobProgressTracker) SetProcessing() {
 	_ = jpt.jobStore.UpdateJob(jpt.jobID, func(job *config.Job) {
+		startedAt := time.Now()
 		job.Status = config.JobStatusProcessing
+		job.StartedAt = &startedAt
 		job.Message = "Processing requests"
 	})
 }
 
-// Finalize marks a job as completed or failed with appropriate status and message.
-func (jpt *JobProgressTracker) Finalize(successful, failed, notProcessed, total int, failedRequests []config.FailedRequest) {
+// Outcome summarizes how the operations of a finished job turned out.
+type Outcome string
+
+const (
+	// OutcomeSucceeded means every operation succeeded.
+	OutcomeSucceeded Outcome = "succeeded"
+	// OutcomeFailed means no operation succeeded.
+	OutcomeFailed Outcome = "failed"
+	// OutcomePartial means some operations succeeded and some failed.
+	OutcomePartial Outcome = "partial"
+)
+
+// DetermineOutcome classifies a job from its success and failure counts.
+func DetermineOutcome(successful, failed int) Outcome {
+	switch {
+	case failed == 0:
+		return OutcomeSucceeded
+	case successful == 0:
+		return OutcomeFailed
+	default:
+		return OutcomePartial
+	}
+}
+
+// Finalize marks a job as completed or failed with appropriate status and message,
+// records how long it was processing, and returns the outcome so synchronous callers
+// can map it to a response. failures may be nil when there is nothing to record.
+func (jpt *JobProgressTracker) Finalize(successful, failed, notProcessed, total int, failures *config.FailureLog) Outcome {
+	outcome := DetermineOutcome(successful, failed)
+	var duration time.Duration
 	_ = jpt.jobStore.UpdateJob(jpt.jobID, func(job *config.Job) {
+		if job.StartedAt != nil {
+			duration = time.Since(*job.StartedAt)
+			job.DurationMs = duration.Milliseconds()
+		}
 		job.SuccessfulOperations = successful
 		job.FailedOperations = failed
 		job.NotProcessedOperations = notProcessed
-		job.FailedRequests = failedRequests
+		if failures != nil {
+			job.ErrorSummary = failures.Summary()
+			job.FailedRequests = failures.Details()
+			job.OmittedFailedRequests = failures.Omitted()
+		}
 
 		// Determine final status and message
-		if failed == 0 {
+		switch outcome {
+		case OutcomeSucceeded:
 			job.Status = config.JobStatusCompleted
 			job.Message = fmt.Sprintf("Successfully processed all %d requests", successful)
-		} else if successful == 0 {
+		case OutcomeFailed:
 			job.Status = config.JobStatusFailed
 			job.Message = fmt.Sprintf("All %d requests failed", failed)
-		} else {
+		default:
 			job.Status = config.JobStatusCompleted
 			job.Message = fmt.Sprintf("Processed %d of %d requests with %d errors", successful, total, failed)
 		}
+		if job.OmittedFailedRequests > 0 {
+			job.Message += fmt.Sprintf("; details kept for the first %d failures only", len(job.FailedRequests))
+		}
 	})
 
 	utils.Logger.Info("Job finalized",
 		zap.String("job_id", jpt.jobID),
+		zap.String("outcome", string(outcome)),
 		zap.Int("successful", successful),
 		zap.Int("failed", failed),
-		zap.Int("total", total))
+		zap.Int("total", total),
+		zap.Duration("duration", duration))
+	return outcome
 }
 
 // MarkFailed marks a job as failed when no valid requests exist.
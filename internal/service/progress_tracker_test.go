@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobProgressTracker_Finalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		successful int
+		failed     int
+		outcome    Outcome
+		status     config.JobStatus
+	}{
+		{"All succeeded", 3, 0, OutcomeSucceeded, config.JobStatusCompleted},
+		{"All failed", 0, 3, OutcomeFailed, config.JobStatusFailed},
+		{"Mixed", 2, 1, OutcomePartial, config.JobStatusCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobStore := config.NewJobStore()
+			jobStore.CreateJob("job-1", "create", tt.successful+tt.failed)
+			tracker := NewJobProgressTracker(jobStore, "job-1")
+
+			outcome := tracker.Finalize(tt.successful, tt.failed, 0, tt.successful+tt.failed, nil)
+
+			assert.Equal(t, tt.outcome, outcome)
+			job, ok := jobStore.GetJob("job-1")
+			assert.True(t, ok)
+			assert.Equal(t, tt.status, job.Status)
+		})
+	}
+}
+
+func TestJobProgressTracker_Duration(t *testing.T) {
+	jobStore := config.NewJobStore()
+	jobStore.CreateJob("job-1", "create", 1)
+	tracker := NewJobProgressTracker(jobStore, "job-1")
+
+	job, _ := jobStore.GetJob("job-1")
+	assert.Nil(t, job.StartedAt)
+
+	tracker.SetProcessing()
+	assert.NotNil(t, job.StartedAt)
+	assert.Zero(t, job.DurationMs)
+
+	time.Sleep(5 * time.Millisecond)
+	tracker.Finalize(1, 0, 0, 1, nil)
+	assert.GreaterOrEqual(t, job.DurationMs, int64(5))
+	assert.LessOrEqual(t, job.DurationMs, time.Since(*job.StartedAt).Milliseconds())
+}
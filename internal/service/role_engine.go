@@ -3,17 +3,24 @@ package service
 
 import (
 	"slices"
+	"strings"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 )
 
 // RoleDecisionEngine encapsulates the logic for determining final user roles.
 type RoleDecisionEngine struct {
-	baseRoles    []string
-	extraRoles   []string
-	afterRemoval []string
+	baseRoles       []string
+	extraRoles      []string
+	protectedRoles  []string
+	afterRemoval    []string
+	sharedRole      string
+	caseInsensitive bool
 }
 
 // NewRoleDecisionEngine creates a new role decision engine.
-func NewRoleDecisionEngine(baseRoles []string, extraRoles []string) *RoleDecisionEngine {
+// Protected roles are never removed from a user by the engine.
+func NewRoleDecisionEngine(baseRoles []string, extraRoles []string, protectedRoles []string) *RoleDecisionEngine {
 	// Filter empty extra roles
 	filteredExtra := make([]string, 0, len(extraRoles))
 	for _, r := range extraRoles {
@@ -30,12 +37,34 @@ func NewRoleDecisionEngine(baseRoles []string, extraRoles []string) *RoleDecisio
 		}
 	}
 
+	// Filter empty protected roles
+	filteredProtected := make([]string, 0, len(protectedRoles))
+	for _, r := range protectedRoles {
+		if r != "" {
+			filteredProtected = append(filteredProtected, r)
+		}
+	}
+
 	return &RoleDecisionEngine{
-		baseRoles:  filteredBase,
-		extraRoles: filteredExtra,
+		baseRoles:      filteredBase,
+		extraRoles:     filteredExtra,
+		protectedRoles: filteredProtected,
+		sharedRole:     config.DefaultSharedRoleName,
 	}
 }
 
+// SetCaseInsensitive controls whether role names are compared ignoring case.
+// Output roles always keep the casing they had on the user.
+func (rde *RoleDecisionEngine) SetCaseInsensitive(caseInsensitive bool) {
+	rde.caseInsensitive = caseInsensitive
+}
+
+// SetSharedRole names the role granting access to shared repositories, which does
+// not count as a project role.
+func (rde *RoleDecisionEngine) SetSharedRole(name string) {
+	rde.sharedRole = name
+}
+
 // SetAfterRemovalRoles sets the list of roles after the target role is removed.
 func (rde *RoleDecisionEngine) SetAfterRemovalRoles(roles []string) {
 	rde.afterRemoval = roles
@@ -44,8 +73,9 @@ func (rde *RoleDecisionEngine) SetAfterRemovalRoles(roles []string) {
 // DecideFinalRoles determines the final list of roles.
 // Logic:
 // 1. Base Roles are ALWAYS included.
-// 2. Extra Roles are kept ONLY if the user has "Other Roles" (active project roles).
-// 3. All other roles (project roles) are kept.
+// 2. Protected Roles the user holds are ALWAYS kept.
+// 3. Extra Roles are kept ONLY if the user has "Other Roles" (active project roles).
+// 4. All other roles (project roles) are kept.
 func (rde *RoleDecisionEngine) DecideFinalRoles() []string {
 	keepExtra := rde.HasOtherRoles()
 
@@ -53,51 +83,62 @@ func (rde *RoleDecisionEngine) DecideFinalRoles() []string {
 	finalSet := make(map[string]struct{})
 	finalRoles := make([]string, 0)
 
-	// 1. Always add Base Roles
+	// 1. Always add Base Roles, preferring the casing the user already has
 	for _, br := range rde.baseRoles {
-		if _, exists := finalSet[br]; !exists {
-			finalSet[br] = struct{}{}
-			finalRoles = append(finalRoles, br)
+		if _, exists := finalSet[rde.key(br)]; !exists {
+			finalSet[rde.key(br)] = struct{}{}
+			finalRoles = append(finalRoles, rde.userCasing(br))
 		}
 	}
 
 	// 2. Process remaining roles
 	for _, r := range rde.afterRemoval {
 		// Skip if it's already added (e.g. it was a base role)
-		if _, exists := finalSet[r]; exists {
+		if _, exists := finalSet[rde.key(r)]; exists {
+			continue
+		}
+
+		// Protected roles are never removed, even if also listed as extra roles
+		if rde.contains(rde.protectedRoles, r) {
+			finalSet[rde.key(r)] = struct{}{}
+			finalRoles = append(finalRoles, r)
 			continue
 		}
 
 		// If it is an Extra Role, check if we should keep it
-		if slices.Contains(rde.extraRoles, r) {
+		if rde.contains(rde.extraRoles, r) {
 			if keepExtra {
-				finalSet[r] = struct{}{}
+				finalSet[rde.key(r)] = struct{}{}
 				finalRoles = append(finalRoles, r)
 			}
 			continue
 		}
 
 		// It is a normal/project role, keep it
-		finalSet[r] = struct{}{}
+		finalSet[rde.key(r)] = struct{}{}
 		finalRoles = append(finalRoles, r)
 	}
 
 	return finalRoles
 }
 
-// HasOtherRoles checks if there are roles other than BaseRoles, repositories.share, or ExtraRoles.
+// HasOtherRoles checks if there are roles other than BaseRoles, ProtectedRoles, the shared role, or ExtraRoles.
 func (rde *RoleDecisionEngine) HasOtherRoles() bool {
 	for _, r := range rde.afterRemoval {
 		// Ignore if it is a Base Role
-		if slices.Contains(rde.baseRoles, r) {
+		if rde.contains(rde.baseRoles, r) {
+			continue
+		}
+		// Ignore if it is a Protected Role
+		if rde.contains(rde.protectedRoles, r) {
 			continue
 		}
 		// Ignore if it is Shared role
-		if r == "repositories.share" {
+		if rde.equal(r, rde.sharedRole) {
 			continue
 		}
 		// Ignore if it is an Extra Role
-		if slices.Contains(rde.extraRoles, r) {
+		if rde.contains(rde.extraRoles, r) {
 			continue
 		}
 		// If we are here, it's a specific project role
@@ -112,9 +153,60 @@ func (rde *RoleDecisionEngine) GetRemovedExtraRoles() []string {
 	removed := make([]string, 0)
 	finalRoles := rde.DecideFinalRoles()
 	for _, r := range rde.extraRoles {
-		if !slices.Contains(finalRoles, r) {
+		if !rde.contains(finalRoles, r) {
 			removed = append(removed, r)
 		}
 	}
 	return removed
 }
+
+// equal compares two role names honouring the case sensitivity setting.
+func (rde *RoleDecisionEngine) equal(a, b string) bool {
+	return roleNamesEqual(a, b, rde.caseInsensitive)
+}
+
+// contains reports whether role is present in roles honouring the case sensitivity setting.
+func (rde *RoleDecisionEngine) contains(roles []string, role string) bool {
+	return slices.ContainsFunc(roles, func(r string) bool { return rde.equal(r, role) })
+}
+
+// key normalizes a role name for set membership.
+func (rde *RoleDecisionEngine) key(role string) string {
+	if rde.caseInsensitive {
+		return strings.ToLower(role)
+	}
+	return role
+}
+
+// userCasing returns the user's spelling of role if they already hold it.
+func (rde *RoleDecisionEngine) userCasing(role string) string {
+	for _, r := range rde.afterRemoval {
+		if rde.equal(r, role) {
+			return r
+		}
+	}
+	return role
+}
+
+// roleNamesEqual compares role names exactly or, when caseInsensitive is set, ignoring case.
+func roleNamesEqual(a, b string, caseInsensitive bool) bool {
+	if caseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// IsProtected reports whether role is in the protected list.
+func (rde *RoleDecisionEngine) IsProtected(role string) bool {
+	return rde.contains(rde.protectedRoles, role)
+}
+
+// removeRole returns roles without the first entry matching target.
+func removeRole(roles []string, target string, caseInsensitive bool) []string {
+	for i, r := range roles {
+		if roleNamesEqual(r, target, caseInsensitive) {
+			return append(roles[:i], roles[i+1:]...)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleDecisionEngine_DecideFinalRoles(t *testing.T) {
+	t.Run("Extra roles removed when no other roles", func(t *testing.T) {
+		engine := NewRoleDecisionEngine([]string{"nx-admin"}, []string{"extra-role"}, nil)
+		engine.SetAfterRemovalRoles([]string{"nx-admin", "extra-role", "repositories.share"})
+
+		assert.False(t, engine.HasOtherRoles())
+		assert.Equal(t, []string{"nx-admin", "repositories.share"}, engine.DecideFinalRoles())
+	})
+
+	t.Run("Extra roles kept when project roles remain", func(t *testing.T) {
+		engine := NewRoleDecisionEngine([]string{"nx-admin"}, []string{"extra-role"}, nil)
+		engine.SetAfterRemovalRoles([]string{"extra-role", "project-role"})
+
+		assert.True(t, engine.HasOtherRoles())
+		assert.Equal(t, []string{"nx-admin", "extra-role", "project-role"}, engine.DecideFinalRoles())
+	})
+}
+
+func TestRoleDecisionEngine_CaseInsensitive(t *testing.T) {
+	userRoles := []string{"Nx-Admin", "EXTRA-ROLE", "Repositories.Share"}
+
+	t.Run("Exact matching treats mixed-case roles as project roles", func(t *testing.T) {
+		engine := NewRoleDecisionEngine([]string{"nx-admin"}, []string{"extra-role"}, nil)
+		engine.SetAfterRemovalRoles(userRoles)
+
+		assert.True(t, engine.HasOtherRoles())
+		assert.Equal(t, []string{"nx-admin", "Nx-Admin", "EXTRA-ROLE", "Repositories.Share"}, engine.DecideFinalRoles())
+	})
+
+	t.Run("Case-insensitive matching recognizes base and extra roles", func(t *testing.T) {
+		engine := NewRoleDecisionEngine([]string{"nx-admin"}, []string{"extra-role"}, nil)
+		engine.SetCaseInsensitive(true)
+		engine.SetAfterRemovalRoles(userRoles)
+
+		assert.False(t, engine.HasOtherRoles())
+		// Original casing from the user is preserved; the extra role is dropped
+		assert.Equal(t, []string{"Nx-Admin", "Repositories.Share"}, engine.DecideFinalRoles())
+		assert.Equal(t, []string{"extra-role"}, engine.GetRemovedExtraRoles())
+	})
+}
+
+func TestRemoveRole(t *testing.T) {
+	assert.Equal(t, []string{"base-role"}, removeRole([]string{"App-Role", "base-role"}, "app-role", true))
+	assert.Equal(t, []string{"App-Role", "base-role"}, removeRole([]string{"App-Role", "base-role"}, "app-role", false))
+}
+
+func TestRoleDecisionEngine_ProtectedRoles(t *testing.T) {
+	engine := NewRoleDecisionEngine([]string{"nx-admin"}, []string{"extra-role", "security-admin"}, []string{"security-admin"})
+	engine.SetAfterRemovalRoles([]string{"nx-admin", "security-admin", "extra-role"})
+
+	// The protected role does not count as an active project role...
+	assert.False(t, engine.HasOtherRoles())
+	// ...but it survives even though extra roles are being stripped
+	assert.Equal(t, []string{"nx-admin", "security-admin"}, engine.DecideFinalRoles())
+	assert.True(t, engine.IsProtected("security-admin"))
+}
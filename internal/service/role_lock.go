@@ -0,0 +1,53 @@
+// internal/service/role_lock.go
+package service
+
+import "sync"
+
+// keyedMutex serializes work per key while letting different keys proceed in parallel.
+// Entries are reference-counted and removed once no goroutine holds or waits on them.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refCountedMutex
+}
+
+type refCountedMutex struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*refCountedMutex)}
+}
+
+// Lock acquires the mutex for key and returns the function that releases it.
+func (km *keyedMutex) Lock(key string) func() {
+	km.mu.Lock()
+	entry, ok := km.locks[key]
+	if !ok {
+		entry = &refCountedMutex{}
+		km.locks[key] = entry
+	}
+	entry.refs++
+	km.mu.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		km.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}
+
+// size returns the number of keys currently tracked.
+func (km *keyedMutex) size() int {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return len(km.locks)
+}
+
+// roleLocks serializes read-modify-write updates to the same Nexus role.
+var roleLocks = newKeyedMutex()
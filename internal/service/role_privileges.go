@@ -0,0 +1,143 @@
+// internal/service/role_privileges.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anmicius0/sonatype-resource-automation/internal/client"
+	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"go.uber.org/zap"
+)
+
+// ErrRoleNotFound is returned by AddPrivilegesToRole when the role does not exist yet.
+var ErrRoleNotFound = errors.New("role not found")
+
+// AddPrivilegesToRole adds every privilege the role lacks with a single GET and PUT,
+// and returns the ones it added. The update is conditional on the role's ETag, so a
+// concurrent change by another instance causes the role to be re-fetched and the
+// additions re-applied, up to maxRoleUpdateAttempts times.
+func AddPrivilegesToRole(ctx context.Context, nexus client.NexusClient, roleName string, privileges []string) ([]string, error) {
+	unlock := roleLocks.Lock(roleName)
+	defer unlock()
+	return addPrivilegesToRoleLocked(ctx, nexus, roleName, privileges)
+}
+
+// addPrivilegesToRoleLocked is AddPrivilegesToRole for callers holding the role's lock.
+func addPrivilegesToRoleLocked(ctx context.Context, nexus client.NexusClient, roleName string, privileges []string) ([]string, error) {
+	for attempt := 1; ; attempt++ {
+		role, err := nexus.GetRole(ctx, roleName)
+		if err != nil {
+			var httpErr *client.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+				return nil, fmt.Errorf("get role failed: %w", err)
+			}
+			role = nil
+		}
+		if role == nil {
+			return nil, ErrRoleNotFound
+		}
+
+		var added []string
+		for _, privilege := range privileges {
+			if !slices.Contains(role.Privileges, privilege) && !slices.Contains(added, privilege) {
+				added = append(added, privilege)
+			}
+		}
+		if len(added) == 0 {
+			return nil, nil
+		}
+		role.Privileges = append(role.Privileges, added...)
+		if err := nexus.UpdateRole(ctx, role); err != nil {
+			if client.IsConflict(err) && attempt < maxRoleUpdateAttempts {
+				utils.WithComponentContext(ctx, "nexus_creator").Warn("Role update conflicted with a concurrent change, retrying",
+					zap.String("role_name", roleName),
+					zap.Strings("privileges", added),
+					zap.Int("attempt", attempt))
+				continue
+			}
+			return nil, fmt.Errorf("update role failed: %w", err)
+		}
+		return added, nil
+	}
+}
+
+// privilegeBatch is the set of privileges waiting to be added to one role. done is
+// closed once the batch is flushed, after added and err are set.
+type privilegeBatch struct {
+	privileges []string
+	done       chan struct{}
+	added      []string
+	err        error
+}
+
+// privilegeFlushTimeout bounds one flush of a privilegeBatch, which no single caller's
+// context governs.
+const privilegeFlushTimeout = time.Minute
+
+// privilegeCoalescer merges concurrent additions to the same role, such as those of
+// one batch job's workers, into a single role update. The first caller for a role
+// starts a flush that runs once it holds the role's lock; callers arriving while it
+// waits join the batch instead of queueing for their own GET and PUT.
+type privilegeCoalescer struct {
+	mu      sync.Mutex
+	pending map[string]*privilegeBatch
+}
+
+func newPrivilegeCoalescer() *privilegeCoalescer {
+	return &privilegeCoalescer{pending: make(map[string]*privilegeBatch)}
+}
+
+// add adds privilege to the role together with any concurrent additions, and returns
+// the privileges the shared update added. The batch is shared by callers of other jobs
+// and requests, so it is flushed detached from their contexts, bounded by
+// privilegeFlushTimeout. A caller whose ctx ends first stops waiting and gets its
+// error, while the flush carries on for the others.
+func (pc *privilegeCoalescer) add(ctx context.Context, nexus client.NexusClient, roleName, privilege string) ([]string, error) {
+	pc.mu.Lock()
+	batch, joined := pc.pending[roleName]
+	if !joined {
+		batch = &privilegeBatch{done: make(chan struct{})}
+		pc.pending[roleName] = batch
+		go pc.flush(context.WithoutCancel(ctx), nexus, roleName, batch)
+	}
+	batch.privileges = append(batch.privileges, privilege)
+	pc.mu.Unlock()
+
+	select {
+	case <-batch.done:
+		return batch.added, batch.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush adds the batch's privileges to the role once it holds the role's lock.
+func (pc *privilegeCoalescer) flush(ctx context.Context, nexus client.NexusClient, roleName string, batch *privilegeBatch) {
+	ctx, cancel := context.WithTimeout(ctx, privilegeFlushTimeout)
+	defer cancel()
+	defer close(batch.done)
+
+	unlock := roleLocks.Lock(roleName)
+	defer unlock()
+	// Later callers start the next batch
+	pc.mu.Lock()
+	delete(pc.pending, roleName)
+	privileges := batch.privileges
+	pc.mu.Unlock()
+
+	if len(privileges) > 1 {
+		utils.WithComponentContext(ctx, "nexus_creator").Debug("Coalescing privilege additions into one role update",
+			zap.String("role_name", roleName),
+			zap.Strings("privileges", privileges))
+	}
+	batch.added, batch.err = addPrivilegesToRoleLocked(ctx, nexus, roleName, privileges)
+}
+
+// privilegeAdds coalesces the privilege additions of concurrent creations.
+var privilegeAdds = newPrivilegeCoalescer()
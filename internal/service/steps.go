@@ -0,0 +1,58 @@
+// internal/service/steps.go
+package service
+
+import (
+	"context"
+	"slices"
+)
+
+// Steps of an operation, as reported by StepProgress.
+const (
+	StepRepository = "repository"
+	StepPrivilege  = "privilege"
+	StepRole       = "role"
+	StepUser       = "user"
+	StepIQ         = "iq"
+)
+
+// StepProgress records how far an operation got: the steps that completed, in order,
+// and the step that failed, if any.
+type StepProgress struct {
+	CompletedSteps []string
+	FailedStep     string
+}
+
+// Complete records step as completed.
+func (p *StepProgress) Complete(step string) {
+	p.CompletedSteps = append(p.CompletedSteps, step)
+}
+
+// Fail records step as the one that stopped the operation.
+func (p *StepProgress) Fail(step string) {
+	p.FailedStep = step
+}
+
+// Record marks step completed when err is nil and failed otherwise, returning err.
+func (p *StepProgress) Record(step string, err error) error {
+	if err != nil {
+		p.Fail(step)
+		return err
+	}
+	p.Complete(step)
+	return nil
+}
+
+// snapshot returns a copy of p whose CompletedSteps is never nil.
+func (p StepProgress) snapshot() StepProgress {
+	completed := slices.Clone(p.CompletedSteps)
+	if completed == nil {
+		completed = []string{}
+	}
+	return StepProgress{CompletedSteps: completed, FailedStep: p.FailedStep}
+}
+
+// namedStep is one step of a manager's workflow.
+type namedStep struct {
+	name string
+	run  func(context.Context) error
+}
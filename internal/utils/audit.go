@@ -0,0 +1,100 @@
+// internal/utils/audit.go
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	AuditLogFileName = "audit.log"
+	// AuditStdout as AUDIT_LOG_FILE writes audit records to standard output
+	AuditStdout = "stdout"
+)
+
+// AuditLogger receives one JSON record per create/delete operation. It is a no-op
+// until InitAudit or a test replaces it.
+var AuditLogger = zap.NewNop()
+
+// AuditRecord is the audit trail entry for a single operation.
+type AuditRecord struct {
+	Action         string
+	Repository     string
+	Role           string
+	User           string
+	OrganizationID string
+	PackageManager string
+	AppID          string
+	// Result is "success" or "failure"; Error holds the failure reason
+	Result string
+	Error  string
+}
+
+// InitAudit opens the audit destination named by `AUDIT_LOG_FILE` (default:
+// audit.log). The file is rotated with the same LOG_MAX_* settings as the app log;
+// `stdout` writes to standard output and `AUDIT_LOG_FILE=""` disables auditing.
+func InitAudit() error {
+	dest, ok := os.LookupEnv("AUDIT_LOG_FILE")
+	if !ok {
+		dest = AuditLogFileName
+	}
+	switch dest {
+	case "":
+		AuditLogger = zap.NewNop()
+	case AuditStdout:
+		AuditLogger = NewAuditLogger(os.Stdout)
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_APPEND|os.O_WRONLY, LogFileMode)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", dest, err)
+		}
+		f.Close()
+		AuditLogger = NewAuditLogger(&lumberjack.Logger{
+			Filename:   dest,
+			MaxSize:    envInt("LOG_MAX_SIZE", DefaultLogMaxSizeMB),
+			MaxBackups: envInt("LOG_MAX_BACKUPS", DefaultLogMaxBackups),
+			MaxAge:     envInt("LOG_MAX_AGE", DefaultLogMaxAgeDays),
+		})
+	}
+	if Logger != nil {
+		Logger.Info("audit logging initialized", zap.String("audit_log_file", dest))
+	}
+	return nil
+}
+
+// NewAuditLogger returns a logger that writes audit records to w as JSON lines
+// with a `timestamp` and no level or message.
+func NewAuditLogger(w io.Writer) *zap.Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.LevelKey = zapcore.OmitKey
+	encoderConfig.MessageKey = zapcore.OmitKey
+	encoderConfig.CallerKey = zapcore.OmitKey
+	encoderConfig.StacktraceKey = zapcore.OmitKey
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(w), zapcore.InfoLevel)
+	return zap.New(core)
+}
+
+// Audit writes record to the audit log, adding the request ID, job ID and caller from ctx.
+func Audit(ctx context.Context, record AuditRecord) {
+	AuditLogger.Info("",
+		zap.String(FieldRequestID, RequestIDFromContext(ctx)),
+		zap.String(FieldJobID, JobIDFromContext(ctx)),
+		zap.String(FieldCaller, CallerFromContext(ctx)),
+		zap.String(FieldAction, record.Action),
+		zap.String(FieldRepo, record.Repository),
+		zap.String("role", record.Role),
+		zap.String("user", record.User),
+		zap.String("organization_id", record.OrganizationID),
+		zap.String("package_manager", record.PackageManager),
+		zap.String("app_id", record.AppID),
+		zap.String("result", record.Result),
+		zap.String("error", record.Error))
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	var buf bytes.Buffer
+	originalAudit := AuditLogger
+	AuditLogger = NewAuditLogger(&buf)
+	defer func() { AuditLogger = originalAudit }()
+
+	ctx := ContextWithCaller(ContextWithJobID(ContextWithRequestID(context.Background(), "req-1"), "job-1"), "10.0.0.5")
+	Audit(ctx, AuditRecord{
+		Action:         "create",
+		Repository:     "npm-release-app1",
+		Role:           "user1",
+		User:           "user1",
+		OrganizationID: "org-id-1",
+		PackageManager: "npm",
+		AppID:          "app1",
+		Result:         "success",
+	})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 1)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(lines[0], &record))
+	assert.NotEmpty(t, record["timestamp"])
+	delete(record, "timestamp")
+	assert.Equal(t, map[string]any{
+		"request_id":      "req-1",
+		"job_id":          "job-1",
+		"caller":          "10.0.0.5",
+		"action":          "create",
+		"repo":            "npm-release-app1",
+		"role":            "user1",
+		"user":            "user1",
+		"organization_id": "org-id-1",
+		"package_manager": "npm",
+		"app_id":          "app1",
+		"result":          "success",
+		"error":           "",
+	}, record)
+}
@@ -0,0 +1,79 @@
+// internal/utils/context.go
+package utils
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// contextKey namespaces the values this package stores in a context.
+type contextKey string
+
+const (
+	requestIDKey contextKey = FieldRequestID
+	jobIDKey     contextKey = FieldJobID
+	callerKey    contextKey = FieldCaller
+)
+
+// ContextWithRequestID returns a copy of ctx carrying the API request ID.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or "" when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// ContextWithJobID returns a copy of ctx carrying the job ID.
+func ContextWithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey, jobID)
+}
+
+// JobIDFromContext returns the job ID stored in ctx, or "" when there is none.
+func JobIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey).(string)
+	return id
+}
+
+// ContextWithCaller returns a copy of ctx carrying the identity of the authenticated caller.
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// CallerFromContext returns the caller stored in ctx, or "" when there is none.
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey).(string)
+	return caller
+}
+
+// LoggerFromContext returns the logger pre-bound with the `request_id` and `job_id`
+// carried by ctx, so log lines from concurrent jobs can be correlated.
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if Logger == nil {
+		return nil
+	}
+	var fields []zap.Field
+	if id := RequestIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String(FieldRequestID, id))
+	}
+	if id := JobIDFromContext(ctx); id != "" {
+		fields = append(fields, zap.String(FieldJobID, id))
+	}
+	if len(fields) == 0 {
+		return Logger
+	}
+	return Logger.With(fields...)
+}
+
+// WithComponentContext is WithComponent for code that has a context: the logger is
+// also bound with the correlation IDs from LoggerFromContext.
+func WithComponentContext(ctx context.Context, component string) *zap.Logger {
+	logger := LoggerFromContext(ctx)
+	if logger == nil {
+		return nil
+	}
+	return logger.With(zap.String(FieldComponent, component))
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	observedZapCore, observedLogs := observer.New(zap.InfoLevel)
+	originalLogger := Logger
+	Logger = zap.New(observedZapCore)
+	defer func() { Logger = originalLogger }()
+
+	ctx := ContextWithJobID(ContextWithRequestID(context.Background(), "req-1"), "job-1")
+	assert.Equal(t, "req-1", RequestIDFromContext(ctx))
+	assert.Equal(t, "job-1", JobIDFromContext(ctx))
+
+	LoggerFromContext(ctx).Info("with ids")
+	WithComponentContext(ctx, "test_component").Info("with component")
+	LoggerFromContext(context.Background()).Info("without ids")
+
+	logs := observedLogs.All()
+	assert.Equal(t, 3, len(logs))
+	assert.Equal(t, map[string]interface{}{"request_id": "req-1", "job_id": "job-1"}, logs[0].ContextMap())
+	assert.Equal(t, map[string]interface{}{"request_id": "req-1", "job_id": "job-1", "component": "test_component"}, logs[1].ContextMap())
+	assert.Empty(t, logs[2].ContextMap())
+}
@@ -1,11 +1,14 @@
 package utils
 
 const (
-	FieldJobID  = "job_id"
-	FieldAction = "action"
-	FieldPath   = "path"
-	FieldSignal = "signal"
-	FieldHost   = "host"
-	FieldPort   = "port"
-	FieldRepo   = "repo"
+	FieldJobID     = "job_id"
+	FieldRequestID = "request_id"
+	FieldCaller    = "caller"
+	FieldAction    = "action"
+	FieldPath      = "path"
+	FieldSignal    = "signal"
+	FieldHost      = "host"
+	FieldPort      = "port"
+	FieldRepo      = "repo"
+	FieldComponent = "component"
 )
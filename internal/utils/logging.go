@@ -4,24 +4,57 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
 	LogFileName = "app.log"
 	LogFileMode = 0644
+
+	// Rotation defaults, overridable via LOG_MAX_SIZE, LOG_MAX_BACKUPS and LOG_MAX_AGE
+	DefaultLogMaxSizeMB  = 100
+	DefaultLogMaxBackups = 5
+	DefaultLogMaxAgeDays = 30
+
+	// ComponentLevelPrefix prefixes the variables overriding the level of one component,
+	// e.g. LOG_LEVEL_nexus_cleaner=debug
+	ComponentLevelPrefix = "LOG_LEVEL_"
+)
+
+// Accepted LOG_FORMAT values: the encoding of the stdout log. The file is always JSON.
+const (
+	LogFormatConsole = "console"
+	LogFormatJSON    = "json"
+)
+
+// Accepted LOG_OUTPUT values: where the application log is written.
+const (
+	LogOutputStdout = "stdout"
+	LogOutputFile   = "file"
+	LogOutputBoth   = "both"
 )
 
 var Logger *zap.Logger
 
-// Init configures zap to write to both console and a log file.
+// Init configures zap to write to stdout and, unless disabled, a rotating log file.
+// The file path comes from `LOG_FILE` (default: app.log); setting `LOG_FILE=""`
+// disables file logging. Existing log files are appended to, not truncated.
+// `LOG_OUTPUT` (stdout, file or both; default both) picks the destinations and
+// `LOG_FORMAT` (console or json; default console) the encoding of stdout.
 // This should be called once at application startup.
 func Init() error {
-	logFile, err := os.OpenFile(LogFileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, LogFileMode)
-	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", LogFileName, err)
+	format := envChoice("LOG_FORMAT", LogFormatConsole)
+	if format != LogFormatConsole && format != LogFormatJSON {
+		return fmt.Errorf("LOG_FORMAT '%s' is invalid (allowed: %s, %s)", format, LogFormatConsole, LogFormatJSON)
+	}
+	output := envChoice("LOG_OUTPUT", LogOutputBoth)
+	if output != LogOutputStdout && output != LogOutputFile && output != LogOutputBoth {
+		return fmt.Errorf("LOG_OUTPUT '%s' is invalid (allowed: %s, %s, %s)", output, LogOutputStdout, LogOutputFile, LogOutputBoth)
 	}
 
 	// Configure encoder
@@ -29,8 +62,11 @@ func Init() error {
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 
-	// Create cores for console and file
-	consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
+	// Create cores for stdout and file
+	stdoutEncoder := zapcore.NewConsoleEncoder(encoderConfig)
+	if format == LogFormatJSON {
+		stdoutEncoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
 	fileEncoder := zapcore.NewJSONEncoder(encoderConfig)
 
 	// Determine log level from environment variable `LOG_LEVEL` (default: info)
@@ -45,21 +81,149 @@ func Init() error {
 		}
 	}
 
-	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level)
-	fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(logFile), level)
+	// The cores accept the most verbose level any component uses; componentLevelCore
+	// then filters each entry by the level of the component that logged it
+	overrides := componentLevels(os.Environ())
+	coreLevel := level
+	for _, l := range overrides {
+		coreLevel = min(coreLevel, l)
+	}
+
+	var cores []zapcore.Core
+	if output != LogOutputFile {
+		cores = append(cores, zapcore.NewCore(stdoutEncoder, zapcore.AddSync(os.Stdout), coreLevel))
+	}
+
+	logFile, ok := os.LookupEnv("LOG_FILE")
+	if !ok {
+		logFile = LogFileName
+	}
+	if output == LogOutputStdout {
+		logFile = ""
+	} else if output == LogOutputFile && logFile == "" {
+		return fmt.Errorf("LOG_OUTPUT=%s needs a LOG_FILE", LogOutputFile)
+	}
+	if logFile != "" {
+		// Touch the file up front so permission problems surface at startup
+		// rather than on the first write.
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, LogFileMode)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", logFile, err)
+		}
+		f.Close()
+
+		rotator := &lumberjack.Logger{
+			Filename:   logFile,
+			MaxSize:    envInt("LOG_MAX_SIZE", DefaultLogMaxSizeMB),
+			MaxBackups: envInt("LOG_MAX_BACKUPS", DefaultLogMaxBackups),
+			MaxAge:     envInt("LOG_MAX_AGE", DefaultLogMaxAgeDays),
+		}
+		cores = append(cores, zapcore.NewCore(fileEncoder, zapcore.AddSync(rotator), coreLevel))
+	}
 
 	// Combine cores
-	core := zapcore.NewTee(consoleCore, fileCore)
+	core := newComponentLevelCore(zapcore.NewTee(cores...), level, overrides)
 	Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 
 	// Emit a startup message describing the chosen log level.
-	Logger.Info("logging initialized", zap.String("log_level", level.String()))
+	Logger.Info("logging initialized",
+		zap.String("log_level", level.String()),
+		zap.String("log_format", format),
+		zap.String("log_output", output),
+		zap.String("log_file", logFile))
 
 	return nil
 }
 
-// Sync flushes any buffered log entries.
+// componentLevels parses the LOG_LEVEL_<component> overrides found in environ, keyed
+// by lower-case component name. Invalid levels are reported and ignored.
+func componentLevels(environ []string) map[string]zapcore.Level {
+	levels := make(map[string]zapcore.Level)
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		component, ok := strings.CutPrefix(key, ComponentLevelPrefix)
+		if !ok || component == "" {
+			continue
+		}
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			fmt.Printf("unknown %s '%s', ignoring it\n", key, value)
+			continue
+		}
+		levels[strings.ToLower(component)] = level
+	}
+	return levels
+}
+
+// componentLevelCore filters entries by level: the global level, or the override of
+// the component once a `component` field is bound by WithComponent. The wrapped core
+// must accept every level it lets through.
+type componentLevelCore struct {
+	zapcore.Core
+	level     zapcore.Level
+	global    zapcore.Level
+	overrides map[string]zapcore.Level
+}
+
+func newComponentLevelCore(core zapcore.Core, global zapcore.Level, overrides map[string]zapcore.Level) zapcore.Core {
+	return &componentLevelCore{Core: core, level: global, global: global, overrides: overrides}
+}
+
+// Enabled reports whether entries at lvl pass the core's current level.
+func (c *componentLevelCore) Enabled(lvl zapcore.Level) bool {
+	return c.level.Enabled(lvl)
+}
+
+// With binds fields to the wrapped core and switches to the level of a bound component.
+func (c *componentLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	next := *c
+	next.Core = c.Core.With(fields)
+	for _, f := range fields {
+		if f.Key == FieldComponent && f.Type == zapcore.StringType {
+			next.level = c.global
+			if override, ok := c.overrides[strings.ToLower(f.String)]; ok {
+				next.level = override
+			}
+		}
+	}
+	return &next
+}
+
+// Check adds the wrapped core to ce when ent passes the core's current level.
+func (c *componentLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// envChoice reads a lower-cased setting from the environment, falling back to def
+// when the variable is unset or empty.
+func envChoice(key, def string) string {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		return strings.ToLower(raw)
+	}
+	return def
+}
+
+// envInt reads a non-negative integer from the environment, falling back to def
+// when the variable is unset or invalid.
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		fmt.Printf("invalid %s '%s', defaulting to %d\n", key, raw, def)
+		return def
+	}
+	return n
+}
+
+// Sync flushes any buffered log and audit entries.
 func Sync() error {
+	_ = AuditLogger.Sync()
 	if Logger != nil {
 		return Logger.Sync()
 	}
@@ -72,5 +236,5 @@ func WithComponent(component string) *zap.Logger {
 	if Logger == nil {
 		return nil
 	}
-	return Logger.With(zap.String("component", component))
+	return Logger.With(zap.String(FieldComponent, component))
 }
@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
 )
 
@@ -38,3 +43,146 @@ func TestWithComponent_NilLogger(t *testing.T) {
 	componentLogger := WithComponent("test_component")
 	assert.Nil(t, componentLogger)
 }
+
+func TestWithComponent_LevelOverride(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+
+	// The observer accepts everything; the wrapping core applies the levels
+	observedZapCore, observedLogs := observer.New(zapcore.DebugLevel)
+	overrides := componentLevels([]string{"LOG_LEVEL_nexus_cleaner=debug", "LOG_LEVEL_bogus=loud", "PATH=/bin"})
+	assert.Equal(t, map[string]zapcore.Level{"nexus_cleaner": zapcore.DebugLevel}, overrides)
+	Logger = zap.New(newComponentLevelCore(observedZapCore, zapcore.InfoLevel, overrides))
+
+	WithComponent("nexus_cleaner").Debug("cleaner debug")
+	WithComponent("nexus_creator").Debug("creator debug")
+	Logger.Debug("global debug")
+	WithComponent("nexus_creator").Info("creator info")
+
+	var messages []string
+	for _, entry := range observedLogs.All() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"cleaner debug", "creator info"}, messages)
+}
+
+func TestInit_ComponentLevel(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+
+	logPath := filepath.Join(t.TempDir(), "service.log")
+	t.Setenv("LOG_FILE", logPath)
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("LOG_LEVEL_http_client", "debug")
+
+	assert.NoError(t, Init())
+	WithComponent("http_client").Debug("client debug")
+	Logger.Debug("global debug")
+	_ = Sync()
+
+	content, err := os.ReadFile(logPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "client debug")
+	assert.NotContains(t, string(content), "global debug")
+}
+
+func TestInit_AppendsToLogFile(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+
+	logPath := filepath.Join(t.TempDir(), "service.log")
+	assert.NoError(t, os.WriteFile(logPath, []byte("previous run\n"), LogFileMode))
+	t.Setenv("LOG_FILE", logPath)
+
+	assert.NoError(t, Init())
+	Logger.Info("new run")
+	_ = Sync()
+
+	content, err := os.ReadFile(logPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "previous run")
+	assert.Contains(t, string(content), "new run")
+}
+
+func TestInit_FileLoggingDisabled(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("LOG_FILE", "")
+
+	assert.NoError(t, Init())
+	Logger.Info("console only")
+
+	_, err := os.Stat(filepath.Join(dir, LogFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInit_JSONToStdout(t *testing.T) {
+	originalLogger := Logger
+	originalStdout := os.Stdout
+	defer func() {
+		Logger = originalLogger
+		os.Stdout = originalStdout
+	}()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", "stdout")
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stdout = w
+
+	assert.NoError(t, Init())
+	WithComponent("nexus_creator").Info("structured", zap.String("repo", "npm-release-app1"))
+	_ = Logger.Sync()
+	os.Stdout = originalStdout
+	assert.NoError(t, w.Close())
+
+	var entries []map[string]any
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var entry map[string]any
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+		entries = append(entries, entry)
+	}
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "logging initialized", entries[0]["msg"])
+		assert.Equal(t, "structured", entries[1]["msg"])
+		assert.Equal(t, "nexus_creator", entries[1]["component"])
+		assert.Equal(t, "npm-release-app1", entries[1]["repo"])
+	}
+
+	// No log file is written with LOG_OUTPUT=stdout
+	_, err = os.Stat(filepath.Join(dir, LogFileName))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInit_InvalidOutputSettings(t *testing.T) {
+	originalLogger := Logger
+	defer func() { Logger = originalLogger }()
+
+	t.Setenv("LOG_FORMAT", "xml")
+	assert.ErrorContains(t, Init(), "LOG_FORMAT 'xml' is invalid")
+
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("LOG_OUTPUT", "syslog")
+	assert.ErrorContains(t, Init(), "LOG_OUTPUT 'syslog' is invalid")
+
+	t.Setenv("LOG_OUTPUT", "file")
+	t.Setenv("LOG_FILE", "")
+	assert.ErrorContains(t, Init(), "needs a LOG_FILE")
+}
+
+func TestEnvInt(t *testing.T) {
+	t.Setenv("LOG_MAX_SIZE", "42")
+	assert.Equal(t, 42, envInt("LOG_MAX_SIZE", 1))
+
+	t.Setenv("LOG_MAX_SIZE", "not-a-number")
+	assert.Equal(t, 1, envInt("LOG_MAX_SIZE", 1))
+
+	t.Setenv("LOG_MAX_SIZE", "")
+	assert.Equal(t, 1, envInt("LOG_MAX_SIZE", 1))
+}
@@ -0,0 +1,21 @@
+// internal/utils/redact.go
+package utils
+
+import "go.uber.org/zap"
+
+// RedactedValue stands in for a secret in logs and API responses.
+const RedactedValue = "[REDACTED]"
+
+// Redact hides a secret while still showing whether one was supplied: a non-empty
+// secret becomes RedactedValue and an empty one stays empty.
+func Redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+// Secret is a zap field whose value is redacted.
+func Secret(key, secret string) zap.Field {
+	return zap.String(key, Redact(secret))
+}
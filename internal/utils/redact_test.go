@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, RedactedValue, Redact("hunter2"))
+	assert.Equal(t, "", Redact(""))
+	assert.Equal(t, RedactedValue, Secret("remote_password", "hunter2").String)
+}
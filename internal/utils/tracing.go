@@ -0,0 +1,62 @@
+// internal/utils/tracing.go
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// TracerName identifies spans emitted by this service.
+	TracerName = "github.com/anmicius0/sonatype-resource-automation"
+	// ServiceName is reported as the `service.name` resource attribute.
+	ServiceName = "sonatype-resource-automation"
+)
+
+// InitTracing configures the global OpenTelemetry tracer provider.
+// When `OTEL_EXPORTER_OTLP_ENDPOINT` is unset, tracing stays a no-op and the
+// returned shutdown function does nothing. Call after Init so the logger is ready.
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		Logger.Info("tracing initialized", zap.Bool("tracing_enabled", false))
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The exporter reads OTEL_EXPORTER_OTLP_* variables (endpoint, headers, TLS) itself.
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	Logger.Info("tracing initialized",
+		zap.Bool("tracing_enabled", true),
+		zap.String("otlp_endpoint", endpoint))
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the service tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
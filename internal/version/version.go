@@ -0,0 +1,32 @@
+// internal/version/version.go
+// Package version exposes build metadata injected at link time, e.g.:
+//
+//	go build -ldflags "-X github.com/anmicius0/sonatype-resource-automation/internal/version.Version=v1.2.3"
+package version
+
+import "runtime"
+
+// Set via -ldflags -X; the defaults identify an unstamped development build.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	GoVersion string
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
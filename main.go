@@ -13,7 +13,9 @@ import (
 	"github.com/anmicius0/sonatype-resource-automation/internal/client"
 	"github.com/anmicius0/sonatype-resource-automation/internal/config"
 	"github.com/anmicius0/sonatype-resource-automation/internal/server"
+	"github.com/anmicius0/sonatype-resource-automation/internal/service"
 	"github.com/anmicius0/sonatype-resource-automation/internal/utils"
+	"github.com/anmicius0/sonatype-resource-automation/internal/version"
 	"go.uber.org/zap"
 )
 
@@ -24,6 +26,22 @@ func main() {
 		os.Exit(1)
 	}
 	defer utils.Sync()
+	if err := utils.InitAudit(); err != nil {
+		utils.Logger.Fatal("Failed to initialize audit logging", zap.Error(err))
+	}
+
+	// Initialize tracing (no-op unless an OTLP endpoint is configured)
+	shutdownTracing, err := utils.InitTracing(context.Background())
+	if err != nil {
+		utils.Logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.DefaultShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			utils.Logger.Error("Tracing shutdown error", zap.Error(err))
+		}
+	}()
 
 	// Load configuration
 	appConfig, err := config.Load()
@@ -36,17 +54,48 @@ func main() {
 	jobStore := config.NewJobStore()
 
 	// Initialize clients and batch manager
-	nexusClient := client.NewNexusClient(appConfig.NexusURL, appConfig.NexusUsername, appConfig.NexusPassword, appConfig.PackageManagers)
-	iqClient := client.NewIQServerClient(appConfig.IQServerURL, appConfig.IQServerUsername, appConfig.IQServerPassword)
+	transport := client.WithTransportSettings(client.TransportSettings{
+		MaxIdleConns:        appConfig.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost: appConfig.HTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     appConfig.HTTPIdleConnTimeout,
+		KeepAlive:           appConfig.HTTPKeepAlive,
+	})
+	nexusClient := client.NewNexusClient(appConfig.NexusURL, appConfig.NexusUsername, appConfig.NexusPassword, appConfig.NexusTimeout, appConfig.PackageManagers, transport)
+	iqClient := client.NewIQServerClient(appConfig.IQServerURL, appConfig.IQServerUsername, appConfig.IQServerPassword, appConfig.IQServerTimeout, appConfig.IQOwnerRoleName, transport)
 	batchManager := server.NewBatchManager(appConfig, jobStore, nexusClient, iqClient)
 
+	// Scan for orphaned repositories in the background when enabled
+	scanCtx, stopScan := context.WithCancel(context.Background())
+	defer stopScan()
+	if appConfig.OrphanScanInterval > 0 {
+		scanner := service.NewOrphanScanner(nexusClient, appConfig.RepositoryPattern(), appConfig.OrphanScanDelete, batchManager.Busy)
+		go scanner.Run(scanCtx, appConfig.OrphanScanInterval)
+	}
+
+	// Flip to ready once the startup checks pass; until then mutating endpoints return 503
+	startupCtx, stopStartup := context.WithCancel(context.Background())
+	defer stopStartup()
+	go func() {
+		if appConfig.StartupBackendCheck {
+			if err := batchManager.AwaitBackends(startupCtx, config.StartupCheckInterval); err != nil {
+				return
+			}
+		}
+		batchManager.MarkStarted()
+	}()
+
+	// Redeliver operation hook events the receiver failed to accept
+	go batchManager.RunHookRetries(scanCtx)
+
 	// Setup HTTP server
 	router := server.NewRouter(appConfig, jobStore, batchManager)
-	startServer(router, appConfig)
+	startServer(router, appConfig, batchManager)
 }
 
-// startServer binds the HTTP server and handles graceful shutdown signals.
-func startServer(router http.Handler, appConfig *config.Config) {
+// startServer binds the HTTP server and handles graceful shutdown signals: open
+// connections get appConfig.ShutdownTimeout to finish, then running batch jobs get
+// appConfig.JobDrainTimeout.
+func startServer(router http.Handler, appConfig *config.Config, batchManager *server.BatchManager) {
 	portStr := strconv.Itoa(appConfig.Port)
 	addr := fmt.Sprintf("%s:%s", appConfig.APIHost, portStr)
 
@@ -61,23 +110,37 @@ func startServer(router http.Handler, appConfig *config.Config) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		sig := <-sigChan
 		utils.Logger.Info("Shutdown signal received", zap.String(utils.FieldSignal, sig.String()))
-		ctx, cancel := context.WithTimeout(context.Background(), config.DefaultShutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), appConfig.ShutdownTimeout)
 		defer cancel()
 		if err := httpServer.Shutdown(ctx); err != nil {
 			utils.Logger.Error("Server shutdown error", zap.Error(err))
 		}
+
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), appConfig.JobDrainTimeout)
+		defer cancelDrain()
+		if err := batchManager.Drain(drainCtx); err != nil {
+			utils.Logger.Warn("Jobs still running after drain timeout",
+				zap.Int("running_jobs", batchManager.RunningJobs()),
+				zap.Duration("timeout", appConfig.JobDrainTimeout))
+		}
 	}()
 
 	utils.Logger.Info("Server starting",
 		zap.String(utils.FieldHost, appConfig.APIHost),
-		zap.String(utils.FieldPort, portStr))
+		zap.String(utils.FieldPort, portStr),
+		zap.String("version", version.Version),
+		zap.String("commit", version.Commit))
 
 	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		utils.Logger.Fatal("Server failed to start", zap.Error(err))
 	}
 
+	// ListenAndServe returns as soon as shutdown begins; wait for the drain to end
+	<-stopped
 	utils.Logger.Info("Server stopped")
 }